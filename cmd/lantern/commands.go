@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
+)
+
+// commands are the subcommands that run instead of an archive extraction.
+// Each returns the process exit code.
+var commands = map[string]func(args []string) int{
+	"compose":   runComposeCommand,
+	"map":       runMapCommand,
+	"palette":   runPaletteCommand,
+	"query":     runQueryCommand,
+	"thumbnail": runThumbnailCommand,
+}
+
+// runCommand runs the subcommand named by the first argument.
+// Returns false if the argument is not a subcommand.
+func runCommand(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		return 0, false
+	}
+
+	return command(args[1:]), true
+}
+
+// initCommand creates the logger and loads the settings for a subcommand.
+func initCommand(settingsFile string) (*logger.FileLogger, *config.Settings, error) {
+	log, err := logger.NewFileLogger(logFile, logger.VerbosityInfo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	settings := config.NewSettings(settingsFile, log)
+	if settingsFile != "" {
+		if err := settings.Initialize(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Could not load settings file: %v\n", err)
+		}
+	}
+
+	log.SetVerbosity(logger.Verbosity(settings.LoggerVerbosity))
+	return log, settings, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/eq"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/exporters"
+)
+
+// runComposeCommand exports a character holding equipment models as a single glTF file
+// to Exports/<character>/Composed/. The equipment is parented to the attach point bones
+// so it follows the character animations.
+func runComposeCommand(args []string) int {
+	flags := flag.NewFlagSet("compose", flag.ExitOnError)
+	settingsFile := flags.String("settings", "", "Path to settings file (optional)")
+	character := flags.String("character", "", "Character model (e.g., hum)")
+	characterArchive := flags.String("character-archive", "global_chr", "Archive containing the character model")
+	primary := flags.String("primary", "", "Equipment model held in the right hand (e.g., it10)")
+	secondary := flags.String("secondary", "", "Equipment model held in the left hand")
+	shield := flags.String("shield", "", "Equipment model worn as a shield")
+	animationArchives := flags.String("animations", "", "Comma separated character archives to retarget shared animations from (or characters for all)")
+	flags.Usage = func() {
+		fmt.Println("Usage: lantern compose [flags]")
+		fmt.Println("")
+		fmt.Println("Flags:")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *character == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return 1
+	}
+
+	log, settings, err := initCommand(*settingsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer log.Close()
+
+	start := time.Now()
+
+	characterModels, err := eq.LoadModelArchive(*characterArchive, log, settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load character archive: %v\n", err)
+		return 1
+	}
+
+	actor := characterModels.FindActor(*character)
+	if actor == nil {
+		fmt.Fprintf(os.Stderr, "Archive %s has no character %s\n", characterModels.Name, *character)
+		return 1
+	}
+
+	if actor.SkeletonReference == nil || actor.SkeletonReference.SkeletonHierarchy == nil {
+		fmt.Fprintf(os.Stderr, "Character %s has no skeleton\n", *character)
+		return 1
+	}
+
+	if *animationArchives != "" {
+		library, err := eq.LoadAnimationLibrary(strings.Split(*animationArchives, ","), log, settings)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load animations: %v\n", err)
+			return 1
+		}
+
+		added := library.Retarget(actor.SkeletonReference.SkeletonHierarchy)
+		fmt.Printf("Retargeted %d animations onto %s\n", len(added), *character)
+	}
+
+	characterName := strings.ToLower(*character)
+	folder := exportDir + characterName + "/Composed/"
+	characterModels.WriteTextures(folder+"Textures/", log)
+
+	equipment := eq.NewEquipmentLoader(log, settings)
+	var attachments []exporters.Attachment
+	nameParts := []string{characterName}
+
+	items := []struct {
+		model       string
+		attachPoint string
+	}{
+		{*primary, exporters.AttachPointPrimary},
+		{*secondary, exporters.AttachPointSecondary},
+		{*shield, exporters.AttachPointShield},
+	}
+
+	for _, item := range items {
+		if item.model == "" {
+			continue
+		}
+
+		itemActor, itemModels, err := equipment.FindActor(item.model)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to find equipment: %v\n", err)
+			return 1
+		}
+
+		itemModels.WriteTextures(folder+"Textures/", log)
+		attachments = append(attachments, exporters.Attachment{Actor: itemActor, AttachPoint: item.attachPoint})
+		nameParts = append(nameParts, strings.ToLower(item.model))
+	}
+
+	name := strings.Join(nameParts, "_")
+	exportPath, err := exporters.ExportComposedActor(actor, attachments, settings, folder, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export composed character: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Composed character written to %s (%.2fs)\n", exportPath, time.Since(start).Seconds())
+	return 0
+}
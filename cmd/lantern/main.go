@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/tmyhres/LanternGoExtract/pkg/config"
@@ -19,6 +20,11 @@ const (
 )
 
 func main() {
+	// Subcommands parse their own flags
+	if code, ok := runCommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
+
 	// Define command line flags
 	var (
 		archiveName  string
@@ -26,7 +32,7 @@ func main() {
 		showHelp     bool
 	)
 
-	flag.StringVar(&archiveName, "archive", "", "Archive name to extract (filename/shortname/all/zones/characters/equipment/sounds)")
+	flag.StringVar(&archiveName, "archive", "", "Archive name to extract (filename/shortname/all/zones/characters/equipment/sounds/clientdata/music)")
 	flag.StringVar(&settingsFile, "settings", "", "Path to settings file (optional)")
 	flag.BoolVar(&showHelp, "help", false, "Show help message")
 	flag.Parse()
@@ -84,7 +90,7 @@ func main() {
 		log.LogWarning(fmt.Sprintf("Failed to copy client data: %v", err))
 	}
 
-	if err := copyMusic(archiveName, log, settings); err != nil {
+	if err := copyMusic(archiveName, exportDir, log, settings); err != nil {
 		log.LogWarning(fmt.Sprintf("Failed to copy music: %v", err))
 	}
 
@@ -98,15 +104,29 @@ func printUsage() {
 	fmt.Println("")
 	fmt.Println("Usage: lantern <archive>")
 	fmt.Println("       lantern -archive=<archive>")
+	fmt.Println("       lantern compose [flags]")
+	fmt.Println("       lantern map [flags] <zone>")
+	fmt.Println("       lantern palette [flags] <action> <bitmap> [palette] [output]")
+	fmt.Println("       lantern query [flags] <zone> [query]")
+	fmt.Println("       lantern thumbnail [flags] [archive...]")
 	fmt.Println("")
 	fmt.Println("Archive options:")
 	fmt.Println("  <filename>   - Extract a specific archive file (e.g., gfaydark.s3d)")
 	fmt.Println("  <shortname>  - Extract zone by shortname (e.g., gfaydark)")
-	fmt.Println("  all          - Extract all valid archives")
+	fmt.Println("  all          - Extract all valid archives, client data and music")
 	fmt.Println("  zones        - Extract all zone archives")
 	fmt.Println("  characters   - Extract all character archives")
 	fmt.Println("  equipment    - Extract all equipment archives")
 	fmt.Println("  sounds       - Extract all sound archives")
+	fmt.Println("  clientdata   - Copy the files listed in ClientDataToCopy (intermediate format only)")
+	fmt.Println("  music        - Copy XMI music files (requires CopyMusic)")
+	fmt.Println("")
+	fmt.Println("Commands:")
+	fmt.Println("  compose      - Export a character holding equipment as a single glTF file")
+	fmt.Println("  map          - Render top-down height, relief and color maps and in-game map files of a zone")
+	fmt.Println("  palette      - Detect the transparent index, export, swap or remap the palette of an 8-bit bitmap")
+	fmt.Println("  query        - Answer line of sight, raycast, ground height and sphere sweep queries for a zone")
+	fmt.Println("  thumbnail    - Render a PNG image of every character, equipment and object model")
 	fmt.Println("")
 	fmt.Println("Flags:")
 	flag.PrintDefaults()
@@ -117,50 +137,21 @@ func extractArchive(filePath, exportPath string, log logger.Logger, settings *co
 	fmt.Printf("Extracting %s\n", filePath)
 	log.LogInfo(fmt.Sprintf("Extracting archive: %s", filePath))
 
-	// Convert config.Settings to eq.Settings
-	eqSettings := &eq.Settings{
-		EverQuestDirectory:             settings.EverQuestDirectory,
-		RawS3dExtract:                  settings.RawS3dExtract,
-		ExportSoundsToSingleFolder:     settings.ExportSoundsToSingleFolder,
-		ExportCharactersToSingleFolder: settings.ExportCharactersToSingleFolder,
-		ExportEquipmentToSingleFolder:  settings.ExportEquipmentToSingleFolder,
-		ExportZoneWithObjects:          settings.ExportZoneWithObjects,
-		ModelExportFormat:              settings.ModelExportFormat,
-	}
-
-	if err := eq.Extract(filePath, exportPath, log, eqSettings); err != nil {
+	if err := eq.Extract(filePath, exportPath, log, settings); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// copyClientData copies client data files to the export directory.
-// TODO: Implement actual copy logic.
+// copyClientData copies the client data files listed in ClientDataToCopy to the export directory.
+// Files are only copied for "all" or "clientdata" extractions.
 func copyClientData(archiveName, exportPath string, log logger.Logger, settings *config.Settings) error {
-	// Only copy for "all" or "clientdata" extractions
-	if archiveName != "all" && archiveName != "clientdata" {
-		return nil
-	}
-
-	log.LogInfo("Copying client data files")
-	// TODO: Implement ClientDataCopier.Copy equivalent
-	return nil
+	return eq.CopyClientData(strings.ToLower(archiveName), exportPath, log, settings)
 }
 
-// copyMusic copies music files to the export directory.
-// TODO: Implement actual copy logic.
-func copyMusic(archiveName string, log logger.Logger, settings *config.Settings) error {
-	if !settings.CopyMusic {
-		return nil
-	}
-
-	// Only copy for "all" or "music" extractions
-	if archiveName != "all" && archiveName != "music" {
-		return nil
-	}
-
-	log.LogInfo("Copying music files")
-	// TODO: Implement MusicCopier.Copy equivalent
-	return nil
+// copyMusic copies XMI music files to the export directory when CopyMusic is enabled.
+// Files are only copied for "all" or "music" extractions.
+func copyMusic(archiveName, exportPath string, log logger.Logger, settings *config.Settings) error {
+	return eq.CopyMusicToFolder(strings.ToLower(archiveName), exportPath, log, settings)
 }
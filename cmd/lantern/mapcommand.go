@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"time"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/eq"
+	"github.com/tmyhres/LanternGoExtract/pkg/zonemap"
+)
+
+// runMapCommand rasterizes a zone from the top down and writes its height, relief and color maps
+// and the in-game map files to Exports/<zone>/Map/.
+func runMapCommand(args []string) int {
+	defaults := zonemap.DefaultOptions()
+
+	flags := flag.NewFlagSet("map", flag.ExitOnError)
+	settingsFile := flags.String("settings", "", "Path to settings file (optional)")
+	resolution := flags.Int("resolution", defaults.Resolution, "Size of the longest image side in pixels")
+	minZ := flags.Float64("min-z", math.Inf(-1), "Ignore geometry below this height")
+	maxZ := flags.Float64("max-z", math.Inf(1), "Ignore geometry above this height")
+	colorMode := flags.String("color", "texture", "Color image source (texture/vertex)")
+	flags.Usage = func() {
+		fmt.Println("Usage: lantern map [flags] <zone>")
+		fmt.Println("")
+		fmt.Println("Flags:")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	options := defaults
+	options.Resolution = *resolution
+	options.MinZ = float32(math.Max(*minZ, -math.MaxFloat32))
+	options.MaxZ = float32(math.Min(*maxZ, math.MaxFloat32))
+
+	switch *colorMode {
+	case "texture":
+		options.ColorMode = zonemap.ColorModeTexture
+	case "vertex":
+		options.ColorMode = zonemap.ColorModeVertex
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown color mode: %s\n", *colorMode)
+		return 1
+	}
+
+	log, settings, err := initCommand(*settingsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer log.Close()
+
+	start := time.Now()
+
+	zone, err := eq.LoadZone(flags.Arg(0), log, settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load zone: %v\n", err)
+		return 1
+	}
+
+	var textures map[string]image.Image
+	if options.ColorMode == zonemap.ColorModeTexture {
+		textures = zone.LoadTextures(log)
+	}
+
+	meshes := zone.Wld.GetMeshes()
+	m := zonemap.Rasterize(meshes, textures, options)
+	if m == nil {
+		fmt.Fprintf(os.Stderr, "Zone %s has no geometry in the height range\n", zone.ShortName)
+		return 1
+	}
+
+	volumes := zone.GetRegionVolumes()
+	m.AddRegionOverlays(volumes)
+
+	folder := exportDir + zone.ShortName + "/Map/"
+	images := map[string]image.Image{
+		"_height.png": m.HeightImage(),
+		"_relief.png": m.ReliefImage(),
+		"_color.png":  m.ColorImage(),
+	}
+
+	for suffix, img := range images {
+		if err := zonemap.WritePng(folder+zone.ShortName+suffix, img); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write map image: %v\n", err)
+			return 1
+		}
+	}
+
+	if err := m.WriteHeightExr(folder + zone.ShortName + "_height.exr"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write height map: %v\n", err)
+		return 1
+	}
+
+	if err := zonemap.BuildMapFile(meshes, volumes, options).WriteFiles(folder, zone.ShortName); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write map files: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Map written to %s (%dx%d, %.2f units per pixel, %.2fs)\n",
+		folder, m.Width, m.Height, m.UnitsPerPixel, time.Since(start).Seconds())
+	return 0
+}
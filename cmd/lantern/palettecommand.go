@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
+)
+
+// runPaletteCommand exports, swaps and remaps the palettes of 8-bit bitmaps, e.g. to recolor armor
+// textures extracted with RawS3dExtract while keeping them in the format the client reads.
+func runPaletteCommand(args []string) int {
+	flags := flag.NewFlagSet("palette", flag.ExitOnError)
+	transparent := flags.String("transparent", "auto", "Transparent index of the bitmap (auto, none or an index)")
+	targetTransparent := flags.Int("target-transparent", -1, "Transparent index in the new palette (default: same as the bitmap)")
+	flags.Usage = func() {
+		fmt.Println("Usage: lantern palette [flags] detect <bitmap>")
+		fmt.Println("       lantern palette [flags] export <bitmap> <palette>")
+		fmt.Println("       lantern palette [flags] swap <bitmap> <palette> <output>")
+		fmt.Println("       lantern palette [flags] remap <bitmap> <palette> <output>")
+		fmt.Println("")
+		fmt.Println("Palettes are JASC .pal or GIMP .gpl files. Swap replaces the palette colors and keeps")
+		fmt.Println("every pixel index. Remap moves every pixel to the closest color of the new palette.")
+		fmt.Println("")
+		fmt.Println("Flags:")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	argCounts := map[string]int{"detect": 2, "export": 3, "swap": 4, "remap": 4}
+	action := flags.Arg(0)
+	if count, ok := argCounts[action]; !ok || flags.NArg() != count {
+		flags.Usage()
+		return 1
+	}
+
+	img, err := loadPalettedBitmap(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	sourceTransparent, err := getTransparentIndex(img, *transparent)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch action {
+	case "detect":
+		if sourceTransparent < 0 {
+			fmt.Println("No transparent index")
+			return 0
+		}
+		r, g, b, _ := img.GetPalette()[sourceTransparent].RGBA()
+		fmt.Printf("Transparent index %d (%d, %d, %d)\n", sourceTransparent, r>>8, g>>8, b>>8)
+		return 0
+
+	case "export":
+		if err := infrastructure.SavePaletteFile(flags.Arg(2), img.GetPalette()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Wrote %d colors to %s\n", len(img.GetPalette()), flags.Arg(2))
+		return 0
+	}
+
+	palette, err := infrastructure.LoadPaletteFile(flags.Arg(2))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if action == "swap" {
+		err = img.SetPalette(palette)
+	} else {
+		target := *targetTransparent
+		if target < 0 && sourceTransparent < len(palette) {
+			target = sourceTransparent
+		}
+		err = img.RemapToPalette(palette, sourceTransparent, target)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if err := img.WriteBmpFile(flags.Arg(3)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("Wrote %s\n", flags.Arg(3))
+	return 0
+}
+
+// loadPalettedBitmap reads an 8-bit bitmap file.
+func loadPalettedBitmap(filePath string) (*infrastructure.EqBmp, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bitmap: %w", err)
+	}
+
+	img, err := infrastructure.NewEqBmpFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if !img.IsPaletted() {
+		return nil, fmt.Errorf("bitmap %s is not paletted", filePath)
+	}
+	return img, nil
+}
+
+// getTransparentIndex parses the transparent flag, detecting the index for "auto" and returning -1 for "none".
+func getTransparentIndex(img *infrastructure.EqBmp, value string) (int, error) {
+	switch value {
+	case "auto":
+		index, _ := img.DetectTransparentIndex()
+		return index, nil
+	case "none":
+		return -1, nil
+	}
+
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 0 || index >= len(img.GetPalette()) {
+		return 0, fmt.Errorf("invalid transparent index: %s", value)
+	}
+	return index, nil
+}
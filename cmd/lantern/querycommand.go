@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/collision"
+	"github.com/tmyhres/LanternGoExtract/pkg/eq"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// queryUsage describes the collision queries and their answers.
+const queryUsage = `Queries (EQ world coordinates):
+  los x1 y1 z1 x2 y2 z2              - "true" if nothing blocks the line, else "false"
+  raycast x y z dx dy dz [distance]  - "hit x y z nx ny nz distance" or "miss"
+  ground x y [z]                     - height of the highest surface, or the first below z, or "none"
+  sweep x1 y1 z1 x2 y2 z2 radius     - "hit x y z nx ny nz distance" with the sphere center, or "miss"
+
+Without a query, queries are read from stdin one per line and answered one per line.`
+
+// runQueryCommand loads the collision geometry of a zone and answers collision queries.
+func runQueryCommand(args []string) int {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	settingsFile := flags.String("settings", "", "Path to settings file (optional)")
+	flags.Usage = func() {
+		fmt.Println("Usage: lantern query [flags] <zone> [query]")
+		fmt.Println("")
+		fmt.Println(queryUsage)
+		fmt.Println("")
+		fmt.Println("Flags:")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 1
+	}
+
+	log, settings, err := initCommand(*settingsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer log.Close()
+
+	zone, err := eq.LoadZone(flags.Arg(0), log, settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load zone: %v\n", err)
+		return 1
+	}
+
+	bvh := zone.BuildCollision()
+
+	if flags.NArg() > 1 {
+		answer, err := answerQuery(bvh, flags.Args()[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(answer)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Loaded %d collision triangles for %s\n", bvh.TriangleCount(), zone.ShortName)
+	if err := answerQueries(bvh, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read queries: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// answerQueries answers one query per input line until the input ends.
+// Invalid queries are answered with an error line so the answers stay in step with the queries.
+func answerQueries(bvh *collision.Bvh, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	writer := bufio.NewWriter(out)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		answer, err := answerQuery(bvh, fields)
+		if err != nil {
+			answer = "error " + err.Error()
+		}
+
+		fmt.Fprintln(writer, answer)
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// answerQuery answers a single query given as its fields.
+func answerQuery(bvh *collision.Bvh, fields []string) (string, error) {
+	name, values, err := parseQuery(fields)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case name == "los" && len(values) == 6:
+		return strconv.FormatBool(bvh.LineOfSight(vec(values[0:3]), vec(values[3:6]))), nil
+
+	case name == "raycast" && (len(values) == 6 || len(values) == 7):
+		distance := float32(1e9)
+		if len(values) == 7 {
+			distance = values[6]
+		}
+		return formatHit(bvh.Raycast(vec(values[0:3]), vec(values[3:6]), distance)), nil
+
+	case name == "ground" && (len(values) == 2 || len(values) == 3):
+		var z float32
+		var ok bool
+		if len(values) == 3 {
+			z, ok = bvh.GroundHeightBelow(vec(values[0:3]))
+		} else {
+			z, ok = bvh.GroundHeightAt(values[0], values[1])
+		}
+		if !ok {
+			return "none", nil
+		}
+		return formatFloat(z), nil
+
+	case name == "sweep" && len(values) == 7:
+		return formatHit(bvh.SweepSphere(vec(values[0:3]), vec(values[3:6]), values[6])), nil
+	}
+
+	return "", fmt.Errorf("invalid query: %s", strings.Join(fields, " "))
+}
+
+// parseQuery splits a query into its lower case name and numeric arguments.
+func parseQuery(fields []string) (string, []float32, error) {
+	values := make([]float32, 0, len(fields)-1)
+	for _, field := range fields[1:] {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(field, ","), 32)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid number %q", field)
+		}
+		values = append(values, float32(value))
+	}
+	return strings.ToLower(fields[0]), values, nil
+}
+
+// formatHit formats a query hit as "hit x y z nx ny nz distance", or "miss".
+func formatHit(hit collision.Hit, ok bool) string {
+	if !ok {
+		return "miss"
+	}
+	return strings.Join([]string{"hit",
+		formatFloat(hit.Position.X), formatFloat(hit.Position.Y), formatFloat(hit.Position.Z),
+		formatFloat(hit.Normal.X), formatFloat(hit.Normal.Y), formatFloat(hit.Normal.Z),
+		formatFloat(hit.Distance)}, " ")
+}
+
+// formatFloat formats a coordinate with up to four decimals.
+func formatFloat(value float32) string {
+	return strconv.FormatFloat(float64(value), 'f', 4, 32)
+}
+
+// vec returns the first three values as a vector.
+func vec(values []float32) datatypes.Vec3 {
+	return datatypes.Vec3{X: values[0], Y: values[1], Z: values[2]}
+}
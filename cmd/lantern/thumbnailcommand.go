@@ -0,0 +1,135 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/eq"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
+	"github.com/tmyhres/LanternGoExtract/pkg/render"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/exporters"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
+)
+
+// runThumbnailCommand renders every model of character, equipment and objects archives
+// to Exports/<archive>/Thumbnails/<model>.png without a GPU.
+func runThumbnailCommand(args []string) int {
+	defaults := render.DefaultOptions()
+
+	flags := flag.NewFlagSet("thumbnail", flag.ExitOnError)
+	settingsFile := flags.String("settings", "", "Path to settings file (optional)")
+	size := flags.Int("size", defaults.Width, "Width and height of the images in pixels")
+	supersampling := flags.Int("supersample", defaults.Supersampling, "Samples per pixel in each direction")
+	yaw := flags.Float64("yaw", defaults.Camera.Yaw, "Camera angle around the model in degrees")
+	pitch := flags.Float64("pitch", defaults.Camera.Pitch, "Camera angle above the horizon in degrees")
+	fieldOfView := flags.Float64("fov", defaults.Camera.FieldOfView, "Vertical field of view in degrees (0 for orthographic)")
+	padding := flags.Float64("padding", defaults.Camera.Padding, "Fraction of the image left empty on each side")
+	ambient := flags.Float64("ambient", defaults.Light.Ambient, "Ambient light strength")
+	diffuse := flags.Float64("diffuse", defaults.Light.Diffuse, "Directional light strength")
+	background := flags.String("background", "", "Background color as RRGGBB or RRGGBBAA (default transparent)")
+	vertexColors := flags.Bool("vertex-colors", defaults.VertexColors, "Multiply surfaces by the vertex colors")
+	animationKey := flags.String("animation", "pos", "Animation to pose skeletal models in")
+	frame := flags.Int("frame", 0, "Frame of the animation")
+	flags.Usage = func() {
+		fmt.Println("Usage: lantern thumbnail [flags] [archive...]")
+		fmt.Println("")
+		fmt.Println("Archives are archive names (e.g., global_chr, gequip, gfaydark_obj) or the keywords")
+		fmt.Println("characters, equipment and objects. All three keywords are rendered by default.")
+		fmt.Println("")
+		fmt.Println("Flags:")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	options := defaults
+	options.Width, options.Height = *size, *size
+	options.Supersampling = *supersampling
+	options.Camera = render.Camera{Yaw: *yaw, Pitch: *pitch, FieldOfView: *fieldOfView, Padding: *padding}
+	options.Light.Ambient = *ambient
+	options.Light.Diffuse = *diffuse
+	options.VertexColors = *vertexColors
+
+	if *background != "" {
+		backgroundColor, err := parseHexColor(*background)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		options.Background = backgroundColor
+	}
+
+	archiveNames := flags.Args()
+	if len(archiveNames) == 0 {
+		archiveNames = []string{"characters", "equipment", "objects"}
+	}
+
+	log, settings, err := initCommand(*settingsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer log.Close()
+
+	start := time.Now()
+	rendered := 0
+
+	for _, archiveName := range eq.ExpandModelArchives(archiveNames, settings) {
+		models, err := eq.LoadModelArchive(archiveName, log, settings)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load archive: %v\n", err)
+			continue
+		}
+
+		textures := models.LoadTextures(log)
+		isCharacterAnimation := models.Wld.GetWldType() == wld.WldTypeCharacters
+		folder := exportDir + models.Name + "/Thumbnails/"
+
+		for _, actor := range models.Wld.GetActors() {
+			scene := render.NewScene(textures)
+			for _, posed := range exporters.GetPosedActorMeshes(actor, *animationKey, *frame, isCharacterAnimation) {
+				scene.AddMesh(posed.Mesh, posed.Positions, posed.Normals)
+			}
+
+			img := scene.Render(options)
+			if img == nil {
+				continue
+			}
+
+			filePath := folder + helpers.CleanActorName(actor.GetName()) + ".png"
+			if err := infrastructure.WriteTexture(img, filePath, infrastructure.TextureFormatPng, false); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write thumbnail: %v\n", err)
+				continue
+			}
+			rendered++
+		}
+	}
+
+	fmt.Printf("Rendered %d thumbnails (%.2fs)\n", rendered, time.Since(start).Seconds())
+	return 0
+}
+
+// parseHexColor parses a color written as RRGGBB or RRGGBBAA.
+func parseHexColor(value string) (color.NRGBA, error) {
+	value = strings.TrimPrefix(value, "#")
+	if len(value) == 6 {
+		value += "ff"
+	}
+
+	parsed, err := strconv.ParseUint(value, 16, 32)
+	if len(value) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("invalid color: %s", value)
+	}
+
+	return color.NRGBA{
+		R: uint8(parsed >> 24),
+		G: uint8(parsed >> 16),
+		B: uint8(parsed >> 8),
+		A: uint8(parsed),
+	}, nil
+}
@@ -0,0 +1,317 @@
+// Package atlas packs the textures used by meshes into atlas pages and rewrites the mesh UVs to
+// match, so a zone or model draws with a few materials instead of one per bitmap.
+//
+// Textures are packed per shader, since the shader is part of the material, with a border of
+// wrapped texels around each one so filtering and mipmaps do not bleed between neighbours.
+// EQ polygons often repeat a texture by using UVs outside [0, 1]. Those polygons are split
+// along the texture edges so each piece maps into a single copy of the texture.
+package atlas
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// Options configures the atlas builder.
+type Options struct {
+	// MaxSize is the largest width and height of an atlas page in pixels.
+	MaxSize int
+
+	// Padding is the number of wrapped texels around each texture.
+	Padding int
+
+	// SplitTiledPolygons splits polygons that repeat a texture along the texture edges.
+	// Tiled polygons keep their own material otherwise.
+	SplitTiledPolygons bool
+
+	// MaxTiles is the largest number of texture repeats a polygon may cover to be split.
+	// It limits the geometry added for floors and walls that repeat a texture many times.
+	MaxTiles int
+}
+
+// DefaultOptions returns options suited to WebGL and mobile targets.
+func DefaultOptions() Options {
+	return Options{
+		MaxSize:            2048,
+		Padding:            4,
+		SplitTiledPolygons: true,
+		MaxTiles:           16,
+	}
+}
+
+// Page is an atlas image and the material that draws it.
+type Page struct {
+	// Image is the packed page.
+	Image *image.NRGBA
+
+	// Material is the material drawing the page. Its bitmap is named after the page.
+	Material *fragments.Material
+}
+
+// Region is the position of a texture in a page, excluding the padding.
+type Region struct {
+	Page   int
+	X, Y   int
+	Width  int
+	Height int
+}
+
+// materialKey groups the materials that can share a page.
+type materialKey struct {
+	shader        fragments.ShaderType
+	brightness    float32
+	scaledAmbient float32
+}
+
+// regionKey identifies a packed texture.
+type regionKey struct {
+	material materialKey
+	bitmap   string
+}
+
+// Atlas is a set of atlas pages and the position of each packed texture.
+type Atlas struct {
+	// Name prefixes the page bitmap names, e.g. "gfaydark" gives "gfaydark_atlas00.bmp".
+	Name string
+
+	// Pages are the packed pages.
+	Pages []*Page
+
+	options  Options
+	textures map[string]image.Image
+	regions  map[regionKey]Region
+}
+
+// packItem is a texture waiting to be packed.
+type packItem struct {
+	key     regionKey
+	texture image.Image
+}
+
+// Build packs the textures used by the meshes into atlas pages.
+// Textures are keyed by lower case bitmap filename. Materials without a loaded texture,
+// animated materials and materials with alternate skins are left out.
+func Build(name string, meshes []*fragments.Mesh, textures map[string]image.Image, options Options) *Atlas {
+	a := &Atlas{
+		Name:     strings.ToLower(name),
+		options:  options,
+		textures: textures,
+		regions:  make(map[regionKey]Region),
+	}
+
+	var keys []materialKey
+	items := make(map[materialKey][]packItem)
+	seen := make(map[regionKey]bool)
+
+	for _, mesh := range meshes {
+		if !isSupportedMesh(mesh) {
+			continue
+		}
+
+		forEachPolygon(mesh, func(polygonIndex, materialIndex int) {
+			polygon := mesh.Indices[polygonIndex]
+			corners := []int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3}
+
+			key, ok := a.getRegionKey(mesh.MaterialList, materialIndex)
+			if !ok || seen[key] || a.getTileCount(getPolygonUvs(mesh, corners)) == 0 {
+				return
+			}
+			seen[key] = true
+
+			if _, exists := items[key.material]; !exists {
+				keys = append(keys, key.material)
+			}
+			items[key.material] = append(items[key.material], packItem{key: key, texture: textures[key.bitmap]})
+		})
+	}
+
+	for _, key := range keys {
+		a.pack(key, items[key])
+	}
+
+	return a
+}
+
+// getRegionKey returns the packing key of a material of a material list.
+// Returns false if the material cannot use the atlas.
+func (a *Atlas) getRegionKey(materialList *fragments.MaterialList, materialIndex int) (regionKey, bool) {
+	if materialList == nil || materialIndex < 0 || materialIndex >= len(materialList.Materials) {
+		return regionKey{}, false
+	}
+
+	material := materialList.Materials[materialIndex]
+	if material == nil || material.ShaderType == fragments.ShaderTypeInvisible || material.ShaderType == fragments.ShaderTypeBoundary {
+		return regionKey{}, false
+	}
+
+	bitmapInfoRef, ok := material.BitmapInfoReference.(*fragments.BitmapInfoReference)
+	if !ok || bitmapInfoRef == nil || bitmapInfoRef.BitmapInfo == nil {
+		return regionKey{}, false
+	}
+
+	bitmapInfo := bitmapInfoRef.BitmapInfo
+	if bitmapInfo.IsAnimated || len(bitmapInfo.BitmapNames) != 1 || bitmapInfo.BitmapNames[0] == nil {
+		return regionKey{}, false
+	}
+
+	if len(materialList.GetMaterialVariants(material)) > 0 {
+		return regionKey{}, false
+	}
+
+	bitmap := strings.ToLower(bitmapInfo.BitmapNames[0].Filename)
+	texture := a.textures[bitmap]
+	if texture == nil {
+		return regionKey{}, false
+	}
+
+	maxTextureSize := a.options.MaxSize - 2*a.options.Padding
+	if texture.Bounds().Dx() > maxTextureSize || texture.Bounds().Dy() > maxTextureSize {
+		return regionKey{}, false
+	}
+
+	return regionKey{
+		material: materialKey{
+			shader:        material.ShaderType,
+			brightness:    material.Brightness,
+			scaledAmbient: material.ScaledAmbient,
+		},
+		bitmap: bitmap,
+	}, true
+}
+
+// pack places the textures of a material key on shelves in new pages.
+// Taller textures are placed first so shelves waste less space.
+func (a *Atlas) pack(key materialKey, items []packItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		bi, bj := items[i].texture.Bounds(), items[j].texture.Bounds()
+		if bi.Dy() != bj.Dy() {
+			return bi.Dy() > bj.Dy()
+		}
+		if bi.Dx() != bj.Dx() {
+			return bi.Dx() > bj.Dx()
+		}
+		return items[i].key.bitmap < items[j].key.bitmap
+	})
+
+	padding := a.options.Padding
+	var placed []packItem
+	var regions []Region
+	x, y, shelfHeight := 0, 0, 0
+	usedWidth, usedHeight := 0, 0
+
+	flush := func() {
+		if len(placed) > 0 {
+			a.addPage(key, placed, regions, usedWidth, usedHeight)
+		}
+		placed, regions = nil, nil
+		x, y, shelfHeight = 0, 0, 0
+		usedWidth, usedHeight = 0, 0
+	}
+
+	for _, item := range items {
+		width := item.texture.Bounds().Dx() + 2*padding
+		height := item.texture.Bounds().Dy() + 2*padding
+
+		if x+width > a.options.MaxSize {
+			x, y, shelfHeight = 0, y+shelfHeight, 0
+		}
+		if y+height > a.options.MaxSize {
+			flush()
+		}
+
+		placed = append(placed, item)
+		regions = append(regions, Region{
+			Page:   len(a.Pages),
+			X:      x + padding,
+			Y:      y + padding,
+			Width:  width - 2*padding,
+			Height: height - 2*padding,
+		})
+
+		x += width
+		shelfHeight = max(shelfHeight, height)
+		usedWidth = max(usedWidth, x)
+		usedHeight = max(usedHeight, y+height)
+	}
+
+	flush()
+}
+
+// addPage draws packed textures into a new page sized to the next power of two.
+func (a *Atlas) addPage(key materialKey, items []packItem, regions []Region, usedWidth, usedHeight int) {
+	page := image.NewNRGBA(image.Rect(0, 0, nextPowerOfTwo(usedWidth), nextPowerOfTwo(usedHeight)))
+
+	for i, item := range items {
+		drawWrapped(page, item.texture, regions[i], a.options.Padding)
+		a.regions[item.key] = regions[i]
+	}
+
+	bitmapName := fmt.Sprintf("%s_atlas%02d", a.Name, len(a.Pages))
+	material := &fragments.Material{
+		BitmapInfoReference: &fragments.BitmapInfoReference{
+			BitmapInfo: &fragments.BitmapInfo{
+				BitmapNames: []*fragments.BitmapName{{Filename: bitmapName + ".bmp"}},
+			},
+		},
+		ShaderType:    key.shader,
+		Brightness:    key.brightness,
+		ScaledAmbient: key.scaledAmbient,
+	}
+	material.SetName(strings.ToUpper(bitmapName) + "_MDF")
+
+	a.Pages = append(a.Pages, &Page{Image: page, Material: material})
+}
+
+// drawWrapped draws a texture into its region and fills the padding with the texels
+// that wrap around the opposite edges.
+func drawWrapped(page *image.NRGBA, texture image.Image, region Region, padding int) {
+	source := image.NewNRGBA(image.Rect(0, 0, region.Width, region.Height))
+	draw.Draw(source, source.Bounds(), texture, texture.Bounds().Min, draw.Src)
+
+	for y := -padding; y < region.Height+padding; y++ {
+		for x := -padding; x < region.Width+padding; x++ {
+			page.SetNRGBA(region.X+x, region.Y+y, source.NRGBAAt(wrapInt(x, region.Width), wrapInt(y, region.Height)))
+		}
+	}
+}
+
+// WritePages writes the pages as PNG files named after their bitmaps to a folder,
+// with a GPU texture next to each in formats other than PNG.
+func (a *Atlas) WritePages(folder string, format infrastructure.TextureFormat) error {
+	for _, page := range a.Pages {
+		bitmapInfo := page.Material.BitmapInfoReference.(*fragments.BitmapInfoReference).BitmapInfo
+		filePath := filepath.Join(folder, bitmapInfo.BitmapNames[0].GetExportFilename())
+		isMasked := page.Material.ShaderType == fragments.ShaderTypeTransparentMasked
+
+		if err := infrastructure.WriteTexture(page.Image, filePath, format, isMasked); err != nil {
+			return fmt.Errorf("failed to write atlas page: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// nextPowerOfTwo returns the smallest power of two not below a value.
+func nextPowerOfTwo(value int) int {
+	size := 1
+	for size < value {
+		size *= 2
+	}
+	return size
+}
+
+// wrapInt wraps a value into the range [0, size).
+func wrapInt(value, size int) int {
+	value %= size
+	if value < 0 {
+		value += size
+	}
+	return value
+}
@@ -0,0 +1,97 @@
+package atlas
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// newTestMaterial creates a diffuse material drawing a bitmap.
+func newTestMaterial(name, bitmap string) *fragments.Material {
+	material := &fragments.Material{
+		BitmapInfoReference: &fragments.BitmapInfoReference{
+			BitmapInfo: &fragments.BitmapInfo{
+				BitmapNames: []*fragments.BitmapName{{Filename: bitmap}},
+			},
+		},
+		ShaderType: fragments.ShaderTypeDiffuse,
+	}
+	material.SetName(name)
+	return material
+}
+
+// newTestMesh creates a quad repeating the first material twice along U and a triangle
+// using the second material once.
+func newTestMesh() *fragments.Mesh {
+	return &fragments.Mesh{
+		MaterialList: &fragments.MaterialList{
+			Materials: []*fragments.Material{
+				newTestMaterial("FLOOR_MDF", "floor.bmp"),
+				newTestMaterial("WALL_MDF", "wall.bmp"),
+			},
+		},
+		Vertices: []fragments.Vec3{
+			{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: 1}, {X: 0, Y: 1},
+			{X: 0, Z: 1}, {X: 1, Z: 1}, {X: 1, Z: 2},
+		},
+		TextureUvCoordinates: []datatypes.Vec2{
+			{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: -1}, {X: 0, Y: -1},
+			{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 1, Y: -1},
+		},
+		Indices: []datatypes.Polygon{
+			{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2},
+			{IsSolid: true, Vertex1: 0, Vertex2: 2, Vertex3: 3},
+			{IsSolid: true, Vertex1: 4, Vertex2: 5, Vertex3: 6},
+		},
+		MaterialGroups: []datatypes.RenderGroup{
+			{PolygonCount: 2, MaterialIndex: 0},
+			{PolygonCount: 1, MaterialIndex: 1},
+		},
+	}
+}
+
+func TestApply(t *testing.T) {
+	textures := map[string]image.Image{
+		"floor.bmp": image.NewNRGBA(image.Rect(0, 0, 64, 64)),
+		"wall.bmp":  image.NewNRGBA(image.Rect(0, 0, 32, 32)),
+	}
+
+	mesh := newTestMesh()
+	a := Build("test", []*fragments.Mesh{mesh}, textures, DefaultOptions())
+	if len(a.Pages) != 1 {
+		t.Fatalf("Expected 1 page, got %d", len(a.Pages))
+	}
+
+	if !a.Apply(mesh) {
+		t.Fatal("Expected the mesh to change")
+	}
+
+	if len(mesh.MaterialGroups) != 1 || mesh.MaterialList.Materials[mesh.MaterialGroups[0].MaterialIndex] != a.Pages[0].Material {
+		t.Fatalf("Expected a single group drawing the page, got %v", mesh.MaterialGroups)
+	}
+
+	// Each floor triangle is split at U = 1 into pieces within one tile
+	if len(mesh.Indices) <= 3 {
+		t.Errorf("Expected the tiled quad to be split, got %d polygons", len(mesh.Indices))
+	}
+
+	floor := a.regions[regionKey{material: materialKey{shader: fragments.ShaderTypeDiffuse}, bitmap: "floor.bmp"}]
+	bounds := a.Pages[0].Image.Bounds()
+	for i, uv := range mesh.TextureUvCoordinates {
+		x, y := float64(uv.X)*float64(bounds.Dx()), float64(-uv.Y)*float64(bounds.Dy())
+		if x < 0 || x > float64(bounds.Dx()) || y < 0 || y > float64(bounds.Dy()) {
+			t.Errorf("Expected vertex %d UV within the page, got %v", i, uv)
+		}
+		if mesh.Vertices[i].Z == 0 && (x < float64(floor.X)-0.01 || x > float64(floor.X+floor.Width)+0.01) {
+			t.Errorf("Expected floor vertex %d within its region, got %v", i, x)
+		}
+	}
+
+	for _, polygon := range mesh.Indices {
+		if !polygon.IsSolid {
+			t.Errorf("Expected split polygons to stay solid")
+		}
+	}
+}
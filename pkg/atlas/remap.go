@@ -0,0 +1,508 @@
+package atlas
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// tileEpsilon keeps UVs that land on a texture edge within the tile they belong to.
+const tileEpsilon = 1e-4
+
+// clipVertex is a polygon corner during splitting, with the UV in image space (s, t).
+type clipVertex struct {
+	source   int
+	position vecmath.Vec3
+	normal   vecmath.Vec3
+	color    [4]float64
+	s, t     float64
+	bone     int
+}
+
+// vertexKey identifies a rewritten vertex. Source vertices are shared by every polygon
+// mapping them into the same tile of the same page.
+type vertexKey struct {
+	source       int
+	tileS, tileT int
+	page         int
+}
+
+// splitVertexKey identifies a vertex created on a texture edge.
+type splitVertexKey struct {
+	position     fragments.Vec3
+	s, t         float32
+	tileS, tileT int
+	page         int
+}
+
+// remapPolygon is a rewritten polygon and its material list index.
+type remapPolygon struct {
+	polygon       datatypes.Polygon
+	materialIndex int
+}
+
+// remapper rebuilds the vertices and polygons of a mesh for an atlas.
+type remapper struct {
+	atlas *Atlas
+	mesh  *fragments.Mesh
+
+	sourceBones []int
+
+	vertices []fragments.Vec3
+	normals  []fragments.Vec3
+	colors   []datatypes.Color
+	uvs      []datatypes.Vec2
+	bones    []int
+
+	vertexIndices      map[vertexKey]int
+	splitVertexIndices map[splitVertexKey]int
+	polygons           []remapPolygon
+}
+
+// Apply rewrites the UVs of a mesh to draw the packed textures from the atlas pages.
+// Polygons that repeat a texture are split along the texture edges, page materials are added
+// to the mesh material list and the render groups are rebuilt. Returns false if nothing changed.
+func (a *Atlas) Apply(mesh *fragments.Mesh) bool {
+	if len(a.Pages) == 0 || !isSupportedMesh(mesh) {
+		return false
+	}
+
+	r := &remapper{
+		atlas:              a,
+		mesh:               mesh,
+		vertexIndices:      make(map[vertexKey]int),
+		splitVertexIndices: make(map[splitVertexKey]int),
+	}
+
+	if len(mesh.MobPieces) > 0 {
+		r.sourceBones = make([]int, len(mesh.Vertices))
+		for bone, piece := range mesh.MobPieces {
+			for i := piece.Start; i < piece.Start+piece.Count && i < len(mesh.Vertices); i++ {
+				r.sourceBones[i] = bone
+			}
+		}
+	}
+
+	changed := false
+	forEachPolygon(mesh, func(polygonIndex, materialIndex int) {
+		if r.addPolygon(mesh.Indices[polygonIndex], materialIndex) {
+			changed = true
+		}
+	})
+
+	if !changed {
+		return false
+	}
+
+	r.apply()
+	return true
+}
+
+// addPolygon adds a polygon, remapped if its material is packed. Returns true if it was remapped.
+func (r *remapper) addPolygon(polygon datatypes.Polygon, materialIndex int) bool {
+	key, ok := r.atlas.getRegionKey(r.mesh.MaterialList, materialIndex)
+	var region Region
+	if ok {
+		region, ok = r.atlas.regions[key]
+	}
+
+	corners := []int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3}
+	uvs := getPolygonUvs(r.mesh, corners)
+	if !ok || r.atlas.getTileCount(uvs) == 0 {
+		var indices [3]int
+		for i, corner := range corners {
+			indices[i] = r.addSourceVertex(corner, vertexKey{source: corner, page: -1}, nil)
+		}
+		r.addTriangle(polygon, indices, materialIndex)
+		return false
+	}
+
+	pageMaterialIndex := r.getPageMaterialIndex(region.Page)
+	minS, maxS, minT, maxT := getTileRange(uvs)
+
+	// Polygons within one tile keep their vertices shared with their neighbours
+	if maxS-minS == 1 && maxT-minT == 1 {
+		var indices [3]int
+		for i, corner := range corners {
+			indices[i] = r.addSourceVertex(corner, vertexKey{source: corner, tileS: minS, tileT: minT, page: region.Page}, &region)
+		}
+		r.addTriangle(polygon, indices, pageMaterialIndex)
+		return true
+	}
+
+	clip := make([]clipVertex, len(corners))
+	for i, corner := range corners {
+		clip[i] = r.newClipVertex(corner)
+	}
+
+	for tileS := minS; tileS < maxS; tileS++ {
+		column := clipPolygon(clip, func(v clipVertex) float64 { return v.s - float64(tileS) })
+		column = clipPolygon(column, func(v clipVertex) float64 { return float64(tileS+1) - v.s })
+
+		for tileT := minT; tileT < maxT; tileT++ {
+			piece := clipPolygon(column, func(v clipVertex) float64 { return v.t - float64(tileT) })
+			piece = clipPolygon(piece, func(v clipVertex) float64 { return float64(tileT+1) - v.t })
+			if len(piece) < 3 {
+				continue
+			}
+
+			indices := make([]int, len(piece))
+			for i, v := range piece {
+				indices[i] = r.addClipVertex(v, tileS, tileT, &region)
+			}
+
+			for i := 1; i+1 < len(indices); i++ {
+				triangle := [3]int{indices[0], indices[i], indices[i+1]}
+				if triangle[0] != triangle[1] && triangle[1] != triangle[2] && triangle[0] != triangle[2] {
+					r.addTriangle(polygon, triangle, pageMaterialIndex)
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// addTriangle adds a rewritten polygon with the solidity of the polygon it came from.
+func (r *remapper) addTriangle(source datatypes.Polygon, indices [3]int, materialIndex int) {
+	r.polygons = append(r.polygons, remapPolygon{
+		polygon: datatypes.Polygon{
+			IsSolid: source.IsSolid,
+			Vertex1: indices[0],
+			Vertex2: indices[1],
+			Vertex3: indices[2],
+		},
+		materialIndex: materialIndex,
+	})
+}
+
+// addSourceVertex adds a copy of a source vertex, with its UV mapped into a region if one is given.
+func (r *remapper) addSourceVertex(source int, key vertexKey, region *Region) int {
+	if index, exists := r.vertexIndices[key]; exists {
+		return index
+	}
+
+	uv := r.mesh.TextureUvCoordinates[source]
+	if region != nil {
+		uv = r.atlas.mapUv(*region, float64(uv.X), float64(-uv.Y), key.tileS, key.tileT)
+	}
+
+	index := len(r.vertices)
+	r.vertices = append(r.vertices, r.mesh.Vertices[source])
+	r.uvs = append(r.uvs, uv)
+	if len(r.mesh.Normals) > 0 {
+		r.normals = append(r.normals, r.mesh.Normals[source])
+	}
+	if len(r.mesh.Colors) > 0 {
+		r.colors = append(r.colors, r.mesh.Colors[source])
+	}
+	r.bones = append(r.bones, r.getSourceBone(source))
+
+	r.vertexIndices[key] = index
+	return index
+}
+
+// addClipVertex adds a corner of a split polygon mapped into a tile of a region.
+func (r *remapper) addClipVertex(v clipVertex, tileS, tileT int, region *Region) int {
+	if v.source >= 0 {
+		return r.addSourceVertex(v.source, vertexKey{source: v.source, tileS: tileS, tileT: tileT, page: region.Page}, region)
+	}
+
+	position := toVec3(v.position)
+	key := splitVertexKey{position: position, s: float32(v.s), t: float32(v.t), tileS: tileS, tileT: tileT, page: region.Page}
+	if index, exists := r.splitVertexIndices[key]; exists {
+		return index
+	}
+
+	index := len(r.vertices)
+	r.vertices = append(r.vertices, position)
+	r.uvs = append(r.uvs, r.atlas.mapUv(*region, v.s, v.t, tileS, tileT))
+	if len(r.mesh.Normals) > 0 {
+		r.normals = append(r.normals, toVec3(vecmath.Normalize(v.normal)))
+	}
+	if len(r.mesh.Colors) > 0 {
+		r.colors = append(r.colors, datatypes.Color{
+			R: int(math.Round(v.color[0])),
+			G: int(math.Round(v.color[1])),
+			B: int(math.Round(v.color[2])),
+			A: int(math.Round(v.color[3])),
+		})
+	}
+	r.bones = append(r.bones, v.bone)
+
+	r.splitVertexIndices[key] = index
+	return index
+}
+
+// newClipVertex creates a clip vertex from a source vertex.
+func (r *remapper) newClipVertex(source int) clipVertex {
+	position := r.mesh.Vertices[source]
+	uv := r.mesh.TextureUvCoordinates[source]
+	v := clipVertex{
+		source:   source,
+		position: vecmath.New(float64(position.X), float64(position.Y), float64(position.Z)),
+		s:        float64(uv.X),
+		t:        float64(-uv.Y),
+		bone:     r.getSourceBone(source),
+	}
+
+	if len(r.mesh.Normals) > 0 {
+		normal := r.mesh.Normals[source]
+		v.normal = vecmath.New(float64(normal.X), float64(normal.Y), float64(normal.Z))
+	}
+	if len(r.mesh.Colors) > 0 {
+		color := r.mesh.Colors[source]
+		v.color = [4]float64{float64(color.R), float64(color.G), float64(color.B), float64(color.A)}
+	}
+
+	return v
+}
+
+// getSourceBone returns the bone of a source vertex, or 0 for meshes without mob pieces.
+func (r *remapper) getSourceBone(source int) int {
+	if r.sourceBones == nil {
+		return 0
+	}
+	return r.sourceBones[source]
+}
+
+// getPageMaterialIndex returns the index of a page material in the mesh material list,
+// adding it if the list does not contain it yet.
+func (r *remapper) getPageMaterialIndex(page int) int {
+	material := r.atlas.Pages[page].Material
+	materialList := r.mesh.MaterialList
+	for i, existing := range materialList.Materials {
+		if existing == material {
+			return i
+		}
+	}
+
+	materialList.Materials = append(materialList.Materials, material)
+	return len(materialList.Materials) - 1
+}
+
+// apply replaces the mesh geometry with the rewritten vertices and polygons.
+// Polygons are grouped by material and vertices are ordered by bone for skinned meshes.
+func (r *remapper) apply() {
+	mesh := r.mesh
+
+	order := make([]int, len(r.vertices))
+	for i := range order {
+		order[i] = i
+	}
+	if r.sourceBones != nil {
+		sort.SliceStable(order, func(i, j int) bool { return r.bones[order[i]] < r.bones[order[j]] })
+	}
+
+	remap := make([]int, len(order))
+	mesh.Vertices = make([]fragments.Vec3, len(order))
+	mesh.TextureUvCoordinates = make([]datatypes.Vec2, len(order))
+	if len(r.normals) > 0 {
+		mesh.Normals = make([]fragments.Vec3, len(order))
+	}
+	if len(r.colors) > 0 {
+		mesh.Colors = make([]datatypes.Color, len(order))
+	}
+
+	for newIndex, oldIndex := range order {
+		remap[oldIndex] = newIndex
+		mesh.Vertices[newIndex] = r.vertices[oldIndex]
+		mesh.TextureUvCoordinates[newIndex] = r.uvs[oldIndex]
+		if len(r.normals) > 0 {
+			mesh.Normals[newIndex] = r.normals[oldIndex]
+		}
+		if len(r.colors) > 0 {
+			mesh.Colors[newIndex] = r.colors[oldIndex]
+		}
+	}
+
+	if r.sourceBones != nil {
+		mesh.MobPieces = make(map[int]datatypes.MobVertexPiece)
+		for newIndex, oldIndex := range order {
+			bone := r.bones[oldIndex]
+			piece, exists := mesh.MobPieces[bone]
+			if !exists {
+				piece.Start = newIndex
+			}
+			piece.Count++
+			mesh.MobPieces[bone] = piece
+		}
+	}
+
+	var materialOrder []int
+	grouped := make(map[int][]datatypes.Polygon)
+	for _, p := range r.polygons {
+		if _, exists := grouped[p.materialIndex]; !exists {
+			materialOrder = append(materialOrder, p.materialIndex)
+		}
+
+		polygon := p.polygon
+		polygon.Vertex1 = remap[polygon.Vertex1]
+		polygon.Vertex2 = remap[polygon.Vertex2]
+		polygon.Vertex3 = remap[polygon.Vertex3]
+		polygon.MaterialIndex = p.materialIndex
+		grouped[p.materialIndex] = append(grouped[p.materialIndex], polygon)
+	}
+
+	mesh.Indices = mesh.Indices[:0]
+	mesh.MaterialGroups = mesh.MaterialGroups[:0]
+	for _, materialIndex := range materialOrder {
+		if materialIndex < 0 {
+			continue
+		}
+		mesh.Indices = append(mesh.Indices, grouped[materialIndex]...)
+		mesh.MaterialGroups = append(mesh.MaterialGroups, datatypes.RenderGroup{
+			PolygonCount:  len(grouped[materialIndex]),
+			MaterialIndex: materialIndex,
+		})
+	}
+
+	// Polygons not covered by a render group stay after the groups
+	mesh.Indices = append(mesh.Indices, grouped[-1]...)
+}
+
+// mapUv maps an image space UV within a tile into a region and returns it as a mesh UV.
+func (a *Atlas) mapUv(region Region, s, t float64, tileS, tileT int) datatypes.Vec2 {
+	bounds := a.Pages[region.Page].Image.Bounds()
+	localS := math.Max(0, math.Min(1, s-float64(tileS)))
+	localT := math.Max(0, math.Min(1, t-float64(tileT)))
+
+	return datatypes.Vec2{
+		X: float32((float64(region.X) + localS*float64(region.Width)) / float64(bounds.Dx())),
+		Y: float32(-(float64(region.Y) + localT*float64(region.Height)) / float64(bounds.Dy())),
+	}
+}
+
+// getTileCount returns the number of texture repeats a polygon covers,
+// or 0 if the polygon cannot be mapped into the atlas.
+func (a *Atlas) getTileCount(uvs [][2]float64) int {
+	minS, maxS, minT, maxT := getTileRange(uvs)
+	count := (maxS - minS) * (maxT - minT)
+
+	if count == 1 || (count > 1 && a.options.SplitTiledPolygons && count <= a.options.MaxTiles) {
+		return count
+	}
+	return 0
+}
+
+// getTileRange returns the tiles covered by image space UVs as half-open ranges.
+func getTileRange(uvs [][2]float64) (minS, maxS, minT, maxT int) {
+	lowS, highS := math.Inf(1), math.Inf(-1)
+	lowT, highT := math.Inf(1), math.Inf(-1)
+	for _, uv := range uvs {
+		lowS, highS = math.Min(lowS, uv[0]), math.Max(highS, uv[0])
+		lowT, highT = math.Min(lowT, uv[1]), math.Max(highT, uv[1])
+	}
+
+	minS, maxS = getTileSpan(lowS, highS)
+	minT, maxT = getTileSpan(lowT, highT)
+	return minS, maxS, minT, maxT
+}
+
+// getTileSpan returns the tiles covered by a coordinate range, at least one.
+func getTileSpan(low, high float64) (int, int) {
+	first := int(math.Floor(low + tileEpsilon))
+	last := int(math.Ceil(high - tileEpsilon))
+	if last <= first {
+		last = first + 1
+	}
+	return first, last
+}
+
+// getPolygonUvs returns the image space UVs (s, t) of polygon corners.
+func getPolygonUvs(mesh *fragments.Mesh, corners []int) [][2]float64 {
+	uvs := make([][2]float64, len(corners))
+	for i, corner := range corners {
+		uv := mesh.TextureUvCoordinates[corner]
+		uvs[i] = [2]float64{float64(uv.X), float64(-uv.Y)}
+	}
+	return uvs
+}
+
+// clipPolygon keeps the part of a polygon where distance is not negative.
+func clipPolygon(polygon []clipVertex, distance func(clipVertex) float64) []clipVertex {
+	var clipped []clipVertex
+	for i, current := range polygon {
+		previous := polygon[(i+len(polygon)-1)%len(polygon)]
+		currentDistance, previousDistance := distance(current), distance(previous)
+
+		if (currentDistance >= 0) != (previousDistance >= 0) {
+			clipped = append(clipped, lerpClipVertex(previous, current, previousDistance/(previousDistance-currentDistance)))
+		}
+		if currentDistance >= 0 {
+			clipped = append(clipped, current)
+		}
+	}
+	return clipped
+}
+
+// lerpClipVertex interpolates between two clip vertices. The new vertex takes the bone of the first.
+func lerpClipVertex(a, b clipVertex, amount float64) clipVertex {
+	if amount <= 0 {
+		return a
+	}
+	if amount >= 1 {
+		return b
+	}
+
+	v := clipVertex{
+		source: -1,
+		s:      a.s + (b.s-a.s)*amount,
+		t:      a.t + (b.t-a.t)*amount,
+		bone:   a.bone,
+	}
+	v.position = vecmath.Add(a.position, vecmath.Scale(vecmath.Sub(b.position, a.position), amount))
+	v.normal = vecmath.Add(a.normal, vecmath.Scale(vecmath.Sub(b.normal, a.normal), amount))
+	for i := range v.color {
+		v.color[i] = a.color[i] + (b.color[i]-a.color[i])*amount
+	}
+	return v
+}
+
+// isSupportedMesh returns true if a mesh has static geometry with a UV for every vertex.
+func isSupportedMesh(mesh *fragments.Mesh) bool {
+	if mesh == nil || mesh.MaterialList == nil || mesh.AnimatedVerticesReference != nil {
+		return false
+	}
+
+	vertexCount := len(mesh.Vertices)
+	if vertexCount == 0 || len(mesh.TextureUvCoordinates) != vertexCount ||
+		(len(mesh.Normals) != 0 && len(mesh.Normals) != vertexCount) ||
+		(len(mesh.Colors) != 0 && len(mesh.Colors) != vertexCount) {
+		return false
+	}
+
+	for _, polygon := range mesh.Indices {
+		for _, corner := range []int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3} {
+			if corner < 0 || corner >= vertexCount {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// forEachPolygon calls fn for each polygon with the material list index of its render group.
+// Polygons not covered by a render group get -1.
+func forEachPolygon(mesh *fragments.Mesh, fn func(polygonIndex, materialIndex int)) {
+	polygonIndex := 0
+	for _, group := range mesh.MaterialGroups {
+		for i := 0; i < group.PolygonCount && polygonIndex < len(mesh.Indices); i++ {
+			fn(polygonIndex, group.MaterialIndex)
+			polygonIndex++
+		}
+	}
+
+	for ; polygonIndex < len(mesh.Indices); polygonIndex++ {
+		fn(polygonIndex, -1)
+	}
+}
+
+// toVec3 converts a vector to a fragment vector.
+func toVec3(v vecmath.Vec3) fragments.Vec3 {
+	return fragments.Vec3{X: float32(v.X), Y: float32(v.Y), Z: float32(v.Z)}
+}
@@ -0,0 +1,197 @@
+package collision
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// maxLeafTriangles is the largest number of triangles stored in a leaf node.
+const maxLeafTriangles = 4
+
+// triangle is a collision triangle in double precision.
+type triangle struct {
+	a, b, c vecmath.Vec3
+}
+
+// node is a node of the hierarchy. Leaves have a triangle count, inner nodes have two children,
+// the first directly after the node and the second at right.
+type node struct {
+	min, max vecmath.Vec3
+	right    int
+	start    int
+	count    int
+}
+
+// Bvh is a bounding volume hierarchy over collision triangles.
+type Bvh struct {
+	triangles []triangle
+	indices   []int
+	nodes     []node
+}
+
+// NewBvh builds a bounding volume hierarchy over the triangles.
+func NewBvh(triangles []Triangle) *Bvh {
+	b := &Bvh{
+		triangles: make([]triangle, len(triangles)),
+		indices:   make([]int, len(triangles)),
+	}
+
+	for i, t := range triangles {
+		b.triangles[i] = triangle{a: toVec3(t[0]), b: toVec3(t[1]), c: toVec3(t[2])}
+		b.indices[i] = i
+	}
+
+	if len(triangles) > 0 {
+		b.build(0, len(triangles))
+	}
+
+	return b
+}
+
+// TriangleCount returns the number of triangles in the hierarchy.
+func (b *Bvh) TriangleCount() int {
+	return len(b.triangles)
+}
+
+// Bounds returns the bounds of all triangles.
+func (b *Bvh) Bounds() (datatypes.Vec3, datatypes.Vec3) {
+	if len(b.nodes) == 0 {
+		return datatypes.Vec3{}, datatypes.Vec3{}
+	}
+	return toDatatypesVec3(b.nodes[0].min), toDatatypesVec3(b.nodes[0].max)
+}
+
+// build adds the node for the triangles in indices[start:end] and its children.
+// Triangles are split at the median centroid along the longest axis of the centroid bounds.
+func (b *Bvh) build(start, end int) int {
+	index := len(b.nodes)
+	b.nodes = append(b.nodes, node{})
+
+	bmin, bmax := b.triangleBounds(b.indices[start])
+	cmin, cmax := b.centroid(b.indices[start]), b.centroid(b.indices[start])
+	for _, t := range b.indices[start+1 : end] {
+		tmin, tmax := b.triangleBounds(t)
+		bmin, bmax = vecmath.Min(bmin, tmin), vecmath.Max(bmax, tmax)
+		c := b.centroid(t)
+		cmin, cmax = vecmath.Min(cmin, c), vecmath.Max(cmax, c)
+	}
+
+	n := node{min: bmin, max: bmax, start: start, count: end - start}
+	extent := vecmath.Sub(cmax, cmin)
+	if n.count <= maxLeafTriangles || (extent.X == 0 && extent.Y == 0 && extent.Z == 0) {
+		b.nodes[index] = n
+		return index
+	}
+
+	axis := 0
+	if extent.Y > extent.X && extent.Y >= extent.Z {
+		axis = 1
+	} else if extent.Z > extent.X && extent.Z > extent.Y {
+		axis = 2
+	}
+
+	span := b.indices[start:end]
+	sort.Slice(span, func(i, j int) bool {
+		return component(b.centroid(span[i]), axis) < component(b.centroid(span[j]), axis)
+	})
+
+	mid := start + n.count/2
+	b.build(start, mid)
+	n.right = b.build(mid, end)
+	n.count = 0
+	b.nodes[index] = n
+
+	return index
+}
+
+// traverse visits the triangles of every leaf whose bounds pass the box test.
+// Traversal stops when visit returns false.
+func (b *Bvh) traverse(testBox func(min, max vecmath.Vec3) bool, visit func(index int) bool) {
+	if len(b.nodes) == 0 {
+		return
+	}
+
+	stack := []int{0}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		n := &b.nodes[current]
+
+		if !testBox(n.min, n.max) {
+			continue
+		}
+
+		if n.count > 0 {
+			for _, t := range b.indices[n.start : n.start+n.count] {
+				if !visit(t) {
+					return
+				}
+			}
+			continue
+		}
+
+		stack = append(stack, n.right, current+1)
+	}
+}
+
+// triangleBounds returns the bounds of a triangle.
+func (b *Bvh) triangleBounds(index int) (vecmath.Vec3, vecmath.Vec3) {
+	t := b.triangles[index]
+	return vecmath.Min(t.a, vecmath.Min(t.b, t.c)), vecmath.Max(t.a, vecmath.Max(t.b, t.c))
+}
+
+// centroid returns the center of a triangle.
+func (b *Bvh) centroid(index int) vecmath.Vec3 {
+	t := b.triangles[index]
+	return vecmath.Scale(vecmath.Add(t.a, vecmath.Add(t.b, t.c)), 1.0/3)
+}
+
+// intersectBox returns true if the segment origin + t*direction with t in [0, maxT] touches the box.
+func intersectBox(origin, inverse vecmath.Vec3, maxT float64, min, max vecmath.Vec3) bool {
+	tmin, tmax := 0.0, maxT
+	for axis := 0; axis < 3; axis++ {
+		o, inv := component(origin, axis), component(inverse, axis)
+		lo, hi := component(min, axis), component(max, axis)
+		if math.IsInf(inv, 0) {
+			if o < lo || o > hi {
+				return false
+			}
+			continue
+		}
+
+		t1, t2 := (lo-o)*inv, (hi-o)*inv
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		tmin, tmax = math.Max(tmin, t1), math.Min(tmax, t2)
+		if tmin > tmax {
+			return false
+		}
+	}
+	return true
+}
+
+// toVec3 converts a vector to double precision.
+func toVec3(v datatypes.Vec3) vecmath.Vec3 {
+	return vecmath.New(float64(v.X), float64(v.Y), float64(v.Z))
+}
+
+// toDatatypesVec3 converts a vector back to single precision.
+func toDatatypesVec3(v vecmath.Vec3) datatypes.Vec3 {
+	return datatypes.Vec3{X: float32(v.X), Y: float32(v.Y), Z: float32(v.Z)}
+}
+
+// component returns the x, y or z component of v for axis 0, 1 or 2.
+func component(v vecmath.Vec3, axis int) float64 {
+	switch axis {
+	case 0:
+		return v.X
+	case 1:
+		return v.Y
+	}
+	return v.Z
+}
@@ -0,0 +1,121 @@
+// Package collision answers raycast, line of sight, ground height and sphere sweep queries
+// against zone collision geometry.
+//
+// The collision triangles are stored in a bounding volume hierarchy. All positions are in
+// EQ world space, the same coordinates the WLD files use. Triangles are double sided.
+package collision
+
+import (
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// Triangle is a collision triangle in EQ world space.
+type Triangle [3]datatypes.Vec3
+
+// Hit describes where a query hit the collision geometry.
+type Hit struct {
+	// Position is the hit point of a ray, or the sphere center at contact for a sweep.
+	Position datatypes.Vec3
+
+	// Normal is the unit surface normal at the hit, facing the query origin.
+	Normal datatypes.Vec3
+
+	// Distance is the distance travelled from the query origin.
+	Distance float32
+
+	// Triangle is the index of the triangle that was hit.
+	Triangle int
+}
+
+// Polygon is a collision triangle with the facing given by the stored vertex normals.
+type Polygon struct {
+	Triangle Triangle
+
+	// Normal is the sum of the vertex normals, negated as they are stored inverted.
+	// It is zero if the mesh has no normals, e.g. for collision polyhedrons.
+	Normal datatypes.Vec3
+}
+
+// CollisionTriangles returns the collision polygons of the meshes in world space.
+// Meshes with a separate collision mesh only collide with their solid polygons, and legacy
+// meshes with a collision polyhedron collide with the polyhedron instead of their polygons.
+func CollisionTriangles(meshes []*fragments.Mesh, legacyMeshes []*fragments.LegacyMesh) []Triangle {
+	polygons := CollisionPolygons(meshes, legacyMeshes)
+
+	triangles := make([]Triangle, len(polygons))
+	for i, polygon := range polygons {
+		triangles[i] = polygon.Triangle
+	}
+	return triangles
+}
+
+// CollisionPolygons returns the same polygons as CollisionTriangles with their stored facing.
+func CollisionPolygons(meshes []*fragments.Mesh, legacyMeshes []*fragments.LegacyMesh) []Polygon {
+	var polygons []Polygon
+
+	for _, mesh := range meshes {
+		if mesh == nil {
+			continue
+		}
+
+		center := datatypes.Vec3{X: mesh.Center.X, Y: mesh.Center.Y, Z: mesh.Center.Z}
+		vertices := make([]datatypes.Vec3, len(mesh.Vertices))
+		for i, v := range mesh.Vertices {
+			vertices[i] = datatypes.Vec3{X: v.X, Y: v.Y, Z: v.Z}
+		}
+		normals := make([]datatypes.Vec3, len(mesh.Normals))
+		for i, n := range mesh.Normals {
+			normals[i] = datatypes.Vec3{X: n.X, Y: n.Y, Z: n.Z}
+		}
+
+		for _, polygon := range mesh.Indices {
+			if mesh.ExportSeparateCollision && !polygon.IsSolid {
+				continue
+			}
+			polygons = appendPolygon(polygons, vertices, normals, center, polygon)
+		}
+	}
+
+	for _, mesh := range legacyMeshes {
+		if mesh == nil {
+			continue
+		}
+
+		if mesh.ExportSeparateCollision && mesh.PolyhedronReference != nil {
+			if polyhedron := mesh.PolyhedronReference.Polyhedron; polyhedron != nil {
+				for _, face := range polyhedron.Faces {
+					polygons = appendPolygon(polygons, polyhedron.Vertices, nil, mesh.Center, *face)
+				}
+			}
+			continue
+		}
+
+		for _, polygon := range mesh.Polygons {
+			if !polygon.IsSolid {
+				continue
+			}
+			polygons = appendPolygon(polygons, mesh.Vertices, mesh.Normals, mesh.Center, *polygon)
+		}
+	}
+
+	return polygons
+}
+
+// appendPolygon appends a polygon offset by the mesh center, skipping polygons with invalid indices.
+func appendPolygon(polygons []Polygon, vertices, normals []datatypes.Vec3, center datatypes.Vec3, polygon datatypes.Polygon) []Polygon {
+	var result Polygon
+	for i, index := range [3]int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3} {
+		if index < 0 || index >= len(vertices) {
+			return polygons
+		}
+		v := vertices[index]
+		result.Triangle[i] = datatypes.Vec3{X: v.X + center.X, Y: v.Y + center.Y, Z: v.Z + center.Z}
+
+		if index < len(normals) {
+			n := normals[index]
+			result.Normal = datatypes.Vec3{X: result.Normal.X - n.X, Y: result.Normal.Y - n.Y, Z: result.Normal.Z - n.Z}
+		}
+	}
+	return append(polygons, result)
+}
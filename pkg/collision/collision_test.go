@@ -0,0 +1,92 @@
+package collision
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// testBvh builds a 20x20 floor at z = 0 split into a grid and a wall at x = 5 up to z = 10.
+func testBvh() *Bvh {
+	var triangles []Triangle
+	for x := -10; x < 10; x += 2 {
+		for y := -10; y < 10; y += 2 {
+			x0, y0, x1, y1 := float32(x), float32(y), float32(x+2), float32(y+2)
+			triangles = append(triangles,
+				Triangle{{X: x0, Y: y0}, {X: x1, Y: y0}, {X: x1, Y: y1}},
+				Triangle{{X: x0, Y: y0}, {X: x1, Y: y1}, {X: x0, Y: y1}})
+		}
+	}
+
+	triangles = append(triangles,
+		Triangle{{X: 5, Y: -10}, {X: 5, Y: 10}, {X: 5, Y: 10, Z: 10}},
+		Triangle{{X: 5, Y: -10}, {X: 5, Y: 10, Z: 10}, {X: 5, Y: -10, Z: 10}})
+
+	return NewBvh(triangles)
+}
+
+// near returns true if two values are within a small tolerance.
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+func TestRaycast(t *testing.T) {
+	b := testBvh()
+
+	hit, ok := b.Raycast(datatypes.Vec3{X: 1, Y: 1, Z: 5}, datatypes.Vec3{Z: -2}, 100)
+	if !ok || !near(hit.Distance, 5) || !near(hit.Position.Z, 0) || !near(hit.Normal.Z, 1) {
+		t.Errorf("Expected the floor 5 units below, got %+v, %v", hit, ok)
+	}
+
+	if _, ok := b.Raycast(datatypes.Vec3{X: 1, Y: 1, Z: 5}, datatypes.Vec3{Z: -1}, 4); ok {
+		t.Error("Expected no hit within 4 units")
+	}
+
+	if b.LineOfSight(datatypes.Vec3{Z: 5}, datatypes.Vec3{X: 10, Z: 5}) {
+		t.Error("Expected the wall to block line of sight")
+	}
+
+	if !b.LineOfSight(datatypes.Vec3{Z: 5}, datatypes.Vec3{X: 4, Y: 3, Z: 1}) {
+		t.Error("Expected line of sight in front of the wall")
+	}
+
+	if z, ok := b.GroundHeightAt(3, 0); !ok || !near(z, 0) {
+		t.Errorf("Expected the floor at height 0, got %v, %v", z, ok)
+	}
+
+	if z, ok := b.GroundHeightBelow(datatypes.Vec3{X: -3.3, Y: 7.1, Z: 2}); !ok || !near(z, 0) {
+		t.Errorf("Expected the floor below, got %v, %v", z, ok)
+	}
+
+	if _, ok := b.GroundHeightAt(20, 0); ok {
+		t.Error("Expected no ground outside the zone")
+	}
+}
+
+func TestSweepSphere(t *testing.T) {
+	b := testBvh()
+
+	// Face contact with the wall
+	hit, ok := b.SweepSphere(datatypes.Vec3{Z: 5}, datatypes.Vec3{X: 10, Z: 5}, 1)
+	if !ok || !near(hit.Position.X, 4) || !near(hit.Distance, 4) || !near(hit.Normal.X, -1) {
+		t.Errorf("Expected to stop 1 unit in front of the wall, got %+v, %v", hit, ok)
+	}
+
+	// Edge contact with the top of the wall
+	hit, ok = b.SweepSphere(datatypes.Vec3{Z: 10.5}, datatypes.Vec3{X: 10, Z: 10.5}, 1)
+	if !ok || !near(hit.Position.X, 5-float32(math.Sqrt(0.75))) {
+		t.Errorf("Expected to touch the top edge of the wall, got %+v, %v", hit, ok)
+	}
+
+	// Clear of the wall
+	if hit, ok := b.SweepSphere(datatypes.Vec3{Z: 11.5}, datatypes.Vec3{X: 10, Z: 11.5}, 1); ok {
+		t.Errorf("Expected to pass over the wall, got %+v", hit)
+	}
+
+	// Already touching the floor
+	hit, ok = b.SweepSphere(datatypes.Vec3{Z: 0.5}, datatypes.Vec3{X: -5, Z: 0.5}, 1)
+	if !ok || hit.Distance != 0 {
+		t.Errorf("Expected an embedded hit at distance 0, got %+v, %v", hit, ok)
+	}
+}
@@ -0,0 +1,286 @@
+package collision
+
+import (
+	"math"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// parallelEpsilon is the determinant below which a ray counts as parallel to a triangle.
+const parallelEpsilon = 1e-12
+
+// edgeEpsilon widens triangles slightly so rays through shared edges do not slip between them.
+const edgeEpsilon = 1e-7
+
+// Raycast returns the closest hit along a ray within maxDistance.
+func (b *Bvh) Raycast(origin, direction datatypes.Vec3, maxDistance float32) (Hit, bool) {
+	o, d := toVec3(origin), vecmath.Normalize(toVec3(direction))
+	if d == (vecmath.Vec3{}) {
+		return Hit{}, false
+	}
+
+	best, hitIndex := float64(maxDistance), -1
+	inverse := vecmath.New(1/d.X, 1/d.Y, 1/d.Z)
+
+	b.traverse(func(min, max vecmath.Vec3) bool {
+		return intersectBox(o, inverse, best, min, max)
+	}, func(index int) bool {
+		if t, ok := intersectTriangle(o, d, b.triangles[index]); ok && t < best {
+			best, hitIndex = t, index
+		}
+		return true
+	})
+
+	if hitIndex < 0 {
+		return Hit{}, false
+	}
+
+	normal := b.triangles[hitIndex].normal()
+	if vecmath.Dot(normal, d) > 0 {
+		normal = vecmath.Scale(normal, -1)
+	}
+
+	return Hit{
+		Position: toDatatypesVec3(vecmath.Add(o, vecmath.Scale(d, best))),
+		Normal:   toDatatypesVec3(normal),
+		Distance: float32(best),
+		Triangle: hitIndex,
+	}, true
+}
+
+// LineOfSight returns true if no collision geometry lies between a and b.
+func (b *Bvh) LineOfSight(from, to datatypes.Vec3) bool {
+	o := toVec3(from)
+	delta := vecmath.Sub(toVec3(to), o)
+	distance := vecmath.Length(delta)
+	if distance == 0 {
+		return true
+	}
+
+	d := vecmath.Scale(delta, 1/distance)
+	inverse := vecmath.New(1/d.X, 1/d.Y, 1/d.Z)
+	visible := true
+
+	b.traverse(func(min, max vecmath.Vec3) bool {
+		return intersectBox(o, inverse, distance, min, max)
+	}, func(index int) bool {
+		if t, ok := intersectTriangle(o, d, b.triangles[index]); ok && t < distance {
+			visible = false
+		}
+		return visible
+	})
+
+	return visible
+}
+
+// GroundHeightAt returns the height of the highest surface at a horizontal position.
+func (b *Bvh) GroundHeightAt(x, y float32) (float32, bool) {
+	min, max := b.Bounds()
+	hit, ok := b.Raycast(datatypes.Vec3{X: x, Y: y, Z: max.Z + 1}, datatypes.Vec3{Z: -1}, max.Z-min.Z+2)
+	return hit.Position.Z, ok
+}
+
+// GroundHeightBelow returns the height of the first surface below a position.
+// Use this instead of GroundHeightAt where there are several floors, e.g. in dungeons.
+func (b *Bvh) GroundHeightBelow(position datatypes.Vec3) (float32, bool) {
+	hit, ok := b.Raycast(position, datatypes.Vec3{Z: -1}, math.MaxFloat32)
+	return hit.Position.Z, ok
+}
+
+// SweepSphere moves a sphere from one position to another and returns the first contact.
+// A sphere that already touches the geometry at the start hits at distance 0.
+func (b *Bvh) SweepSphere(from, to datatypes.Vec3, radius float32) (Hit, bool) {
+	p0, r := toVec3(from), float64(radius)
+	d := vecmath.Sub(toVec3(to), p0)
+	padding := vecmath.New(r, r, r)
+	inverse := vecmath.New(1/d.X, 1/d.Y, 1/d.Z)
+
+	best, hitIndex := 1.0, -1
+	var contact vecmath.Vec3
+
+	b.traverse(func(min, max vecmath.Vec3) bool {
+		return intersectBox(p0, inverse, best, vecmath.Sub(min, padding), vecmath.Add(max, padding))
+	}, func(index int) bool {
+		if t, point, ok := sweepTriangle(p0, d, r, b.triangles[index], best); ok && (hitIndex < 0 || t < best) {
+			best, hitIndex, contact = t, index, point
+		}
+		return true
+	})
+
+	if hitIndex < 0 {
+		return Hit{}, false
+	}
+
+	center := vecmath.Add(p0, vecmath.Scale(d, best))
+	normal := vecmath.Normalize(vecmath.Sub(center, contact))
+	if normal == (vecmath.Vec3{}) {
+		normal = b.triangles[hitIndex].normal()
+	}
+
+	return Hit{
+		Position: toDatatypesVec3(center),
+		Normal:   toDatatypesVec3(normal),
+		Distance: float32(best * vecmath.Length(d)),
+		Triangle: hitIndex,
+	}, true
+}
+
+// normal returns the unit normal of the triangle from its winding.
+func (t triangle) normal() vecmath.Vec3 {
+	return vecmath.Normalize(vecmath.Cross(vecmath.Sub(t.b, t.a), vecmath.Sub(t.c, t.a)))
+}
+
+// intersectTriangle returns the distance along a unit ray to a triangle from either side.
+func intersectTriangle(origin, direction vecmath.Vec3, t triangle) (float64, bool) {
+	e1, e2 := vecmath.Sub(t.b, t.a), vecmath.Sub(t.c, t.a)
+	p := vecmath.Cross(direction, e2)
+	det := vecmath.Dot(e1, p)
+	if math.Abs(det) < parallelEpsilon {
+		return 0, false
+	}
+
+	inv := 1 / det
+	s := vecmath.Sub(origin, t.a)
+	u := vecmath.Dot(s, p) * inv
+	if u < -edgeEpsilon || u > 1+edgeEpsilon {
+		return 0, false
+	}
+
+	q := vecmath.Cross(s, e1)
+	v := vecmath.Dot(direction, q) * inv
+	if v < -edgeEpsilon || u+v > 1+edgeEpsilon {
+		return 0, false
+	}
+
+	distance := vecmath.Dot(e2, q) * inv
+	return distance, distance >= 0
+}
+
+// sweepTriangle returns the first time in [0, maxT] a sphere moving along d touches a triangle,
+// and the touched point on the triangle. The face is tested first, then the edges and vertices.
+func sweepTriangle(p0, d vecmath.Vec3, r float64, t triangle, maxT float64) (float64, vecmath.Vec3, bool) {
+	n := t.normal()
+	if n == (vecmath.Vec3{}) {
+		return 0, vecmath.Vec3{}, false
+	}
+
+	distance := vecmath.Dot(n, vecmath.Sub(p0, t.a))
+	approach := vecmath.Dot(n, d)
+	if distance < 0 {
+		n, distance, approach = vecmath.Scale(n, -1), -distance, -approach
+	}
+
+	if distance < r {
+		// The sphere already cuts the plane, it either touches the triangle now or reaches it at an edge
+		closest := closestPointOnTriangle(p0, t)
+		if offset := vecmath.Sub(p0, closest); vecmath.Dot(offset, offset) < r*r {
+			return 0, closest, true
+		}
+	} else if approach < 0 {
+		time := (distance - r) / -approach
+		if time <= maxT {
+			contact := vecmath.Sub(vecmath.Add(p0, vecmath.Scale(d, time)), vecmath.Scale(n, r))
+			if pointInTriangle(contact, t, n) {
+				return time, contact, true
+			}
+		}
+	}
+
+	found := false
+	var contact vecmath.Vec3
+
+	for _, v := range [3]vecmath.Vec3{t.a, t.b, t.c} {
+		offset := vecmath.Sub(p0, v)
+		if time, ok := lowestRoot(vecmath.Dot(d, d), 2*vecmath.Dot(d, offset), vecmath.Dot(offset, offset)-r*r, maxT); ok {
+			maxT, contact, found = time, v, true
+		}
+	}
+
+	for _, e := range [3][2]vecmath.Vec3{{t.a, t.b}, {t.b, t.c}, {t.c, t.a}} {
+		edge, base := vecmath.Sub(e[1], e[0]), vecmath.Sub(e[0], p0)
+		edgeSq, edgeDotVelocity, edgeDotBase := vecmath.Dot(edge, edge), vecmath.Dot(edge, d), vecmath.Dot(edge, base)
+
+		a := edgeSq*-vecmath.Dot(d, d) + edgeDotVelocity*edgeDotVelocity
+		b := edgeSq*2*vecmath.Dot(d, base) - 2*edgeDotVelocity*edgeDotBase
+		c := edgeSq*(r*r-vecmath.Dot(base, base)) + edgeDotBase*edgeDotBase
+
+		if time, ok := lowestRoot(a, b, c, maxT); ok {
+			f := (edgeDotVelocity*time - edgeDotBase) / edgeSq
+			if f >= 0 && f <= 1 {
+				maxT, contact, found = time, vecmath.Add(e[0], vecmath.Scale(edge, f)), true
+			}
+		}
+	}
+
+	return maxT, contact, found
+}
+
+// lowestRoot returns the smaller root of a*x^2 + b*x + c if it lies in [0, maxRoot].
+func lowestRoot(a, b, c, maxRoot float64) (float64, bool) {
+	if a == 0 {
+		return 0, false
+	}
+
+	determinant := b*b - 4*a*c
+	if determinant < 0 {
+		return 0, false
+	}
+
+	root := math.Sqrt(determinant)
+	r1, r2 := (-b-root)/(2*a), (-b+root)/(2*a)
+	if r1 > r2 {
+		r1 = r2
+	}
+
+	return r1, r1 >= 0 && r1 <= maxRoot
+}
+
+// pointInTriangle returns true if a point on the triangle plane lies inside the triangle.
+func pointInTriangle(p vecmath.Vec3, t triangle, n vecmath.Vec3) bool {
+	for _, e := range [3][2]vecmath.Vec3{{t.a, t.b}, {t.b, t.c}, {t.c, t.a}} {
+		if vecmath.Dot(vecmath.Cross(vecmath.Sub(e[1], e[0]), vecmath.Sub(p, e[0])), n) < -edgeEpsilon {
+			return false
+		}
+	}
+	return true
+}
+
+// closestPointOnTriangle returns the point of the triangle closest to p.
+func closestPointOnTriangle(p vecmath.Vec3, t triangle) vecmath.Vec3 {
+	ab, ac, ap := vecmath.Sub(t.b, t.a), vecmath.Sub(t.c, t.a), vecmath.Sub(p, t.a)
+	d1, d2 := vecmath.Dot(ab, ap), vecmath.Dot(ac, ap)
+	if d1 <= 0 && d2 <= 0 {
+		return t.a
+	}
+
+	bp := vecmath.Sub(p, t.b)
+	d3, d4 := vecmath.Dot(ab, bp), vecmath.Dot(ac, bp)
+	if d3 >= 0 && d4 <= d3 {
+		return t.b
+	}
+
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return vecmath.Add(t.a, vecmath.Scale(ab, d1/(d1-d3)))
+	}
+
+	cp := vecmath.Sub(p, t.c)
+	d5, d6 := vecmath.Dot(ab, cp), vecmath.Dot(ac, cp)
+	if d6 >= 0 && d5 <= d6 {
+		return t.c
+	}
+
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return vecmath.Add(t.a, vecmath.Scale(ac, d2/(d2-d6)))
+	}
+
+	va := d3*d6 - d5*d4
+	if va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		return vecmath.Add(t.b, vecmath.Scale(vecmath.Sub(t.c, t.b), (d4-d3)/((d4-d3)+(d5-d6))))
+	}
+
+	denominator := 1 / (va + vb + vc)
+	return vecmath.Add(t.a, vecmath.Add(vecmath.Scale(ab, vb*denominator), vecmath.Scale(ac, vc*denominator)))
+}
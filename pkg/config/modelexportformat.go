@@ -0,0 +1,15 @@
+package config
+
+// ModelExportFormat represents the format for exporting models.
+type ModelExportFormat int
+
+const (
+	// ModelExportFormatIntermediate exports to intermediate text format.
+	ModelExportFormatIntermediate ModelExportFormat = iota
+
+	// ModelExportFormatObj exports to OBJ format.
+	ModelExportFormatObj
+
+	// ModelExportFormatGltf exports to glTF format.
+	ModelExportFormatGltf
+)
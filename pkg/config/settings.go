@@ -8,7 +8,6 @@ import (
 
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
-	"github.com/tmyhres/LanternGoExtract/pkg/wld"
 )
 
 // Settings holds the configuration options for the Lantern extractor.
@@ -33,7 +32,7 @@ type Settings struct {
 	ExportHiddenGeometry bool
 
 	// ModelExportFormat sets the desired model export format.
-	ModelExportFormat wld.ModelExportFormat
+	ModelExportFormat ModelExportFormat
 
 	// ExportCharactersToSingleFolder exports all characters to one folder.
 	ExportCharactersToSingleFolder bool
@@ -45,8 +44,23 @@ type Settings struct {
 	ExportSoundsToSingleFolder bool
 
 	// ExportAllAnimationFrames exports all OBJ frames for all animations.
+	// For glTF, every skeletal animation is written to the model.
 	ExportAllAnimationFrames bool
 
+	// AnimationSampleRate resamples glTF and intermediate skeletal animations to
+	// this many frames per second. Zero keeps the frames of the EQ tracks.
+	AnimationSampleRate int
+
+	// ReduceAnimationKeyframes removes animation keyframes that interpolating
+	// their neighbours reproduces, which makes animated models much smaller.
+	ReduceAnimationKeyframes bool
+
+	// ExtractRootMotion moves the horizontal translation and turn of the skeleton
+	// root into a separate root motion curve so animations play in place. glTF
+	// stores the curve in the animation extras, the intermediate format in a
+	// '_rootmotion' file next to the animation.
+	ExtractRootMotion bool
+
 	// ExportZoneWithObjects exports zones with their objects.
 	ExportZoneWithObjects bool
 
@@ -61,6 +75,59 @@ type Settings struct {
 	// into one file. This takes more space but makes models more portable.
 	ExportGltfInGlbFormat bool
 
+	// ExportGltfSoundInstances adds the zone sound emitters and music regions to
+	// the zone glTF as empty nodes carrying their sound data in extras.
+	ExportGltfSoundInstances bool
+
+	// BakeVertexLighting computes vertex colors for zone meshes that have none
+	// from the zone light instances and ambient light. The baked colors are also
+	// written to the 'VertexColors' folder next to the zone export.
+	BakeVertexLighting bool
+
+	// ExportRegionVolumes exports the water, lava, PvP, slippery and zoneline
+	// regions of a zone as closed volumes built from the BSP tree. glTF zones get
+	// them as nodes under 'Regions', other formats as a '_regions' OBJ file.
+	ExportRegionVolumes bool
+
+	// ExportNavMesh builds a navigation mesh from the zone collision geometry and
+	// writes it as '_navmesh.obj' for viewing, '_navmesh.json' and the binary
+	// '_navmesh.nav' tile format next to the zone export.
+	ExportNavMesh bool
+
+	// ExportBvhAnimations writes every animation of skeletal models as a BVH file
+	// to the 'Animations' folder of the model export.
+	ExportBvhAnimations bool
+
+	// ExportFbxModels writes skeletal models with their skinned meshes and all
+	// animations as ASCII FBX files next to the model export.
+	ExportFbxModels bool
+
+	// RetargetAnimationArchives lists the character archives, separated by commas, whose
+	// animations are retargeted onto exported character models, so each model gets every
+	// animation the client plays for it. "characters" uses all character archives.
+	// Empty exports the animations of the model's own archive only.
+	RetargetAnimationArchives string
+
+	// ExportEquipmentManifests writes a JSON manifest for each item model with its
+	// bounds, materials, particle bones, animations and a suggested attach bone
+	// to the 'Manifests' folder of the equipment export.
+	ExportEquipmentManifests bool
+
+	// ExportTextureAtlas packs the textures of zone and character meshes into atlas
+	// pages and rewrites the mesh UVs to use them, so each mesh draws with far fewer
+	// materials. Not used for the intermediate format.
+	ExportTextureAtlas bool
+
+	// TextureAtlasSize is the largest width and height of an atlas page in pixels.
+	// Zero uses 2048.
+	TextureAtlasSize int
+
+	// TextureFormat adds a GPU texture with a full mip chain next to each PNG texture.
+	// 0 = PNG only, 1 = DDS (BC1/BC3), 2 = KTX2 (RGBA8).
+	// glTF textures reference DDS files through MSFT_texture_dds and keep the PNG as the fallback.
+	// KTX2 files are not Basis compressed, so they are only named in the texture extras.
+	TextureFormat infrastructure.TextureFormat
+
 	// ClientDataToCopy specifies additional files to copy when extracting
 	// with "all" or "clientdata".
 	ClientDataToCopy string
@@ -88,11 +155,20 @@ func NewSettings(settingsFilePath string, log logger.Logger) *Settings {
 		RawS3dExtract:        false,
 		ExportZoneMeshGroups: false,
 		ExportHiddenGeometry: false,
-		ModelExportFormat:    wld.ModelExportFormatGltf,
+		ModelExportFormat:    ModelExportFormatGltf,
 		LoggerVerbosity:      0,
 	}
 }
 
+// Logger returns the logger the settings were created with.
+// Settings created without one return a logger that discards all messages.
+func (s *Settings) Logger() logger.Logger {
+	if s.logger == nil {
+		return logger.NewNullLogger()
+	}
+	return s.logger
+}
+
 // Initialize loads settings from the settings file.
 func (s *Settings) Initialize() error {
 	data, err := os.ReadFile(s.settingsFilePath)
@@ -134,7 +210,7 @@ func (s *Settings) Initialize() error {
 
 	if val, ok := parsedSettings["ModelExportFormat"]; ok {
 		if intVal, err := strconv.Atoi(val); err == nil {
-			s.ModelExportFormat = wld.ModelExportFormat(intVal)
+			s.ModelExportFormat = ModelExportFormat(intVal)
 		}
 	}
 
@@ -154,6 +230,20 @@ func (s *Settings) Initialize() error {
 		s.ExportAllAnimationFrames = parseBool(val)
 	}
 
+	if val, ok := parsedSettings["AnimationSampleRate"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil && intVal >= 0 {
+			s.AnimationSampleRate = intVal
+		}
+	}
+
+	if val, ok := parsedSettings["ReduceAnimationKeyframes"]; ok {
+		s.ReduceAnimationKeyframes = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["ExtractRootMotion"]; ok {
+		s.ExtractRootMotion = parseBool(val)
+	}
+
 	if val, ok := parsedSettings["ExportGltfVertexColors"]; ok {
 		s.ExportGltfVertexColors = parseBool(val)
 	}
@@ -162,6 +252,54 @@ func (s *Settings) Initialize() error {
 		s.ExportGltfInGlbFormat = parseBool(val)
 	}
 
+	if val, ok := parsedSettings["ExportGltfSoundInstances"]; ok {
+		s.ExportGltfSoundInstances = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["BakeVertexLighting"]; ok {
+		s.BakeVertexLighting = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["ExportRegionVolumes"]; ok {
+		s.ExportRegionVolumes = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["ExportNavMesh"]; ok {
+		s.ExportNavMesh = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["ExportBvhAnimations"]; ok {
+		s.ExportBvhAnimations = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["ExportFbxModels"]; ok {
+		s.ExportFbxModels = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["RetargetAnimationArchives"]; ok {
+		s.RetargetAnimationArchives = val
+	}
+
+	if val, ok := parsedSettings["ExportEquipmentManifests"]; ok {
+		s.ExportEquipmentManifests = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["ExportTextureAtlas"]; ok {
+		s.ExportTextureAtlas = parseBool(val)
+	}
+
+	if val, ok := parsedSettings["TextureAtlasSize"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil && intVal >= 0 {
+			s.TextureAtlasSize = intVal
+		}
+	}
+
+	if val, ok := parsedSettings["TextureFormat"]; ok {
+		if intVal, err := strconv.Atoi(val); err == nil {
+			s.TextureFormat = infrastructure.TextureFormat(intVal)
+		}
+	}
+
 	if val, ok := parsedSettings["ClientDataToCopy"]; ok {
 		s.ClientDataToCopy = val
 	}
@@ -1,42 +1,26 @@
 package eq
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
 
 	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/atlas"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
+	"github.com/tmyhres/LanternGoExtract/pkg/lighting"
+	"github.com/tmyhres/LanternGoExtract/pkg/navmesh"
 	"github.com/tmyhres/LanternGoExtract/pkg/sound"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/bsp"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/exporters"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
 )
 
-// Settings contains configuration options for archive extraction.
-type Settings struct {
-	// EverQuestDirectory is the path to the EverQuest installation.
-	EverQuestDirectory string
-
-	// RawS3dExtract extracts raw S3D contents without WLD processing.
-	RawS3dExtract bool
-
-	// ExportSoundsToSingleFolder exports all sounds to a single "sounds" folder.
-	ExportSoundsToSingleFolder bool
-
-	// ExportCharactersToSingleFolder exports all characters to a single folder.
-	ExportCharactersToSingleFolder bool
-
-	// ExportEquipmentToSingleFolder exports all equipment to a single folder.
-	ExportEquipmentToSingleFolder bool
-
-	// ExportZoneWithObjects merges zone and object data during export.
-	ExportZoneWithObjects bool
-
-	// ModelExportFormat determines the output format for 3D models.
-	ModelExportFormat wld.ModelExportFormat
-}
-
 // Extract extracts and processes an archive file.
 //
 // The extraction process varies based on archive type:
@@ -55,7 +39,7 @@ type Settings struct {
 //   - settings: Configuration options
 //
 // Returns an error if extraction fails.
-func Extract(path, rootFolder string, log logger.Logger, settings *Settings) error {
+func Extract(path, rootFolder string, log logger.Logger, settings *config.Settings) error {
 	archiveName := getFileNameWithoutExtension(path)
 	if archiveName == "" {
 		return nil
@@ -81,7 +65,7 @@ func Extract(path, rootFolder string, log logger.Logger, settings *Settings) err
 
 	// For non-WLD archives, extract textures and sounds only
 	if !arc.IsWldArchive() {
-		writeS3dTextures(arc, filepath.Join(rootFolder, shortName), log)
+		writeS3dTextures(arc, filepath.Join(rootFolder, shortName), settings.TextureFormat, log)
 
 		if IsUsedSoundArchive(archiveName) {
 			soundPath := filepath.Join(rootFolder, shortName)
@@ -127,7 +111,7 @@ func Extract(path, rootFolder string, log logger.Logger, settings *Settings) err
 }
 
 // extractArchiveZone extracts a zone archive with its associated data.
-func extractArchiveZone(path, rootFolder string, log logger.Logger, settings *Settings, shortName string, wldFileInArchive archive.File, arc archive.Archive) {
+func extractArchiveZone(path, rootFolder string, log logger.Logger, settings *config.Settings, shortName string, wldFileInArchive archive.File, arc archive.Archive) {
 	// Some Kunark zones have a "_lit" archive for additional lighting data
 	litPath := strings.Replace(path, shortName+".s3d", shortName+"_lit.s3d", 1)
 	litPath = strings.Replace(litPath, shortName+".t3d", shortName+"_lit.t3d", 1)
@@ -139,14 +123,13 @@ func extractArchiveZone(path, rootFolder string, log logger.Logger, settings *Se
 		if err := arcLit.Initialize(); err == nil {
 			litWldFileInArchive := arcLit.GetFile(shortName + "_lit.wld")
 			if litWldFileInArchive != nil {
-				wldSettings := convertToWldSettings(settings)
-				wldFileLit = wld.NewWldFileZone(litWldFileInArchive, shortName, wld.WldTypeZone, log, wldSettings, nil)
+				wldFileLit = wld.NewWldFileZone(litWldFileInArchive, shortName, wld.WldTypeZone, log, settings, nil)
 				wldFileLit.Initialize(rootFolder, false)
 
 				// Also check for lights WLD in the lit archive
 				litLightsFile := arcLit.GetFile(shortName + "_lit.wld")
 				if litLightsFile != nil {
-					lightsWldFile := wld.NewWldFileLights(litLightsFile, shortName, wld.WldTypeLights, log, wldSettings, wldFileLit)
+					lightsWldFile := wld.NewWldFileLights(litLightsFile, shortName, wld.WldTypeLights, log, settings, wldFileLit)
 					lightsWldFile.Initialize(rootFolder, true)
 				}
 			}
@@ -154,8 +137,7 @@ func extractArchiveZone(path, rootFolder string, log logger.Logger, settings *Se
 	}
 
 	// Create main zone WLD file
-	wldSettings := convertToWldSettings(settings)
-	wldFile := wld.NewWldFileZone(wldFileInArchive, shortName, wld.WldTypeZone, log, wldSettings, wldFileLit)
+	wldFile := wld.NewWldFileZone(wldFileInArchive, shortName, wld.WldTypeZone, log, settings, wldFileLit)
 
 	// If merging zone with objects, inject additional data
 	if settings.ExportZoneWithObjects {
@@ -166,29 +148,38 @@ func extractArchiveZone(path, rootFolder string, log logger.Logger, settings *Se
 		wldFile.ShortName = shortName
 	}
 
-	texturePath := filepath.Join(rootFolder, shortName, "Zone", "Textures")
-	initializeWldAndWriteTextures(wldFile, rootFolder, texturePath, arc, settings, log)
+	// Load sounds before the zone so they can be added to the zone scene
+	soundEntries := loadSoundEntries(shortName, log, settings)
+	if settings.ExportGltfSoundInstances {
+		wldFile.AudioInstances = soundEntries.AudioInstances()
+	}
 
-	// Process lights WLD
+	// Process lights WLD before the zone so its lights can be added to the zone scene
 	lightsFileInArchive := arc.GetFile("lights" + WldFormatExtension)
 	if lightsFileInArchive != nil {
-		lightsWldFile := wld.NewWldFileLights(lightsFileInArchive, shortName, wld.WldTypeLights, log, wldSettings, wldFileLit)
+		lightsWldFile := wld.NewWldFileLights(lightsFileInArchive, shortName, wld.WldTypeLights, log, settings, wldFileLit)
 		lightsWldFile.Initialize(rootFolder, true)
+		wldFile.LightInstances = lightsWldFile.GetLightInstances()
 	}
 
+	texturePath := filepath.Join(rootFolder, shortName, "Zone", "Textures")
+	initializeWldAndWriteTextures(wldFile, rootFolder, texturePath, arc, settings, log)
+
 	// Process zone objects WLD
 	zoneObjectsFileInArchive := arc.GetFile("objects" + WldFormatExtension)
 	if zoneObjectsFileInArchive != nil {
-		zoneObjectsWldFile := wld.NewWldFileZoneObjects(zoneObjectsFileInArchive, shortName, wld.WldTypeZoneObjects, log, wldSettings, wldFileLit)
+		zoneObjectsWldFile := wld.NewWldFileZoneObjects(zoneObjectsFileInArchive, shortName, wld.WldTypeZoneObjects, log, settings, wldFileLit)
 		zoneObjectsWldFile.Initialize(rootFolder, true)
 	}
 
 	// Extract sound data
-	extractSoundData(shortName, rootFolder, log, settings)
+	if err := soundEntries.ExportSoundData(shortName, rootFolder); err != nil {
+		log.LogError("Failed to export sound data: " + err.Error())
+	}
 }
 
 // extractArchiveObjects extracts an objects archive.
-func extractArchiveObjects(path, rootFolder string, log logger.Logger, settings *Settings, wldFileInArchive archive.File, shortName string, arc archive.Archive) {
+func extractArchiveObjects(path, rootFolder string, log logger.Logger, settings *config.Settings, wldFileInArchive archive.File, shortName string, arc archive.Archive) {
 	// Some zones have a "_2_obj" archive for additional objects
 	obj2Path := strings.Replace(path, shortName+"_obj", shortName+"_2_obj", 1)
 	arcObj2, _ := archive.GetArchive(obj2Path, log)
@@ -198,15 +189,13 @@ func extractArchiveObjects(path, rootFolder string, log logger.Logger, settings
 		if err := arcObj2.Initialize(); err == nil {
 			obj2WldFileInArchive := arcObj2.GetFile(shortName + "_2_obj.wld")
 			if obj2WldFileInArchive != nil {
-				wldSettings := convertToWldSettings(settings)
-				wldFileObj2 = wld.NewWldFileZone(obj2WldFileInArchive, shortName, wld.WldTypeZone, log, wldSettings, nil)
+				wldFileObj2 = wld.NewWldFileZone(obj2WldFileInArchive, shortName, wld.WldTypeZone, log, settings, nil)
 				wldFileObj2.Initialize(rootFolder, false)
 			}
 		}
 	}
 
-	wldSettings := convertToWldSettings(settings)
-	wldFile := wld.NewWldFileZone(wldFileInArchive, shortName, wld.WldTypeObjects, log, wldSettings, wldFileObj2)
+	wldFile := wld.NewWldFileZone(wldFileInArchive, shortName, wld.WldTypeObjects, log, settings, wldFileObj2)
 
 	correctShortname := GetCorrectZoneShortname(shortName)
 	texturePath := filepath.Join(rootFolder, correctShortname, "Objects", "Textures")
@@ -214,7 +203,7 @@ func extractArchiveObjects(path, rootFolder string, log logger.Logger, settings
 }
 
 // extractArchiveCharacters extracts a characters archive.
-func extractArchiveCharacters(path, rootFolder string, log logger.Logger, settings *Settings, archiveName string, wldFileInArchive archive.File, shortName string, arc archive.Archive) {
+func extractArchiveCharacters(path, rootFolder string, log logger.Logger, settings *config.Settings, archiveName string, wldFileInArchive archive.File, shortName string, arc archive.Archive) {
 	var wldFileToInject wld.WldFile
 
 	// global3_chr contains only animations and needs global_chr data
@@ -233,19 +222,17 @@ func extractArchiveCharacters(path, rootFolder string, log logger.Logger, settin
 
 		wldFileInArchive2 := arc2.GetFile("global_chr.wld")
 		if wldFileInArchive2 != nil {
-			wldSettings := convertToWldSettings(settings)
-			wldToInject := wld.NewWldFileCharacters(wldFileInArchive2, "global_chr", wld.WldTypeCharacters, log, wldSettings, nil)
+			wldToInject := wld.NewWldFileCharacters(wldFileInArchive2, "global_chr", wld.WldTypeCharacters, log, settings, nil)
 			wldToInject.Initialize(rootFolder, false)
 			wldFileToInject = wldToInject
 		}
 	}
 
-	wldSettings := convertToWldSettings(settings)
-	wldFile := wld.NewWldFileCharacters(wldFileInArchive, shortName, wld.WldTypeCharacters, log, wldSettings, wldFileToInject)
+	wldFile := wld.NewWldFileCharacters(wldFileInArchive, shortName, wld.WldTypeCharacters, log, settings, wldFileToInject)
 
 	// Determine export path
 	var exportPath string
-	if settings.ExportCharactersToSingleFolder && settings.ModelExportFormat == wld.ModelExportFormatIntermediate {
+	if settings.ExportCharactersToSingleFolder && settings.ModelExportFormat == config.ModelExportFormatIntermediate {
 		exportPath = filepath.Join(rootFolder, "characters", "Textures")
 	} else {
 		correctShortname := GetCorrectZoneShortname(shortName)
@@ -256,22 +243,20 @@ func extractArchiveCharacters(path, rootFolder string, log logger.Logger, settin
 }
 
 // extractArchiveSky extracts a sky archive.
-func extractArchiveSky(rootFolder string, log logger.Logger, settings *Settings, wldFileInArchive archive.File, shortName string, arc archive.Archive) {
-	wldSettings := convertToWldSettings(settings)
-	wldFile := wld.NewWldFileZone(wldFileInArchive, shortName, wld.WldTypeSky, log, wldSettings, nil)
+func extractArchiveSky(rootFolder string, log logger.Logger, settings *config.Settings, wldFileInArchive archive.File, shortName string, arc archive.Archive) {
+	wldFile := wld.NewWldFileZone(wldFileInArchive, shortName, wld.WldTypeSky, log, settings, nil)
 
 	texturePath := filepath.Join(rootFolder, shortName, "Textures")
 	initializeWldAndWriteTextures(wldFile, rootFolder, texturePath, arc, settings, log)
 }
 
 // extractArchiveEquipment extracts an equipment archive.
-func extractArchiveEquipment(rootFolder string, log logger.Logger, settings *Settings, wldFileInArchive archive.File, shortName string, arc archive.Archive) {
-	wldSettings := convertToWldSettings(settings)
-	wldFile := wld.NewWldFileEquipment(wldFileInArchive, shortName, wld.WldTypeEquipment, log, wldSettings, nil)
+func extractArchiveEquipment(rootFolder string, log logger.Logger, settings *config.Settings, wldFileInArchive archive.File, shortName string, arc archive.Archive) {
+	wldFile := wld.NewWldFileEquipment(wldFileInArchive, shortName, wld.WldTypeEquipment, log, settings, nil)
 
 	// Determine export path
 	var exportPath string
-	if settings.ExportEquipmentToSingleFolder && settings.ModelExportFormat == wld.ModelExportFormatIntermediate {
+	if settings.ExportEquipmentToSingleFolder && settings.ModelExportFormat == config.ModelExportFormatIntermediate {
 		exportPath = filepath.Join(rootFolder, "equipment", "Textures")
 	} else {
 		exportPath = filepath.Join(rootFolder, shortName, "Textures")
@@ -281,21 +266,184 @@ func extractArchiveEquipment(rootFolder string, log logger.Logger, settings *Set
 }
 
 // initializeWldAndWriteTextures initializes a WLD file and writes its textures.
-func initializeWldAndWriteTextures(wldFile wld.WldFile, rootFolder, texturePath string, arc archive.Archive, settings *Settings, log logger.Logger) {
-	if settings.ModelExportFormat != wld.ModelExportFormatGltf {
+func initializeWldAndWriteTextures(wldFile wld.WldFile, rootFolder, texturePath string, arc archive.Archive, settings *config.Settings, log logger.Logger) {
+	if settings.ModelExportFormat != config.ModelExportFormatGltf {
 		// Standard flow: initialize, then write textures
 		wldFile.Initialize(rootFolder, true)
-		writeWldTextures(arc, wldFile, texturePath, log)
+		retargetCharacterAnimations(wldFile, settings, log)
+		writeWldTextures(arc, wldFile, texturePath, settings.TextureFormat, log)
+		buildTextureAtlases(arc, wldFile, texturePath, settings, log)
+		bakeZoneVertexLighting(wldFile, settings, log)
+		exportWldMeshes(wldFile, settings, log)
+		exportRegionVolumes(wldFile, settings, log)
+		exportZoneNavMesh(wldFile, settings, log)
+		exportSkeletalAnimations(wldFile, settings, log)
+		exportEquipmentManifests(wldFile, settings, log)
 	} else {
 		// glTF export requires textures to be present first
 		wldFile.Initialize(rootFolder, false)
-		writeWldTextures(arc, wldFile, texturePath, log)
+		retargetCharacterAnimations(wldFile, settings, log)
+		writeWldTextures(arc, wldFile, texturePath, settings.TextureFormat, log)
+		buildTextureAtlases(arc, wldFile, texturePath, settings, log)
+		bakeZoneVertexLighting(wldFile, settings, log)
 		exportWldToGltf(wldFile, settings, log)
+		exportZoneNavMesh(wldFile, settings, log)
+		exportSkeletalAnimations(wldFile, settings, log)
+		exportEquipmentManifests(wldFile, settings, log)
+	}
+}
+
+// retargetCharacterAnimations adds the animations of the RetargetAnimationArchives setting
+// that the character models play but their own archive lacks, so they are exported with the models.
+func retargetCharacterAnimations(wldFile wld.WldFile, settings *config.Settings, log logger.Logger) {
+	if settings.RetargetAnimationArchives == "" || wldFile.GetWldType() != wld.WldTypeCharacters {
+		return
+	}
+
+	library, err := getExtractionAnimationLibrary(log, settings)
+	if err != nil {
+		log.LogError("Failed to load animation library: " + err.Error())
+		return
+	}
+
+	// The cached library is shared by every archive, so the archive's own tracks go into a copy
+	library = library.Overlay(wld.GetFragmentsByType[*fragments.TrackFragment](wldFile))
+	retargetedCount := 0
+
+	for _, actor := range wldFile.GetActors() {
+		if actor.SkeletonReference == nil || actor.SkeletonReference.SkeletonHierarchy == nil {
+			continue
+		}
+		retargetedCount += len(library.Retarget(actor.SkeletonReference.SkeletonHierarchy))
+	}
+
+	if retargetedCount > 0 {
+		log.LogInfo(fmt.Sprintf("Retargeted %d animations onto character models", retargetedCount))
+	}
+}
+
+// buildTextureAtlases packs the textures of zone and character meshes into atlas pages
+// written next to the other textures and rewrites the meshes to use them.
+// Zones share one atlas. Characters get one atlas per model.
+func buildTextureAtlases(arc archive.Archive, wldFile wld.WldFile, texturePath string, settings *config.Settings, log logger.Logger) {
+	if !settings.ExportTextureAtlas || settings.ModelExportFormat == config.ModelExportFormatIntermediate {
+		return
+	}
+
+	options := atlas.DefaultOptions()
+	if settings.TextureAtlasSize > 0 {
+		options.MaxSize = settings.TextureAtlasSize
+	}
+
+	type atlasGroup struct {
+		name   string
+		meshes []*fragments.Mesh
+	}
+
+	var groups []atlasGroup
+	switch wldFile.GetWldType() {
+	case wld.WldTypeZone:
+		groups = append(groups, atlasGroup{name: wldFile.GetZoneName(), meshes: wldFile.GetMeshes()})
+	case wld.WldTypeCharacters:
+		for _, actor := range wldFile.GetActors() {
+			meshes, _ := exporters.GetActorMeshes(actor)
+			groups = append(groups, atlasGroup{name: helpers.CleanActorName(actor.GetName()), meshes: meshes})
+		}
+	default:
+		return
+	}
+
+	textures := loadWldTextures(arc, wldFile, log)
+	pageCount, meshCount := 0, 0
+
+	for _, group := range groups {
+		textureAtlas := atlas.Build(group.name, group.meshes, textures, options)
+		if len(textureAtlas.Pages) == 0 {
+			continue
+		}
+
+		if err := textureAtlas.WritePages(texturePath, settings.TextureFormat); err != nil {
+			log.LogError("Failed to write texture atlas: " + err.Error())
+			continue
+		}
+
+		for _, mesh := range group.meshes {
+			if textureAtlas.Apply(mesh) {
+				meshCount++
+			}
+		}
+		pageCount += len(textureAtlas.Pages)
+	}
+
+	if pageCount > 0 {
+		log.LogInfo(fmt.Sprintf("Packed textures of %d meshes into %d atlas pages", meshCount, pageCount))
+	}
+}
+
+// bakeZoneVertexLighting bakes vertex colors for zone meshes without any and writes them as sidecar files.
+// It runs after the zone WLD is initialized, which imports the prebaked colors of the _lit archive,
+// so only meshes the client has no colors for are baked.
+func bakeZoneVertexLighting(wldFile wld.WldFile, settings *config.Settings, log logger.Logger) {
+	zoneWldFile, ok := wldFile.(*wld.WldFileZone)
+	if !ok || !settings.BakeVertexLighting || wldFile.GetWldType() != wld.WldTypeZone {
+		return
+	}
+
+	ambient := datatypes.NewColor(0, 0, 0, 255)
+	if ambientLights := wld.GetFragmentsByType[*fragments.GlobalAmbientLight](wldFile); len(ambientLights) > 0 {
+		ambient = ambientLights[0].Color
+	}
+
+	options := lighting.DefaultOptions()
+	if bspTrees := wld.GetFragmentsByType[*fragments.BspTree](wldFile); len(bspTrees) > 0 {
+		options.Occluder = lighting.NewBspOccluder(bspTrees[0])
+	}
+
+	baker := lighting.NewBaker(zoneWldFile.LightInstances, ambient, options)
+	baked := baker.ApplyToMeshes(wldFile.GetMeshes(), true)
+	sidecarFolder := filepath.Join(wldFile.GetExportFolderForWldType(), "VertexColors")
+
+	for _, mesh := range baked {
+		meshName := helpers.CleanName(mesh.GetName(), "Mesh", true)
+		if err := lighting.WriteVertexColorSidecar(filepath.Join(sidecarFolder, meshName+".txt"), mesh.Colors); err != nil {
+			log.LogError("Failed to write baked vertex colors: " + err.Error())
+		}
+	}
+
+	if len(baked) > 0 {
+		log.LogInfo(fmt.Sprintf("Baked vertex lighting for %d meshes", len(baked)))
+	}
+}
+
+// exportWldMeshes exports the meshes of a WLD file in the intermediate or OBJ format.
+func exportWldMeshes(wldFile wld.WldFile, settings *config.Settings, log logger.Logger) {
+	meshes := wldFile.GetMeshes()
+	materialLists := wldFile.GetMaterialLists()
+	exportFolder := wldFile.GetExportFolderForWldType()
+	zoneName := wldFile.GetZoneName()
+	wldType := wldFile.GetWldType()
+
+	switch settings.ModelExportFormat {
+	case config.ModelExportFormatIntermediate:
+		legacyMeshes := wld.GetFragmentsByType[*fragments.LegacyMesh](wldFile)
+		if err := exporters.ExportMeshes(meshes, legacyMeshes, materialLists, wldType, exportFolder, zoneName, settings, log); err != nil {
+			log.LogError("Failed to export meshes: " + err.Error())
+		}
+
+		skeletons := wld.GetFragmentsByType[*fragments.SkeletonHierarchy](wldFile)
+		if err := exporters.ExportSkeletonsAndAnimations(skeletons, wldType, exportFolder, settings); err != nil {
+			log.LogError("Failed to export skeletons and animations: " + err.Error())
+		}
+	case config.ModelExportFormatObj:
+		actors := wldFile.GetActors()
+		if err := exporters.ExportActorsToObj(actors, meshes, materialLists, nil, wldType, exportFolder, zoneName, settings); err != nil {
+			log.LogError("Failed to export to OBJ: " + err.Error())
+		}
 	}
 }
 
 // exportWldToGltf exports a WLD file to glTF format.
-func exportWldToGltf(wldFile wld.WldFile, settings *Settings, log logger.Logger) {
+func exportWldToGltf(wldFile wld.WldFile, settings *config.Settings, log logger.Logger) {
 	actors := wldFile.GetActors()
 	meshes := wldFile.GetMeshes()
 	materialLists := wldFile.GetMaterialLists()
@@ -304,19 +452,124 @@ func exportWldToGltf(wldFile wld.WldFile, settings *Settings, log logger.Logger)
 	zoneName := wldFile.GetZoneName()
 	wldType := wldFile.GetWldType()
 
-	exportSettings := &exporters.ActorExportSettings{
-		ExportGltfInGlbFormat:          false,
-		ExportGltfVertexColors:         false,
-		ExportZoneWithObjects:          settings.ExportZoneWithObjects,
-		ExportAllAnimationFrames:       false,
-		ExportCharactersToSingleFolder: settings.ExportCharactersToSingleFolder,
+	if err := exporters.ExportActorsToGltf(actors, meshes, materialLists, wldType, zoneName, exportFolder, settings, getZoneSceneData(wldFile, settings)); err != nil {
+		log.LogError("Failed to export to glTF: " + err.Error())
 	}
+}
 
-	if err := exporters.ExportActorsToGltf(actors, meshes, materialLists, wldType, zoneName, exportFolder, exportSettings); err != nil {
-		log.LogError("Failed to export to glTF: " + err.Error())
+// getZoneSceneData collects the lights, sounds and regions of a zone WLD file for the zone scene.
+func getZoneSceneData(wldFile wld.WldFile, settings *config.Settings) *exporters.ZoneSceneData {
+	zoneWldFile, ok := wldFile.(*wld.WldFileZone)
+	if !ok || wldFile.GetWldType() != wld.WldTypeZone {
+		return nil
+	}
+
+	sceneData := &exporters.ZoneSceneData{
+		LightInstances: zoneWldFile.LightInstances,
+		AudioInstances: zoneWldFile.AudioInstances,
+	}
+
+	if ambientLights := wld.GetFragmentsByType[*fragments.GlobalAmbientLight](wldFile); len(ambientLights) > 0 {
+		sceneData.AmbientLight = ambientLights[0]
+	}
+
+	if settings.ExportRegionVolumes {
+		sceneData.RegionVolumes = buildRegionVolumes(wldFile)
+	}
+
+	return sceneData
+}
+
+// exportRegionVolumes writes the region volumes of a zone WLD file to an OBJ file.
+func exportRegionVolumes(wldFile wld.WldFile, settings *config.Settings, log logger.Logger) {
+	if !settings.ExportRegionVolumes || wldFile.GetWldType() != wld.WldTypeZone {
+		return
+	}
+
+	volumes := buildRegionVolumes(wldFile)
+	if err := exporters.ExportRegionVolumesToObj(volumes, wldFile.GetExportFolderForWldType(), wldFile.GetZoneName()); err != nil {
+		log.LogError("Failed to export region volumes: " + err.Error())
 	}
 }
 
+// exportZoneNavMesh builds a navmesh from the zone collision geometry and writes it in all navmesh formats.
+func exportZoneNavMesh(wldFile wld.WldFile, settings *config.Settings, log logger.Logger) {
+	if !settings.ExportNavMesh || wldFile.GetWldType() != wld.WldTypeZone {
+		return
+	}
+
+	var tree *fragments.BspTree
+	if bspTrees := wld.GetFragmentsByType[*fragments.BspTree](wldFile); len(bspTrees) > 0 {
+		tree = bspTrees[0]
+	}
+
+	legacyMeshes := wld.GetFragmentsByType[*fragments.LegacyMesh](wldFile)
+	triangles := navmesh.CollisionTriangles(wldFile.GetMeshes(), legacyMeshes)
+	navMesh := navmesh.Build(triangles, tree, navmesh.DefaultConfig())
+	basePath := wldFile.GetExportFolderForWldType() + wldFile.GetZoneName() + "_navmesh"
+
+	if err := navMesh.WriteObj(basePath + ".obj"); err != nil {
+		log.LogError("Failed to export navmesh: " + err.Error())
+	}
+	if err := navMesh.WriteJSON(basePath + ".json"); err != nil {
+		log.LogError("Failed to export navmesh: " + err.Error())
+	}
+	if err := navMesh.WriteBinary(basePath + ".nav"); err != nil {
+		log.LogError("Failed to export navmesh: " + err.Error())
+	}
+
+	log.LogInfo(fmt.Sprintf("Built navmesh with %d polygons in %d tiles", navMesh.PolygonCount(), len(navMesh.Tiles)))
+}
+
+// exportSkeletalAnimations writes the skeletal models of a WLD file as FBX and their animations as BVH files.
+func exportSkeletalAnimations(wldFile wld.WldFile, settings *config.Settings, log logger.Logger) {
+	if !settings.ExportBvhAnimations && !settings.ExportFbxModels {
+		return
+	}
+
+	isCharacterAnimation := wldFile.GetWldType() == wld.WldTypeCharacters
+	exportFolder := wldFile.GetExportFolderForWldType()
+
+	for _, actor := range wldFile.GetActors() {
+		if actor.ActorType != datatypes.ActorTypeSkeletal || actor.SkeletonReference == nil {
+			continue
+		}
+
+		if settings.ExportBvhAnimations {
+			if err := exporters.ExportSkeletonAnimationsToBvh(actor.SkeletonReference.SkeletonHierarchy, isCharacterAnimation, exportFolder); err != nil {
+				log.LogError("Failed to export BVH animations: " + err.Error())
+			}
+		}
+
+		if settings.ExportFbxModels {
+			if err := exporters.ExportSkeletalActorToFbx(actor, isCharacterAnimation, exportFolder, settings); err != nil {
+				log.LogError("Failed to export FBX model: " + err.Error())
+			}
+		}
+	}
+}
+
+// exportEquipmentManifests writes a JSON manifest for each item model of an equipment WLD file.
+func exportEquipmentManifests(wldFile wld.WldFile, settings *config.Settings, log logger.Logger) {
+	if !settings.ExportEquipmentManifests || wldFile.GetWldType() != wld.WldTypeEquipment {
+		return
+	}
+
+	if err := exporters.ExportEquipmentManifests(wldFile.GetActors(), wldFile.GetExportFolderForWldType()); err != nil {
+		log.LogError("Failed to export equipment manifests: " + err.Error())
+	}
+}
+
+// buildRegionVolumes builds the special region volumes from the zone BSP tree.
+func buildRegionVolumes(wldFile wld.WldFile) []*bsp.RegionVolume {
+	bspTrees := wld.GetFragmentsByType[*fragments.BspTree](wldFile)
+	if len(bspTrees) == 0 {
+		return nil
+	}
+
+	return bsp.BuildRegionVolumes(bspTrees[0], bsp.MeshBounds(wldFile.GetMeshes()))
+}
+
 // writeS3dSounds writes sound files from an archive to disk.
 func writeS3dSounds(arc archive.Archive, filePath string, log logger.Logger) {
 	allFiles := arc.GetAllFiles()
@@ -328,14 +581,14 @@ func writeS3dSounds(arc archive.Archive, filePath string, log logger.Logger) {
 	}
 }
 
-// writeS3dTextures writes texture files from an archive to disk as PNG.
-func writeS3dTextures(arc archive.Archive, filePath string, log logger.Logger) {
+// writeS3dTextures writes texture files from an archive to disk as PNG and in the texture format.
+func writeS3dTextures(arc archive.Archive, filePath string, format infrastructure.TextureFormat, log logger.Logger) {
 	allFiles := arc.GetAllFiles()
 
 	for _, file := range allFiles {
 		name := strings.ToLower(file.GetName())
 		if strings.HasSuffix(name, ".bmp") || strings.HasSuffix(name, ".dds") {
-			infrastructure.WriteImageAsPng(file.GetBytes(), filePath, file.GetName(), false, log)
+			infrastructure.WriteImage(file.GetBytes(), filePath, file.GetName(), false, format, log)
 		}
 	}
 }
@@ -343,11 +596,11 @@ func writeS3dTextures(arc archive.Archive, filePath string, log logger.Logger) {
 // WriteWldTextures writes textures referenced by a WLD file to disk.
 // This is exported for use by other packages that need to write WLD textures.
 func WriteWldTextures(arc archive.Archive, wldFile wld.WldFile, zoneName string, log logger.Logger) {
-	writeWldTextures(arc, wldFile, zoneName, log)
+	writeWldTextures(arc, wldFile, zoneName, infrastructure.TextureFormatPng, log)
 }
 
-// writeWldTextures writes textures referenced by a WLD file to disk.
-func writeWldTextures(arc archive.Archive, wldFile wld.WldFile, zoneName string, log logger.Logger) {
+// writeWldTextures writes textures referenced by a WLD file to disk as PNG and in the texture format.
+func writeWldTextures(arc archive.Archive, wldFile wld.WldFile, zoneName string, format infrastructure.TextureFormat, log logger.Logger) {
 	allBitmaps := wldFile.GetAllBitmapNames()
 	maskedBitmaps := getMaskedBitmaps(wldFile)
 
@@ -370,12 +623,12 @@ func writeWldTextures(arc archive.Archive, wldFile wld.WldFile, zoneName string,
 		}
 
 		isMasked := maskedBitmaps != nil && containsString(maskedBitmaps, bitmap)
-		infrastructure.WriteImageAsPng(pfsFile.GetBytes(), zoneName, bitmap, isMasked, log)
+		infrastructure.WriteImage(pfsFile.GetBytes(), zoneName, bitmap, isMasked, format, log)
 	}
 }
 
-// extractSoundData extracts sound data for a zone.
-func extractSoundData(shortName, rootFolder string, log logger.Logger, settings *Settings) {
+// loadSoundEntries loads the sound emitters and music regions for a zone.
+func loadSoundEntries(shortName string, log logger.Logger, settings *config.Settings) *sound.EffSounds {
 	envAudio := sound.NewEnvAudio()
 
 	// Try to load defaults.dat first, then defaults.eal
@@ -395,19 +648,7 @@ func extractSoundData(shortName, rootFolder string, log logger.Logger, settings
 	soundEntriesPath := settings.EverQuestDirectory + shortName + "_sounds" + SoundFormatExtension
 	soundEntries := sound.NewEffSounds(soundEntriesPath, sounds, envAudio)
 	soundEntries.Initialize(log)
-	soundEntries.ExportSoundData(shortName, rootFolder)
-}
-
-// convertToWldSettings converts extractor settings to WLD settings.
-func convertToWldSettings(settings *Settings) *wld.Settings {
-	if settings == nil {
-		return nil
-	}
-	return &wld.Settings{
-		ModelExportFormat:              settings.ModelExportFormat,
-		ExportCharactersToSingleFolder: settings.ExportCharactersToSingleFolder,
-		ExportEquipmentToSingleFolder:  settings.ExportEquipmentToSingleFolder,
-	}
+	return soundEntries
 }
 
 // Helper functions
@@ -0,0 +1,72 @@
+package eq
+
+import (
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/retarget"
+)
+
+// newTestTrack creates a single frame animation track.
+func newTestTrack(name string) *fragments.TrackFragment {
+	track := &fragments.TrackFragment{
+		TrackDefFragment: &fragments.TrackDefFragment{Frames: make([]datatypes.BoneTransform, 1)},
+	}
+	track.SetName(name)
+	return track
+}
+
+// newTestCharactersWld creates a characters WLD file with a single bone model and the given tracks.
+func newTestCharactersWld(model string, settings *config.Settings, tracks ...*fragments.TrackFragment) (wld.WldFile, *fragments.SkeletonHierarchy) {
+	boneName := model + "_DAG"
+	skeleton := &fragments.SkeletonHierarchy{
+		ModelBase:        model,
+		Skeleton:         []*fragments.SkeletonBone{{Index: 0, Name: boneName}},
+		Animations:       make(map[string]*datatypes.Animation),
+		BoneMapping:      map[int]string{0: boneName},
+		BoneMappingClean: map[int]string{0: datatypes.CleanBoneAndStripBase(boneName, model)},
+	}
+
+	wldFile := wld.NewBaseWldFile(nil, "test_chr", wld.WldTypeCharacters, logger.NewNullLogger(), settings, nil)
+	for _, track := range tracks {
+		wldFile.Fragments = append(wldFile.Fragments, track)
+	}
+	wldFile.Fragments = append(wldFile.Fragments, &fragments.Actor{
+		ActorType:         datatypes.ActorTypeSkeletal,
+		SkeletonReference: &fragments.SkeletonHierarchyReference{SkeletonHierarchy: skeleton},
+	})
+
+	return wldFile, skeleton
+}
+
+func TestRetargetCharacterAnimations(t *testing.T) {
+	settings := &config.Settings{RetargetAnimationArchives: "test_library_chr"}
+	library := retarget.NewLibrary(nil)
+	library.AddTracks([]*fragments.TrackFragment{newTestTrack("C01DWF_TRACK")})
+	extractionAnimationLibraries[settings.RetargetAnimationArchives] = library
+	defer delete(extractionAnimationLibraries, settings.RetargetAnimationArchives)
+
+	// The archive's own tracks are retargeted along with the library animations
+	first, firstSkeleton := newTestCharactersWld("dwf", settings, newTestTrack("C02DWF_TRACK"))
+	retargetCharacterAnimations(first, settings, logger.NewNullLogger())
+	if firstSkeleton.Animations["c01"] == nil || firstSkeleton.Animations["c02"] == nil {
+		t.Fatalf("Expected c01 and c02 on the first model, got %v", firstSkeleton.Animations)
+	}
+
+	// A later archive does not see the tracks of the first
+	second, secondSkeleton := newTestCharactersWld("dwf", settings)
+	retargetCharacterAnimations(second, settings, logger.NewNullLogger())
+	if secondSkeleton.Animations["c01"] == nil {
+		t.Error("Expected c01 on the second model")
+	}
+	if secondSkeleton.Animations["c02"] != nil {
+		t.Error("Expected c02 of the first archive not to be retargeted onto the second")
+	}
+	if names := library.AnimationNames("dwf"); len(names) != 1 || names[0] != "c01" {
+		t.Errorf("Expected the cached library to keep only c01, got %v", names)
+	}
+}
@@ -6,8 +6,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
-	"github.com/tmyhres/LanternGoExtract/pkg/wld"
 )
 
 const (
@@ -15,16 +15,6 @@ const (
 	ClientDataDirectory = "clientdata"
 )
 
-// ClientDataCopierSettings contains the configuration needed for client data copying.
-type ClientDataCopierSettings struct {
-	// ClientDataToCopy is a comma-separated list of files to copy from the EverQuest directory.
-	ClientDataToCopy string
-	// ModelExportFormat determines the export format for models.
-	ModelExportFormat wld.ModelExportFormat
-	// EverQuestDirectory is the path to the EverQuest installation directory.
-	EverQuestDirectory string
-}
-
 // CopyClientData copies client data files from the EverQuest directory to the export folder.
 // It only copies when the fileName is "clientdata" or "all" and the export format is Intermediate.
 //
@@ -35,7 +25,7 @@ type ClientDataCopierSettings struct {
 //   - settings: Configuration containing file list and paths
 //
 // Returns an error if copying fails.
-func CopyClientData(fileName, rootFolder string, log logger.Logger, settings *ClientDataCopierSettings) error {
+func CopyClientData(fileName, rootFolder string, log logger.Logger, settings *config.Settings) error {
 	if settings == nil {
 		return nil
 	}
@@ -44,7 +34,7 @@ func CopyClientData(fileName, rootFolder string, log logger.Logger, settings *Cl
 		return nil
 	}
 
-	if settings.ModelExportFormat != wld.ModelExportFormatIntermediate {
+	if settings.ModelExportFormat != config.ModelExportFormatIntermediate {
 		return nil
 	}
 
@@ -56,7 +46,7 @@ func CopyClientData(fileName, rootFolder string, log logger.Logger, settings *Cl
 }
 
 // writeAllClientDataFiles copies all configured client data files to the destination.
-func writeAllClientDataFiles(rootFolder string, log logger.Logger, settings *ClientDataCopierSettings) error {
+func writeAllClientDataFiles(rootFolder string, log logger.Logger, settings *config.Settings) error {
 	destDir := filepath.Join(rootFolder, ClientDataDirectory)
 
 	if err := os.MkdirAll(destDir, 0755); err != nil {
@@ -79,7 +69,7 @@ func writeAllClientDataFiles(rootFolder string, log logger.Logger, settings *Cli
 }
 
 // getClientDataFilePaths returns a list of valid file paths from the settings.
-func getClientDataFilePaths(settings *ClientDataCopierSettings) []string {
+func getClientDataFilePaths(settings *config.Settings) []string {
 	var paths []string
 
 	files := strings.Split(settings.ClientDataToCopy, ",")
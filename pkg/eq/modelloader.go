@@ -0,0 +1,212 @@
+package eq
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/retarget"
+)
+
+// ModelArchive is a character, equipment or objects WLD file loaded without exporting it.
+type ModelArchive struct {
+	// Name is the archive name without extension, e.g. "global_chr".
+	Name string
+
+	// Wld is the initialized character, equipment or objects WLD file.
+	Wld wld.WldFile
+
+	// Archive is the archive the WLD file was loaded from.
+	Archive archive.Archive
+}
+
+// LoadModelArchive loads and initializes the WLD file of a character, equipment or objects archive in the EverQuest directory.
+func LoadModelArchive(archiveName string, log logger.Logger, settings *config.Settings) (*ModelArchive, error) {
+	archiveName = strings.ToLower(strings.TrimSuffix(archiveName, ".s3d"))
+	path := filepath.Join(settings.EverQuestDirectory, archiveName+".s3d")
+
+	arc, err := archive.GetArchive(path, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive %s: %w", path, err)
+	}
+
+	if err := arc.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize archive %s: %w", path, err)
+	}
+
+	wldFileInArchive := arc.GetFile(archiveName + WldFormatExtension)
+	if wldFileInArchive == nil {
+		return nil, fmt.Errorf("archive %s has no WLD file", path)
+	}
+
+	var wldFile wld.WldFile
+	switch {
+	case IsEquipmentArchive(archiveName):
+		wldFile = wld.NewWldFileEquipment(wldFileInArchive, archiveName, wld.WldTypeEquipment, log, settings, nil)
+	case IsCharacterArchive(archiveName):
+		wldFile = wld.NewWldFileCharacters(wldFileInArchive, archiveName, wld.WldTypeCharacters, log, settings, nil)
+	case IsObjectsArchive(archiveName):
+		wldFile = wld.NewWldFileZone(wldFileInArchive, archiveName, wld.WldTypeObjects, log, settings, nil)
+	default:
+		return nil, fmt.Errorf("archive %s is not a character, equipment or objects archive", archiveName)
+	}
+
+	if err := wldFile.Initialize("", false); err != nil {
+		return nil, fmt.Errorf("failed to initialize WLD file %s: %w", archiveName, err)
+	}
+
+	return &ModelArchive{Name: archiveName, Wld: wldFile, Archive: arc}, nil
+}
+
+// FindActor returns the actor of a model, e.g. "hum" or "it10", or nil if the archive does not contain it.
+func (a *ModelArchive) FindActor(modelName string) *fragments.Actor {
+	actorName := strings.ToUpper(modelName) + "_ACTORDEF"
+	for _, actor := range wld.GetFragmentsByType[*fragments.Actor](a.Wld) {
+		if strings.EqualFold(actor.GetName(), actorName) {
+			return actor
+		}
+	}
+	return nil
+}
+
+// WriteTextures writes the textures used by the archive models to a folder as PNG files.
+func (a *ModelArchive) WriteTextures(folder string, log logger.Logger) {
+	writeWldTextures(a.Archive, a.Wld, folder, infrastructure.TextureFormatPng, log)
+}
+
+// LoadTextures decodes the textures used by the archive models, keyed by lower case bitmap filename.
+// Textures that are missing or cannot be decoded are skipped.
+func (a *ModelArchive) LoadTextures(log logger.Logger) map[string]image.Image {
+	return loadWldTextures(a.Archive, a.Wld, log)
+}
+
+// EquipmentLoader finds equipment models in the equipment archives of the EverQuest directory.
+// Archives are loaded on demand and kept for later lookups.
+type EquipmentLoader struct {
+	log      logger.Logger
+	settings *config.Settings
+	paths    []string
+	archives map[string]*ModelArchive
+}
+
+// NewEquipmentLoader creates a new equipment loader for the EverQuest directory in the settings.
+func NewEquipmentLoader(log logger.Logger, settings *config.Settings) *EquipmentLoader {
+	return &EquipmentLoader{
+		log:      log,
+		settings: settings,
+		paths:    GetValidEqFilePaths(settings.EverQuestDirectory, "equipment"),
+		archives: make(map[string]*ModelArchive),
+	}
+}
+
+// FindActor returns the actor of an equipment model, e.g. "it10", and the archive containing it.
+func (l *EquipmentLoader) FindActor(modelName string) (*fragments.Actor, *ModelArchive, error) {
+	for _, path := range l.paths {
+		fileName := strings.ToLower(filepath.Base(path))
+		if !strings.HasSuffix(fileName, ".s3d") {
+			continue
+		}
+		archiveName := strings.TrimSuffix(fileName, ".s3d")
+
+		modelArchive, exists := l.archives[archiveName]
+		if !exists {
+			loaded, err := LoadModelArchive(archiveName, l.log, l.settings)
+			if err != nil {
+				l.log.LogWarning(err.Error())
+			}
+			modelArchive = loaded
+			l.archives[archiveName] = modelArchive
+		}
+
+		if modelArchive == nil {
+			continue
+		}
+
+		if actor := modelArchive.FindActor(modelName); actor != nil {
+			return actor, modelArchive, nil
+		}
+	}
+
+	return nil, nil, fmt.Errorf("no equipment archive contains %s", modelName)
+}
+
+// LoadAnimationLibrary collects the animations of character archives into a library that retargets them
+// using the models in animationsources.txt. The "characters" keyword loads every character archive.
+func LoadAnimationLibrary(archiveNames []string, log logger.Logger, settings *config.Settings) (*retarget.Library, error) {
+	sources, err := wld.ParseAnimationSources(wld.AnimationSourcesFile)
+	if err != nil {
+		log.LogWarning(err.Error())
+	}
+
+	library := retarget.NewLibrary(sources)
+	for _, archiveName := range ExpandModelArchives(archiveNames, settings) {
+		modelArchive, err := LoadModelArchive(archiveName, log, settings)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load animation archive: %w", err)
+		}
+		library.AddWld(modelArchive.Wld)
+	}
+
+	return library, nil
+}
+
+// extractionAnimationLibraries caches the libraries used while extracting, keyed by the archive list,
+// so the character archives are loaded once per run rather than once per extracted archive.
+var extractionAnimationLibraries = make(map[string]*retarget.Library)
+
+// getExtractionAnimationLibrary returns the animation library of the RetargetAnimationArchives setting.
+func getExtractionAnimationLibrary(log logger.Logger, settings *config.Settings) (*retarget.Library, error) {
+	if library, exists := extractionAnimationLibraries[settings.RetargetAnimationArchives]; exists {
+		return library, nil
+	}
+
+	var archiveNames []string
+	for _, archiveName := range strings.Split(settings.RetargetAnimationArchives, ",") {
+		if archiveName = strings.TrimSpace(archiveName); archiveName != "" {
+			archiveNames = append(archiveNames, archiveName)
+		}
+	}
+
+	library, err := LoadAnimationLibrary(archiveNames, log, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	extractionAnimationLibraries[settings.RetargetAnimationArchives] = library
+	return library, nil
+}
+
+// ExpandModelArchives replaces the "characters", "equipment" and "objects" keywords with the names
+// of all archives of that kind in the EverQuest directory.
+func ExpandModelArchives(archiveNames []string, settings *config.Settings) []string {
+	var expanded []string
+	for _, archiveName := range archiveNames {
+		var isKind func(string) bool
+		switch strings.ToLower(archiveName) {
+		case "characters":
+			isKind = IsCharacterArchive
+		case "equipment":
+			isKind = IsEquipmentArchive
+		case "objects":
+			isKind = IsObjectsArchive
+		default:
+			expanded = append(expanded, archiveName)
+			continue
+		}
+
+		for _, path := range GetValidEqFilePaths(settings.EverQuestDirectory, "all") {
+			fileName := strings.ToLower(filepath.Base(path))
+			if strings.HasSuffix(fileName, ".s3d") && isKind(fileName) {
+				expanded = append(expanded, strings.TrimSuffix(fileName, ".s3d"))
+			}
+		}
+	}
+	return expanded
+}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
 )
 
@@ -16,14 +17,6 @@ const (
 	DefaultExportsFolder = "Exports"
 )
 
-// MusicCopierSettings contains the configuration needed for music copying.
-type MusicCopierSettings struct {
-	// CopyMusic indicates whether music files should be copied.
-	CopyMusic bool
-	// EverQuestDirectory is the path to the EverQuest installation directory.
-	EverQuestDirectory string
-}
-
 // CopyMusic copies XMI music files from the EverQuest directory to the exports folder.
 // It only operates when shortname is "music" or "all" and CopyMusic is enabled.
 //
@@ -33,7 +26,7 @@ type MusicCopierSettings struct {
 //   - settings: Configuration containing the EverQuest directory path
 //
 // Returns an error if copying fails.
-func CopyMusic(shortname string, log logger.Logger, settings *MusicCopierSettings) error {
+func CopyMusic(shortname string, log logger.Logger, settings *config.Settings) error {
 	if settings == nil {
 		return nil
 	}
@@ -91,7 +84,7 @@ func CopyMusic(shortname string, log logger.Logger, settings *MusicCopierSetting
 //   - settings: Configuration containing the EverQuest directory path
 //
 // Returns an error if copying fails.
-func CopyMusicToFolder(shortname, exportFolder string, log logger.Logger, settings *MusicCopierSettings) error {
+func CopyMusicToFolder(shortname, exportFolder string, log logger.Logger, settings *config.Settings) error {
 	if settings == nil {
 		return nil
 	}
@@ -0,0 +1,108 @@
+package eq
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/collision"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/bsp"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// Zone is a zone WLD file loaded for analysis without exporting it.
+type Zone struct {
+	// ShortName is the zone shortname, e.g. "gfaydark".
+	ShortName string
+
+	// Wld is the initialized zone WLD file.
+	Wld *wld.WldFileZone
+
+	// Archive is the zone archive the WLD file was loaded from.
+	Archive archive.Archive
+}
+
+// LoadZone loads and initializes the zone WLD file from the zone archive in the EverQuest directory.
+func LoadZone(shortName string, log logger.Logger, settings *config.Settings) (*Zone, error) {
+	shortName = strings.ToLower(shortName)
+	path := filepath.Join(settings.EverQuestDirectory, shortName+".s3d")
+
+	arc, err := archive.GetArchive(path, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archive %s: %w", path, err)
+	}
+
+	if err := arc.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize archive %s: %w", path, err)
+	}
+
+	wldFileInArchive := arc.GetFile(shortName + WldFormatExtension)
+	if wldFileInArchive == nil {
+		return nil, fmt.Errorf("archive %s has no zone WLD file", path)
+	}
+
+	wldFile := wld.NewWldFileZone(wldFileInArchive, shortName, wld.WldTypeZone, log, settings, nil)
+	if err := wldFile.Initialize("", false); err != nil {
+		return nil, fmt.Errorf("failed to initialize zone WLD file: %w", err)
+	}
+
+	return &Zone{ShortName: shortName, Wld: wldFile, Archive: arc}, nil
+}
+
+// LoadTextures decodes the textures used by the zone, keyed by lower case bitmap filename.
+// Textures that are missing or cannot be decoded are skipped.
+func (z *Zone) LoadTextures(log logger.Logger) map[string]image.Image {
+	return loadWldTextures(z.Archive, z.Wld, log)
+}
+
+// loadWldTextures decodes the textures used by a WLD file from its archive, keyed by lower case bitmap filename.
+// Textures that are missing or cannot be decoded are skipped.
+func loadWldTextures(arc archive.Archive, wldFile wld.WldFile, log logger.Logger) map[string]image.Image {
+	textures := make(map[string]image.Image)
+
+	for _, bitmap := range wldFile.GetAllBitmapNames() {
+		name := strings.ToLower(bitmap)
+		if _, exists := textures[name]; exists {
+			continue
+		}
+
+		file := arc.GetFile(name)
+		if file == nil {
+			continue
+		}
+
+		img, err := infrastructure.DecodeImage(file.GetBytes(), name)
+		if err != nil {
+			log.LogWarning(err.Error())
+			continue
+		}
+		textures[name] = img
+	}
+
+	return textures
+}
+
+// GetBspTree returns the zone BSP tree, or nil if the zone has none.
+func (z *Zone) GetBspTree() *fragments.BspTree {
+	if bspTrees := wld.GetFragmentsByType[*fragments.BspTree](z.Wld); len(bspTrees) > 0 {
+		return bspTrees[0]
+	}
+	return nil
+}
+
+// GetRegionVolumes returns the special region volumes built from the zone BSP tree.
+func (z *Zone) GetRegionVolumes() []*bsp.RegionVolume {
+	return buildRegionVolumes(z.Wld)
+}
+
+// BuildCollision returns a bounding volume hierarchy over the zone collision geometry.
+func (z *Zone) BuildCollision() *collision.Bvh {
+	legacyMeshes := wld.GetFragmentsByType[*fragments.LegacyMesh](z.Wld)
+	return collision.NewBvh(collision.CollisionTriangles(z.Wld.GetMeshes(), legacyMeshes))
+}
@@ -0,0 +1,327 @@
+package infrastructure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// DDS header flags and caps used when writing.
+const (
+	ddsdCaps        = 0x1
+	ddsdHeight      = 0x2
+	ddsdWidth       = 0x4
+	ddsdPixelFormat = 0x1000
+	ddsdMipMapCount = 0x20000
+	ddsdLinearSize  = 0x80000
+
+	ddsCapsComplex = 0x8
+	ddsCapsTexture = 0x1000
+	ddsCapsMipMap  = 0x400000
+)
+
+// EncodeDds writes a mip chain as a DDS texture. Images without transparency or with only cut-out
+// transparency are stored as DXT1 (BC1), anything with partial transparency as DXT5 (BC3).
+// Unlike the DDS files in the EQ archives, rows are stored top-down as glTF expects.
+func EncodeDds(w io.Writer, levels []*image.NRGBA) error {
+	if len(levels) == 0 {
+		return fmt.Errorf("failed to encode DDS: no image")
+	}
+
+	width, height := levels[0].Bounds().Dx(), levels[0].Bounds().Dy()
+	fourCC, blockSize := "DXT1", 8
+	hasAlpha, hasPartialAlpha := getAlphaUsage(levels[0])
+	if hasPartialAlpha {
+		fourCC, blockSize = "DXT5", 16
+	}
+
+	flags := uint32(ddsdCaps | ddsdHeight | ddsdWidth | ddsdPixelFormat | ddsdLinearSize)
+	caps := uint32(ddsCapsTexture)
+	if len(levels) > 1 {
+		flags |= ddsdMipMapCount
+		caps |= ddsCapsComplex | ddsCapsMipMap
+	}
+
+	var header [31]uint32
+	header[0] = 124
+	header[1] = flags
+	header[2] = uint32(height)
+	header[3] = uint32(width)
+	header[4] = uint32(((width + 3) / 4) * ((height + 3) / 4) * blockSize)
+	header[6] = uint32(len(levels))
+	header[18] = 32
+	header[19] = ddpfFourCC
+	header[20] = binary.LittleEndian.Uint32([]byte(fourCC))
+	header[26] = caps
+
+	if _, err := w.Write([]byte("DDS ")); err != nil {
+		return fmt.Errorf("failed to write DDS header: %w", err)
+	}
+	if err := binary.Write(w, binary.LittleEndian, header); err != nil {
+		return fmt.Errorf("failed to write DDS header: %w", err)
+	}
+
+	for _, level := range levels {
+		var data []byte
+		if hasPartialAlpha {
+			data = encodeBlocks(level, 16, encodeDXT5Block)
+		} else {
+			data = encodeBlocks(level, 8, func(pixels *[16]color.NRGBA) []byte {
+				return encodeDXT1Block(pixels, hasAlpha)
+			})
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write DDS data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// getAlphaUsage returns whether an image has transparent texels and whether any of them are
+// neither fully opaque nor fully transparent.
+func getAlphaUsage(img *image.NRGBA) (hasAlpha, hasPartialAlpha bool) {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 255 {
+			hasAlpha = true
+			if img.Pix[i] != 0 {
+				return true, true
+			}
+		}
+	}
+	return hasAlpha, false
+}
+
+// encodeBlocks encodes an image as 4x4 blocks in row order. Texels past the edges repeat the last row or column.
+func encodeBlocks(img *image.NRGBA, blockSize int, encode func(*[16]color.NRGBA) []byte) []byte {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	blockWidth, blockHeight := (width+3)/4, (height+3)/4
+	data := make([]byte, 0, blockWidth*blockHeight*blockSize)
+
+	var pixels [16]color.NRGBA
+	for by := 0; by < blockHeight; by++ {
+		for bx := 0; bx < blockWidth; bx++ {
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					pixels[y*4+x] = img.NRGBAAt(min(bx*4+x, width-1), min(by*4+y, height-1))
+				}
+			}
+			data = append(data, encode(&pixels)...)
+		}
+	}
+
+	return data
+}
+
+// encodeDXT1Block encodes a 4x4 block as DXT1. If transparency is allowed, texels below the alpha
+// cutoff use the transparent index of the three color mode.
+func encodeDXT1Block(pixels *[16]color.NRGBA, allowTransparent bool) []byte {
+	var transparent [16]bool
+	hasTransparent := false
+	for i, p := range pixels {
+		if allowTransparent && p.A < maskedAlphaCutoff {
+			transparent[i] = true
+			hasTransparent = true
+		}
+	}
+
+	c0, c1 := fitColorEndpoints(pixels, transparent)
+	if hasTransparent {
+		// Three color mode needs c0 <= c1
+		if c0 > c1 {
+			c0, c1 = c1, c0
+		}
+	} else if c0 < c1 {
+		c0, c1 = c1, c0
+	}
+
+	palette := getDXTColorPalette(c0, c1, !hasTransparent)
+	paletteSize := 4
+	if hasTransparent || c0 == c1 {
+		paletteSize = 3
+	}
+
+	var indices uint32
+	for i, p := range pixels {
+		index := uint32(3)
+		if !transparent[i] {
+			index = uint32(getNearestColor(p, palette[:paletteSize]))
+		}
+		indices |= index << (uint(i) * 2)
+	}
+
+	block := make([]byte, 8)
+	binary.LittleEndian.PutUint16(block[0:], c0)
+	binary.LittleEndian.PutUint16(block[2:], c1)
+	binary.LittleEndian.PutUint32(block[4:], indices)
+	return block
+}
+
+// encodeDXT5Block encodes a 4x4 block as DXT5 with interpolated alpha.
+func encodeDXT5Block(pixels *[16]color.NRGBA) []byte {
+	block := make([]byte, 16)
+
+	// Alpha endpoints span the block in the eight value mode, which needs alpha0 > alpha1
+	alpha0, alpha1 := uint8(0), uint8(255)
+	for _, p := range pixels {
+		alpha0, alpha1 = max(alpha0, p.A), min(alpha1, p.A)
+	}
+	block[0], block[1] = alpha0, alpha1
+
+	if alpha0 > alpha1 {
+		alphas := [8]int{int(alpha0), int(alpha1)}
+		for i := 1; i < 7; i++ {
+			alphas[i+1] = ((7-i)*int(alpha0) + i*int(alpha1)) / 7
+		}
+
+		var alphaBits uint64
+		for i, p := range pixels {
+			best, bestDistance := 0, math.MaxInt
+			for j, alpha := range alphas {
+				if distance := abs(int(p.A) - alpha); distance < bestDistance {
+					best, bestDistance = j, distance
+				}
+			}
+			alphaBits |= uint64(best) << (uint(i) * 3)
+		}
+
+		for i := 0; i < 6; i++ {
+			block[2+i] = uint8(alphaBits >> (uint(i) * 8))
+		}
+	}
+
+	// The color block always uses four colors. Transparent texels do not pull the endpoints.
+	var ignored [16]bool
+	anyVisible := false
+	for i, p := range pixels {
+		ignored[i] = p.A == 0
+		anyVisible = anyVisible || !ignored[i]
+	}
+	if !anyVisible {
+		ignored = [16]bool{}
+	}
+
+	c0, c1 := fitColorEndpoints(pixels, ignored)
+	if c0 < c1 {
+		c0, c1 = c1, c0
+	}
+	palette := getDXTColorPalette(c0, c1, true)
+
+	var indices uint32
+	for i, p := range pixels {
+		indices |= uint32(getNearestColor(p, palette[:])) << (uint(i) * 2)
+	}
+
+	binary.LittleEndian.PutUint16(block[8:], c0)
+	binary.LittleEndian.PutUint16(block[10:], c1)
+	binary.LittleEndian.PutUint32(block[12:], indices)
+	return block
+}
+
+// fitColorEndpoints returns two RGB565 endpoints at the ends of the principal axis of the block colors.
+// Ignored texels do not contribute.
+func fitColorEndpoints(pixels *[16]color.NRGBA, ignored [16]bool) (uint16, uint16) {
+	var mean [3]float64
+	count := 0
+	for i, p := range pixels {
+		if ignored[i] {
+			continue
+		}
+		mean[0] += float64(p.R)
+		mean[1] += float64(p.G)
+		mean[2] += float64(p.B)
+		count++
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	for i := range mean {
+		mean[i] /= float64(count)
+	}
+
+	var covariance [3][3]float64
+	for i, p := range pixels {
+		if ignored[i] {
+			continue
+		}
+		d := [3]float64{float64(p.R) - mean[0], float64(p.G) - mean[1], float64(p.B) - mean[2]}
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				covariance[j][k] += d[j] * d[k]
+			}
+		}
+	}
+
+	// Power iteration finds the axis of largest variance
+	axis := [3]float64{1, 1, 1}
+	for iteration := 0; iteration < 8; iteration++ {
+		var next [3]float64
+		for j := 0; j < 3; j++ {
+			next[j] = covariance[j][0]*axis[0] + covariance[j][1]*axis[1] + covariance[j][2]*axis[2]
+		}
+		length := math.Sqrt(next[0]*next[0] + next[1]*next[1] + next[2]*next[2])
+		if length == 0 {
+			break
+		}
+		axis = [3]float64{next[0] / length, next[1] / length, next[2] / length}
+	}
+
+	low, high := math.Inf(1), math.Inf(-1)
+	for i, p := range pixels {
+		if ignored[i] {
+			continue
+		}
+		projection := (float64(p.R)-mean[0])*axis[0] + (float64(p.G)-mean[1])*axis[1] + (float64(p.B)-mean[2])*axis[2]
+		low, high = math.Min(low, projection), math.Max(high, projection)
+	}
+
+	endpoint := func(t float64) uint16 {
+		return rgbToRgb565(mean[0]+axis[0]*t, mean[1]+axis[1]*t, mean[2]+axis[2]*t)
+	}
+	return endpoint(high), endpoint(low)
+}
+
+// getDXTColorPalette returns the colors a DXT color block decodes to, the inverse of decodeDXT1Block.
+func getDXTColorPalette(c0, c1 uint16, fourColors bool) [4]color.RGBA {
+	palette := [4]color.RGBA{rgb565ToRGBA(c0), rgb565ToRGBA(c1)}
+	if fourColors && c0 > c1 {
+		palette[2] = interpolateColors(palette[0], palette[1], 2, 1)
+		palette[3] = interpolateColors(palette[0], palette[1], 1, 2)
+	} else {
+		palette[2] = interpolateColors(palette[0], palette[1], 1, 1)
+		palette[3] = interpolateColors(palette[0], palette[1], 1, 2)
+	}
+	return palette
+}
+
+// getNearestColor returns the index of the palette color closest to a texel.
+func getNearestColor(p color.NRGBA, palette []color.RGBA) int {
+	best, bestDistance := 0, math.MaxInt
+	for i, c := range palette {
+		dr, dg, db := int(p.R)-int(c.R), int(p.G)-int(c.G), int(p.B)-int(c.B)
+		if distance := dr*dr + dg*dg + db*db; distance < bestDistance {
+			best, bestDistance = i, distance
+		}
+	}
+	return best
+}
+
+// rgbToRgb565 rounds an 8-bit per channel color to RGB565.
+func rgbToRgb565(r, g, b float64) uint16 {
+	quantize := func(value float64, maxValue int) uint16 {
+		return uint16(math.Max(0, math.Min(float64(maxValue), math.Round(value*float64(maxValue)/255))))
+	}
+	return quantize(r, 31)<<11 | quantize(g, 63)<<5 | quantize(b, 31)
+}
+
+// abs returns the absolute value of an integer.
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
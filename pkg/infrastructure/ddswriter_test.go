@@ -0,0 +1,128 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// newTestTexture creates a texture with a smooth gradient and the given alpha.
+func newTestTexture(width, height int, alpha func(x, y int) uint8) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(64 + x*4), G: uint8(32 + y*2), B: 128, A: alpha(x, y)})
+		}
+	}
+	return img
+}
+
+// maxColorError returns the largest channel difference between two images.
+func maxColorError(a *image.NRGBA, b *image.RGBA, compareAlpha bool) int {
+	worst := 0
+	for y := 0; y < a.Bounds().Dy(); y++ {
+		for x := 0; x < a.Bounds().Dx(); x++ {
+			ca, cb := a.NRGBAAt(x, y), b.RGBAAt(x, y)
+			if ca.A == 0 {
+				continue
+			}
+			worst = max(worst, abs(int(ca.R)-int(cb.R)), abs(int(ca.G)-int(cb.G)), abs(int(ca.B)-int(cb.B)))
+			if compareAlpha {
+				worst = max(worst, abs(int(ca.A)-int(cb.A)))
+			}
+		}
+	}
+	return worst
+}
+
+func TestEncodeDds(t *testing.T) {
+	tests := []struct {
+		name   string
+		fourCC string
+		alpha  func(x, y int) uint8
+	}{
+		{"opaque", "DXT1", func(x, y int) uint8 { return 255 }},
+		{"masked", "DXT1", func(x, y int) uint8 { return uint8(255 * ((x/4 + y/4) % 2)) }},
+		{"blended", "DXT5", func(x, y int) uint8 { return uint8(x * 16) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			img := newTestTexture(16, 16, test.alpha)
+			levels := GenerateMipmaps(img, false)
+
+			var buffer bytes.Buffer
+			if err := EncodeDds(&buffer, levels); err != nil {
+				t.Fatal(err)
+			}
+
+			header, err := parseDDSHeader(buffer.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if header.PixelFormat.FourCC != test.fourCC || header.MipMapCount != 5 {
+				t.Fatalf("Expected %s with 5 levels, got %s with %d", test.fourCC, header.PixelFormat.FourCC, header.MipMapCount)
+			}
+
+			decoded, err := decodeDDSPixels(header, buffer.Bytes()[128:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if worst := maxColorError(img, decoded, test.fourCC == "DXT5"); worst > 12 {
+				t.Errorf("Expected the decoded texture close to the source, worst channel error %d", worst)
+			}
+
+			for y := 0; y < 16; y++ {
+				for x := 0; x < 16; x++ {
+					if isTransparent := decoded.RGBAAt(x, y).A < 128; isTransparent != (img.NRGBAAt(x, y).A < 128) {
+						t.Fatalf("Expected the transparency of (%d, %d) to survive", x, y)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeKtx2(t *testing.T) {
+	levels := GenerateMipmaps(newTestTexture(8, 4, func(x, y int) uint8 { return 255 }), false)
+
+	var buffer bytes.Buffer
+	if err := EncodeKtx2(&buffer, levels); err != nil {
+		t.Fatal(err)
+	}
+	data := buffer.Bytes()
+
+	if !bytes.Equal(data[:12], ktx2Identifier) {
+		t.Fatal("Expected the KTX2 identifier")
+	}
+	if levelCount := binary.LittleEndian.Uint32(data[40:]); levelCount != 4 {
+		t.Fatalf("Expected 4 levels, got %d", levelCount)
+	}
+
+	// The base level is last in the file and holds the source pixels
+	offset := binary.LittleEndian.Uint64(data[80:])
+	length := binary.LittleEndian.Uint64(data[88:])
+	if offset+length != uint64(len(data)) || !bytes.Equal(data[offset:], levels[0].Pix) {
+		t.Errorf("Expected the base level at the end of the file")
+	}
+}
+
+func TestGenerateMipmapsKeepsMaskedCoverage(t *testing.T) {
+	// Thin opaque lines cover a quarter of the texture and blur away without coverage scaling
+	img := newTestTexture(32, 32, func(x, y int) uint8 {
+		if x%4 == 0 {
+			return 255
+		}
+		return 0
+	})
+
+	levels := GenerateMipmaps(img, true)
+	coverage := getAlphaCoverage(levels[0], 1)
+	for i, level := range levels[1:4] {
+		if levelCoverage := getAlphaCoverage(level, 1); levelCoverage < coverage-0.1 {
+			t.Errorf("Expected level %d coverage near %.2f, got %.2f", i+1, coverage, levelCoverage)
+		}
+	}
+}
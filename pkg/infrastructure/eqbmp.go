@@ -29,6 +29,9 @@ const (
 type EqBmp struct {
 	img     image.Image
 	palette color.Palette
+
+	// indexed is the paletted image as decoded, kept for palette edits after transparency is applied.
+	indexed *image.Paletted
 }
 
 // NewEqBmp creates a new EqBmp from the given reader (expects BMP format).
@@ -45,6 +48,7 @@ func NewEqBmp(r io.Reader) (*EqBmp, error) {
 	// Extract palette if it's a paletted image
 	if paletted, ok := img.(*image.Paletted); ok {
 		eq.palette = paletted.Palette
+		eq.indexed = paletted
 	}
 
 	return eq, nil
@@ -0,0 +1,382 @@
+package infrastructure
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Bitmap and palette file layout constants.
+const (
+	bmpFileHeaderSize = 14
+	bmpInfoHeaderSize = 40
+	maxPaletteSize    = 256
+
+	jascPaletteHeader = "JASC-PAL"
+	gimpPaletteHeader = "GIMP Palette"
+)
+
+// GetPalette returns the palette of a paletted bitmap, or nil for true color bitmaps.
+func (e *EqBmp) GetPalette() color.Palette {
+	if e.indexed == nil {
+		return nil
+	}
+	return e.indexed.Palette
+}
+
+// GetPaletteUsage returns the number of pixels using each palette index.
+func (e *EqBmp) GetPaletteUsage() []int {
+	if e.indexed == nil {
+		return nil
+	}
+
+	usage := make([]int, len(e.indexed.Palette))
+	bounds := e.indexed.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if index := int(e.indexed.ColorIndexAt(x, y)); index < len(usage) {
+				usage[index]++
+			}
+		}
+	}
+	return usage
+}
+
+// DetectTransparentIndex returns the palette index most likely used for transparency in a masked bitmap.
+// Transparent areas cover much of a cut-out texture, usually reach its edges and use a key color that
+// stands apart from the colors actually drawn. Each used index is scored by its share of the pixels and
+// of the border pixels, scaled by the distance of its color to the nearest other used color.
+// Index 0, the usual transparent index, wins close calls. Returns false if the bitmap is not paletted.
+// Extraction keeps the known indices; this is meant for the palette tooling.
+func (e *EqBmp) DetectTransparentIndex() (int, bool) {
+	if e.indexed == nil {
+		return 0, false
+	}
+
+	usage := e.GetPaletteUsage()
+	borderUsage := make([]int, len(usage))
+	bounds := e.indexed.Bounds()
+	pixelCount, borderCount := 0, 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			index := int(e.indexed.ColorIndexAt(x, y))
+			if index >= len(usage) {
+				continue
+			}
+			pixelCount++
+
+			if x == bounds.Min.X || x == bounds.Max.X-1 || y == bounds.Min.Y || y == bounds.Max.Y-1 {
+				borderUsage[index]++
+				borderCount++
+			}
+		}
+	}
+
+	if pixelCount == 0 {
+		return 0, true
+	}
+
+	best, bestScore := 0, 0.0
+	for index, count := range usage {
+		if count == 0 {
+			continue
+		}
+
+		isolation := math.Inf(1)
+		for other, otherCount := range usage {
+			if other != index && otherCount > 0 {
+				isolation = math.Min(isolation, getColorDistance(e.indexed.Palette[index], e.indexed.Palette[other]))
+			}
+		}
+		if math.IsInf(isolation, 1) {
+			isolation = 0
+		}
+
+		share := float64(count)/float64(pixelCount) + float64(borderUsage[index])/float64(borderCount)
+		score := share * isolation / 441.7
+		if index == 0 {
+			score *= 2
+		}
+
+		if score > bestScore {
+			best, bestScore = index, score
+		}
+	}
+
+	return best, true
+}
+
+// SetPalette replaces the colors of a paletted bitmap, keeping the palette index of every pixel.
+// Transparency applied before is dropped.
+func (e *EqBmp) SetPalette(palette color.Palette) error {
+	if e.indexed == nil {
+		return fmt.Errorf("failed to set palette: bitmap is not paletted")
+	}
+	if len(palette) > maxPaletteSize {
+		return fmt.Errorf("failed to set palette: %d colors exceed %d", len(palette), maxPaletteSize)
+	}
+
+	for index, count := range e.GetPaletteUsage() {
+		if count > 0 && index >= len(palette) {
+			return fmt.Errorf("failed to set palette: index %d is used but the palette has %d colors", index, len(palette))
+		}
+	}
+
+	e.setIndexed(&image.Paletted{
+		Pix:     e.indexed.Pix,
+		Stride:  e.indexed.Stride,
+		Rect:    e.indexed.Rect,
+		Palette: copyPalette(palette),
+	})
+	return nil
+}
+
+// RemapToPalette converts a paletted bitmap to another palette, moving each pixel to the closest color.
+// Pixels using the source transparent index move to the target transparent index, which no other pixel
+// is mapped to. Pass -1 for either index if the bitmap has no transparency. Transparency applied before is dropped.
+func (e *EqBmp) RemapToPalette(palette color.Palette, sourceTransparent, targetTransparent int) error {
+	if e.indexed == nil {
+		return fmt.Errorf("failed to remap palette: bitmap is not paletted")
+	}
+	if len(palette) == 0 || len(palette) > maxPaletteSize {
+		return fmt.Errorf("failed to remap palette: invalid palette size %d", len(palette))
+	}
+	if targetTransparent >= len(palette) {
+		return fmt.Errorf("failed to remap palette: transparent index %d is outside the palette", targetTransparent)
+	}
+
+	mapping := make([]uint8, len(e.indexed.Palette))
+	for index, source := range e.indexed.Palette {
+		if index == sourceTransparent && targetTransparent >= 0 {
+			mapping[index] = uint8(targetTransparent)
+			continue
+		}
+
+		best, bestDistance := -1, math.Inf(1)
+		for target, c := range palette {
+			if target == targetTransparent {
+				continue
+			}
+			if distance := getColorDistance(source, c); distance < bestDistance {
+				best, bestDistance = target, distance
+			}
+		}
+		if best < 0 {
+			return fmt.Errorf("failed to remap palette: no visible colors")
+		}
+		mapping[index] = uint8(best)
+	}
+
+	remapped := image.NewPaletted(e.indexed.Rect, copyPalette(palette))
+	for i, index := range e.indexed.Pix {
+		if int(index) < len(mapping) {
+			remapped.Pix[i] = mapping[index]
+		}
+	}
+
+	e.setIndexed(remapped)
+	return nil
+}
+
+// setIndexed replaces the paletted image and the image exported from it.
+func (e *EqBmp) setIndexed(indexed *image.Paletted) {
+	e.indexed = indexed
+	e.palette = indexed.Palette
+	e.img = indexed
+}
+
+// WriteBmp writes a paletted bitmap as an uncompressed 8-bit BMP, the format the client reads.
+func (e *EqBmp) WriteBmp(w io.Writer) error {
+	if e.indexed == nil {
+		return fmt.Errorf("failed to write BMP: bitmap is not paletted")
+	}
+
+	bounds := e.indexed.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	rowSize := (width + 3) &^ 3
+	paletteSize := len(e.indexed.Palette)
+	pixelOffset := bmpFileHeaderSize + bmpInfoHeaderSize + paletteSize*4
+	fileSize := pixelOffset + rowSize*height
+
+	var buffer bytes.Buffer
+	buffer.WriteString("BM")
+	binary.Write(&buffer, binary.LittleEndian, []uint32{uint32(fileSize), 0, uint32(pixelOffset)})
+	binary.Write(&buffer, binary.LittleEndian, []uint32{bmpInfoHeaderSize, uint32(width), uint32(height)})
+	binary.Write(&buffer, binary.LittleEndian, []uint16{1, 8})
+	binary.Write(&buffer, binary.LittleEndian, []uint32{
+		0, // BI_RGB
+		uint32(rowSize * height),
+		2835, 2835, // 72 DPI
+		uint32(paletteSize),
+		0,
+	})
+
+	for _, c := range e.indexed.Palette {
+		r, g, b, _ := c.RGBA()
+		buffer.Write([]byte{uint8(b >> 8), uint8(g >> 8), uint8(r >> 8), 0})
+	}
+
+	// Rows are stored bottom-up and padded to 4 bytes
+	row := make([]byte, rowSize)
+	for y := height - 1; y >= 0; y-- {
+		start := e.indexed.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+		copy(row, e.indexed.Pix[start:start+width])
+		buffer.Write(row)
+	}
+
+	if _, err := w.Write(buffer.Bytes()); err != nil {
+		return fmt.Errorf("failed to write BMP: %w", err)
+	}
+	return nil
+}
+
+// WriteBmpFile writes a paletted bitmap as an 8-bit BMP file.
+func (e *EqBmp) WriteBmpFile(filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	return e.WriteBmp(file)
+}
+
+// WritePaletteJasc writes a palette in the JASC .pal format used by Paint Shop Pro and most pixel art editors.
+func WritePaletteJasc(w io.Writer, palette color.Palette) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s\r\n0100\r\n%d\r\n", jascPaletteHeader, len(palette))
+	for _, c := range palette {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(&builder, "%d %d %d\r\n", r>>8, g>>8, b>>8)
+	}
+
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("failed to write palette: %w", err)
+	}
+	return nil
+}
+
+// WritePaletteGimp writes a palette in the GIMP .gpl format, naming each color after its index.
+func WritePaletteGimp(w io.Writer, palette color.Palette, name string) error {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s\nName: %s\nColumns: 16\n#\n", gimpPaletteHeader, name)
+	for index, c := range palette {
+		r, g, b, _ := c.RGBA()
+		fmt.Fprintf(&builder, "%3d %3d %3d\tIndex %d\n", r>>8, g>>8, b>>8, index)
+	}
+
+	if _, err := io.WriteString(w, builder.String()); err != nil {
+		return fmt.Errorf("failed to write palette: %w", err)
+	}
+	return nil
+}
+
+// ReadPalette reads a palette in the JASC .pal or GIMP .gpl format.
+func ReadPalette(r io.Reader) (color.Palette, error) {
+	scanner := bufio.NewScanner(r)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("failed to read palette: empty file")
+	}
+
+	header := strings.TrimSpace(scanner.Text())
+	isJasc := header == jascPaletteHeader
+	if !isJasc && header != gimpPaletteHeader {
+		return nil, fmt.Errorf("failed to read palette: unknown format %q", header)
+	}
+
+	// JASC palettes have a version and a color count before the colors
+	skipLines := 0
+	if isJasc {
+		skipLines = 2
+	}
+
+	var palette color.Palette
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if skipLines > 0 {
+			skipLines--
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.Contains(line, ":") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("failed to read palette: invalid color %q", line)
+		}
+
+		var channels [3]uint8
+		for i := range channels {
+			value, err := strconv.Atoi(fields[i])
+			if err != nil || value < 0 || value > 255 {
+				return nil, fmt.Errorf("failed to read palette: invalid color %q", line)
+			}
+			channels[i] = uint8(value)
+		}
+		palette = append(palette, color.RGBA{R: channels[0], G: channels[1], B: channels[2], A: 255})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read palette: %w", err)
+	}
+	if len(palette) == 0 || len(palette) > maxPaletteSize {
+		return nil, fmt.Errorf("failed to read palette: invalid palette size %d", len(palette))
+	}
+	return palette, nil
+}
+
+// LoadPaletteFile reads a .pal or .gpl palette file.
+func LoadPaletteFile(filePath string) (color.Palette, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open palette file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadPalette(file)
+}
+
+// SavePaletteFile writes a palette file in the GIMP format for .gpl files and the JASC format otherwise.
+func SavePaletteFile(filePath string, palette color.Palette) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create palette file: %w", err)
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(filePath), ".gpl") {
+		name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+		return WritePaletteGimp(file, palette, name)
+	}
+	return WritePaletteJasc(file, palette)
+}
+
+// copyPalette returns an opaque copy of a palette. BMP palettes have no alpha.
+func copyPalette(palette color.Palette) color.Palette {
+	copied := make(color.Palette, len(palette))
+	for i, c := range palette {
+		r, g, b, _ := c.RGBA()
+		copied[i] = color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 255}
+	}
+	return copied
+}
+
+// getColorDistance returns the RGB distance between two colors on a 0-255 scale.
+func getColorDistance(a, b color.Color) float64 {
+	ar, ag, ab, _ := a.RGBA()
+	br, bg, bb, _ := b.RGBA()
+	dr := float64(ar>>8) - float64(br>>8)
+	dg := float64(ag>>8) - float64(bg>>8)
+	db := float64(ab>>8) - float64(bb>>8)
+	return math.Sqrt(dr*dr + dg*dg + db*db)
+}
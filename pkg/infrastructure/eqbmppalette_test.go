@@ -0,0 +1,108 @@
+package infrastructure
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// newTestBitmap creates a 10x6 paletted bitmap with a rug of dark browns drawn with indices 0-3
+// on a magenta background using index 47.
+func newTestBitmap() *EqBmp {
+	palette := make(color.Palette, 256)
+	for i := range palette {
+		palette[i] = color.RGBA{R: uint8(i), G: uint8(i / 2), B: uint8(i / 4), A: 255}
+	}
+	palette[47] = color.RGBA{R: 255, B: 255, A: 255}
+
+	img := image.NewPaletted(image.Rect(0, 0, 10, 6), palette)
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 10; x++ {
+			index := uint8(47)
+			if x > 1 && x < 8 && y > 0 && y < 5 {
+				index = uint8((x + y) % 4)
+			}
+			img.SetColorIndex(x, y, index)
+		}
+	}
+
+	return &EqBmp{img: img, palette: palette, indexed: img}
+}
+
+func TestWriteBmp(t *testing.T) {
+	source := newTestBitmap()
+
+	var buffer bytes.Buffer
+	if err := source.WriteBmp(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := NewEqBmpFromBytes(buffer.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.IsPaletted() {
+		t.Fatal("Expected an 8-bit bitmap")
+	}
+
+	if !bytes.Equal(decoded.indexed.Pix, source.indexed.Pix) {
+		t.Error("Expected the pixel indices to survive")
+	}
+	if decoded.GetPalette()[47] != source.GetPalette()[47] {
+		t.Errorf("Expected the palette to survive, got %v", decoded.GetPalette()[47])
+	}
+}
+
+func TestPaletteFiles(t *testing.T) {
+	palette := newTestBitmap().GetPalette()
+
+	for _, format := range []string{"jasc", "gimp"} {
+		var buffer bytes.Buffer
+		if format == "jasc" {
+			WritePaletteJasc(&buffer, palette)
+		} else {
+			WritePaletteGimp(&buffer, palette, "test")
+		}
+
+		read, err := ReadPalette(&buffer)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(read) != len(palette) || read[47] != palette[47] || read[200] != palette[200] {
+			t.Errorf("%s: expected the palette to survive", format)
+		}
+	}
+}
+
+func TestDetectTransparentIndex(t *testing.T) {
+	index, ok := newTestBitmap().DetectTransparentIndex()
+	if !ok || index != 47 {
+		t.Errorf("Expected transparent index 47, got %d", index)
+	}
+}
+
+func TestRemapToPalette(t *testing.T) {
+	img := newTestBitmap()
+
+	// A palette with a green key at 0 followed by the rug colors
+	palette := color.Palette{color.RGBA{G: 255, A: 255}}
+	for i := 0; i < 4; i++ {
+		palette = append(palette, color.RGBA{R: uint8(i), G: uint8(i / 2), B: uint8(i / 4), A: 255})
+	}
+
+	if err := img.RemapToPalette(palette, 47, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if index := img.indexed.ColorIndexAt(0, 0); index != 0 {
+		t.Errorf("Expected the background on the transparent index, got %d", index)
+	}
+	for y := 1; y < 5; y++ {
+		for x := 2; x < 8; x++ {
+			if index := img.indexed.ColorIndexAt(x, y); index != uint8((x+y)%4+1) {
+				t.Fatalf("Expected (%d, %d) on index %d, got %d", x, y, (x+y)%4+1, index)
+			}
+		}
+	}
+}
@@ -18,21 +18,27 @@ import (
 // It automatically detects the format based on the file magic bytes.
 // If isMasked is true, transparency handling will be applied.
 func WriteImageAsPng(data []byte, filePath, fileName string, isMasked bool, log logger.Logger) error {
+	return WriteImage(data, filePath, fileName, isMasked, TextureFormatPng, log)
+}
+
+// WriteImage writes image bytes (BMP or DDS format) to a PNG file and, for DDS and KTX2,
+// a GPU texture with mipmaps next to it.
+func WriteImage(data []byte, filePath, fileName string, isMasked bool, format TextureFormat, log logger.Logger) error {
 	// Check for DDS magic number: "DDS "
 	// https://docs.microsoft.com/en-us/windows/win32/direct3ddds/dx-graphics-dds-pguide#dds-file-layout
 	isDDS := len(data) >= 4 && string(data[0:4]) == "DDS "
 
 	if strings.HasSuffix(strings.ToLower(fileName), ".bmp") && !isDDS {
 		outputName := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".png"
-		return writeBmpAsPng(data, filePath, outputName, isMasked, log)
+		return writeBmpAsPng(data, filePath, outputName, isMasked, format, log)
 	}
 
 	outputName := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".png"
-	return writeDdsAsPng(data, filePath, outputName)
+	return writeDdsAsPng(data, filePath, outputName, format)
 }
 
 // writeBmpAsPng converts a BMP image to PNG with optional transparency handling.
-func writeBmpAsPng(data []byte, filePath, fileName string, isMasked bool, log logger.Logger) error {
+func writeBmpAsPng(data []byte, filePath, fileName string, isMasked bool, format TextureFormat, log logger.Logger) error {
 	if filePath == "" {
 		return nil
 	}
@@ -60,45 +66,81 @@ func writeBmpAsPng(data []byte, filePath, fileName string, isMasked bool, log lo
 	}
 
 	outputPath := filepath.Join(filePath, fileName)
+	if format != TextureFormatPng {
+		return WriteTexture(img.GetImage(), outputPath, format, isMasked)
+	}
 	return img.WritePng(outputPath)
 }
 
 // writeDdsAsPng converts a DDS texture to PNG.
 // Supports DXT1, DXT3, DXT5, and uncompressed RGBA32 formats.
-func writeDdsAsPng(data []byte, filePath, fileName string) error {
+func writeDdsAsPng(data []byte, filePath, fileName string, format TextureFormat) error {
+	img, err := decodeDdsImage(data)
+	if err != nil {
+		return err
+	}
+
+	// Ensure directory exists
+	if err := os.MkdirAll(filePath, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write PNG
+	outputPath := filepath.Join(filePath, fileName)
+	if format != TextureFormatPng {
+		return WriteTexture(img, outputPath, format, false)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	return png.Encode(file, img)
+}
+
+// decodeDdsImage decodes a DDS texture into an upright image.
+func decodeDdsImage(data []byte) (*image.RGBA, error) {
 	if len(data) < 128 {
-		return fmt.Errorf("DDS file too small")
+		return nil, fmt.Errorf("DDS file too small")
 	}
 
 	// Parse DDS header
 	dds, err := parseDDSHeader(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Decode the pixel data based on format
 	img, err := decodeDDSPixels(dds, data[128:])
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Flip vertically (DDS textures are stored bottom-up)
-	img = flipVertical(img)
+	return flipVertical(img), nil
+}
 
-	// Ensure directory exists
-	if err := os.MkdirAll(filePath, 0755); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+// DecodeImage decodes image bytes (BMP or DDS format) without writing them to disk.
+// Magenta is made transparent in bitmaps, matching the exported PNG files of unmasked textures.
+func DecodeImage(data []byte, fileName string) (image.Image, error) {
+	isDDS := len(data) >= 4 && string(data[0:4]) == "DDS "
+
+	if strings.HasSuffix(strings.ToLower(fileName), ".bmp") && !isDDS {
+		img, err := NewEqBmpFromBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode bitmap %s: %w", fileName, err)
+		}
+		img.MakeMagentaTransparent()
+		return img.GetImage(), nil
 	}
 
-	// Write PNG
-	outputPath := filepath.Join(filePath, fileName)
-	file, err := os.Create(outputPath)
+	img, err := decodeDdsImage(data)
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return nil, fmt.Errorf("failed to decode DDS %s: %w", fileName, err)
 	}
-	defer file.Close()
-
-	return png.Encode(file, img)
+	return img, nil
 }
 
 // DDSHeader represents a DDS file header.
@@ -0,0 +1,67 @@
+package infrastructure
+
+import (
+	"bytes"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetPaletteIndex(t *testing.T) {
+	tests := map[string]int{
+		"bearrug.png":  47,
+		"furpile1.png": 250,
+		"clhe0004.png": 255,
+		"kahe0001.png": 255,
+		"fence.png":    0,
+	}
+
+	for fileName, expected := range tests {
+		if index := getPaletteIndex(fileName); index != expected {
+			t.Errorf("Expected %s to use index %d, got %d", fileName, expected, index)
+		}
+	}
+}
+
+func TestWriteBmpAsPngTransparentIndex(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := newTestBitmap().WriteBmp(&buffer); err != nil {
+		t.Fatal(err)
+	}
+
+	// The background uses index 47 and the rug starts at index 0. Only the known
+	// file uses 47, every other masked bitmap keeps index 0.
+	tests := []struct {
+		fileName           string
+		isBackgroundMasked bool
+	}{
+		{"bearrug.png", true},
+		{"rug.png", false},
+	}
+
+	dir := t.TempDir()
+	for _, test := range tests {
+		if err := writeBmpAsPng(buffer.Bytes(), dir, test.fileName, true, TextureFormatPng, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		file, err := os.Open(filepath.Join(dir, test.fileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := png.Decode(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// (0, 0) is background and (3, 1) uses index 0
+		_, _, _, backgroundAlpha := img.At(0, 0).RGBA()
+		_, _, _, rugAlpha := img.At(3, 1).RGBA()
+		if (backgroundAlpha == 0) != test.isBackgroundMasked || (rugAlpha == 0) == test.isBackgroundMasked {
+			t.Errorf("%s: expected background masked %v, got background alpha %d and index 0 alpha %d",
+				test.fileName, test.isBackgroundMasked, backgroundAlpha, rugAlpha)
+		}
+	}
+}
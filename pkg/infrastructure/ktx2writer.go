@@ -0,0 +1,132 @@
+package infrastructure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+)
+
+// ktx2Identifier starts every KTX2 file.
+var ktx2Identifier = []byte{0xAB, 0x4B, 0x54, 0x58, 0x20, 0x32, 0x30, 0xBB, 0x0D, 0x0A, 0x1A, 0x0A}
+
+// KTX2 format values used when writing.
+const (
+	vkFormatR8G8B8A8Srgb = 43
+
+	khrDfModelRgbsda       = 1
+	khrDfPrimariesBt709    = 1
+	khrDfTransferSrgb      = 2
+	khrDfChannelAlpha      = 15
+	khrDfSampleTypeLinear  = 0x10
+	ktx2HeaderSize         = 80
+	ktx2LevelIndexSize     = 24
+	ktx2DfdBlockHeaderSize = 24
+	ktx2DfdSampleSize      = 16
+)
+
+// EncodeKtx2 writes a mip chain as a KTX2 texture with uncompressed sRGB RGBA8 levels.
+// The levels are not Basis supercompressed. Loaders that only accept Basis payloads need
+// the file transcoded first, e.g. with toktx or basisu.
+func EncodeKtx2(w io.Writer, levels []*image.NRGBA) error {
+	if len(levels) == 0 {
+		return fmt.Errorf("failed to encode KTX2: no image")
+	}
+
+	dfd := getKtx2RgbaDfd()
+	dfdOffset := ktx2HeaderSize + ktx2LevelIndexSize*len(levels)
+	dataOffset := dfdOffset + len(dfd)
+
+	var buffer bytes.Buffer
+	buffer.Write(ktx2Identifier)
+	writeUint32s(&buffer,
+		vkFormatR8G8B8A8Srgb,
+		1, // typeSize
+		uint32(levels[0].Bounds().Dx()),
+		uint32(levels[0].Bounds().Dy()),
+		0, // pixelDepth
+		0, // layerCount
+		1, // faceCount
+		uint32(len(levels)),
+		0, // supercompressionScheme
+		uint32(dfdOffset),
+		uint32(len(dfd)),
+		0, // kvdByteOffset
+		0, // kvdByteLength
+	)
+	binary.Write(&buffer, binary.LittleEndian, [2]uint64{}) // sgdByteOffset, sgdByteLength
+
+	// Level data is stored smallest first while the index lists the base level first
+	offsets := make([]int, len(levels))
+	offset := dataOffset
+	for i := len(levels) - 1; i >= 0; i-- {
+		offsets[i] = offset
+		offset += len(getTightPixels(levels[i]))
+	}
+
+	for i, level := range levels {
+		length := uint64(len(getTightPixels(level)))
+		binary.Write(&buffer, binary.LittleEndian, [3]uint64{uint64(offsets[i]), length, length})
+	}
+
+	buffer.Write(dfd)
+	for i := len(levels) - 1; i >= 0; i-- {
+		buffer.Write(getTightPixels(levels[i]))
+	}
+
+	if _, err := w.Write(buffer.Bytes()); err != nil {
+		return fmt.Errorf("failed to write KTX2 data: %w", err)
+	}
+	return nil
+}
+
+// getKtx2RgbaDfd returns the data format descriptor of sRGB RGBA8 with straight alpha.
+func getKtx2RgbaDfd() []byte {
+	const sampleCount = 4
+	blockSize := ktx2DfdBlockHeaderSize + ktx2DfdSampleSize*sampleCount
+
+	var buffer bytes.Buffer
+	writeUint32s(&buffer,
+		uint32(4+blockSize), // dfdTotalSize
+		0,                   // vendorId and descriptorType
+		2|uint32(blockSize)<<16,
+		khrDfModelRgbsda|khrDfPrimariesBt709<<8|khrDfTransferSrgb<<16,
+		0, // texelBlockDimension, one texel
+		4, // bytesPlane0
+		0,
+	)
+
+	channels := []uint32{0, 1, 2, khrDfChannelAlpha | khrDfSampleTypeLinear}
+	for i, channel := range channels {
+		writeUint32s(&buffer,
+			uint32(i*8)|7<<16|channel<<24, // bitOffset, bitLength - 1, channelType
+			0,                             // samplePosition
+			0,                             // sampleLower
+			255,                           // sampleUpper
+		)
+	}
+
+	return buffer.Bytes()
+}
+
+// getTightPixels returns the pixels of an image without row padding.
+func getTightPixels(img *image.NRGBA) []byte {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	if img.Stride == width*4 {
+		return img.Pix[:width*height*4]
+	}
+
+	pixels := make([]byte, 0, width*height*4)
+	for y := 0; y < height; y++ {
+		pixels = append(pixels, img.Pix[y*img.Stride:y*img.Stride+width*4]...)
+	}
+	return pixels
+}
+
+// writeUint32s writes little endian 32-bit values to a buffer.
+func writeUint32s(buffer *bytes.Buffer, values ...uint32) {
+	for _, value := range values {
+		binary.Write(buffer, binary.LittleEndian, value)
+	}
+}
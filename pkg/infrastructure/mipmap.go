@@ -0,0 +1,115 @@
+package infrastructure
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// maskedAlphaCutoff is the alpha at which masked textures are cut out, matching the glTF alpha cutoff.
+const maskedAlphaCutoff = 128
+
+// GenerateMipmaps returns the full mip chain of an image, from the image itself down to 1x1.
+// Colors are averaged by alpha so transparent texels do not darken or tint their neighbours.
+// Masked textures keep the share of texels above the alpha cutoff from level to level,
+// so cut-out foliage and fences do not thin out or disappear in the distance.
+func GenerateMipmaps(img image.Image, isMasked bool) []*image.NRGBA {
+	base := image.NewNRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(base, base.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	levels := []*image.NRGBA{base}
+	coverage := getAlphaCoverage(base, 1)
+
+	current := base
+	for current.Bounds().Dx() > 1 || current.Bounds().Dy() > 1 {
+		current = downsample(current)
+
+		level := current
+		if isMasked {
+			level = scaleAlpha(current, findAlphaScale(current, coverage))
+		}
+		levels = append(levels, level)
+	}
+
+	return levels
+}
+
+// downsample halves an image with an alpha-weighted box filter. Odd edges reuse the last texel.
+func downsample(img *image.NRGBA) *image.NRGBA {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	result := image.NewNRGBA(image.Rect(0, 0, max(1, width/2), max(1, height/2)))
+
+	for y := 0; y < result.Bounds().Dy(); y++ {
+		for x := 0; x < result.Bounds().Dx(); x++ {
+			var r, g, b, a, weight, plainR, plainG, plainB int
+			for dy := 0; dy < 2; dy++ {
+				for dx := 0; dx < 2; dx++ {
+					c := img.NRGBAAt(min(2*x+dx, width-1), min(2*y+dy, height-1))
+					alpha := int(c.A)
+					r += int(c.R) * alpha
+					g += int(c.G) * alpha
+					b += int(c.B) * alpha
+					a += alpha
+					weight += alpha
+					plainR += int(c.R)
+					plainG += int(c.G)
+					plainB += int(c.B)
+				}
+			}
+
+			// Fully transparent texels keep a plain average so the color is still defined
+			if weight == 0 {
+				result.SetNRGBA(x, y, color.NRGBA{R: uint8(plainR / 4), G: uint8(plainG / 4), B: uint8(plainB / 4)})
+				continue
+			}
+
+			result.SetNRGBA(x, y, color.NRGBA{
+				R: uint8((r + weight/2) / weight),
+				G: uint8((g + weight/2) / weight),
+				B: uint8((b + weight/2) / weight),
+				A: uint8((a + 2) / 4),
+			})
+		}
+	}
+
+	return result
+}
+
+// getAlphaCoverage returns the share of texels at or above the alpha cutoff after scaling their alpha.
+func getAlphaCoverage(img *image.NRGBA, scale float64) float64 {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	covered := 0
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if float64(img.NRGBAAt(x, y).A)*scale >= maskedAlphaCutoff {
+				covered++
+			}
+		}
+	}
+	return float64(covered) / float64(width*height)
+}
+
+// findAlphaScale returns the alpha scale that brings the coverage of a level closest to a target.
+func findAlphaScale(img *image.NRGBA, coverage float64) float64 {
+	low, high := 0.0, 16.0
+	for i := 0; i < 16; i++ {
+		middle := (low + high) / 2
+		if getAlphaCoverage(img, middle) < coverage {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	return high
+}
+
+// scaleAlpha returns a copy of an image with its alpha scaled and clamped.
+func scaleAlpha(img *image.NRGBA, scale float64) *image.NRGBA {
+	result := image.NewNRGBA(img.Bounds())
+	copy(result.Pix, img.Pix)
+
+	for i := 3; i < len(result.Pix); i += 4 {
+		result.Pix[i] = uint8(min(255, float64(result.Pix[i])*scale+0.5))
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package infrastructure
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TextureFormat is the GPU texture format written next to the PNG textures.
+type TextureFormat int
+
+const (
+	// TextureFormatPng writes PNG textures only.
+	TextureFormatPng TextureFormat = iota
+
+	// TextureFormatDds also writes DDS textures compressed as BC1 or BC3 with mipmaps.
+	TextureFormatDds
+
+	// TextureFormatKtx2 also writes KTX2 textures with mipmaps.
+	TextureFormatKtx2
+)
+
+// Extension returns the file extension of the texture format, including the dot.
+func (f TextureFormat) Extension() string {
+	switch f {
+	case TextureFormatDds:
+		return ".dds"
+	case TextureFormatKtx2:
+		return ".ktx2"
+	default:
+		return ".png"
+	}
+}
+
+// GetTexturePath returns the path of the texture written next to a PNG texture in a format.
+func GetTexturePath(pngPath string, format TextureFormat) string {
+	return strings.TrimSuffix(pngPath, filepath.Ext(pngPath)) + format.Extension()
+}
+
+// WriteTexture writes an image as a PNG file and, for DDS and KTX2, a texture with the same name
+// in that format. The PNG stays the fallback for viewers without GPU texture support.
+func WriteTexture(img image.Image, filePath string, format TextureFormat, isMasked bool) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if err := writeTextureFile(filePath, func(file *bufio.Writer) error {
+		return png.Encode(file, img)
+	}); err != nil {
+		return err
+	}
+
+	if format == TextureFormatPng {
+		return nil
+	}
+
+	levels := GenerateMipmaps(img, isMasked)
+	return writeTextureFile(GetTexturePath(filePath, format), func(file *bufio.Writer) error {
+		if format == TextureFormatDds {
+			return EncodeDds(file, levels)
+		}
+		return EncodeKtx2(file, levels)
+	})
+}
+
+// writeTextureFile creates a file and writes it through a buffered writer.
+func writeTextureFile(filePath string, encode func(*bufio.Writer) error) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := encode(writer); err != nil {
+		return fmt.Errorf("failed to write texture %s: %w", filePath, err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write texture %s: %w", filePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Package lighting provides offline vertex lighting for zone meshes.
+package lighting
+
+import (
+	"math"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// Options configures the vertex light baker.
+type Options struct {
+	// FalloffExponent shapes the falloff from the light center to its radius.
+	// 1 gives a linear falloff. The meaning of LightSource.Attenuation is unconfirmed,
+	// so the falloff is driven by the light instance radius only.
+	FalloffExponent float32
+
+	// Intensity scales the contribution of every light.
+	Intensity float32
+
+	// UseNormals enables the Lambert term. Without it, lights contribute by distance only.
+	UseNormals bool
+
+	// Occluder blocks light between a light and a vertex. May be nil.
+	Occluder Occluder
+}
+
+// DefaultOptions returns the default baking options.
+func DefaultOptions() Options {
+	return Options{
+		FalloffExponent: 1,
+		Intensity:       1,
+		UseNormals:      true,
+	}
+}
+
+// light is a light instance resolved to world position, color and radius.
+type light struct {
+	position vecmath.Vec3
+	color    [3]float32
+	radius   float32
+}
+
+// Baker computes per-vertex lighting from zone light instances and the ambient light.
+type Baker struct {
+	lights  []light
+	ambient [3]float32
+	options Options
+}
+
+// NewBaker creates a new Baker for the given light instances and ambient color.
+func NewBaker(lightInstances []*fragments.LightInstance, ambient datatypes.Color, options Options) *Baker {
+	b := &Baker{
+		ambient: [3]float32{
+			float32(ambient.R) / 255.0,
+			float32(ambient.G) / 255.0,
+			float32(ambient.B) / 255.0,
+		},
+		options: options,
+	}
+
+	for _, instance := range lightInstances {
+		if instance == nil || instance.Radius <= 0 {
+			continue
+		}
+
+		color := [3]float32{1, 1, 1}
+		if instance.LightReference != nil && instance.LightReference.LightSource != nil &&
+			instance.LightReference.LightSource.IsColoredLight {
+			sourceColor := instance.LightReference.LightSource.Color
+			color = [3]float32{sourceColor.X, sourceColor.Y, sourceColor.Z}
+		}
+
+		b.lights = append(b.lights, light{
+			position: vecmath.New(float64(instance.Position.X), float64(instance.Position.Y), float64(instance.Position.Z)),
+			color:    color,
+			radius:   instance.Radius,
+		})
+	}
+
+	return b
+}
+
+// BakeMesh returns one lit color per vertex of the mesh.
+// The alpha channel is set to full, matching fully lit client vertex colors.
+func (b *Baker) BakeMesh(mesh *fragments.Mesh) []datatypes.Color {
+	colors := make([]datatypes.Color, len(mesh.Vertices))
+
+	for i, vertex := range mesh.Vertices {
+		position := vecmath.New(
+			float64(vertex.X+mesh.Center.X),
+			float64(vertex.Y+mesh.Center.Y),
+			float64(vertex.Z+mesh.Center.Z),
+		)
+
+		var normal vecmath.Vec3
+		hasNormal := b.options.UseNormals && i < len(mesh.Normals)
+		if hasNormal {
+			// Normals are stored inverted, the exporters negate them too
+			n := mesh.Normals[i]
+			normal = vecmath.Normalize(vecmath.New(float64(-n.X), float64(-n.Y), float64(-n.Z)))
+			hasNormal = normal != vecmath.Vec3{}
+		}
+
+		lit := b.ambient
+		for _, l := range b.lights {
+			contribution := b.lightContribution(l, position, normal, hasNormal)
+			if contribution <= 0 {
+				continue
+			}
+
+			for c := 0; c < 3; c++ {
+				lit[c] += l.color[c] * contribution
+			}
+		}
+
+		colors[i] = datatypes.NewColor(toByte(lit[0]), toByte(lit[1]), toByte(lit[2]), 255)
+	}
+
+	return colors
+}
+
+// lightContribution returns the light intensity reaching a vertex.
+func (b *Baker) lightContribution(l light, position, normal vecmath.Vec3, hasNormal bool) float32 {
+	toLight := vecmath.Sub(l.position, position)
+	distance := float32(vecmath.Length(toLight))
+	if distance >= l.radius {
+		return 0
+	}
+
+	falloff := 1 - distance/l.radius
+	if b.options.FalloffExponent > 0 && b.options.FalloffExponent != 1 {
+		falloff = float32(math.Pow(float64(falloff), float64(b.options.FalloffExponent)))
+	}
+
+	contribution := falloff * b.options.Intensity
+
+	if hasNormal && distance > 0 {
+		lambert := float32(vecmath.Dot(normal, vecmath.Normalize(toLight)))
+		if lambert <= 0 {
+			return 0
+		}
+		contribution *= lambert
+	}
+
+	if b.options.Occluder != nil && b.options.Occluder.Occluded(l.position, position) {
+		return 0
+	}
+
+	return contribution
+}
+
+// ApplyToMeshes bakes every mesh and replaces its vertex colors. Returns the baked meshes.
+// If onlyUnlit is true, meshes that already have vertex colors are left alone.
+func (b *Baker) ApplyToMeshes(meshes []*fragments.Mesh, onlyUnlit bool) []*fragments.Mesh {
+	var baked []*fragments.Mesh
+
+	for _, mesh := range meshes {
+		if mesh == nil || (onlyUnlit && len(mesh.Colors) > 0) {
+			continue
+		}
+
+		mesh.Colors = b.BakeMesh(mesh)
+		baked = append(baked, mesh)
+	}
+
+	return baked
+}
+
+// toByte converts a light value to a clamped 0-255 color channel.
+func toByte(value float32) int {
+	if value <= 0 {
+		return 0
+	}
+	if value >= 1 {
+		return 255
+	}
+	return int(value*255 + 0.5)
+}
@@ -0,0 +1,80 @@
+package lighting
+
+import (
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+func TestBakeMesh(t *testing.T) {
+	// One vertex facing up with its normal stored inverted
+	mesh := &fragments.Mesh{
+		Vertices: []fragments.Vec3{{X: 0, Y: 0, Z: 0}},
+		Normals:  []fragments.Vec3{{X: 0, Y: 0, Z: -1}},
+	}
+
+	lights := []*fragments.LightInstance{{
+		Position: datatypes.Vec3{X: 0, Y: 0, Z: 5},
+		Radius:   10,
+	}}
+	ambient := datatypes.NewColor(10, 20, 30, 255)
+
+	colors := NewBaker(lights, ambient, DefaultOptions()).BakeMesh(mesh)
+	if len(colors) != 1 {
+		t.Fatalf("Expected 1 color, got %d", len(colors))
+	}
+
+	// Half the radius away with a linear falloff adds 0.5 of white
+	expected := datatypes.NewColor(138, 148, 158, 255)
+	if colors[0] != expected {
+		t.Errorf("Expected %v, got %v", expected, colors[0])
+	}
+
+	// A polygon between the light and the vertex blocks it
+	blocker := &fragments.Mesh{
+		Vertices: []fragments.Vec3{{X: -1, Y: -1, Z: 2}, {X: 1, Y: -1, Z: 2}, {X: 0, Y: 1, Z: 2}},
+		Indices:  []datatypes.Polygon{{Vertex1: 0, Vertex2: 1, Vertex3: 2}},
+	}
+	region := &fragments.BspRegion{ContainsPolygons: true, Mesh: blocker}
+	tree := &fragments.BspTree{Nodes: []*datatypes.BspNode{{LeftNode: -1, RightNode: -1, Region: region}}}
+
+	options := DefaultOptions()
+	options.Occluder = NewBspOccluder(tree)
+	colors = NewBaker(lights, ambient, options).BakeMesh(mesh)
+	if colors[0] != ambient {
+		t.Errorf("Expected occluded vertex to be ambient %v, got %v", ambient, colors[0])
+	}
+}
+
+func TestApplyToMeshes(t *testing.T) {
+	unlit := &fragments.Mesh{Vertices: []fragments.Vec3{{X: 0, Y: 0, Z: 0}}}
+	prelit := &fragments.Mesh{
+		Vertices: []fragments.Vec3{{X: 0, Y: 0, Z: 0}},
+		Colors:   []datatypes.Color{datatypes.NewColor(1, 2, 3, 255)},
+	}
+
+	// The light source attenuation is not a falloff, the light falls off linearly
+	lights := []*fragments.LightInstance{{
+		Position: datatypes.Vec3{X: 0, Y: 0, Z: 5},
+		Radius:   10,
+		LightReference: &fragments.LightSourceReference{LightSource: &fragments.LightSource{
+			IsColoredLight: true,
+			Color:          datatypes.Vec4{X: 1, Y: 1, Z: 1, W: 1},
+			Attenuation:    2,
+		}},
+	}}
+
+	baked := NewBaker(lights, datatypes.NewColor(0, 0, 0, 255), DefaultOptions()).ApplyToMeshes([]*fragments.Mesh{unlit, prelit}, true)
+	if len(baked) != 1 || baked[0] != unlit {
+		t.Fatalf("Expected only the unlit mesh to be baked, got %d meshes", len(baked))
+	}
+
+	expected := datatypes.NewColor(128, 128, 128, 255)
+	if unlit.Colors[0] != expected {
+		t.Errorf("Expected %v, got %v", expected, unlit.Colors[0])
+	}
+	if prelit.Colors[0] != datatypes.NewColor(1, 2, 3, 255) {
+		t.Errorf("Expected the prebaked colors to be kept, got %v", prelit.Colors[0])
+	}
+}
@@ -0,0 +1,186 @@
+package lighting
+
+import (
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// occlusionBias shortens each occlusion segment so a vertex does not shadow itself.
+const occlusionBias = 0.05
+
+// Occluder tests whether geometry blocks the line between two points.
+type Occluder interface {
+	Occluded(from, to vecmath.Vec3) bool
+}
+
+// triangle is a triangle in world space.
+type triangle [3]vecmath.Vec3
+
+// BspOccluder uses the zone BSP tree to find the leaf regions a segment passes through
+// and tests the segment against the polygons of those regions only.
+type BspOccluder struct {
+	nodes     []*datatypes.BspNode
+	triangles map[*fragments.BspRegion][]triangle
+}
+
+// NewBspOccluder creates a new BspOccluder from a linked zone BSP tree.
+func NewBspOccluder(tree *fragments.BspTree) *BspOccluder {
+	o := &BspOccluder{
+		nodes:     tree.Nodes,
+		triangles: make(map[*fragments.BspRegion][]triangle),
+	}
+
+	for _, node := range tree.Nodes {
+		region, ok := node.Region.(*fragments.BspRegion)
+		if !ok || region == nil || !region.ContainsPolygons {
+			continue
+		}
+
+		if _, exists := o.triangles[region]; exists {
+			continue
+		}
+
+		o.triangles[region] = getRegionTriangles(region)
+	}
+
+	return o
+}
+
+// Occluded returns true if any region polygon blocks the segment between from and to.
+func (o *BspOccluder) Occluded(from, to vecmath.Vec3) bool {
+	direction := vecmath.Sub(to, from)
+	segmentLength := vecmath.Length(direction)
+	if segmentLength <= 2*occlusionBias || len(o.nodes) == 0 {
+		return false
+	}
+
+	// Trim both ends so the light's and vertex's own polygons are not hit
+	unit := vecmath.Scale(direction, 1/segmentLength)
+	start := vecmath.Add(from, vecmath.Scale(unit, occlusionBias))
+	end := vecmath.Sub(to, vecmath.Scale(unit, occlusionBias))
+
+	return o.segmentBlocked(0, start, end, start, end)
+}
+
+// segmentBlocked walks the BSP tree along the part of the segment [a, b] inside the node
+// and tests the full segment [from, to] against the polygons of each leaf it reaches.
+func (o *BspOccluder) segmentBlocked(nodeIdx int, a, b, from, to vecmath.Vec3) bool {
+	if nodeIdx < 0 || nodeIdx >= len(o.nodes) {
+		return false
+	}
+
+	node := o.nodes[nodeIdx]
+	if node.LeftNode < 0 && node.RightNode < 0 {
+		region, ok := node.Region.(*fragments.BspRegion)
+		if !ok || region == nil {
+			return false
+		}
+
+		for _, tri := range o.triangles[region] {
+			if segmentIntersectsTriangle(from, to, tri) {
+				return true
+			}
+		}
+		return false
+	}
+
+	distanceA := planeDistance(node, a)
+	distanceB := planeDistance(node, b)
+
+	// Positive distances are on the left side of the plane
+	if distanceA >= 0 && distanceB >= 0 {
+		return o.segmentBlocked(node.LeftNode, a, b, from, to)
+	}
+	if distanceA < 0 && distanceB < 0 {
+		return o.segmentBlocked(node.RightNode, a, b, from, to)
+	}
+
+	t := distanceA / (distanceA - distanceB)
+	split := vecmath.Add(a, vecmath.Scale(vecmath.Sub(b, a), t))
+
+	if distanceA >= 0 {
+		return o.segmentBlocked(node.LeftNode, a, split, from, to) ||
+			o.segmentBlocked(node.RightNode, split, b, from, to)
+	}
+
+	return o.segmentBlocked(node.RightNode, a, split, from, to) ||
+		o.segmentBlocked(node.LeftNode, split, b, from, to)
+}
+
+// planeDistance returns the signed distance of a point from the node's split plane.
+func planeDistance(node *datatypes.BspNode, p vecmath.Vec3) float64 {
+	return float64(node.NormalX)*p.X + float64(node.NormalY)*p.Y + float64(node.NormalZ)*p.Z + float64(node.SplitDistance)
+}
+
+// getRegionTriangles returns the world space polygons of a region mesh.
+func getRegionTriangles(region *fragments.BspRegion) []triangle {
+	var mesh *fragments.Mesh
+	switch ref := region.Mesh.(type) {
+	case *fragments.Mesh:
+		mesh = ref
+	case *fragments.MeshReference:
+		mesh, _ = ref.Mesh.(*fragments.Mesh)
+	}
+
+	if mesh == nil {
+		return nil
+	}
+
+	return meshTriangles(mesh)
+}
+
+// meshTriangles returns the polygons of a mesh in world space.
+func meshTriangles(mesh *fragments.Mesh) []triangle {
+	triangles := make([]triangle, 0, len(mesh.Indices))
+	for _, polygon := range mesh.Indices {
+		indices := [3]int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3}
+
+		var tri triangle
+		valid := true
+		for i, index := range indices {
+			if index < 0 || index >= len(mesh.Vertices) {
+				valid = false
+				break
+			}
+			v := mesh.Vertices[index]
+			tri[i] = vecmath.New(float64(v.X+mesh.Center.X), float64(v.Y+mesh.Center.Y), float64(v.Z+mesh.Center.Z))
+		}
+
+		if valid {
+			triangles = append(triangles, tri)
+		}
+	}
+	return triangles
+}
+
+// segmentIntersectsTriangle tests the segment [from, to] against a triangle using Möller-Trumbore.
+func segmentIntersectsTriangle(from, to vecmath.Vec3, tri triangle) bool {
+	const epsilon = 1e-7
+
+	direction := vecmath.Sub(to, from)
+	edge1 := vecmath.Sub(tri[1], tri[0])
+	edge2 := vecmath.Sub(tri[2], tri[0])
+
+	p := vecmath.Cross(direction, edge2)
+	det := vecmath.Dot(edge1, p)
+	if det > -epsilon && det < epsilon {
+		return false
+	}
+
+	invDet := 1 / det
+	s := vecmath.Sub(from, tri[0])
+	u := vecmath.Dot(s, p) * invDet
+	if u < 0 || u > 1 {
+		return false
+	}
+
+	q := vecmath.Cross(s, edge1)
+	v := vecmath.Dot(direction, q) * invDet
+	if v < 0 || u+v > 1 {
+		return false
+	}
+
+	t := vecmath.Dot(edge2, q) * invDet
+	return t >= 0 && t <= 1
+}
@@ -0,0 +1,21 @@
+package lighting
+
+import (
+	"fmt"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/exporters"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// WriteVertexColorSidecar writes baked colors in the same text format as exported vertex colors.
+func WriteVertexColorSidecar(filePath string, colors []datatypes.Color) error {
+	writer := exporters.NewVertexColorsWriter()
+	writer.AddFragmentData(&fragments.VertexColors{Colors: colors})
+
+	if err := writer.WriteAssetToFile(filePath); err != nil {
+		return fmt.Errorf("failed to write vertex color sidecar: %w", err)
+	}
+
+	return nil
+}
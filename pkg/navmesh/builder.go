@@ -0,0 +1,528 @@
+package navmesh
+
+import (
+	"math"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/bsp"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// areaProbeHeight is how far above a floor the BSP is queried for the area type.
+const areaProbeHeight = 1
+
+// Neighbor directions: +X, +Y, -X, -Y.
+var (
+	directionX = [4]int{1, 0, -1, 0}
+	directionY = [4]int{0, 1, 0, -1}
+)
+
+// areaPriority orders the region types when a region has several.
+var areaPriority = []datatypes.RegionType{
+	datatypes.RegionTypeLava,
+	datatypes.RegionTypeZoneline,
+	datatypes.RegionTypePvp,
+	datatypes.RegionTypeFreezingWater,
+	datatypes.RegionTypeWater,
+	datatypes.RegionTypeWaterBlockLos,
+	datatypes.RegionTypeSlippery,
+}
+
+// floor is the walkable top of a span.
+type floor struct {
+	z          float32
+	ceiling    float32
+	x          int
+	y          int
+	neighbors  [4]int
+	area       datatypes.RegionType
+	region     int
+	polygon    int
+	removed    bool
+	isInterior bool
+}
+
+// edgeFloor is a floor on the edge of a tile that neighboring tiles link to.
+type edgeFloor struct {
+	z   float32
+	ref PolyRef
+}
+
+// pendingLink is a link from a polygon to a floor in a neighboring tile.
+type pendingLink struct {
+	from PolyRef
+	x    int
+	y    int
+	z    float32
+}
+
+// builder holds the state shared by all tiles of a build.
+type builder struct {
+	config     Config
+	tree       *fragments.BspTree
+	mesh       *NavMesh
+	minX       float32
+	minY       float32
+	border     int
+	walkableZ  float32
+	nextRegion int
+	edges      map[[2]int][]edgeFloor
+	links      []pendingLink
+}
+
+// Build builds a navmesh from collision triangles.
+// The BSP tree is used to tag polygons with region types and may be nil.
+func Build(triangles []Triangle, tree *fragments.BspTree, config Config) *NavMesh {
+	mesh := &NavMesh{Config: config}
+	if len(triangles) == 0 || config.CellSize <= 0 || config.TileSize <= 0 {
+		return mesh
+	}
+
+	min, max := triangleBounds(triangles)
+	tileWorldSize := config.CellSize * float32(config.TileSize)
+	mesh.TilesX = int(math.Ceil(float64((max.X - min.X) / tileWorldSize)))
+	mesh.TilesY = int(math.Ceil(float64((max.Y - min.Y) / tileWorldSize)))
+	mesh.TilesX = int(math.Max(float64(mesh.TilesX), 1))
+	mesh.TilesY = int(math.Max(float64(mesh.TilesY), 1))
+	mesh.Min = min
+	mesh.Max = datatypes.Vec3{
+		X: min.X + float32(mesh.TilesX)*tileWorldSize,
+		Y: min.Y + float32(mesh.TilesY)*tileWorldSize,
+		Z: max.Z,
+	}
+
+	b := &builder{
+		config:    config,
+		tree:      tree,
+		mesh:      mesh,
+		minX:      min.X,
+		minY:      min.Y,
+		border:    int(math.Ceil(float64(config.AgentRadius/config.CellSize))) + 1,
+		walkableZ: float32(math.Cos(float64(config.MaxSlope) * math.Pi / 180)),
+		edges:     make(map[[2]int][]edgeFloor),
+	}
+
+	bins := b.binTriangles(triangles)
+	for ty := 0; ty < mesh.TilesY; ty++ {
+		for tx := 0; tx < mesh.TilesX; tx++ {
+			b.buildTile(tx, ty, triangles, bins[tx+ty*mesh.TilesX])
+		}
+	}
+
+	b.resolveLinks()
+	return mesh
+}
+
+// triangleBounds returns the bounds of all triangles.
+func triangleBounds(triangles []Triangle) (datatypes.Vec3, datatypes.Vec3) {
+	min := triangles[0].Vertices[0]
+	max := min
+	for _, triangle := range triangles {
+		for _, v := range triangle.Vertices {
+			min = datatypes.Vec3{X: minFloat(min.X, v.X), Y: minFloat(min.Y, v.Y), Z: minFloat(min.Z, v.Z)}
+			max = datatypes.Vec3{X: maxFloat(max.X, v.X), Y: maxFloat(max.Y, v.Y), Z: maxFloat(max.Z, v.Z)}
+		}
+	}
+	return min, max
+}
+
+// binTriangles returns the triangle indices overlapping each tile including its border.
+func (b *builder) binTriangles(triangles []Triangle) [][]int {
+	bins := make([][]int, b.mesh.TilesX*b.mesh.TilesY)
+	tileWorldSize := b.config.CellSize * float32(b.config.TileSize)
+	borderSize := b.config.CellSize * float32(b.border)
+
+	for i, triangle := range triangles {
+		min, max := triangleBounds([]Triangle{triangle})
+		tx0 := vecmath.ClampInt(int(math.Floor(float64((min.X-borderSize-b.minX)/tileWorldSize))), 0, b.mesh.TilesX-1)
+		tx1 := vecmath.ClampInt(int(math.Floor(float64((max.X+borderSize-b.minX)/tileWorldSize))), 0, b.mesh.TilesX-1)
+		ty0 := vecmath.ClampInt(int(math.Floor(float64((min.Y-borderSize-b.minY)/tileWorldSize))), 0, b.mesh.TilesY-1)
+		ty1 := vecmath.ClampInt(int(math.Floor(float64((max.Y+borderSize-b.minY)/tileWorldSize))), 0, b.mesh.TilesY-1)
+
+		for ty := ty0; ty <= ty1; ty++ {
+			for tx := tx0; tx <= tx1; tx++ {
+				bins[tx+ty*b.mesh.TilesX] = append(bins[tx+ty*b.mesh.TilesX], i)
+			}
+		}
+	}
+
+	return bins
+}
+
+// buildTile builds the polygons of one tile and records links to its neighbors.
+func (b *builder) buildTile(tx, ty int, triangles []Triangle, indices []int) {
+	if len(indices) == 0 {
+		return
+	}
+
+	size := b.config.TileSize + 2*b.border
+	startX := tx*b.config.TileSize - b.border
+	startY := ty*b.config.TileSize - b.border
+
+	h := newHeightfield(size, size,
+		b.minX+float32(startX)*b.config.CellSize,
+		b.minY+float32(startY)*b.config.CellSize,
+		b.config.CellSize)
+
+	for _, index := range indices {
+		triangle := triangles[index]
+		h.rasterizeTriangle(triangle, triangle.Normal.Z >= b.walkableZ, b.config.MaxClimb)
+	}
+	h.filterWalkable(b.config.AgentHeight, b.config.MaxClimb)
+
+	floors, cells := b.buildFloors(h)
+	b.connectFloors(floors, cells, size)
+	b.erode(floors)
+	b.tagAreas(floors, h)
+	regionCount := b.buildRegions(floors)
+
+	tile := &Tile{X: tx, Y: ty}
+	tileIdx := len(b.mesh.Tiles)
+	b.buildPolygons(tile, floors, cells, size, h)
+	b.nextRegion += regionCount
+
+	if len(tile.Polygons) == 0 {
+		return
+	}
+
+	b.mesh.Tiles = append(b.mesh.Tiles, tile)
+	b.linkPolygons(tile, tileIdx, floors, startX, startY)
+}
+
+// buildFloors collects the walkable span tops of the heightfield.
+// cells holds the floor indices of each column.
+func (b *builder) buildFloors(h *heightfield) ([]*floor, [][]int) {
+	var floors []*floor
+	cells := make([][]int, len(h.columns))
+	interiorMin := b.border
+	interiorMax := b.border + b.config.TileSize
+
+	for i, column := range h.columns {
+		x := i % h.width
+		y := i / h.width
+
+		for j, s := range column {
+			if !s.walkable {
+				continue
+			}
+
+			ceiling := float32(math.MaxFloat32)
+			if j+1 < len(column) {
+				ceiling = column[j+1].min
+			}
+
+			cells[i] = append(cells[i], len(floors))
+			floors = append(floors, &floor{
+				z:          s.max,
+				ceiling:    ceiling,
+				x:          x,
+				y:          y,
+				neighbors:  [4]int{-1, -1, -1, -1},
+				polygon:    -1,
+				region:     -1,
+				isInterior: x >= interiorMin && x < interiorMax && y >= interiorMin && y < interiorMax,
+			})
+		}
+	}
+
+	return floors, cells
+}
+
+// connectFloors links each floor to the closest reachable floor in each direction.
+func (b *builder) connectFloors(floors []*floor, cells [][]int, size int) {
+	for _, f := range floors {
+		for d := 0; d < 4; d++ {
+			nx := f.x + directionX[d]
+			ny := f.y + directionY[d]
+			if nx < 0 || ny < 0 || nx >= size || ny >= size {
+				continue
+			}
+
+			best := -1
+			bestStep := float32(math.MaxFloat32)
+			for _, candidate := range cells[nx+ny*size] {
+				other := floors[candidate]
+				step := float32(math.Abs(float64(other.z - f.z)))
+				clearance := minFloat(f.ceiling, other.ceiling) - maxFloat(f.z, other.z)
+				if step <= b.config.MaxClimb && clearance >= b.config.AgentHeight && step < bestStep {
+					best = candidate
+					bestStep = step
+				}
+			}
+			f.neighbors[d] = best
+		}
+	}
+}
+
+// erode removes floors closer than the agent radius to a wall or ledge.
+func (b *builder) erode(floors []*floor) {
+	iterations := int(math.Ceil(float64(b.config.AgentRadius / b.config.CellSize)))
+
+	for i := 0; i < iterations; i++ {
+		var boundary []*floor
+		for _, f := range floors {
+			if f.removed {
+				continue
+			}
+			for _, n := range f.neighbors {
+				if n < 0 || floors[n].removed {
+					boundary = append(boundary, f)
+					break
+				}
+			}
+		}
+
+		for _, f := range boundary {
+			f.removed = true
+		}
+	}
+
+	for _, f := range floors {
+		for d, n := range f.neighbors {
+			if n >= 0 && floors[n].removed {
+				f.neighbors[d] = -1
+			}
+		}
+	}
+}
+
+// tagAreas sets the area of each floor from the BSP region above it.
+func (b *builder) tagAreas(floors []*floor, h *heightfield) {
+	for _, f := range floors {
+		if f.removed {
+			continue
+		}
+
+		point := datatypes.Vec3{
+			X: h.originX + (float32(f.x)+0.5)*h.cellSize,
+			Y: h.originY + (float32(f.y)+0.5)*h.cellSize,
+			Z: f.z + areaProbeHeight,
+		}
+		f.area = pickArea(bsp.RegionTypesAt(b.tree, point))
+	}
+}
+
+// pickArea returns the most significant region type.
+func pickArea(regionTypes []datatypes.RegionType) datatypes.RegionType {
+	for _, area := range areaPriority {
+		for _, t := range regionTypes {
+			if t == area {
+				return area
+			}
+		}
+	}
+	return datatypes.RegionTypeNormal
+}
+
+// buildRegions flood fills connected interior floors of the same area into regions.
+// Returns the number of regions.
+func (b *builder) buildRegions(floors []*floor) int {
+	regionCount := 0
+
+	for _, start := range floors {
+		if start.removed || !start.isInterior || start.region >= 0 {
+			continue
+		}
+
+		region := b.nextRegion + regionCount
+		regionCount++
+		start.region = region
+		stack := []*floor{start}
+
+		for len(stack) > 0 {
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			for _, n := range f.neighbors {
+				if n < 0 {
+					continue
+				}
+				other := floors[n]
+				if other.removed || !other.isInterior || other.region >= 0 || other.area != start.area {
+					continue
+				}
+				other.region = region
+				stack = append(stack, other)
+			}
+		}
+	}
+
+	return regionCount
+}
+
+// canMerge returns true if a floor can join the polygon being built.
+func canMerge(floors []*floor, index int, start *floor) bool {
+	if index < 0 {
+		return false
+	}
+	f := floors[index]
+	return !f.removed && f.isInterior && f.polygon < 0 && f.region == start.region && f.area == start.area
+}
+
+// buildPolygons greedily merges interior floors into rectangles.
+func (b *builder) buildPolygons(tile *Tile, floors []*floor, cells [][]int, size int, h *heightfield) {
+	vertexIndices := make(map[[3]int64]int)
+
+	vertexIndex := func(x, y int, z float32) int {
+		key := [3]int64{int64(x), int64(y), int64(math.Round(float64(z) * 100))}
+		if idx, ok := vertexIndices[key]; ok {
+			return idx
+		}
+		idx := len(tile.Vertices)
+		vertexIndices[key] = idx
+		tile.Vertices = append(tile.Vertices, datatypes.Vec3{
+			X: h.originX + float32(x)*h.cellSize,
+			Y: h.originY + float32(y)*h.cellSize,
+			Z: z,
+		})
+		return idx
+	}
+
+	for y := b.border; y < b.border+b.config.TileSize; y++ {
+		for x := b.border; x < b.border+b.config.TileSize; x++ {
+			for _, index := range cells[x+y*size] {
+				start := floors[index]
+				if !canMerge(floors, index, start) {
+					continue
+				}
+
+				rows := [][]int{{index}}
+
+				// Extend along +X
+				for current := index; ; {
+					next := floors[current].neighbors[0]
+					if !canMerge(floors, next, start) {
+						break
+					}
+					rows[0] = append(rows[0], next)
+					current = next
+				}
+
+				// Extend along +Y while the whole row connects
+				for {
+					last := rows[len(rows)-1]
+					row := make([]int, 0, len(last))
+					for k, below := range last {
+						next := floors[below].neighbors[1]
+						if !canMerge(floors, next, start) || (k > 0 && floors[row[k-1]].neighbors[0] != next) {
+							row = nil
+							break
+						}
+						row = append(row, next)
+					}
+					if row == nil {
+						break
+					}
+					rows = append(rows, row)
+				}
+
+				polygonIdx := len(tile.Polygons)
+				for _, row := range rows {
+					for _, f := range row {
+						floors[f].polygon = polygonIdx
+					}
+				}
+
+				first := rows[0]
+				last := rows[len(rows)-1]
+				x0, y0 := start.x, start.y
+				x1, y1 := x0+len(first), y0+len(rows)
+
+				tile.Polygons = append(tile.Polygons, &Polygon{
+					Vertices: []int{
+						vertexIndex(x0, y0, floors[first[0]].z),
+						vertexIndex(x1, y0, floors[first[len(first)-1]].z),
+						vertexIndex(x1, y1, floors[last[len(last)-1]].z),
+						vertexIndex(x0, y1, floors[last[0]].z),
+					},
+					Area:   start.area,
+					Region: start.region,
+				})
+			}
+		}
+	}
+}
+
+// linkPolygons links polygons that share connected floors and records the tile edges.
+func (b *builder) linkPolygons(tile *Tile, tileIdx int, floors []*floor, startX, startY int) {
+	interiorMax := b.border + b.config.TileSize - 1
+
+	for _, f := range floors {
+		if f.removed || !f.isInterior || f.polygon < 0 {
+			continue
+		}
+
+		ref := PolyRef{Tile: tileIdx, Polygon: f.polygon}
+		globalX, globalY := startX+f.x, startY+f.y
+
+		if f.x == b.border || f.y == b.border || f.x == interiorMax || f.y == interiorMax {
+			key := [2]int{globalX, globalY}
+			b.edges[key] = append(b.edges[key], edgeFloor{z: f.z, ref: ref})
+		}
+
+		for d, n := range f.neighbors {
+			if n < 0 {
+				continue
+			}
+
+			other := floors[n]
+			if !other.isInterior {
+				b.links = append(b.links, pendingLink{
+					from: ref,
+					x:    globalX + directionX[d],
+					y:    globalY + directionY[d],
+					z:    other.z,
+				})
+				continue
+			}
+
+			if other.polygon >= 0 && other.polygon != f.polygon {
+				tile.Polygons[f.polygon].addNeighbor(PolyRef{Tile: tileIdx, Polygon: other.polygon})
+			}
+		}
+	}
+}
+
+// resolveLinks connects polygons across tile edges.
+// Neighboring tiles rasterize the same triangles along their shared edge,
+// so the floor heights match up to the step height.
+func (b *builder) resolveLinks() {
+	for _, link := range b.links {
+		best := -1
+		bestStep := float32(math.MaxFloat32)
+
+		candidates := b.edges[[2]int{link.x, link.y}]
+		for i, candidate := range candidates {
+			step := float32(math.Abs(float64(candidate.z - link.z)))
+			if step <= b.config.MaxClimb && step < bestStep {
+				best = i
+				bestStep = step
+			}
+		}
+
+		if best < 0 {
+			continue
+		}
+
+		target := candidates[best].ref
+		b.mesh.Tiles[link.from.Tile].Polygons[link.from.Polygon].addNeighbor(target)
+		b.mesh.Tiles[target.Tile].Polygons[target.Polygon].addNeighbor(link.from)
+	}
+}
+
+// minFloat returns the smaller of a and b.
+func minFloat(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxFloat returns the larger of a and b.
+func maxFloat(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
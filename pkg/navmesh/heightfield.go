@@ -0,0 +1,214 @@
+package navmesh
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// minCellCoverage is the fraction of a cell a polygon must cover to add a span.
+const minCellCoverage = 1e-4
+
+// verticalThreshold is the normal Z below which a triangle is treated as a wall.
+const verticalThreshold = 1e-3
+
+// span is a solid vertical interval of a heightfield column.
+type span struct {
+	min      float32
+	max      float32
+	walkable bool
+}
+
+// heightfield is a grid of columns of solid spans.
+// Cell (0, 0) starts at originX, originY in world space.
+type heightfield struct {
+	width    int
+	height   int
+	originX  float32
+	originY  float32
+	cellSize float32
+	columns  [][]span
+}
+
+// newHeightfield creates an empty heightfield.
+func newHeightfield(width, height int, originX, originY, cellSize float32) *heightfield {
+	return &heightfield{
+		width:    width,
+		height:   height,
+		originX:  originX,
+		originY:  originY,
+		cellSize: cellSize,
+		columns:  make([][]span, width*height),
+	}
+}
+
+// rasterizeTriangle adds the parts of a triangle covering each cell as spans.
+func (h *heightfield) rasterizeTriangle(triangle Triangle, walkable bool, mergeDistance float32) {
+	minX, minY := triangle.Vertices[0].X, triangle.Vertices[0].Y
+	maxX, maxY := minX, minY
+	for _, v := range triangle.Vertices[1:] {
+		minX = float32(math.Min(float64(minX), float64(v.X)))
+		minY = float32(math.Min(float64(minY), float64(v.Y)))
+		maxX = float32(math.Max(float64(maxX), float64(v.X)))
+		maxY = float32(math.Max(float64(maxY), float64(v.Y)))
+	}
+
+	x0 := vecmath.ClampInt(int(math.Floor(float64((minX-h.originX)/h.cellSize))), 0, h.width-1)
+	x1 := vecmath.ClampInt(int(math.Floor(float64((maxX-h.originX)/h.cellSize))), 0, h.width-1)
+	y0 := vecmath.ClampInt(int(math.Floor(float64((minY-h.originY)/h.cellSize))), 0, h.height-1)
+	y1 := vecmath.ClampInt(int(math.Floor(float64((maxY-h.originY)/h.cellSize))), 0, h.height-1)
+
+	if maxX < h.originX || maxY < h.originY ||
+		minX >= h.originX+float32(h.width)*h.cellSize || minY >= h.originY+float32(h.height)*h.cellSize {
+		return
+	}
+
+	polygon := triangle.Vertices[:]
+	for y := y0; y <= y1; y++ {
+		cellMinY := h.originY + float32(y)*h.cellSize
+		row := clipPolygon(polygon, 1, cellMinY, true)
+		row = clipPolygon(row, 1, cellMinY+h.cellSize, false)
+		if len(row) < 3 {
+			continue
+		}
+
+		for x := x0; x <= x1; x++ {
+			cellMinX := h.originX + float32(x)*h.cellSize
+			cell := clipPolygon(row, 0, cellMinX, true)
+			cell = clipPolygon(cell, 0, cellMinX+h.cellSize, false)
+			if len(cell) < 3 {
+				continue
+			}
+
+			// Edges lying on a cell border leave a polygon with no area behind
+			if polygonArea2D(cell) <= minCellCoverage*h.cellSize*h.cellSize && !isVertical(triangle) {
+				continue
+			}
+
+			spanMin, spanMax := cell[0].Z, cell[0].Z
+			for _, v := range cell[1:] {
+				spanMin = float32(math.Min(float64(spanMin), float64(v.Z)))
+				spanMax = float32(math.Max(float64(spanMax), float64(v.Z)))
+			}
+
+			h.addSpan(x, y, span{min: spanMin, max: spanMax, walkable: walkable}, mergeDistance)
+		}
+	}
+}
+
+// addSpan inserts a span into a column, merging it with any spans it overlaps.
+// If the merged tops are within the merge distance, the span stays walkable if either was.
+func (h *heightfield) addSpan(x, y int, s span, mergeDistance float32) {
+	column := h.columns[x+y*h.width]
+	kept := column[:0]
+
+	for _, existing := range column {
+		if existing.min > s.max || existing.max < s.min {
+			kept = append(kept, existing)
+			continue
+		}
+
+		if float32(math.Abs(float64(existing.max-s.max))) <= mergeDistance {
+			s.walkable = s.walkable || existing.walkable
+		} else if existing.max > s.max {
+			s.walkable = existing.walkable
+		}
+
+		s.min = float32(math.Min(float64(s.min), float64(existing.min)))
+		s.max = float32(math.Max(float64(s.max), float64(existing.max)))
+	}
+
+	kept = append(kept, s)
+	sort.Slice(kept, func(i, j int) bool { return kept[i].min < kept[j].min })
+	h.columns[x+y*h.width] = kept
+}
+
+// filterWalkable removes walkable flags without enough clearance and marks
+// low obstacles on top of walkable spans as walkable so steps can be climbed.
+func (h *heightfield) filterWalkable(agentHeight, maxClimb float32) {
+	for i, column := range h.columns {
+		previousWalkable := false
+		var previousMax float32
+
+		for j := range column {
+			s := &column[j]
+			wasWalkable := s.walkable
+
+			if !s.walkable && previousWalkable && s.max-previousMax <= maxClimb {
+				s.walkable = true
+			}
+
+			previousWalkable = wasWalkable
+			previousMax = s.max
+		}
+
+		for j := range column {
+			if j+1 < len(column) && column[j+1].min-column[j].max < agentHeight {
+				column[j].walkable = false
+			}
+		}
+
+		h.columns[i] = column
+	}
+}
+
+// clipPolygon keeps the part of a polygon on one side of an axis aligned line.
+// Axis 0 clips on X and axis 1 on Y. If keepAbove is true, coordinates >= value are kept.
+func clipPolygon(polygon []datatypes.Vec3, axis int, value float32, keepAbove bool) []datatypes.Vec3 {
+	if len(polygon) == 0 {
+		return nil
+	}
+
+	distance := func(v datatypes.Vec3) float32 {
+		d := v.X - value
+		if axis == 1 {
+			d = v.Y - value
+		}
+		if !keepAbove {
+			d = -d
+		}
+		return d
+	}
+
+	result := make([]datatypes.Vec3, 0, len(polygon)+2)
+	for i, current := range polygon {
+		next := polygon[(i+1)%len(polygon)]
+		dc := distance(current)
+		dn := distance(next)
+
+		if dc >= 0 {
+			result = append(result, current)
+		}
+
+		if (dc >= 0) != (dn >= 0) {
+			t := dc / (dc - dn)
+			result = append(result, datatypes.Vec3{
+				X: current.X + (next.X-current.X)*t,
+				Y: current.Y + (next.Y-current.Y)*t,
+				Z: current.Z + (next.Z-current.Z)*t,
+			})
+		}
+	}
+
+	return result
+}
+
+// polygonArea2D returns the area of a polygon projected onto the XY plane.
+func polygonArea2D(polygon []datatypes.Vec3) float32 {
+	// Relative to the first point to keep precision far from the origin
+	var area float32
+	origin := polygon[0]
+	for i := 1; i+1 < len(polygon); i++ {
+		ax, ay := polygon[i].X-origin.X, polygon[i].Y-origin.Y
+		bx, by := polygon[i+1].X-origin.X, polygon[i+1].Y-origin.Y
+		area += ax*by - bx*ay
+	}
+	return float32(math.Abs(float64(area))) / 2
+}
+
+// isVertical returns true if a triangle has no area seen from above.
+func isVertical(triangle Triangle) bool {
+	return float32(math.Abs(float64(triangle.Normal.Z))) < verticalThreshold
+}
@@ -0,0 +1,53 @@
+package navmesh
+
+import (
+	"github.com/tmyhres/LanternGoExtract/pkg/collision"
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// Triangle is a collision triangle in EQ world space with its face normal.
+type Triangle struct {
+	Vertices [3]datatypes.Vec3
+
+	// Normal is the unit face normal, pointing away from the solid side.
+	Normal datatypes.Vec3
+}
+
+// CollisionTriangles returns the collision polygons of the meshes in world space.
+// These are the same polygons collision queries use, see collision.CollisionTriangles.
+func CollisionTriangles(meshes []*fragments.Mesh, legacyMeshes []*fragments.LegacyMesh) []Triangle {
+	var triangles []Triangle
+
+	for _, polygon := range collision.CollisionPolygons(meshes, legacyMeshes) {
+		normal, ok := faceNormal(polygon.Triangle, polygon.Normal)
+		if !ok {
+			continue
+		}
+
+		triangles = append(triangles, Triangle{Vertices: polygon.Triangle, Normal: normal})
+	}
+
+	return triangles
+}
+
+// faceNormal returns the unit normal of a triangle facing the same way as its vertex normals.
+// The winding is not reliable, triangles without vertex normals are taken to face up.
+func faceNormal(v collision.Triangle, storedNormal datatypes.Vec3) (datatypes.Vec3, bool) {
+	var p [3]vecmath.Vec3
+	for i := range v {
+		p[i] = vecmath.New(float64(v[i].X), float64(v[i].Y), float64(v[i].Z))
+	}
+
+	n := vecmath.Normalize(vecmath.Cross(vecmath.Sub(p[1], p[0]), vecmath.Sub(p[2], p[0])))
+	if n == (vecmath.Vec3{}) {
+		return datatypes.Vec3{}, false
+	}
+	facing := vecmath.Dot(n, vecmath.New(float64(storedNormal.X), float64(storedNormal.Y), float64(storedNormal.Z)))
+	if facing < 0 || (facing == 0 && n.Z < 0) {
+		n = vecmath.Scale(n, -1)
+	}
+
+	return datatypes.Vec3{X: float32(n.X), Y: float32(n.Y), Z: float32(n.Z)}, true
+}
@@ -0,0 +1,116 @@
+// Package navmesh builds navigation meshes from zone collision geometry.
+//
+// The builder follows the usual voxel approach: the collision polygons are rasterized
+// into a heightfield, walkable spans are filtered by slope, step height and
+// clearance, the walkable area is eroded by the agent radius and the remaining
+// cells are merged into convex polygons. Each polygon is tagged with the BSP
+// region type it lies in, so water and lava can be weighted by the pathfinder.
+package navmesh
+
+import (
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// Config controls the navmesh build. Distances are in EQ world units.
+type Config struct {
+	// CellSize is the horizontal size of a heightfield cell.
+	CellSize float32 `json:"cellSize"`
+
+	// AgentHeight is the minimum clearance above a walkable surface.
+	AgentHeight float32 `json:"agentHeight"`
+
+	// AgentRadius is the distance walkable areas are kept away from walls and ledges.
+	AgentRadius float32 `json:"agentRadius"`
+
+	// MaxClimb is the highest step the agent can walk up or down.
+	MaxClimb float32 `json:"maxClimb"`
+
+	// MaxSlope is the steepest walkable slope in degrees.
+	MaxSlope float32 `json:"maxSlope"`
+
+	// TileSize is the number of cells along each side of a tile.
+	TileSize int `json:"tileSize"`
+}
+
+// DefaultConfig returns settings for a human sized agent.
+func DefaultConfig() Config {
+	return Config{
+		CellSize:    2,
+		AgentHeight: 6,
+		AgentRadius: 2,
+		MaxClimb:    3,
+		MaxSlope:    50,
+		TileSize:    64,
+	}
+}
+
+// NavMesh is a tiled polygon navigation mesh.
+type NavMesh struct {
+	// Config is the configuration the mesh was built with.
+	Config Config
+
+	// Min and Max are the bounds of the tile grid.
+	Min datatypes.Vec3
+	Max datatypes.Vec3
+
+	// TilesX and TilesY are the size of the tile grid.
+	TilesX int
+	TilesY int
+
+	// Tiles are the tiles that contain at least one polygon.
+	Tiles []*Tile
+}
+
+// Tile is one square of the navmesh grid.
+type Tile struct {
+	// X and Y are the tile coordinates in the tile grid.
+	X int
+	Y int
+
+	// Vertices are the polygon vertices in EQ world space.
+	Vertices []datatypes.Vec3
+
+	// Polygons are the convex walkable polygons of the tile.
+	Polygons []*Polygon
+}
+
+// Polygon is a convex walkable polygon.
+type Polygon struct {
+	// Vertices index into the tile vertices, counter-clockwise seen from above.
+	Vertices []int
+
+	// Area is the BSP region type the polygon lies in. Normal ground is RegionTypeNormal.
+	Area datatypes.RegionType
+
+	// Region is the connected walkable region the polygon belongs to.
+	// Region ids are unique across the whole mesh.
+	Region int
+
+	// Neighbors are the polygons the agent can move to directly.
+	Neighbors []PolyRef
+}
+
+// PolyRef references a polygon by tile and polygon index.
+type PolyRef struct {
+	Tile    int
+	Polygon int
+}
+
+// PolygonCount returns the number of polygons in all tiles.
+func (m *NavMesh) PolygonCount() int {
+	count := 0
+	for _, tile := range m.Tiles {
+		count += len(tile.Polygons)
+	}
+	return count
+}
+
+// addNeighbor links a polygon to another unless the link already exists.
+func (p *Polygon) addNeighbor(ref PolyRef) {
+	for _, existing := range p.Neighbors {
+		if existing == ref {
+			return
+		}
+	}
+	p.Neighbors = append(p.Neighbors, ref)
+}
@@ -0,0 +1,211 @@
+package navmesh
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+func TestBuild(t *testing.T) {
+	// A flat 40x40 floor at z = 0, normals stored inverted
+	mesh := &fragments.Mesh{
+		Vertices: []fragments.Vec3{{X: -20, Y: -20}, {X: 20, Y: -20}, {X: 20, Y: 20}, {X: -20, Y: 20}},
+		Normals:  []fragments.Vec3{{Z: -1}, {Z: -1}, {Z: -1}, {Z: -1}},
+		Indices: []datatypes.Polygon{
+			{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2},
+			{IsSolid: true, Vertex1: 0, Vertex2: 2, Vertex3: 3},
+		},
+	}
+
+	triangles := CollisionTriangles([]*fragments.Mesh{mesh}, nil)
+	if len(triangles) != 2 || triangles[0].Normal.Z != 1 {
+		t.Fatalf("Expected 2 up facing triangles, got %v", triangles)
+	}
+
+	// Water on the positive X side
+	water := &fragments.BspRegionType{RegionTypes: []datatypes.RegionType{datatypes.RegionTypeWater}}
+	tree := &fragments.BspTree{Nodes: []*datatypes.BspNode{
+		{NormalX: 1, LeftNode: 1, RightNode: 2},
+		{LeftNode: -1, RightNode: -1, Region: &fragments.BspRegion{RegionType: water}},
+		{LeftNode: -1, RightNode: -1, Region: &fragments.BspRegion{}},
+	}}
+
+	config := DefaultConfig()
+	config.TileSize = 8
+	navMesh := Build(triangles, tree, config)
+
+	if navMesh.TilesX != 3 || navMesh.TilesY != 3 || len(navMesh.Tiles) == 0 {
+		t.Fatalf("Expected a 3x3 tile grid, got %dx%d with %d tiles", navMesh.TilesX, navMesh.TilesY, len(navMesh.Tiles))
+	}
+
+	areas := make(map[datatypes.RegionType]bool)
+	crossTileLinks := 0
+	for tileIdx, tile := range navMesh.Tiles {
+		for _, polygon := range tile.Polygons {
+			areas[polygon.Area] = true
+			for _, v := range polygon.Vertices {
+				p := tile.Vertices[v]
+				// Eroded by the agent radius from the floor edges
+				if p.X < -18.01 || p.X > 18.01 || p.Y < -18.01 || p.Y > 18.01 || p.Z != 0 {
+					t.Fatalf("Vertex %v is outside the eroded floor", p)
+				}
+			}
+			for _, neighbor := range polygon.Neighbors {
+				if neighbor.Tile != tileIdx {
+					crossTileLinks++
+				}
+			}
+		}
+	}
+
+	if !areas[datatypes.RegionTypeWater] || !areas[datatypes.RegionTypeNormal] {
+		t.Errorf("Expected both water and normal polygons, got %v", areas)
+	}
+	if crossTileLinks == 0 {
+		t.Error("Expected polygons to link across tiles")
+	}
+
+	var buffer bytes.Buffer
+	if err := navMesh.encodeBinary(&buffer); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	decoded, err := ReadBinary(&buffer)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, navMesh) {
+		t.Error("Decoded navmesh does not match the original")
+	}
+}
+
+func TestBuildTagsLinkedWaterRegion(t *testing.T) {
+	mesh := &fragments.Mesh{
+		Vertices: []fragments.Vec3{{X: -20, Y: -20}, {X: 20, Y: -20}, {X: 20, Y: 20}, {X: -20, Y: 20}},
+		Normals:  []fragments.Vec3{{Z: -1}, {Z: -1}, {Z: -1}, {Z: -1}},
+		Indices: []datatypes.Polygon{
+			{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2},
+			{IsSolid: true, Vertex1: 0, Vertex2: 2, Vertex3: 3},
+		},
+	}
+
+	// Leaves reference their regions by id and the water region type by region index,
+	// linked the same way the zone WLD links them
+	tree := &fragments.BspTree{Nodes: []*datatypes.BspNode{
+		{NormalX: 1, LeftNode: 1, RightNode: 2},
+		{LeftNode: -1, RightNode: -1, RegionID: 1},
+		{LeftNode: -1, RightNode: -1, RegionID: 2},
+	}}
+	regions := []*fragments.BspRegion{{}, {}}
+	water := &fragments.BspRegionType{
+		RegionTypes:      []datatypes.RegionType{datatypes.RegionTypeWater},
+		BspRegionIndices: []int{0},
+	}
+	tree.LinkBspRegions(regions)
+	water.LinkRegionType(regions)
+
+	config := DefaultConfig()
+	config.TileSize = 8
+	navMesh := Build(CollisionTriangles([]*fragments.Mesh{mesh}, nil), tree, config)
+
+	waterPolygons := 0
+	for _, tile := range navMesh.Tiles {
+		for _, polygon := range tile.Polygons {
+			minX, maxX := float32(100), float32(-100)
+			for _, v := range polygon.Vertices {
+				minX = minFloat(minX, tile.Vertices[v].X)
+				maxX = maxFloat(maxX, tile.Vertices[v].X)
+			}
+
+			switch {
+			case minX > 0.5:
+				if polygon.Area != datatypes.RegionTypeWater {
+					t.Fatalf("Expected a polygon in the water leaf to be water, got %v", polygon.Area)
+				}
+				waterPolygons++
+			case maxX < -0.5:
+				if polygon.Area != datatypes.RegionTypeNormal {
+					t.Fatalf("Expected a polygon outside the water leaf to be normal, got %v", polygon.Area)
+				}
+			}
+		}
+	}
+
+	if waterPolygons == 0 {
+		t.Error("Expected polygons in the water leaf")
+	}
+}
+
+func TestCollisionTriangles(t *testing.T) {
+	// A floor wound clockwise from above and a passable polygon the collision export drops
+	mesh := &fragments.Mesh{
+		ExportSeparateCollision: true,
+		Vertices:                []fragments.Vec3{{X: 0, Y: 0}, {X: 0, Y: 1}, {X: 1, Y: 0}},
+		Indices: []datatypes.Polygon{
+			{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2},
+			{IsSolid: false, Vertex1: 0, Vertex2: 2, Vertex3: 1},
+		},
+	}
+
+	// Legacy meshes collide with their solid polygons
+	legacy := &fragments.LegacyMesh{
+		Vertices: []datatypes.Vec3{{X: 0, Y: 0, Z: 5}, {X: 1, Y: 0, Z: 5}, {X: 0, Y: 1, Z: 5}},
+		Polygons: []*datatypes.Polygon{{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2}},
+	}
+
+	triangles := CollisionTriangles([]*fragments.Mesh{mesh}, []*fragments.LegacyMesh{legacy})
+	if len(triangles) != 2 {
+		t.Fatalf("Expected the solid polygon and the legacy polygon, got %d triangles", len(triangles))
+	}
+	for _, triangle := range triangles {
+		if triangle.Normal.Z != 1 {
+			t.Errorf("Expected an up facing normal, got %v", triangle.Normal)
+		}
+	}
+	if triangles[1].Vertices[0].Z != 5 {
+		t.Errorf("Expected the legacy polygon second, got %v", triangles[1].Vertices)
+	}
+}
+
+func TestCeilingNotWalkable(t *testing.T) {
+	// A floor at z = 0 under a ceiling at z = 50 with the same winding, normals stored inverted
+	mesh := &fragments.Mesh{
+		Vertices: []fragments.Vec3{
+			{X: -20, Y: -20}, {X: 20, Y: -20}, {X: 20, Y: 20}, {X: -20, Y: 20},
+			{X: -20, Y: -20, Z: 50}, {X: 20, Y: -20, Z: 50}, {X: 20, Y: 20, Z: 50}, {X: -20, Y: 20, Z: 50},
+		},
+		Normals: []fragments.Vec3{{Z: -1}, {Z: -1}, {Z: -1}, {Z: -1}, {Z: 1}, {Z: 1}, {Z: 1}, {Z: 1}},
+		Indices: []datatypes.Polygon{
+			{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2},
+			{IsSolid: true, Vertex1: 0, Vertex2: 2, Vertex3: 3},
+			{IsSolid: true, Vertex1: 4, Vertex2: 5, Vertex3: 6},
+			{IsSolid: true, Vertex1: 4, Vertex2: 6, Vertex3: 7},
+		},
+	}
+
+	triangles := CollisionTriangles([]*fragments.Mesh{mesh}, nil)
+	if len(triangles) != 4 || triangles[0].Normal.Z != 1 || triangles[2].Normal.Z != -1 {
+		t.Fatalf("Expected an up facing floor and a down facing ceiling, got %v", triangles)
+	}
+
+	config := DefaultConfig()
+	config.TileSize = 8
+	navMesh := Build(triangles, nil, config)
+
+	polygons := 0
+	for _, tile := range navMesh.Tiles {
+		for _, polygon := range tile.Polygons {
+			polygons++
+			for _, v := range polygon.Vertices {
+				if tile.Vertices[v].Z != 0 {
+					t.Fatalf("Expected only the floor to be walkable, got a vertex at %v", tile.Vertices[v])
+				}
+			}
+		}
+	}
+	if polygons == 0 {
+		t.Error("Expected the floor to be walkable")
+	}
+}
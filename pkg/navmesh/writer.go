@@ -0,0 +1,310 @@
+package navmesh
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// binaryMagic identifies a binary navmesh file.
+const binaryMagic = "LNAV"
+
+// binaryVersion is the version of the binary navmesh format.
+const binaryVersion = 1
+
+// WriteObj writes the navmesh polygons as an OBJ file with one group per area type.
+// Vertices use the same coordinate conversion as the zone OBJ so both files line up.
+func (m *NavMesh) WriteObj(filePath string) error {
+	var sb strings.Builder
+	sb.WriteString("# Lantern Extractor Navigation Mesh\n")
+	sb.WriteString(fmt.Sprintf("# Tiles: %d, Polygons: %d\n", len(m.Tiles), m.PolygonCount()))
+
+	groups := make(map[datatypes.RegionType][]string)
+	var groupOrder []datatypes.RegionType
+	baseVertex := 1
+
+	for _, tile := range m.Tiles {
+		for _, v := range tile.Vertices {
+			sb.WriteString(fmt.Sprintf("v %f %f %f\n", -v.X, v.Z, v.Y))
+		}
+
+		for _, polygon := range tile.Polygons {
+			indices := make([]string, len(polygon.Vertices))
+			for i, index := range polygon.Vertices {
+				indices[i] = fmt.Sprint(baseVertex + index)
+			}
+
+			if _, exists := groups[polygon.Area]; !exists {
+				groupOrder = append(groupOrder, polygon.Area)
+			}
+			groups[polygon.Area] = append(groups[polygon.Area], "f "+strings.Join(indices, " "))
+		}
+
+		baseVertex += len(tile.Vertices)
+	}
+
+	for _, area := range groupOrder {
+		sb.WriteString("g " + strings.ToLower(area.String()) + "\n")
+		for _, face := range groups[area] {
+			sb.WriteString(face + "\n")
+		}
+	}
+
+	return writeFile(filePath, []byte(sb.String()))
+}
+
+// jsonNavMesh is the JSON layout of a navmesh.
+type jsonNavMesh struct {
+	Config Config      `json:"config"`
+	Min    [3]float32  `json:"min"`
+	Max    [3]float32  `json:"max"`
+	TilesX int         `json:"tilesX"`
+	TilesY int         `json:"tilesY"`
+	Tiles  []*jsonTile `json:"tiles"`
+}
+
+// jsonTile is the JSON layout of a tile.
+type jsonTile struct {
+	X        int            `json:"x"`
+	Y        int            `json:"y"`
+	Vertices [][3]float32   `json:"vertices"`
+	Polygons []*jsonPolygon `json:"polygons"`
+}
+
+// jsonPolygon is the JSON layout of a polygon. Neighbors are [tile, polygon] pairs.
+type jsonPolygon struct {
+	Vertices  []int    `json:"vertices"`
+	Area      string   `json:"area"`
+	Region    int      `json:"region"`
+	Neighbors [][2]int `json:"neighbors"`
+}
+
+// WriteJSON writes the navmesh tiles as JSON. Positions are in EQ world space.
+func (m *NavMesh) WriteJSON(filePath string) error {
+	out := &jsonNavMesh{
+		Config: m.Config,
+		Min:    [3]float32{m.Min.X, m.Min.Y, m.Min.Z},
+		Max:    [3]float32{m.Max.X, m.Max.Y, m.Max.Z},
+		TilesX: m.TilesX,
+		TilesY: m.TilesY,
+		Tiles:  make([]*jsonTile, 0, len(m.Tiles)),
+	}
+
+	for _, tile := range m.Tiles {
+		t := &jsonTile{X: tile.X, Y: tile.Y}
+		for _, v := range tile.Vertices {
+			t.Vertices = append(t.Vertices, [3]float32{v.X, v.Y, v.Z})
+		}
+
+		for _, polygon := range tile.Polygons {
+			p := &jsonPolygon{
+				Vertices:  polygon.Vertices,
+				Area:      polygon.Area.String(),
+				Region:    polygon.Region,
+				Neighbors: make([][2]int, 0, len(polygon.Neighbors)),
+			}
+			for _, neighbor := range polygon.Neighbors {
+				p.Neighbors = append(p.Neighbors, [2]int{neighbor.Tile, neighbor.Polygon})
+			}
+			t.Polygons = append(t.Polygons, p)
+		}
+
+		out.Tiles = append(out.Tiles, t)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode navmesh: %w", err)
+	}
+
+	return writeFile(filePath, data)
+}
+
+// WriteBinary writes the navmesh in the little endian binary tile format:
+//
+//	header: "LNAV", version u32, cellSize, agentHeight, agentRadius, maxClimb, maxSlope f32,
+//	        tileSize i32, min 3×f32, max 3×f32, tilesX i32, tilesY i32, tileCount i32
+//	tile:   x i32, y i32, vertexCount i32, vertices 3×f32, polygonCount i32
+//	polygon: area u8, vertexCount u8, region i32, vertex indices u32,
+//	         neighborCount u16, neighbors (tile u32, polygon u32)
+func (m *NavMesh) WriteBinary(filePath string) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create navmesh directory: %w", err)
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create navmesh file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := m.encodeBinary(writer); err != nil {
+		return fmt.Errorf("failed to write navmesh: %w", err)
+	}
+
+	return writer.Flush()
+}
+
+// encodeBinary writes the binary format to w.
+func (m *NavMesh) encodeBinary(w io.Writer) error {
+	write := func(values ...interface{}) error {
+		for _, value := range values {
+			if err := binary.Write(w, binary.LittleEndian, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	c := m.Config
+	if err := write([]byte(binaryMagic), uint32(binaryVersion),
+		c.CellSize, c.AgentHeight, c.AgentRadius, c.MaxClimb, c.MaxSlope, int32(c.TileSize),
+		m.Min.X, m.Min.Y, m.Min.Z, m.Max.X, m.Max.Y, m.Max.Z,
+		int32(m.TilesX), int32(m.TilesY), int32(len(m.Tiles))); err != nil {
+		return err
+	}
+
+	for _, tile := range m.Tiles {
+		if err := write(int32(tile.X), int32(tile.Y), int32(len(tile.Vertices))); err != nil {
+			return err
+		}
+		for _, v := range tile.Vertices {
+			if err := write(v.X, v.Y, v.Z); err != nil {
+				return err
+			}
+		}
+
+		if err := write(int32(len(tile.Polygons))); err != nil {
+			return err
+		}
+		for _, polygon := range tile.Polygons {
+			if err := write(uint8(polygon.Area), uint8(len(polygon.Vertices)), int32(polygon.Region)); err != nil {
+				return err
+			}
+			for _, index := range polygon.Vertices {
+				if err := write(uint32(index)); err != nil {
+					return err
+				}
+			}
+			if err := write(uint16(len(polygon.Neighbors))); err != nil {
+				return err
+			}
+			for _, neighbor := range polygon.Neighbors {
+				if err := write(uint32(neighbor.Tile), uint32(neighbor.Polygon)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ReadBinary reads a navmesh written by WriteBinary.
+func ReadBinary(r io.Reader) (*NavMesh, error) {
+	read := func(values ...interface{}) error {
+		for _, value := range values {
+			if err := binary.Read(r, binary.LittleEndian, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	magic := make([]byte, len(binaryMagic))
+	var version uint32
+	if err := read(magic, &version); err != nil {
+		return nil, fmt.Errorf("failed to read navmesh header: %w", err)
+	}
+	if string(magic) != binaryMagic || version != binaryVersion {
+		return nil, fmt.Errorf("unsupported navmesh file %q version %d", magic, version)
+	}
+
+	m := &NavMesh{}
+	var tileSize, tilesX, tilesY, tileCount int32
+	c := &m.Config
+	if err := read(&c.CellSize, &c.AgentHeight, &c.AgentRadius, &c.MaxClimb, &c.MaxSlope, &tileSize,
+		&m.Min.X, &m.Min.Y, &m.Min.Z, &m.Max.X, &m.Max.Y, &m.Max.Z,
+		&tilesX, &tilesY, &tileCount); err != nil {
+		return nil, fmt.Errorf("failed to read navmesh header: %w", err)
+	}
+	c.TileSize = int(tileSize)
+	m.TilesX = int(tilesX)
+	m.TilesY = int(tilesY)
+
+	for i := int32(0); i < tileCount; i++ {
+		var x, y, vertexCount int32
+		if err := read(&x, &y, &vertexCount); err != nil {
+			return nil, fmt.Errorf("failed to read navmesh tile: %w", err)
+		}
+
+		tile := &Tile{X: int(x), Y: int(y), Vertices: make([]datatypes.Vec3, vertexCount)}
+		for j := range tile.Vertices {
+			v := &tile.Vertices[j]
+			if err := read(&v.X, &v.Y, &v.Z); err != nil {
+				return nil, fmt.Errorf("failed to read navmesh vertex: %w", err)
+			}
+		}
+
+		var polygonCount int32
+		if err := read(&polygonCount); err != nil {
+			return nil, fmt.Errorf("failed to read navmesh tile: %w", err)
+		}
+
+		for j := int32(0); j < polygonCount; j++ {
+			var area, polygonVertexCount uint8
+			var region int32
+			if err := read(&area, &polygonVertexCount, &region); err != nil {
+				return nil, fmt.Errorf("failed to read navmesh polygon: %w", err)
+			}
+
+			polygon := &Polygon{Area: datatypes.RegionType(area), Region: int(region)}
+			for k := uint8(0); k < polygonVertexCount; k++ {
+				var index uint32
+				if err := read(&index); err != nil {
+					return nil, fmt.Errorf("failed to read navmesh polygon: %w", err)
+				}
+				polygon.Vertices = append(polygon.Vertices, int(index))
+			}
+
+			var neighborCount uint16
+			if err := read(&neighborCount); err != nil {
+				return nil, fmt.Errorf("failed to read navmesh polygon: %w", err)
+			}
+			for k := uint16(0); k < neighborCount; k++ {
+				var neighborTile, neighborPolygon uint32
+				if err := read(&neighborTile, &neighborPolygon); err != nil {
+					return nil, fmt.Errorf("failed to read navmesh polygon: %w", err)
+				}
+				polygon.Neighbors = append(polygon.Neighbors, PolyRef{Tile: int(neighborTile), Polygon: int(neighborPolygon)})
+			}
+
+			tile.Polygons = append(tile.Polygons, polygon)
+		}
+
+		m.Tiles = append(m.Tiles, tile)
+	}
+
+	return m, nil
+}
+
+// writeFile writes data to a file, creating its directory.
+func writeFile(filePath string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create navmesh directory: %w", err)
+	}
+
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write navmesh file: %w", err)
+	}
+
+	return nil
+}
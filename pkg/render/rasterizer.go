@@ -0,0 +1,453 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// alphaCutoff is the texture alpha below which a pixel of an opaque or masked surface is a hole.
+const alphaCutoff = 0.5
+
+// view projects model space positions to pixels of the supersampled image.
+type view struct {
+	center, right, up, toEye vecmath.Vec3
+
+	// distance is the distance from the camera to the center, zero for an orthographic view.
+	distance float64
+
+	// light is the unit direction towards the light in model space.
+	light vecmath.Vec3
+
+	// scale, midX and midY map projected positions to pixels.
+	scale, midX, midY float64
+
+	width, height float64
+}
+
+// projectedVertex is a vertex in pixel coordinates.
+type projectedVertex struct {
+	x, y float64
+
+	// depth increases towards the camera and is linear in screen space.
+	depth float64
+
+	// weight is the perspective correction weight of the vertex attributes.
+	weight float64
+}
+
+// framebuffer holds premultiplied colors and depths.
+type framebuffer struct {
+	width, height int
+	colors        [][4]float64
+	depths        []float64
+}
+
+// Render draws the scene into a new image.
+// Returns nil if the scene is empty or the image has no pixels.
+func (s *Scene) Render(options Options) *image.NRGBA {
+	if s.IsEmpty() || options.Width <= 0 || options.Height <= 0 {
+		return nil
+	}
+
+	samples := options.Supersampling
+	if samples < 1 {
+		samples = 1
+	}
+
+	width, height := options.Width*samples, options.Height*samples
+	v := newView(s.triangles, options, width, height)
+
+	buffer := &framebuffer{
+		width:  width,
+		height: height,
+		colors: make([][4]float64, width*height),
+		depths: make([]float64, width*height),
+	}
+	for i := range buffer.depths {
+		buffer.depths[i] = math.Inf(-1)
+	}
+
+	// Opaque surfaces first, then blended surfaces from back to front over them
+	var blended []*triangle
+	for i := range s.triangles {
+		t := &s.triangles[i]
+		if isBlendedShader(t.shader) {
+			blended = append(blended, t)
+			continue
+		}
+		buffer.drawTriangle(t, v, options)
+	}
+
+	sort.SliceStable(blended, func(i, j int) bool {
+		return v.averageDepth(blended[i]) < v.averageDepth(blended[j])
+	})
+	for _, t := range blended {
+		buffer.drawTriangle(t, v, options)
+	}
+
+	return buffer.resolve(options, samples)
+}
+
+// newView places the camera and fits the projected triangles into the image.
+func newView(triangles []triangle, options Options, width, height int) *view {
+	low := vecmath.New(math.Inf(1), math.Inf(1), math.Inf(1))
+	high := vecmath.New(math.Inf(-1), math.Inf(-1), math.Inf(-1))
+	for _, t := range triangles {
+		for _, vertex := range t.vertices {
+			low = vecmath.Min(low, vertex.position)
+			high = vecmath.Max(high, vertex.position)
+		}
+	}
+
+	v := &view{
+		center: vecmath.Scale(vecmath.Add(low, high), 0.5),
+		width:  float64(width),
+		height: float64(height),
+	}
+
+	radius := 0.0
+	for _, t := range triangles {
+		for _, vertex := range t.vertices {
+			radius = math.Max(radius, vecmath.Length(vecmath.Sub(vertex.position, v.center)))
+		}
+	}
+	if radius == 0 {
+		radius = 1
+	}
+
+	yaw := options.Camera.Yaw * math.Pi / 180
+	pitch := options.Camera.Pitch * math.Pi / 180
+	v.toEye = vecmath.New(math.Cos(pitch)*math.Cos(yaw), math.Cos(pitch)*math.Sin(yaw), math.Sin(pitch))
+	v.right = vecmath.New(-math.Sin(yaw), math.Cos(yaw), 0)
+	v.up = vecmath.Cross(v.right, vecmath.Scale(v.toEye, -1))
+
+	if options.Camera.FieldOfView > 0 {
+		halfAngle := math.Min(options.Camera.FieldOfView, 170) * math.Pi / 360
+		v.distance = radius / math.Sin(halfAngle)
+	}
+
+	direction := options.Light.Direction
+	v.light = vecmath.Normalize(vecmath.Add(vecmath.Add(
+		vecmath.Scale(v.right, direction[0]),
+		vecmath.Scale(v.up, direction[1])),
+		vecmath.Scale(v.toEye, direction[2])))
+
+	// Fit the projected bounds into the image inside the padding
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, t := range triangles {
+		for _, vertex := range t.vertices {
+			x, y, _ := v.project(vertex.position)
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+	}
+
+	padding := math.Min(math.Max(options.Camera.Padding, 0), 0.45)
+	availableWidth := v.width * (1 - 2*padding)
+	availableHeight := v.height * (1 - 2*padding)
+	spanX, spanY := maxX-minX, maxY-minY
+
+	v.scale = 1
+	switch {
+	case spanX > 0 && spanY > 0:
+		v.scale = math.Min(availableWidth/spanX, availableHeight/spanY)
+	case spanX > 0:
+		v.scale = availableWidth / spanX
+	case spanY > 0:
+		v.scale = availableHeight / spanY
+	}
+	v.midX, v.midY = (minX+maxX)/2, (minY+maxY)/2
+
+	return v
+}
+
+// project returns the position on the image plane and the distance from the camera plane.
+// The distance is measured from the center for an orthographic view.
+func (v *view) project(position vecmath.Vec3) (float64, float64, float64) {
+	relative := vecmath.Sub(position, v.center)
+	x, y := vecmath.Dot(relative, v.right), vecmath.Dot(relative, v.up)
+	w := v.distance - vecmath.Dot(relative, v.toEye)
+
+	if v.distance > 0 {
+		return x / w, y / w, w
+	}
+	return x, y, w
+}
+
+// toPixel projects a position to pixel coordinates.
+func (v *view) toPixel(position vecmath.Vec3) projectedVertex {
+	x, y, w := v.project(position)
+	p := projectedVertex{
+		x: v.width/2 + (x-v.midX)*v.scale,
+		y: v.height/2 - (y-v.midY)*v.scale,
+	}
+
+	if v.distance > 0 {
+		p.depth = 1 / w
+		p.weight = 1 / w
+	} else {
+		p.depth = -w
+		p.weight = 1
+	}
+	return p
+}
+
+// averageDepth returns the mean depth of the corners of a triangle, used to sort blended triangles.
+func (v *view) averageDepth(t *triangle) float64 {
+	total := 0.0
+	for _, vertex := range t.vertices {
+		total += v.toPixel(vertex.position).depth
+	}
+	return total / 3
+}
+
+// drawTriangle rasterizes a triangle with depth testing. Blended shaders do not write depth.
+func (f *framebuffer) drawTriangle(t *triangle, v *view, options Options) {
+	var p [3]projectedVertex
+	for i, vertex := range t.vertices {
+		p[i] = v.toPixel(vertex.position)
+	}
+
+	area := edge(p[0], p[1], p[2].x, p[2].y)
+	if area == 0 || math.IsNaN(area) {
+		return
+	}
+
+	x0 := vecmath.ClampInt(int(math.Floor(math.Min(p[0].x, math.Min(p[1].x, p[2].x)))), 0, f.width-1)
+	x1 := vecmath.ClampInt(int(math.Ceil(math.Max(p[0].x, math.Max(p[1].x, p[2].x)))), 0, f.width-1)
+	y0 := vecmath.ClampInt(int(math.Floor(math.Min(p[0].y, math.Min(p[1].y, p[2].y)))), 0, f.height-1)
+	y1 := vecmath.ClampInt(int(math.Ceil(math.Max(p[0].y, math.Max(p[1].y, p[2].y)))), 0, f.height-1)
+
+	blended := isBlendedShader(t.shader)
+
+	for py := y0; py <= y1; py++ {
+		for px := x0; px <= x1; px++ {
+			sx, sy := float64(px)+0.5, float64(py)+0.5
+			w0 := edge(p[1], p[2], sx, sy) / area
+			w1 := edge(p[2], p[0], sx, sy) / area
+			w2 := 1 - w0 - w1
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+
+			i := px + py*f.width
+			depth := w0*p[0].depth + w1*p[1].depth + w2*p[2].depth
+			if depth <= f.depths[i] {
+				continue
+			}
+
+			// Perspective correct attribute weights
+			b0, b1, b2 := w0*p[0].weight, w1*p[1].weight, w2*p[2].weight
+			total := b0 + b1 + b2
+			b0, b1, b2 = b0/total, b1/total, b2/total
+
+			pixel, ok := shade(t, v, options, b0, b1, b2)
+			if !ok {
+				continue
+			}
+
+			if blended {
+				f.blend(i, pixel, t.shader)
+				continue
+			}
+
+			f.colors[i] = [4]float64{pixel[0], pixel[1], pixel[2], 1}
+			f.depths[i] = depth
+		}
+	}
+}
+
+// blend composites a blended surface over a pixel.
+func (f *framebuffer) blend(i int, pixel [4]float64, shader fragments.ShaderType) {
+	alpha := pixel[3] * shaderOpacity(shader)
+	dst := f.colors[i]
+
+	if isAdditiveShader(shader) {
+		f.colors[i] = [4]float64{
+			dst[0] + pixel[0]*alpha,
+			dst[1] + pixel[1]*alpha,
+			dst[2] + pixel[2]*alpha,
+			dst[3] + alpha*(1-dst[3]),
+		}
+		return
+	}
+
+	f.colors[i] = [4]float64{
+		pixel[0]*alpha + dst[0]*(1-alpha),
+		pixel[1]*alpha + dst[1]*(1-alpha),
+		pixel[2]*alpha + dst[2]*(1-alpha),
+		alpha + dst[3]*(1-alpha),
+	}
+}
+
+// shade returns the lit color and texture alpha of a point of a triangle.
+// Returns false if the point is cut out by the texture alpha.
+func shade(t *triangle, v *view, options Options, b0, b1, b2 float64) ([4]float64, bool) {
+	a, b, c := t.vertices[0], t.vertices[1], t.vertices[2]
+	pixel := [4]float64{1, 1, 1, 1}
+
+	if t.texture != nil {
+		u := a.uv[0]*b0 + b.uv[0]*b1 + c.uv[0]*b2
+		w := a.uv[1]*b0 + b.uv[1]*b1 + c.uv[1]*b2
+		pixel = sampleBilinear(t.texture, u, w)
+	}
+
+	if !isBlendedShader(t.shader) && pixel[3] < alphaCutoff {
+		return pixel, false
+	}
+
+	if options.VertexColors {
+		for channel := 0; channel < 3; channel++ {
+			pixel[channel] *= a.color[channel]*b0 + b.color[channel]*b1 + c.color[channel]*b2
+		}
+	}
+
+	if !isUnlitShader(t.shader) {
+		normal := vecmath.Normalize(vecmath.Add(vecmath.Add(
+			vecmath.Scale(a.normal, b0),
+			vecmath.Scale(b.normal, b1)),
+			vecmath.Scale(c.normal, b2)))
+
+		// Both sides of a polygon are lit like its front
+		if vecmath.Dot(normal, v.toEye) < 0 {
+			normal = vecmath.Scale(normal, -1)
+		}
+
+		intensity := options.Light.Ambient + options.Light.Diffuse*math.Max(0, vecmath.Dot(normal, v.light))
+		for channel := 0; channel < 3; channel++ {
+			pixel[channel] = math.Min(1, pixel[channel]*intensity)
+		}
+	}
+
+	return pixel, true
+}
+
+// sampleBilinear samples a texture with wrapping and returns straight alpha channels in [0, 1].
+func sampleBilinear(texture *image.NRGBA, u, v float64) [4]float64 {
+	bounds := texture.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	x := (u-math.Floor(u))*float64(width) - 0.5
+	y := (v-math.Floor(v))*float64(height) - 0.5
+	fx, fy := math.Floor(x), math.Floor(y)
+	tx, ty := x-fx, y-fy
+
+	var out [4]float64
+	for _, corner := range [4]struct {
+		dx, dy int
+		weight float64
+	}{
+		{0, 0, (1 - tx) * (1 - ty)},
+		{1, 0, tx * (1 - ty)},
+		{0, 1, (1 - tx) * ty},
+		{1, 1, tx * ty},
+	} {
+		px := bounds.Min.X + wrapInt(int(fx)+corner.dx, width)
+		py := bounds.Min.Y + wrapInt(int(fy)+corner.dy, height)
+		pixel := texture.NRGBAAt(px, py)
+		out[0] += float64(pixel.R) / 255 * corner.weight
+		out[1] += float64(pixel.G) / 255 * corner.weight
+		out[2] += float64(pixel.B) / 255 * corner.weight
+		out[3] += float64(pixel.A) / 255 * corner.weight
+	}
+	return out
+}
+
+// resolve averages the samples of each pixel and composites them over the background.
+func (f *framebuffer) resolve(options Options, samples int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, options.Width, options.Height))
+	background := options.Background
+	backgroundAlpha := float64(background.A) / 255
+	sampleCount := float64(samples * samples)
+
+	for y := 0; y < options.Height; y++ {
+		for x := 0; x < options.Width; x++ {
+			var sum [4]float64
+			for sy := 0; sy < samples; sy++ {
+				for sx := 0; sx < samples; sx++ {
+					sample := f.colors[(x*samples+sx)+(y*samples+sy)*f.width]
+					for channel := range sum {
+						sum[channel] += math.Min(1, sample[channel])
+					}
+				}
+			}
+
+			alpha := sum[3] / sampleCount
+			outAlpha := alpha + backgroundAlpha*(1-alpha)
+			if outAlpha <= 0 {
+				img.SetNRGBA(x, y, color.NRGBA{})
+				continue
+			}
+
+			var rgb [3]uint8
+			for channel, value := range [3]uint8{background.R, background.G, background.B} {
+				premultiplied := sum[channel]/sampleCount + float64(value)/255*backgroundAlpha*(1-alpha)
+				rgb[channel] = uint8(math.Round(math.Min(1, premultiplied/outAlpha) * 255))
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: uint8(math.Round(outAlpha * 255))})
+		}
+	}
+
+	return img
+}
+
+// isHiddenShader returns true for shaders that are not drawn by the client.
+func isHiddenShader(shader fragments.ShaderType) bool {
+	return shader == fragments.ShaderTypeInvisible || shader == fragments.ShaderTypeBoundary
+}
+
+// isBlendedShader returns true for shaders blended over the surfaces behind them.
+func isBlendedShader(shader fragments.ShaderType) bool {
+	switch shader {
+	case fragments.ShaderTypeTransparent25, fragments.ShaderTypeTransparent50, fragments.ShaderTypeTransparent75,
+		fragments.ShaderTypeTransparentSkydome:
+		return true
+	}
+	return isAdditiveShader(shader)
+}
+
+// isAdditiveShader returns true for shaders that add their color to the surfaces behind them.
+func isAdditiveShader(shader fragments.ShaderType) bool {
+	return shader == fragments.ShaderTypeTransparentAdditive ||
+		shader == fragments.ShaderTypeTransparentAdditiveUnlit ||
+		shader == fragments.ShaderTypeTransparentAdditiveUnlitSkydome
+}
+
+// isUnlitShader returns true for shaders that ignore lighting.
+func isUnlitShader(shader fragments.ShaderType) bool {
+	return shader == fragments.ShaderTypeTransparentAdditiveUnlit ||
+		shader == fragments.ShaderTypeTransparentAdditiveUnlitSkydome
+}
+
+// shaderOpacity returns the opacity of a blended shader.
+func shaderOpacity(shader fragments.ShaderType) float64 {
+	switch shader {
+	case fragments.ShaderTypeTransparent25:
+		return 0.75
+	case fragments.ShaderTypeTransparent50, fragments.ShaderTypeTransparentSkydome:
+		return 0.5
+	case fragments.ShaderTypeTransparent75:
+		return 0.25
+	}
+	return 1
+}
+
+// edge returns twice the signed area of the triangle (a, b, p).
+func edge(a, b projectedVertex, px, py float64) float64 {
+	return (b.x-a.x)*(py-a.y) - (b.y-a.y)*(px-a.x)
+}
+
+// wrapInt wraps a value into the range [0, size).
+func wrapInt(value, size int) int {
+	value %= size
+	if value < 0 {
+		value += size
+	}
+	return value
+}
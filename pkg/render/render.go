@@ -0,0 +1,242 @@
+// Package render is a software rasterizer for thumbnails and inventory icons of EQ models.
+// It draws the meshes GltfWriter exports with their textures, vertex colors and an optional
+// skeleton pose without a GPU. The camera orbits the model and frames it to fill the image,
+// and a directional light that moves with the camera shades it.
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// Camera places the camera on a sphere around the model, looking at its center.
+type Camera struct {
+	// Yaw is the angle around the up axis in degrees. At zero the camera is on the +X axis
+	// and positive angles move it counter-clockwise seen from above.
+	Yaw float64
+
+	// Pitch is the angle above the horizon in degrees.
+	Pitch float64
+
+	// FieldOfView is the vertical field of view in degrees. Zero renders an orthographic view.
+	FieldOfView float64
+
+	// Padding is the fraction of the image width and height left empty on each side of the model.
+	Padding float64
+}
+
+// Light is a directional light fixed to the camera with an ambient term.
+type Light struct {
+	// Direction points towards the light in camera space: X is right, Y is up and Z points at the viewer.
+	Direction [3]float64
+
+	// Diffuse is the strength of the directional light.
+	Diffuse float64
+
+	// Ambient is the light reaching every surface.
+	Ambient float64
+}
+
+// Options configures the renderer.
+type Options struct {
+	// Width and Height are the size of the image in pixels.
+	Width  int
+	Height int
+
+	// Supersampling renders this many samples per pixel in each direction and averages them
+	// to smooth edges. Values below 1 take one sample.
+	Supersampling int
+
+	// Camera places the camera around the model.
+	Camera Camera
+
+	// Light shades the model.
+	Light Light
+
+	// Background is the color of empty pixels. The default is transparent.
+	Background color.NRGBA
+
+	// VertexColors multiplies surfaces by the mesh vertex colors.
+	VertexColors bool
+}
+
+// DefaultOptions returns options for a 256 pixel icon seen from the front left and slightly above.
+func DefaultOptions() Options {
+	return Options{
+		Width:         256,
+		Height:        256,
+		Supersampling: 2,
+		Camera: Camera{
+			Yaw:         30,
+			Pitch:       15,
+			FieldOfView: 30,
+			Padding:     0.05,
+		},
+		Light: Light{
+			Direction: [3]float64{-0.4, 0.6, 0.7},
+			Diffuse:   0.7,
+			Ambient:   0.45,
+		},
+		VertexColors: true,
+	}
+}
+
+// vertex is a triangle corner in EQ model space with its surface attributes.
+type vertex struct {
+	position vecmath.Vec3
+	normal   vecmath.Vec3
+	uv       [2]float64
+	color    [3]float64
+}
+
+// triangle is a polygon with the texture and shader of its material.
+type triangle struct {
+	vertices [3]vertex
+	texture  *image.NRGBA
+	shader   fragments.ShaderType
+}
+
+// Scene is a set of meshes to render.
+type Scene struct {
+	// textures are the decoded textures keyed by lower case bitmap filename.
+	textures map[string]image.Image
+
+	// converted caches the textures converted for sampling.
+	converted map[string]*image.NRGBA
+
+	triangles []triangle
+}
+
+// NewScene creates an empty scene. Textures are keyed by lower case bitmap filename and may be nil.
+func NewScene(textures map[string]image.Image) *Scene {
+	return &Scene{
+		textures:  textures,
+		converted: make(map[string]*image.NRGBA),
+	}
+}
+
+// IsEmpty returns true if the scene has nothing to draw.
+func (s *Scene) IsEmpty() bool {
+	return len(s.triangles) == 0
+}
+
+// AddMesh adds the visible polygons of a mesh to the scene. Positions and normals replace the
+// vertices and normals of the mesh when set, e.g. with the posed meshes of an actor. Otherwise the
+// vertices are offset by the mesh center and the stored normals, which point inwards, are flipped.
+func (s *Scene) AddMesh(mesh *fragments.Mesh, positions, normals []fragments.Vec3) {
+	if mesh == nil {
+		return
+	}
+
+	if positions == nil {
+		positions = make([]fragments.Vec3, len(mesh.Vertices))
+		for i, v := range mesh.Vertices {
+			positions[i] = fragments.Vec3{X: v.X + mesh.Center.X, Y: v.Y + mesh.Center.Y, Z: v.Z + mesh.Center.Z}
+		}
+	}
+
+	if normals == nil {
+		normals = make([]fragments.Vec3, len(mesh.Normals))
+		for i, n := range mesh.Normals {
+			normals[i] = fragments.Vec3{X: -n.X, Y: -n.Y, Z: -n.Z}
+		}
+	}
+
+	forEachPolygon(mesh, func(polygon datatypes.Polygon, material *fragments.Material) {
+		shader := fragments.ShaderTypeDiffuse
+		var texture *image.NRGBA
+		if material != nil {
+			shader = material.ShaderType
+			texture = s.getTexture(material)
+		}
+
+		if isHiddenShader(shader) {
+			return
+		}
+
+		t := triangle{texture: texture, shader: shader}
+		for i, index := range [3]int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3} {
+			if index < 0 || index >= len(positions) {
+				return
+			}
+			t.vertices[i] = newVertex(mesh, index, positions[index], normals)
+		}
+
+		s.triangles = append(s.triangles, t)
+	})
+}
+
+// newVertex collects the attributes of a mesh vertex. Missing normals face up and missing colors are white.
+func newVertex(mesh *fragments.Mesh, index int, position fragments.Vec3, normals []fragments.Vec3) vertex {
+	v := vertex{
+		position: vecmath.New(float64(position.X), float64(position.Y), float64(position.Z)),
+		normal:   vecmath.New(0, 0, 1),
+		color:    [3]float64{1, 1, 1},
+	}
+
+	if index < len(normals) {
+		n := normals[index]
+		v.normal = vecmath.New(float64(n.X), float64(n.Y), float64(n.Z))
+	}
+
+	if index < len(mesh.TextureUvCoordinates) {
+		uv := mesh.TextureUvCoordinates[index]
+		v.uv = [2]float64{float64(uv.X), -float64(uv.Y)}
+	}
+
+	if index < len(mesh.Colors) {
+		c := mesh.Colors[index]
+		v.color = [3]float64{float64(c.R) / 255, float64(c.G) / 255, float64(c.B) / 255}
+	}
+
+	return v
+}
+
+// getTexture returns the texture of a material's first frame, or nil if it has none or it was not loaded.
+func (s *Scene) getTexture(material *fragments.Material) *image.NRGBA {
+	bitmapInfoRef, ok := material.BitmapInfoReference.(*fragments.BitmapInfoReference)
+	if !ok || bitmapInfoRef == nil || bitmapInfoRef.BitmapInfo == nil || len(bitmapInfoRef.BitmapInfo.BitmapNames) == 0 {
+		return nil
+	}
+
+	name := strings.ToLower(bitmapInfoRef.BitmapInfo.BitmapNames[0].Filename)
+	if texture, exists := s.converted[name]; exists {
+		return texture
+	}
+
+	var texture *image.NRGBA
+	if img := s.textures[name]; img != nil {
+		texture = image.NewNRGBA(img.Bounds())
+		draw.Draw(texture, texture.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+
+	s.converted[name] = texture
+	return texture
+}
+
+// forEachPolygon calls fn for each polygon with the material of its render group.
+func forEachPolygon(mesh *fragments.Mesh, fn func(datatypes.Polygon, *fragments.Material)) {
+	polygonIndex := 0
+	for _, group := range mesh.MaterialGroups {
+		var material *fragments.Material
+		if mesh.MaterialList != nil && group.MaterialIndex >= 0 && group.MaterialIndex < len(mesh.MaterialList.Materials) {
+			material = mesh.MaterialList.Materials[group.MaterialIndex]
+		}
+
+		for i := 0; i < group.PolygonCount && polygonIndex < len(mesh.Indices); i++ {
+			fn(mesh.Indices[polygonIndex], material)
+			polygonIndex++
+		}
+	}
+
+	// Polygons not covered by a render group have no material
+	for ; polygonIndex < len(mesh.Indices); polygonIndex++ {
+		fn(mesh.Indices[polygonIndex], nil)
+	}
+}
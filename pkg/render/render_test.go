@@ -0,0 +1,58 @@
+package render
+
+import (
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// newTestQuad creates a square in the YZ plane facing the +X axis with a red and a blue half.
+func newTestQuad() *fragments.Mesh {
+	return &fragments.Mesh{
+		Vertices: []fragments.Vec3{{Y: -1, Z: -1}, {Y: 1, Z: -1}, {Y: 1, Z: 1}, {Y: -1, Z: 1}},
+		Normals:  []fragments.Vec3{{X: -1}, {X: -1}, {X: -1}, {X: -1}},
+		Colors: []datatypes.Color{
+			{R: 255, A: 255}, {B: 255, A: 255}, {B: 255, A: 255}, {R: 255, A: 255},
+		},
+		Indices: []datatypes.Polygon{
+			{Vertex1: 0, Vertex2: 1, Vertex3: 2},
+			{Vertex1: 0, Vertex2: 2, Vertex3: 3},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	scene := NewScene(nil)
+	scene.AddMesh(newTestQuad(), nil, nil)
+
+	options := DefaultOptions()
+	options.Width, options.Height = 64, 64
+	options.Camera = Camera{Padding: 0.1}
+
+	img := scene.Render(options)
+	if img == nil {
+		t.Fatal("Expected an image")
+	}
+
+	if corner := img.NRGBAAt(1, 1); corner.A != 0 {
+		t.Errorf("Expected a transparent corner, got %v", corner)
+	}
+
+	// The camera on +X sees +Y on the right
+	left, right := img.NRGBAAt(16, 32), img.NRGBAAt(48, 32)
+	if left.A != 255 || left.R <= left.B {
+		t.Errorf("Expected an opaque red left side, got %v", left)
+	}
+	if right.A != 255 || right.B <= right.R {
+		t.Errorf("Expected an opaque blue right side, got %v", right)
+	}
+
+	// The quad is fitted inside the padding
+	if edge := img.NRGBAAt(8, 32); edge.A == 0 {
+		t.Errorf("Expected the quad to reach the padding, got %v", edge)
+	}
+	if outside := img.NRGBAAt(5, 32); outside.A != 0 {
+		t.Errorf("Expected the padding to be empty, got %v", outside)
+	}
+}
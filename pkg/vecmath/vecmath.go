@@ -0,0 +1,82 @@
+// Package vecmath provides the double precision vector math and the scalar helpers shared by
+// the geometry packages.
+//
+// The vectors make no assumption about the coordinate system, callers convert from the
+// single precision WLD vectors at their boundaries.
+package vecmath
+
+import "math"
+
+// Vec3 is a double precision 3D vector.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+// New creates a vector from its components.
+func New(x, y, z float64) Vec3 {
+	return Vec3{x, y, z}
+}
+
+// Add returns a + b.
+func Add(a, b Vec3) Vec3 {
+	return Vec3{a.X + b.X, a.Y + b.Y, a.Z + b.Z}
+}
+
+// Sub returns a - b.
+func Sub(a, b Vec3) Vec3 {
+	return Vec3{a.X - b.X, a.Y - b.Y, a.Z - b.Z}
+}
+
+// Scale returns v * s.
+func Scale(v Vec3, s float64) Vec3 {
+	return Vec3{v.X * s, v.Y * s, v.Z * s}
+}
+
+// Dot returns the dot product of a and b.
+func Dot(a, b Vec3) float64 {
+	return a.X*b.X + a.Y*b.Y + a.Z*b.Z
+}
+
+// Cross returns the cross product of a and b.
+func Cross(a, b Vec3) Vec3 {
+	return Vec3{
+		a.Y*b.Z - a.Z*b.Y,
+		a.Z*b.X - a.X*b.Z,
+		a.X*b.Y - a.Y*b.X,
+	}
+}
+
+// Length returns the length of v.
+func Length(v Vec3) float64 {
+	return math.Sqrt(Dot(v, v))
+}
+
+// Normalize returns v scaled to unit length, or the zero vector if v has no length.
+func Normalize(v Vec3) Vec3 {
+	l := Length(v)
+	if l == 0 {
+		return Vec3{}
+	}
+	return Scale(v, 1/l)
+}
+
+// Min returns the component wise minimum of a and b.
+func Min(a, b Vec3) Vec3 {
+	return Vec3{math.Min(a.X, b.X), math.Min(a.Y, b.Y), math.Min(a.Z, b.Z)}
+}
+
+// Max returns the component wise maximum of a and b.
+func Max(a, b Vec3) Vec3 {
+	return Vec3{math.Max(a.X, b.X), math.Max(a.Y, b.Y), math.Max(a.Z, b.Z)}
+}
+
+// ClampInt clamps a value to the range [min, max].
+func ClampInt(value, min, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+	return value
+}
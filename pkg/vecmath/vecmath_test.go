@@ -0,0 +1,30 @@
+package vecmath
+
+import "testing"
+
+func TestVec3(t *testing.T) {
+	a := Vec3{1, 0, 0}
+	b := Vec3{0, 2, 0}
+
+	if got := Cross(a, b); got != (Vec3{0, 0, 2}) {
+		t.Errorf("Expected cross product (0, 0, 2), got %v", got)
+	}
+	if got := Dot(Add(a, b), Sub(a, b)); got != -3 {
+		t.Errorf("Expected dot product -3, got %v", got)
+	}
+	if got := Normalize(Vec3{0, 0, -2}); got != (Vec3{0, 0, -1}) {
+		t.Errorf("Expected unit vector (0, 0, -1), got %v", got)
+	}
+	if got := Normalize(Vec3{}); got != (Vec3{}) {
+		t.Errorf("Expected the zero vector to stay zero, got %v", got)
+	}
+	if Min(a, b) != (Vec3{0, 0, 0}) || Max(a, b) != (Vec3{1, 2, 0}) {
+		t.Errorf("Expected component wise bounds, got %v and %v", Min(a, b), Max(a, b))
+	}
+}
+
+func TestClampInt(t *testing.T) {
+	if ClampInt(-1, 0, 9) != 0 || ClampInt(10, 0, 9) != 9 || ClampInt(5, 0, 9) != 5 {
+		t.Error("Expected values to be clamped to [0, 9]")
+	}
+}
@@ -0,0 +1,124 @@
+package animation
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Clip is an animation with the keyframes of each bone track.
+type Clip struct {
+	// Name is the animation name, e.g. c01.
+	Name string
+
+	// DurationMs is the length of the clip in milliseconds.
+	DurationMs float64
+
+	// Tracks maps bone names to their keyframes sorted by time.
+	Tracks map[string][]Keyframe
+}
+
+// NewClip creates an empty clip.
+func NewClip(name string, durationMs float64) *Clip {
+	return &Clip{
+		Name:       name,
+		DurationMs: durationMs,
+		Tracks:     make(map[string][]Keyframe),
+	}
+}
+
+// AddTrack adds the keyframes of a bone. The clip is extended to cover the last key.
+func (c *Clip) AddTrack(boneName string, keys []Keyframe) {
+	c.Tracks[boneName] = keys
+	if len(keys) > 0 && keys[len(keys)-1].TimeMs > c.DurationMs {
+		c.DurationMs = keys[len(keys)-1].TimeMs
+	}
+}
+
+// BoneNames returns the sorted names of the bones with tracks.
+func (c *Clip) BoneNames() []string {
+	names := make([]string, 0, len(c.Tracks))
+	for name := range c.Tracks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Apply processes the keyframes of every track with the options.
+func (c *Clip) Apply(options Options) {
+	for name, keys := range c.Tracks {
+		c.Tracks[name] = options.Apply(keys)
+	}
+}
+
+// Trim returns the part of the clip between two times as a new clip starting at zero.
+// Keys are added at both ends by sampling so the pose at the cut is kept.
+func (c *Clip) Trim(startMs, endMs float64) *Clip {
+	startMs = clampTime(startMs, c.DurationMs)
+	endMs = clampTime(endMs, c.DurationMs)
+	if endMs < startMs {
+		startMs, endMs = endMs, startMs
+	}
+
+	trimmed := NewClip(c.Name, endMs-startMs)
+	for name, keys := range c.Tracks {
+		trimmed.Tracks[name] = trimKeys(keys, startMs, endMs)
+	}
+	return trimmed
+}
+
+// Split cuts the clip at the given times and returns the parts in order.
+// Parts are named after the clip with a two digit suffix, e.g. c01_00 and c01_01.
+func (c *Clip) Split(timesMs ...float64) []*Clip {
+	cuts := []float64{0}
+	for _, timeMs := range timesMs {
+		if timeMs > 0 && timeMs < c.DurationMs {
+			cuts = append(cuts, timeMs)
+		}
+	}
+	sort.Float64s(cuts)
+	cuts = append(cuts, c.DurationMs)
+
+	var parts []*Clip
+	for i := 0; i+1 < len(cuts); i++ {
+		if cuts[i+1]-cuts[i] <= 0 {
+			continue
+		}
+
+		part := c.Trim(cuts[i], cuts[i+1])
+		part.Name = fmt.Sprintf("%s_%02d", c.Name, len(parts))
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// trimKeys returns the keys between two times shifted to start at zero,
+// with sampled keys at both ends.
+func trimKeys(keys []Keyframe, startMs, endMs float64) []Keyframe {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	trimmed := []Keyframe{{TimeMs: 0, Transform: Sample(keys, startMs)}}
+	for _, key := range keys {
+		if key.TimeMs > startMs && key.TimeMs < endMs {
+			trimmed = append(trimmed, Keyframe{TimeMs: key.TimeMs - startMs, Transform: key.Transform})
+		}
+	}
+
+	if endMs > startMs {
+		trimmed = append(trimmed, Keyframe{TimeMs: endMs - startMs, Transform: Sample(keys, endMs)})
+	}
+	return trimmed
+}
+
+// clampTime clamps a time to the length of a clip.
+func clampTime(timeMs, durationMs float64) float64 {
+	if timeMs < 0 {
+		return 0
+	}
+	if timeMs > durationMs {
+		return durationMs
+	}
+	return timeMs
+}
@@ -0,0 +1,288 @@
+// Package animation resamples, reduces and cuts skeletal animation keyframes.
+// EQ tracks store a bone transform for every frame at a fixed frame time, which often
+// repeats the same transform many times. The functions here work on keyframes with explicit
+// times so writers can resample tracks to a target frame rate with quaternion slerp,
+// drop keys that interpolation reproduces within a tolerance and trim or split clips.
+package animation
+
+import (
+	"math"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// Keyframe is a bone transform at a point in time.
+type Keyframe struct {
+	// TimeMs is the time of the key in milliseconds from the start of the clip.
+	TimeMs float64
+
+	// Transform is the bone transform relative to its parent.
+	Transform datatypes.BoneTransform
+}
+
+// Tolerance is the largest difference between a key and the interpolation of its neighbours
+// for the key to be removed.
+type Tolerance struct {
+	// Translation is the largest distance in model units.
+	Translation float64
+
+	// Rotation is the largest angle in radians.
+	Rotation float64
+
+	// Scale is the largest scale difference.
+	Scale float64
+}
+
+// DefaultTolerance returns a tolerance below what is visible on EQ models.
+func DefaultTolerance() Tolerance {
+	return Tolerance{
+		Translation: 0.001,
+		Rotation:    0.0005,
+		Scale:       0.001,
+	}
+}
+
+// Options selects the processing applied to the keyframes of every bone.
+type Options struct {
+	// SampleRate resamples tracks to this many frames per second. Zero keeps the source keys.
+	SampleRate int
+
+	// Reduce removes keys that interpolation reproduces within Tolerance.
+	Reduce bool
+
+	// Tolerance is the tolerance used by Reduce.
+	Tolerance Tolerance
+
+	// ExtractRootMotion moves the horizontal translation and yaw of the root bone into
+	// a separate root motion curve. Writers apply it to the root bone before resampling.
+	ExtractRootMotion bool
+}
+
+// IsEnabled reports whether the options change keyframes at all.
+func (o Options) IsEnabled() bool {
+	return o.SampleRate > 0 || o.Reduce
+}
+
+// Apply resamples and then reduces keyframes as selected by the options.
+// Rotations are normalized and missing scales set to one first.
+func (o Options) Apply(keys []Keyframe) []Keyframe {
+	normalized := make([]Keyframe, len(keys))
+	for i, key := range keys {
+		normalized[i] = Keyframe{TimeMs: key.TimeMs, Transform: normalizeTransform(key.Transform)}
+	}
+	keys = normalized
+
+	if o.SampleRate > 0 {
+		keys = Resample(keys, o.SampleRate)
+	}
+	if o.Reduce {
+		keys = Reduce(keys, o.Tolerance)
+	}
+	return keys
+}
+
+// FromFrames creates keyframes from track frames spaced by a fixed frame time.
+func FromFrames(frames []datatypes.BoneTransform, frameTimeMs int) []Keyframe {
+	keys := make([]Keyframe, len(frames))
+	for i, frame := range frames {
+		keys[i] = Keyframe{
+			TimeMs:    float64(i * frameTimeMs),
+			Transform: frame,
+		}
+	}
+	return keys
+}
+
+// Sample returns the transform at a time, interpolating between the surrounding keys.
+// Times outside the keys hold the first or last key.
+func Sample(keys []Keyframe, timeMs float64) datatypes.BoneTransform {
+	if len(keys) == 0 {
+		return datatypes.BoneTransform{Rotation: datatypes.Quat{W: 1}, Scale: 1}
+	}
+
+	if timeMs <= keys[0].TimeMs {
+		return keys[0].Transform
+	}
+
+	last := keys[len(keys)-1]
+	if timeMs >= last.TimeMs {
+		return last.Transform
+	}
+
+	// Keys are sorted by time, so find the first key after the time
+	next := 1
+	for next < len(keys)-1 && keys[next].TimeMs <= timeMs {
+		next++
+	}
+
+	previous := keys[next-1]
+	span := keys[next].TimeMs - previous.TimeMs
+	if span <= 0 {
+		return keys[next].Transform
+	}
+
+	return Interpolate(previous.Transform, keys[next].Transform, (timeMs-previous.TimeMs)/span)
+}
+
+// Interpolate blends two transforms. Translation and scale are interpolated linearly
+// and rotation along the shortest arc.
+func Interpolate(a, b datatypes.BoneTransform, t float64) datatypes.BoneTransform {
+	return datatypes.BoneTransform{
+		Translation: datatypes.Vec3{
+			X: lerp(a.Translation.X, b.Translation.X, t),
+			Y: lerp(a.Translation.Y, b.Translation.Y, t),
+			Z: lerp(a.Translation.Z, b.Translation.Z, t),
+		},
+		Rotation: Slerp(a.Rotation, b.Rotation, t),
+		Scale:    lerp(a.Scale, b.Scale, t),
+	}
+}
+
+// Slerp spherically interpolates between two rotations along the shortest arc.
+func Slerp(a, b datatypes.Quat, t float64) datatypes.Quat {
+	ax, ay, az, aw := float64(a.X), float64(a.Y), float64(a.Z), float64(a.W)
+	bx, by, bz, bw := float64(b.X), float64(b.Y), float64(b.Z), float64(b.W)
+
+	dot := ax*bx + ay*by + az*bz + aw*bw
+	if dot < 0 {
+		bx, by, bz, bw = -bx, -by, -bz, -bw
+		dot = -dot
+	}
+
+	// Nearly identical rotations are interpolated linearly to avoid dividing by zero
+	wa, wb := 1-t, t
+	if dot < 0.9995 {
+		theta := math.Acos(dot)
+		sinTheta := math.Sin(theta)
+		wa = math.Sin((1-t)*theta) / sinTheta
+		wb = math.Sin(t*theta) / sinTheta
+	}
+
+	return normalizeQuat(datatypes.Quat{
+		X: float32(wa*ax + wb*bx),
+		Y: float32(wa*ay + wb*by),
+		Z: float32(wa*az + wb*bz),
+		W: float32(wa*aw + wb*bw),
+	})
+}
+
+// Resample returns keys at a fixed rate from the first to the last key.
+// The last key is always kept so the clip keeps its length.
+func Resample(keys []Keyframe, sampleRate int) []Keyframe {
+	if len(keys) < 2 || sampleRate <= 0 {
+		return keys
+	}
+
+	start := keys[0].TimeMs
+	end := keys[len(keys)-1].TimeMs
+	step := 1000 / float64(sampleRate)
+
+	count := int(math.Floor((end-start)/step+1e-6)) + 1
+	resampled := make([]Keyframe, 0, count+1)
+	for i := 0; i < count; i++ {
+		timeMs := start + float64(i)*step
+		resampled = append(resampled, Keyframe{TimeMs: timeMs, Transform: Sample(keys, timeMs)})
+	}
+
+	if end-resampled[len(resampled)-1].TimeMs > 1e-6 {
+		resampled = append(resampled, Keyframe{TimeMs: end, Transform: keys[len(keys)-1].Transform})
+	}
+
+	return resampled
+}
+
+// Reduce removes keys that interpolating between the kept keys reproduces within the tolerance.
+// The first and last keys are kept, unless every key is the same and a single key remains.
+func Reduce(keys []Keyframe, tolerance Tolerance) []Keyframe {
+	if len(keys) < 2 {
+		return keys
+	}
+
+	if isConstant(keys, tolerance) {
+		return keys[:1]
+	}
+
+	reduced := []Keyframe{keys[0]}
+	anchor := 0
+
+	for candidate := 1; candidate < len(keys)-1; candidate++ {
+		// Keep the candidate if skipping it would move any key since the anchor out of tolerance
+		next := keys[candidate+1]
+		for skipped := anchor + 1; skipped <= candidate; skipped++ {
+			span := next.TimeMs - keys[anchor].TimeMs
+			t := 0.0
+			if span > 0 {
+				t = (keys[skipped].TimeMs - keys[anchor].TimeMs) / span
+			}
+
+			interpolated := Interpolate(keys[anchor].Transform, next.Transform, t)
+			if !withinTolerance(interpolated, keys[skipped].Transform, tolerance) {
+				reduced = append(reduced, keys[candidate])
+				anchor = candidate
+				break
+			}
+		}
+	}
+
+	return append(reduced, keys[len(keys)-1])
+}
+
+// isConstant reports whether every key is within the tolerance of the first key.
+func isConstant(keys []Keyframe, tolerance Tolerance) bool {
+	for _, key := range keys[1:] {
+		if !withinTolerance(keys[0].Transform, key.Transform, tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// withinTolerance reports whether two transforms differ by less than the tolerance.
+func withinTolerance(a, b datatypes.BoneTransform, tolerance Tolerance) bool {
+	dx := float64(a.Translation.X - b.Translation.X)
+	dy := float64(a.Translation.Y - b.Translation.Y)
+	dz := float64(a.Translation.Z - b.Translation.Z)
+	if math.Sqrt(dx*dx+dy*dy+dz*dz) > tolerance.Translation {
+		return false
+	}
+
+	if math.Abs(float64(a.Scale-b.Scale)) > tolerance.Scale {
+		return false
+	}
+
+	return rotationAngle(a.Rotation, b.Rotation) <= tolerance.Rotation
+}
+
+// rotationAngle returns the angle in radians between two unit rotations.
+func rotationAngle(a, b datatypes.Quat) float64 {
+	dot := math.Abs(float64(a.X*b.X + a.Y*b.Y + a.Z*b.Z + a.W*b.W))
+	return 2 * math.Acos(math.Min(1, dot))
+}
+
+// normalizeTransform returns a transform with a unit rotation and a scale of one when it has none.
+func normalizeTransform(transform datatypes.BoneTransform) datatypes.BoneTransform {
+	transform.Rotation = normalizeQuat(transform.Rotation)
+	if transform.Scale == 0 {
+		transform.Scale = 1
+	}
+	return transform
+}
+
+// normalizeQuat returns a unit quaternion, or the identity for a zero quaternion.
+func normalizeQuat(q datatypes.Quat) datatypes.Quat {
+	length := math.Sqrt(float64(q.X*q.X + q.Y*q.Y + q.Z*q.Z + q.W*q.W))
+	if length == 0 {
+		return datatypes.Quat{W: 1}
+	}
+	return datatypes.Quat{
+		X: float32(float64(q.X) / length),
+		Y: float32(float64(q.Y) / length),
+		Z: float32(float64(q.Z) / length),
+		W: float32(float64(q.W) / length),
+	}
+}
+
+// lerp linearly interpolates between two values.
+func lerp(a, b float32, t float64) float32 {
+	return float32(float64(a) + (float64(b)-float64(a))*t)
+}
@@ -0,0 +1,152 @@
+package animation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// newTestKeys creates keys moving along X by one unit per frame and holding still for the second half.
+func newTestKeys() []Keyframe {
+	frames := make([]datatypes.BoneTransform, 10)
+	for i := range frames {
+		x := float32(i)
+		if i >= 5 {
+			x = 5
+		}
+		frames[i] = datatypes.BoneTransform{
+			Translation: datatypes.Vec3{X: x},
+			Rotation:    datatypes.Quat{W: 1},
+			Scale:       1,
+		}
+	}
+	return FromFrames(frames, 100)
+}
+
+func TestSlerp(t *testing.T) {
+	halfAngle := float32(math.Sqrt2 / 2)
+	rotated := datatypes.Quat{Z: halfAngle, W: halfAngle}
+
+	// Halfway to a 90 degree rotation is a 45 degree rotation
+	result := Slerp(datatypes.Quat{W: 1}, rotated, 0.5)
+	expected := float32(math.Sin(math.Pi / 8))
+	if math.Abs(float64(result.Z-expected)) > 1e-5 {
+		t.Errorf("Expected Z %f, got %f", expected, result.Z)
+	}
+
+	// The opposite sign of the same rotation takes the short way around
+	result = Slerp(datatypes.Quat{W: 1}, datatypes.Quat{W: -1}, 0.5)
+	if math.Abs(float64(result.W)) < 0.9999 {
+		t.Errorf("Expected identity, got %v", result)
+	}
+}
+
+func TestResample(t *testing.T) {
+	keys := Resample(newTestKeys(), 20)
+	if len(keys) != 19 {
+		t.Fatalf("Expected 19 keys, got %d", len(keys))
+	}
+
+	if keys[1].TimeMs != 50 || math.Abs(float64(keys[1].Transform.Translation.X-0.5)) > 1e-5 {
+		t.Errorf("Expected X 0.5 at 50ms, got %f at %f", keys[1].Transform.Translation.X, keys[1].TimeMs)
+	}
+
+	if keys[len(keys)-1].TimeMs != 900 {
+		t.Errorf("Expected the last key at 900ms, got %f", keys[len(keys)-1].TimeMs)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	keys := Reduce(newTestKeys(), DefaultTolerance())
+
+	// The linear movement and the hold each need only their end keys
+	expectedTimes := []float64{0, 500, 900}
+	if len(keys) != len(expectedTimes) {
+		t.Fatalf("Expected %d keys, got %d", len(expectedTimes), len(keys))
+	}
+	for i, key := range keys {
+		if key.TimeMs != expectedTimes[i] {
+			t.Errorf("Expected key %d at %f, got %f", i, expectedTimes[i], key.TimeMs)
+		}
+	}
+
+	// Frames without rotation or scale are normalized to the identity before reducing
+	options := Options{Reduce: true, Tolerance: DefaultTolerance()}
+	constant := options.Apply(FromFrames(make([]datatypes.BoneTransform, 5), 100))
+	if len(constant) != 1 {
+		t.Errorf("Expected a constant track to reduce to 1 key, got %d", len(constant))
+	}
+}
+
+func TestClipSplit(t *testing.T) {
+	clip := NewClip("c01", 0)
+	clip.AddTrack("root", newTestKeys())
+
+	parts := clip.Split(250)
+	if len(parts) != 2 || parts[0].Name != "c01_00" || parts[1].Name != "c01_01" {
+		t.Fatalf("Expected c01_00 and c01_01, got %d parts", len(parts))
+	}
+
+	second := parts[1].Tracks["root"]
+	if parts[1].DurationMs != 650 || second[0].TimeMs != 0 {
+		t.Errorf("Expected the second part to last 650ms from 0, got %f", parts[1].DurationMs)
+	}
+	if math.Abs(float64(second[0].Transform.Translation.X-2.5)) > 1e-5 {
+		t.Errorf("Expected the cut to start at X 2.5, got %f", second[0].Transform.Translation.X)
+	}
+}
+
+func TestExtractRootMotion(t *testing.T) {
+	frames := make([]datatypes.BoneTransform, 3)
+	for i := range frames {
+		yaw := float64(i) * math.Pi / 4
+		frames[i] = datatypes.BoneTransform{
+			Translation: datatypes.Vec3{X: float32(i), Y: 1, Z: float32(i) * 0.5},
+			Rotation:    yawQuat(yaw),
+			Scale:       1,
+		}
+	}
+
+	keys, motion := ExtractRootMotion(FromFrames(frames, 100))
+	if !motion.HasMotion() {
+		t.Fatal("Expected root motion")
+	}
+
+	last := motion.Keys[2]
+	if last.Translation.X != 2 || last.Translation.Y != 0 || math.Abs(last.Yaw-math.Pi/2) > 1e-5 {
+		t.Errorf("Expected a movement of 2 and a turn of 90 degrees, got %v", last)
+	}
+
+	// The clip plays in place but keeps its vertical movement
+	inPlace := keys[2].Transform
+	if inPlace.Translation.X != 0 || inPlace.Translation.Y != 1 || inPlace.Translation.Z != 1 {
+		t.Errorf("Expected the root at (0, 1, 1), got %v", inPlace.Translation)
+	}
+	if rotationAngle(inPlace.Rotation, datatypes.Quat{W: 1}) > 1e-4 {
+		t.Errorf("Expected the turn to be removed, got %v", inPlace.Rotation)
+	}
+}
+
+func TestAddClosingKey(t *testing.T) {
+	motion := &RootMotion{Keys: []RootMotionKey{
+		{TimeMs: 0},
+		{TimeMs: 100, Translation: datatypes.Vec3{X: 1}, Yaw: 0.1},
+		{TimeMs: 200, Translation: datatypes.Vec3{X: 2}, Yaw: 0.2},
+	}}
+
+	motion.AddClosingKey(200)
+	if len(motion.Keys) != 3 {
+		t.Fatalf("Expected no key at the time of the last key, got %d keys", len(motion.Keys))
+	}
+
+	// The closing key continues the last step
+	motion.AddClosingKey(300)
+	closing := motion.Keys[len(motion.Keys)-1]
+	if closing.TimeMs != 300 || closing.Translation.X != 3 || math.Abs(closing.Yaw-0.3) > 1e-9 {
+		t.Errorf("Expected a closing key at 300ms moved by 3 and turned by 0.3, got %v", closing)
+	}
+
+	var none *RootMotion
+	none.AddClosingKey(100)
+}
@@ -0,0 +1,138 @@
+package animation
+
+import (
+	"math"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// rootMotionEpsilon is the smallest movement or turn treated as root motion.
+const rootMotionEpsilon = 1e-4
+
+// RootMotionKey is the movement of a model at a point in time relative to the first frame.
+type RootMotionKey struct {
+	// TimeMs is the time of the key in milliseconds from the start of the clip.
+	TimeMs float64
+
+	// Translation is the horizontal movement in EQ model space. Z is always zero.
+	Translation datatypes.Vec3
+
+	// Yaw is the turn around the up axis in radians.
+	Yaw float64
+}
+
+// RootMotion is the movement of a model extracted from the root bone of a clip.
+type RootMotion struct {
+	Keys []RootMotionKey
+}
+
+// HasMotion reports whether the model moves or turns at all.
+func (m *RootMotion) HasMotion() bool {
+	if m == nil {
+		return false
+	}
+
+	for _, key := range m.Keys {
+		if math.Abs(float64(key.Translation.X)) > rootMotionEpsilon ||
+			math.Abs(float64(key.Translation.Y)) > rootMotionEpsilon ||
+			math.Abs(key.Yaw) > rootMotionEpsilon {
+			return true
+		}
+	}
+	return false
+}
+
+// AddClosingKey adds a key at the end of a looped clip, where playback wraps to the first frame.
+// The movement and turn continue at the rate of the last frame step so the next cycle starts
+// from where the model has moved to. Does nothing if the time is not after the last key.
+func (m *RootMotion) AddClosingKey(timeMs float64) {
+	if m == nil || len(m.Keys) == 0 {
+		return
+	}
+
+	last := m.Keys[len(m.Keys)-1]
+	if timeMs <= last.TimeMs {
+		return
+	}
+
+	closing := RootMotionKey{TimeMs: timeMs, Translation: last.Translation, Yaw: last.Yaw}
+	if len(m.Keys) > 1 {
+		previous := m.Keys[len(m.Keys)-2]
+		if step := last.TimeMs - previous.TimeMs; step > 0 {
+			scale := (timeMs - last.TimeMs) / step
+			closing.Translation.X += float32(float64(last.Translation.X-previous.Translation.X) * scale)
+			closing.Translation.Y += float32(float64(last.Translation.Y-previous.Translation.Y) * scale)
+			closing.Yaw += (last.Yaw - previous.Yaw) * scale
+		}
+	}
+
+	m.Keys = append(m.Keys, closing)
+}
+
+// ExtractRootMotion splits the horizontal translation and yaw of the root bone keys from
+// the rest of the animation. The returned keys play in place: the root holds the horizontal
+// position and heading of the first frame while the root motion carries the movement from there.
+// Vertical movement such as the bob of a walk cycle stays in the keys.
+func ExtractRootMotion(keys []Keyframe) ([]Keyframe, *RootMotion) {
+	motion := &RootMotion{Keys: make([]RootMotionKey, len(keys))}
+	if len(keys) == 0 {
+		return keys, motion
+	}
+
+	first := normalizeTransform(keys[0].Transform)
+	firstYaw := yawOf(first.Rotation)
+	previousYaw := firstYaw
+
+	inPlace := make([]Keyframe, len(keys))
+	for i, key := range keys {
+		transform := normalizeTransform(key.Transform)
+
+		// Unwrap the heading so turns past half a circle keep counting
+		yaw := yawOf(transform.Rotation)
+		for yaw-previousYaw > math.Pi {
+			yaw -= 2 * math.Pi
+		}
+		for yaw-previousYaw < -math.Pi {
+			yaw += 2 * math.Pi
+		}
+		previousYaw = yaw
+		deltaYaw := yaw - firstYaw
+
+		motion.Keys[i] = RootMotionKey{
+			TimeMs: key.TimeMs,
+			Translation: datatypes.Vec3{
+				X: transform.Translation.X - first.Translation.X,
+				Y: transform.Translation.Y - first.Translation.Y,
+			},
+			Yaw: deltaYaw,
+		}
+
+		transform.Translation.X = first.Translation.X
+		transform.Translation.Y = first.Translation.Y
+		transform.Rotation = normalizeQuat(quatMultiply(yawQuat(-deltaYaw), transform.Rotation))
+		inPlace[i] = Keyframe{TimeMs: key.TimeMs, Transform: transform}
+	}
+
+	return inPlace, motion
+}
+
+// yawOf returns the rotation of a unit quaternion around the up (Z) axis in radians.
+// This is the twist of a swing-twist decomposition around Z.
+func yawOf(q datatypes.Quat) float64 {
+	return 2 * math.Atan2(float64(q.Z), float64(q.W))
+}
+
+// yawQuat returns the rotation around the up (Z) axis by an angle in radians.
+func yawQuat(yaw float64) datatypes.Quat {
+	return datatypes.Quat{Z: float32(math.Sin(yaw / 2)), W: float32(math.Cos(yaw / 2))}
+}
+
+// quatMultiply returns the rotation b followed by the rotation a.
+func quatMultiply(a, b datatypes.Quat) datatypes.Quat {
+	return datatypes.Quat{
+		X: a.W*b.X + a.X*b.W + a.Y*b.Z - a.Z*b.Y,
+		Y: a.W*b.Y - a.X*b.Z + a.Y*b.W + a.Z*b.X,
+		Z: a.W*b.Z + a.X*b.Y - a.Y*b.X + a.Z*b.W,
+		W: a.W*b.W - a.X*b.X - a.Y*b.Y - a.Z*b.Z,
+	}
+}
@@ -0,0 +1,209 @@
+package bsp
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+)
+
+// planeEpsilon is the distance within which a point is considered to lie on a plane.
+const planeEpsilon = 1e-3
+
+// face is a convex polygon of a cell.
+type face []vecmath.Vec3
+
+// cell is a convex polyhedron described by its faces.
+type cell []face
+
+// newBoxCell creates a cell covering the bounds.
+func newBoxCell(bounds Bounds) cell {
+	min := vecmath.New(float64(bounds.Min.X), float64(bounds.Min.Y), float64(bounds.Min.Z))
+	max := vecmath.New(float64(bounds.Max.X), float64(bounds.Max.Y), float64(bounds.Max.Z))
+
+	corner := func(i int) vecmath.Vec3 {
+		c := min
+		if i&1 != 0 {
+			c.X = max.X
+		}
+		if i&2 != 0 {
+			c.Y = max.Y
+		}
+		if i&4 != 0 {
+			c.Z = max.Z
+		}
+		return c
+	}
+
+	sides := [6][4]int{
+		{0, 2, 6, 4}, // -X
+		{1, 5, 7, 3}, // +X
+		{0, 4, 5, 1}, // -Y
+		{2, 3, 7, 6}, // +Y
+		{0, 1, 3, 2}, // -Z
+		{4, 6, 7, 5}, // +Z
+	}
+
+	box := make(cell, 0, len(sides))
+	for _, side := range sides {
+		f := make(face, 0, 4)
+		for _, i := range side {
+			f = append(f, corner(i))
+		}
+		box = append(box, f)
+	}
+	return box
+}
+
+// clip keeps the part of the cell where normal·p + distance has the given sign.
+// The opening left by the plane is closed with a new face.
+func (c cell) clip(normal vecmath.Vec3, distance float64, keepPositive bool) cell {
+	sign := 1.0
+	if !keepPositive {
+		sign = -1.0
+	}
+
+	signedDistance := func(p vecmath.Vec3) float64 {
+		return sign * (vecmath.Dot(normal, p) + distance)
+	}
+
+	clipped := make(cell, 0, len(c)+1)
+	var capPoints []vecmath.Vec3
+	faceOnPlane := false
+
+	for _, f := range c {
+		var result face
+		onPlaneCount := 0
+		for i, current := range f {
+			next := f[(i+1)%len(f)]
+			dc := signedDistance(current)
+			dn := signedDistance(next)
+
+			if dc >= -planeEpsilon {
+				result = append(result, current)
+				if dc <= planeEpsilon {
+					capPoints = append(capPoints, current)
+					onPlaneCount++
+				}
+			}
+
+			if (dc > planeEpsilon && dn < -planeEpsilon) || (dc < -planeEpsilon && dn > planeEpsilon) {
+				t := dc / (dc - dn)
+				split := vecmath.Add(current, vecmath.Scale(vecmath.Sub(next, current), t))
+				result = append(result, split)
+				capPoints = append(capPoints, split)
+			}
+		}
+
+		if onPlaneCount == len(f) {
+			faceOnPlane = true
+		}
+
+		if len(result) >= 3 {
+			clipped = append(clipped, result)
+		}
+	}
+
+	// A face already lying on the plane closes the cell
+	if faceOnPlane {
+		return clipped
+	}
+
+	if capFace := buildCapFace(capPoints, normal); len(capFace) >= 3 {
+		clipped = append(clipped, capFace)
+	}
+
+	return clipped
+}
+
+// buildCapFace orders the unique points on a clipping plane into a convex polygon.
+func buildCapFace(points []vecmath.Vec3, normal vecmath.Vec3) face {
+	unique := make(face, 0, len(points))
+	for _, p := range points {
+		duplicate := false
+		for _, u := range unique {
+			if vecmath.Length(vecmath.Sub(p, u)) <= planeEpsilon {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			unique = append(unique, p)
+		}
+	}
+
+	if len(unique) < 3 {
+		return nil
+	}
+
+	var center vecmath.Vec3
+	for _, p := range unique {
+		center = vecmath.Add(center, p)
+	}
+	center = vecmath.Scale(center, 1/float64(len(unique)))
+
+	u, v := planeBasis(normal)
+	sort.Slice(unique, func(i, j int) bool {
+		a := vecmath.Sub(unique[i], center)
+		b := vecmath.Sub(unique[j], center)
+		return math.Atan2(vecmath.Dot(a, v), vecmath.Dot(a, u)) < math.Atan2(vecmath.Dot(b, v), vecmath.Dot(b, u))
+	})
+
+	// Points along a single edge do not form a face
+	if vecmath.Length(faceNormal(unique)) <= planeEpsilon {
+		return nil
+	}
+
+	return unique
+}
+
+// orient reverses any face whose winding does not point away from the cell center.
+func (c cell) orient() {
+	center := c.center()
+	for _, f := range c {
+		if vecmath.Dot(faceNormal(f), vecmath.Sub(f[0], center)) < 0 {
+			for i, j := 0, len(f)-1; i < j; i, j = i+1, j-1 {
+				f[i], f[j] = f[j], f[i]
+			}
+		}
+	}
+}
+
+// center returns the average of all face points.
+func (c cell) center() vecmath.Vec3 {
+	var sum vecmath.Vec3
+	count := 0
+	for _, f := range c {
+		for _, p := range f {
+			sum = vecmath.Add(sum, p)
+			count++
+		}
+	}
+	if count == 0 {
+		return sum
+	}
+	return vecmath.Scale(sum, 1/float64(count))
+}
+
+// faceNormal returns the unnormalized normal of a polygon using Newell's method.
+func faceNormal(f face) vecmath.Vec3 {
+	var n vecmath.Vec3
+	for i, current := range f {
+		next := f[(i+1)%len(f)]
+		n.X += (current.Y - next.Y) * (current.Z + next.Z)
+		n.Y += (current.Z - next.Z) * (current.X + next.X)
+		n.Z += (current.X - next.X) * (current.Y + next.Y)
+	}
+	return n
+}
+
+// planeBasis returns two unit vectors spanning the plane with the given normal.
+func planeBasis(normal vecmath.Vec3) (vecmath.Vec3, vecmath.Vec3) {
+	reference := vecmath.New(0, 0, 1)
+	if math.Abs(normal.Z) > 0.9 {
+		reference = vecmath.New(1, 0, 0)
+	}
+	u := vecmath.Normalize(vecmath.Cross(reference, normal))
+	v := vecmath.Normalize(vecmath.Cross(normal, u))
+	return u, v
+}
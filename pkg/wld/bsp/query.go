@@ -0,0 +1,46 @@
+package bsp
+
+import (
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// RegionAt returns the leaf region containing the point, or nil if there is none.
+func RegionAt(tree *fragments.BspTree, point datatypes.Vec3) *fragments.BspRegion {
+	if tree == nil {
+		return nil
+	}
+
+	nodeIdx := 0
+	for steps := 0; steps <= len(tree.Nodes); steps++ {
+		if nodeIdx < 0 || nodeIdx >= len(tree.Nodes) {
+			return nil
+		}
+
+		node := tree.Nodes[nodeIdx]
+		if node.LeftNode < 0 && node.RightNode < 0 {
+			region, _ := node.Region.(*fragments.BspRegion)
+			return region
+		}
+
+		// Positive distances are on the left side of the plane
+		distance := node.NormalX*point.X + node.NormalY*point.Y + node.NormalZ*point.Z + node.SplitDistance
+		if distance >= 0 {
+			nodeIdx = node.LeftNode
+		} else {
+			nodeIdx = node.RightNode
+		}
+	}
+
+	return nil
+}
+
+// RegionTypesAt returns the region types of the leaf containing the point.
+// Points outside any special region return nil.
+func RegionTypesAt(tree *fragments.BspTree, point datatypes.Vec3) []datatypes.RegionType {
+	region := RegionAt(tree, point)
+	if region == nil || region.RegionType == nil {
+		return nil
+	}
+	return region.RegionType.RegionTypes
+}
@@ -0,0 +1,291 @@
+// Package bsp provides geometry built from the zone BSP tree.
+package bsp
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// boundsPadding extends the zone bounds so cells at the zone edges are not cut flush with the geometry.
+const boundsPadding = 10
+
+// weldPrecision is the grid size used to merge vertices and match shared faces.
+const weldPrecision = 0.01
+
+// Bounds is an axis aligned bounding box.
+type Bounds struct {
+	Min datatypes.Vec3
+	Max datatypes.Vec3
+}
+
+// RegionVolume is the merged volume of all BSP leaf regions sharing a region type.
+type RegionVolume struct {
+	// Name is a unique name describing the region types, e.g. "water_0" or "zoneline_2".
+	Name string
+
+	// RegionTypes are the region types of the volume.
+	RegionTypes []datatypes.RegionType
+
+	// Zoneline is the zoneline destination if the volume is a zoneline.
+	Zoneline *datatypes.ZonelineInfo
+
+	// RegionString is the raw region type string the volume was decoded from.
+	RegionString string
+
+	// RegionCount is the number of leaf regions merged into the volume.
+	RegionCount int
+
+	// Vertices are the volume vertices in EQ world space.
+	Vertices []datatypes.Vec3
+
+	// Triangles index into Vertices. Faces point out of the volume.
+	Triangles [][3]int
+}
+
+// HasRegionType returns true if the volume has the given region type.
+func (v *RegionVolume) HasRegionType(regionType datatypes.RegionType) bool {
+	for _, t := range v.RegionTypes {
+		if t == regionType {
+			return true
+		}
+	}
+	return false
+}
+
+// MeshBounds returns the bounds of the given meshes in world space, padded slightly.
+func MeshBounds(meshes []*fragments.Mesh) Bounds {
+	min := datatypes.Vec3{X: math.MaxFloat32, Y: math.MaxFloat32, Z: math.MaxFloat32}
+	max := datatypes.Vec3{X: -math.MaxFloat32, Y: -math.MaxFloat32, Z: -math.MaxFloat32}
+	found := false
+
+	for _, mesh := range meshes {
+		if mesh == nil {
+			continue
+		}
+		for _, v := range mesh.Vertices {
+			p := datatypes.Vec3{X: v.X + mesh.Center.X, Y: v.Y + mesh.Center.Y, Z: v.Z + mesh.Center.Z}
+			min = datatypes.Vec3{X: minFloat(min.X, p.X), Y: minFloat(min.Y, p.Y), Z: minFloat(min.Z, p.Z)}
+			max = datatypes.Vec3{X: maxFloat(max.X, p.X), Y: maxFloat(max.Y, p.Y), Z: maxFloat(max.Z, p.Z)}
+			found = true
+		}
+	}
+
+	if !found {
+		return Bounds{}
+	}
+
+	return Bounds{
+		Min: datatypes.Vec3{X: min.X - boundsPadding, Y: min.Y - boundsPadding, Z: min.Z - boundsPadding},
+		Max: datatypes.Vec3{X: max.X + boundsPadding, Y: max.Y + boundsPadding, Z: max.Z + boundsPadding},
+	}
+}
+
+// regionGroup collects the leaf cells of one volume.
+type regionGroup struct {
+	regionType  *fragments.BspRegionType
+	cells       []cell
+	regionCount int
+}
+
+// BuildRegionVolumes builds one volume per distinct region type from the BSP leaves.
+// Each leaf cell is the bounds clipped by the split planes on the path to the leaf.
+// The region vertex lists are not used as they are empty in the known client files.
+// Faces shared by two cells of the same volume are removed, other internal faces are kept.
+func BuildRegionVolumes(tree *fragments.BspTree, bounds Bounds) []*RegionVolume {
+	if tree == nil || len(tree.Nodes) == 0 {
+		return nil
+	}
+
+	groups := make(map[string]*regionGroup)
+	var keys []string
+
+	var walk func(nodeIdx int, current cell, depth int)
+	walk = func(nodeIdx int, current cell, depth int) {
+		if nodeIdx < 0 || nodeIdx >= len(tree.Nodes) || len(current) < 4 || depth > len(tree.Nodes) {
+			return
+		}
+
+		node := tree.Nodes[nodeIdx]
+		if node.LeftNode < 0 && node.RightNode < 0 {
+			region, ok := node.Region.(*fragments.BspRegion)
+			if !ok || region == nil || !isSpecialRegion(region.RegionType) {
+				return
+			}
+
+			key := regionGroupKey(region.RegionType)
+			group, exists := groups[key]
+			if !exists {
+				group = &regionGroup{regionType: region.RegionType}
+				groups[key] = group
+				keys = append(keys, key)
+			}
+			group.cells = append(group.cells, current)
+			group.regionCount++
+			return
+		}
+
+		normal := vecmath.New(float64(node.NormalX), float64(node.NormalY), float64(node.NormalZ))
+		normalLength := vecmath.Length(normal)
+		if normalLength == 0 {
+			return
+		}
+		normal = vecmath.Scale(normal, 1/normalLength)
+		distance := float64(node.SplitDistance) / normalLength
+
+		// Positive distances are on the left side of the plane
+		walk(node.LeftNode, current.clip(normal, distance, true), depth+1)
+		walk(node.RightNode, current.clip(normal, distance, false), depth+1)
+	}
+
+	walk(0, newBoxCell(bounds), 0)
+
+	sort.Strings(keys)
+	nameCounts := make(map[string]int)
+	volumes := make([]*RegionVolume, 0, len(keys))
+
+	for _, key := range keys {
+		group := groups[key]
+		volume := buildVolume(group.cells)
+		if len(volume.Triangles) == 0 {
+			continue
+		}
+
+		baseName := regionTypeName(group.regionType)
+		volume.Name = fmt.Sprintf("%s_%d", baseName, nameCounts[baseName])
+		nameCounts[baseName]++
+
+		volume.RegionTypes = group.regionType.RegionTypes
+		volume.Zoneline = group.regionType.Zoneline
+		volume.RegionString = group.regionType.RegionString
+		volume.RegionCount = group.regionCount
+		volumes = append(volumes, volume)
+	}
+
+	return volumes
+}
+
+// isSpecialRegion returns true if the region type is anything other than normal.
+func isSpecialRegion(regionType *fragments.BspRegionType) bool {
+	if regionType == nil {
+		return false
+	}
+	for _, t := range regionType.RegionTypes {
+		if t != datatypes.RegionTypeNormal {
+			return true
+		}
+	}
+	return false
+}
+
+// regionGroupKey returns the key that groups leaves into one volume.
+// Zonelines are kept apart per destination, other regions are grouped by type.
+func regionGroupKey(regionType *fragments.BspRegionType) string {
+	if regionType.Zoneline != nil {
+		return "zoneline:" + regionType.RegionString
+	}
+	return regionTypeName(regionType)
+}
+
+// regionTypeName joins the lower case region type names, e.g. "water_zoneline".
+func regionTypeName(regionType *fragments.BspRegionType) string {
+	names := make([]string, 0, len(regionType.RegionTypes))
+	for _, t := range regionType.RegionTypes {
+		names = append(names, strings.ToLower(t.String()))
+	}
+	return strings.Join(names, "_")
+}
+
+// buildVolume merges cells into a single triangle mesh.
+func buildVolume(cells []cell) *RegionVolume {
+	faceCounts := make(map[string]int)
+	var faces []face
+
+	for _, c := range cells {
+		c.orient()
+		for _, f := range c {
+			faceCounts[faceKey(f)]++
+			faces = append(faces, f)
+		}
+	}
+
+	volume := &RegionVolume{}
+	vertexIndices := make(map[[3]int64]int)
+
+	vertexIndex := func(p vecmath.Vec3) int {
+		key := weldKey(p)
+		if idx, ok := vertexIndices[key]; ok {
+			return idx
+		}
+		idx := len(volume.Vertices)
+		vertexIndices[key] = idx
+		volume.Vertices = append(volume.Vertices, datatypes.Vec3{X: float32(p.X), Y: float32(p.Y), Z: float32(p.Z)})
+		return idx
+	}
+
+	for _, f := range faces {
+		// The face is shared with a neighboring cell of the same volume
+		if faceCounts[faceKey(f)] > 1 {
+			continue
+		}
+
+		first := vertexIndex(f[0])
+		for i := 1; i+1 < len(f); i++ {
+			second := vertexIndex(f[i])
+			third := vertexIndex(f[i+1])
+			if first == second || second == third || first == third {
+				continue
+			}
+			volume.Triangles = append(volume.Triangles, [3]int{first, second, third})
+		}
+	}
+
+	return volume
+}
+
+// weldKey snaps a point to the weld grid.
+func weldKey(p vecmath.Vec3) [3]int64 {
+	return [3]int64{
+		int64(math.Round(p.X / weldPrecision)),
+		int64(math.Round(p.Y / weldPrecision)),
+		int64(math.Round(p.Z / weldPrecision)),
+	}
+}
+
+// faceKey identifies a face by its welded points regardless of winding.
+func faceKey(f face) string {
+	keys := make([][3]int64, 0, len(f))
+	for _, p := range f {
+		keys = append(keys, weldKey(p))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		for k := 0; k < 3; k++ {
+			if keys[i][k] != keys[j][k] {
+				return keys[i][k] < keys[j][k]
+			}
+		}
+		return false
+	})
+	return fmt.Sprint(keys)
+}
+
+// minFloat returns the smaller of a and b.
+func minFloat(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxFloat returns the larger of a and b.
+func maxFloat(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
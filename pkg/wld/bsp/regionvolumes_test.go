@@ -0,0 +1,47 @@
+package bsp
+
+import (
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+func TestBuildRegionVolumes(t *testing.T) {
+	water := &fragments.BspRegionType{RegionTypes: []datatypes.RegionType{datatypes.RegionTypeWater}}
+
+	// Split at x = 0 and z = 0, with water in both cells on the positive x side
+	tree := &fragments.BspTree{Nodes: []*datatypes.BspNode{
+		{NormalX: 1, LeftNode: 1, RightNode: 4},
+		{NormalZ: 1, LeftNode: 2, RightNode: 3},
+		{LeftNode: -1, RightNode: -1, Region: &fragments.BspRegion{RegionType: water}},
+		{LeftNode: -1, RightNode: -1, Region: &fragments.BspRegion{RegionType: water}},
+		{LeftNode: -1, RightNode: -1, Region: &fragments.BspRegion{}},
+	}}
+
+	bounds := Bounds{
+		Min: datatypes.Vec3{X: -10, Y: -10, Z: -10},
+		Max: datatypes.Vec3{X: 10, Y: 10, Z: 10},
+	}
+
+	volumes := BuildRegionVolumes(tree, bounds)
+	if len(volumes) != 1 {
+		t.Fatalf("Expected 1 volume, got %d", len(volumes))
+	}
+
+	volume := volumes[0]
+	if volume.Name != "water_0" || volume.RegionCount != 2 {
+		t.Errorf("Expected water_0 with 2 regions, got %s with %d", volume.Name, volume.RegionCount)
+	}
+
+	// Two boxes with the shared face removed: 10 quads
+	if len(volume.Triangles) != 20 {
+		t.Errorf("Expected 20 triangles, got %d", len(volume.Triangles))
+	}
+
+	for _, v := range volume.Vertices {
+		if v.X < -0.01 {
+			t.Fatalf("Vertex %v is outside the water half", v)
+		}
+	}
+}
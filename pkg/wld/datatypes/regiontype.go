@@ -14,3 +14,27 @@ const (
 	RegionTypeSlippery      RegionType = 7
 	RegionTypeUnknown       RegionType = 8
 )
+
+// String returns the string representation of the region type.
+func (t RegionType) String() string {
+	switch t {
+	case RegionTypeNormal:
+		return "Normal"
+	case RegionTypeWater:
+		return "Water"
+	case RegionTypeLava:
+		return "Lava"
+	case RegionTypePvp:
+		return "Pvp"
+	case RegionTypeZoneline:
+		return "Zoneline"
+	case RegionTypeWaterBlockLos:
+		return "WaterBlockLos"
+	case RegionTypeFreezingWater:
+		return "FreezingWater"
+	case RegionTypeSlippery:
+		return "Slippery"
+	default:
+		return "Unknown"
+	}
+}
@@ -5,28 +5,21 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/animation"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
 )
 
-// ActorExportSettings contains settings for actor export.
-type ActorExportSettings struct {
-	ExportGltfInGlbFormat          bool
-	ExportGltfVertexColors         bool
-	ExportZoneWithObjects          bool
-	ExportAllAnimationFrames       bool
-	ExportCharactersToSingleFolder bool
-}
-
 // ExportActorsToGltf exports all actors from a WLD file to glTF format.
 func ExportActorsToGltf(actors []*fragments.Actor, meshes []*fragments.Mesh, materialLists []*fragments.MaterialList,
-	wldType wld.WldType, shortName string, exportFolder string, settings *ActorExportSettings) error {
+	wldType wld.WldType, shortName string, exportFolder string, settings *config.Settings, zoneScene *ZoneSceneData) error {
 
 	// For a zone wld, we ignore actors and just export all meshes
 	if wldType == wld.WldTypeZone {
-		return ExportZoneMeshes(meshes, materialLists, shortName, exportFolder, settings)
+		return ExportZoneMeshes(meshes, materialLists, shortName, exportFolder, settings, zoneScene)
 	}
 
 	for _, actor := range actors {
@@ -49,7 +42,7 @@ func ExportActorsToGltf(actors []*fragments.Actor, meshes []*fragments.Mesh, mat
 
 // ExportZoneMeshes exports zone meshes to a combined glTF file.
 func ExportZoneMeshes(zoneMeshes []*fragments.Mesh, materialLists []*fragments.MaterialList,
-	shortName string, exportFolder string, settings *ActorExportSettings) error {
+	shortName string, exportFolder string, settings *config.Settings, zoneScene *ZoneSceneData) error {
 
 	if len(zoneMeshes) == 0 {
 		return nil
@@ -61,6 +54,9 @@ func ExportZoneMeshes(zoneMeshes []*fragments.Mesh, materialLists []*fragments.M
 	}
 
 	gltfWriter := NewGltfWriter(settings.ExportGltfVertexColors, exportFormat)
+
+	gltfWriter.SetTextureFormat(settings.TextureFormat)
+	gltfWriter.SetLogger(settings.Logger())
 	textureImageFolder := exportFolder + "Textures/"
 	gltfWriter.GenerateGltfMaterials(materialLists, textureImageFolder)
 
@@ -78,13 +74,14 @@ func ExportZoneMeshes(zoneMeshes []*fragments.Mesh, materialLists []*fragments.M
 	}
 
 	gltfWriter.AddCombinedMeshToScene(true, shortName, "", nil)
+	gltfWriter.AddZoneSceneData(zoneScene)
 
 	exportFilePath := fmt.Sprintf("%s%s.gltf", exportFolder, shortName)
 	return gltfWriter.WriteAssetToFile(exportFilePath, true, "")
 }
 
 // ExportStaticActor exports a static actor (mesh-only) to glTF.
-func ExportStaticActor(actor *fragments.Actor, settings *ActorExportSettings, exportFolder string) error {
+func ExportStaticActor(actor *fragments.Actor, settings *config.Settings, exportFolder string) error {
 	if actor == nil || actor.MeshReference == nil {
 		return nil
 	}
@@ -101,6 +98,9 @@ func ExportStaticActor(actor *fragments.Actor, settings *ActorExportSettings, ex
 
 	gltfWriter := NewGltfWriter(settings.ExportGltfVertexColors, exportFormat)
 
+	gltfWriter.SetTextureFormat(settings.TextureFormat)
+	gltfWriter.SetLogger(settings.Logger())
+
 	textureImageFolder := exportFolder + "Textures/"
 	materialLists := []*fragments.MaterialList{mesh.MaterialList}
 	gltfWriter.GenerateGltfMaterials(materialLists, textureImageFolder)
@@ -112,7 +112,7 @@ func ExportStaticActor(actor *fragments.Actor, settings *ActorExportSettings, ex
 }
 
 // ExportSkeletalActor exports a skeletal actor (with animations) to glTF.
-func ExportSkeletalActor(actor *fragments.Actor, settings *ActorExportSettings, exportFolder string, wldType wld.WldType) error {
+func ExportSkeletalActor(actor *fragments.Actor, settings *config.Settings, exportFolder string, wldType wld.WldType) error {
 	if actor == nil || actor.SkeletonReference == nil {
 		return nil
 	}
@@ -129,6 +129,10 @@ func ExportSkeletalActor(actor *fragments.Actor, settings *ActorExportSettings,
 
 	gltfWriter := NewGltfWriter(settings.ExportGltfVertexColors, exportFormat)
 
+	gltfWriter.SetTextureFormat(settings.TextureFormat)
+	gltfWriter.SetLogger(settings.Logger())
+	gltfWriter.SetAnimationOptions(getAnimationOptions(settings))
+
 	// Collect all material lists
 	materialLists := collectSkeletonMaterialLists(skeleton)
 
@@ -137,28 +141,9 @@ func ExportSkeletalActor(actor *fragments.Actor, settings *ActorExportSettings,
 
 	isCharacterAnimation := wldType == wld.WldTypeCharacters
 
-	// Add bone meshes
-	for i, bone := range skeleton.Skeleton {
-		mesh := getMeshFromBone(bone)
-		if mesh == nil {
-			continue
-		}
-
-		// Shift mesh vertices (this would modify the mesh in place in the original)
-		// For now, we add the mesh data directly
-		gltfWriter.AddFragmentDataWithSkeleton(mesh, skeleton, "", i)
-	}
+	addSkeletonMeshes(gltfWriter, skeleton)
 
-	// Add skeleton meshes
 	if skeleton.Meshes != nil {
-		for _, meshFrag := range skeleton.Meshes {
-			mesh, ok := meshFrag.(*fragments.Mesh)
-			if !ok {
-				continue
-			}
-			gltfWriter.AddFragmentDataWithSkeleton(mesh, skeleton, "", -1)
-		}
-
 		// Handle secondary meshes
 		for i, secondaryMeshFrag := range skeleton.SecondaryMeshes {
 			secondaryMesh, ok := secondaryMeshFrag.(*fragments.Mesh)
@@ -167,6 +152,10 @@ func ExportSkeletalActor(actor *fragments.Actor, settings *ActorExportSettings,
 			}
 
 			secondaryGltfWriter := NewGltfWriter(settings.ExportGltfVertexColors, exportFormat)
+
+			secondaryGltfWriter.SetTextureFormat(settings.TextureFormat)
+			secondaryGltfWriter.SetLogger(settings.Logger())
+			secondaryGltfWriter.SetAnimationOptions(getAnimationOptions(settings))
 			secondaryGltfWriter.CopyMaterialList(gltfWriter)
 
 			// Add primary mesh
@@ -217,7 +206,8 @@ func ExportZoneWithObjects(
 	objectInstances []*fragments.ObjectInstance,
 	shortName string,
 	exportFolder string,
-	settings *ActorExportSettings,
+	settings *config.Settings,
+	zoneScene *ZoneSceneData,
 ) error {
 	if len(zoneMeshes) == 0 {
 		return nil
@@ -229,6 +219,9 @@ func ExportZoneWithObjects(
 	}
 
 	gltfWriter := NewGltfWriter(settings.ExportGltfVertexColors, exportFormat)
+
+	gltfWriter.SetTextureFormat(settings.TextureFormat)
+	gltfWriter.SetLogger(settings.Logger())
 	textureImageFolder := exportFolder + "Textures/"
 
 	// Combine all material lists
@@ -339,12 +332,48 @@ func ExportZoneWithObjects(
 		}
 	}
 
+	gltfWriter.AddZoneSceneData(zoneScene)
+
 	exportFilePath := fmt.Sprintf("%s%s.gltf", exportFolder, shortName)
 	return gltfWriter.WriteAssetToFile(exportFilePath, true, "")
 }
 
 // Helper functions
 
+// addSkeletonMeshes adds the bone meshes and the skinned meshes of a skeleton to the writer.
+func addSkeletonMeshes(gltfWriter *GltfWriter, skeleton *fragments.SkeletonHierarchy) {
+	// Add bone meshes
+	for i, bone := range skeleton.Skeleton {
+		mesh := getMeshFromBone(bone)
+		if mesh == nil {
+			continue
+		}
+
+		// Shift mesh vertices (this would modify the mesh in place in the original)
+		// For now, we add the mesh data directly
+		gltfWriter.AddFragmentDataWithSkeleton(mesh, skeleton, "", i)
+	}
+
+	// Add skeleton meshes
+	for _, meshFrag := range skeleton.Meshes {
+		mesh, ok := meshFrag.(*fragments.Mesh)
+		if !ok {
+			continue
+		}
+		gltfWriter.AddFragmentDataWithSkeleton(mesh, skeleton, "", -1)
+	}
+}
+
+// getAnimationOptions returns the animation resampling, keyframe reduction and root motion selected in the settings.
+func getAnimationOptions(settings *config.Settings) animation.Options {
+	return animation.Options{
+		SampleRate:        settings.AnimationSampleRate,
+		Reduce:            settings.ReduceAnimationKeyframes,
+		Tolerance:         animation.DefaultTolerance(),
+		ExtractRootMotion: settings.ExtractRootMotion,
+	}
+}
+
 // collectSkeletonMaterialLists collects all material lists from a skeleton.
 func collectSkeletonMaterialLists(skeleton *fragments.SkeletonHierarchy) []*fragments.MaterialList {
 	materialListSet := make(map[*fragments.MaterialList]bool)
@@ -6,27 +6,13 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
 )
 
-// ActorObjExporterSettings contains configuration for actor OBJ export.
-type ActorObjExporterSettings struct {
-	// ExportHiddenGeometry exports invisible and boundary surfaces.
-	ExportHiddenGeometry bool
-
-	// ExportZoneMeshGroups exports zone meshes as groups.
-	ExportZoneMeshGroups bool
-
-	// ExportAllAnimationFrames exports all animation frames.
-	ExportAllAnimationFrames bool
-
-	// ExportZoneWithObjects includes object instances in zone export.
-	ExportZoneWithObjects bool
-}
-
 // ObjBackupVertices stores original vertices for restoration after skeleton transformations.
 var ObjBackupVertices = make(map[*fragments.Mesh][]fragments.Vec3)
 
@@ -39,7 +25,7 @@ func ExportActorsToObj(
 	wldType wld.WldType,
 	exportFolder string,
 	zoneShortname string,
-	settings *ActorObjExporterSettings,
+	settings *config.Settings,
 ) error {
 	// For zone WLD, ignore actors and export all meshes
 	if wldType == wld.WldTypeZone {
@@ -69,7 +55,7 @@ func exportZoneToObj(
 	objectInstances []*fragments.ObjectInstance,
 	exportFolder string,
 	zoneShortname string,
-	settings *ActorObjExporterSettings,
+	settings *config.Settings,
 ) error {
 	if len(meshes) == 0 {
 		return nil
@@ -138,7 +124,7 @@ func exportZoneToObj(
 }
 
 // exportStaticActorToObj exports a static actor to OBJ format.
-func exportStaticActorToObj(actor *fragments.Actor, exportFolder string, settings *ActorObjExporterSettings) error {
+func exportStaticActorToObj(actor *fragments.Actor, exportFolder string, settings *config.Settings) error {
 	if actor.MeshReference == nil {
 		return nil
 	}
@@ -204,7 +190,7 @@ func exportStaticActorToObj(actor *fragments.Actor, exportFolder string, setting
 }
 
 // exportSkeletalActorToObj exports a skeletal actor to OBJ format.
-func exportSkeletalActorToObj(actor *fragments.Actor, wldType wld.WldType, exportFolder string, settings *ActorObjExporterSettings) error {
+func exportSkeletalActorToObj(actor *fragments.Actor, wldType wld.WldType, exportFolder string, settings *config.Settings) error {
 	if actor.SkeletonReference == nil {
 		return nil
 	}
@@ -264,6 +250,7 @@ func exportSkeletalActorToObj(actor *fragments.Actor, wldType wld.WldType, expor
 	materialListWriter := NewMeshObjMtlWriter(settings.ExportHiddenGeometry, "")
 
 	for _, ml := range materialLists {
+		materialListWriter.SetSkinID(0)
 		materialListWriter.AddFragmentData(ml)
 
 		var savePath string
@@ -307,17 +294,21 @@ func writeObjAnimationFrame(
 	frameIndex int,
 	wldType wld.WldType,
 	exportFolder string,
-	settings *ActorObjExporterSettings,
+	settings *config.Settings,
 ) error {
 	meshWriter := NewMeshObjWriter(datatypes.ObjExportTypeTextured, settings.ExportHiddenGeometry,
 		settings.ExportZoneMeshGroups, "", "")
-	meshWriter.SetIsCharacterModel(wldType == wld.WldTypeCharacters)
+	isCharacterAnimation := wldType == wld.WldTypeCharacters
+	meshWriter.SetIsCharacterModel(isCharacterAnimation)
 
-	// Add bone meshes
+	// Add bone meshes, posed by the bone they are attached to
 	for _, bone := range skeleton.Skeleton {
 		if bone.MeshReference != nil {
 			if meshRef, ok := bone.MeshReference.(*fragments.MeshReference); ok {
 				if meshRef.Mesh != nil {
+					if mesh, ok := meshRef.Mesh.(*fragments.Mesh); ok {
+						shiftObjMeshVertices(mesh, skeleton, isCharacterAnimation, animation, frameIndex, bone.Index)
+					}
 					meshWriter.AddFragmentData(meshRef.Mesh)
 				}
 			}
@@ -328,8 +319,7 @@ func writeObjAnimationFrame(
 	if skeleton.Meshes != nil {
 		for _, meshFrag := range skeleton.Meshes {
 			if mesh, ok := meshFrag.(*fragments.Mesh); ok {
-				// In full implementation, would call MeshExportHelper.ShiftMeshVertices here
-				// ObjBackupVertices[mesh] = mesh.Vertices // backup
+				shiftObjMeshVertices(mesh, skeleton, isCharacterAnimation, animation, frameIndex, -1)
 				meshWriter.AddFragmentData(mesh)
 			}
 		}
@@ -344,7 +334,7 @@ func writeObjAnimationFrame(
 				if len(skeleton.Meshes) > 0 {
 					meshWriter2.AddFragmentData(skeleton.Meshes[0])
 				}
-				// ObjBackupVertices[mesh] = mesh.Vertices // backup
+				shiftObjMeshVertices(mesh, skeleton, isCharacterAnimation, animation, frameIndex, -1)
 				meshWriter2.AddFragmentData(mesh)
 
 				skeletonName := helpers.CleanName(skeleton.GetName(), "SkeletonHierarchy", true)
@@ -382,6 +372,19 @@ func writeObjAnimationFrame(
 	return nil
 }
 
+// shiftObjMeshVertices poses a mesh for an animation frame.
+// The unposed vertices are backed up so they can be restored after the frame is written.
+// Meshes attached to a single bone pass its index, skinned meshes pass -1.
+func shiftObjMeshVertices(mesh *fragments.Mesh, skeleton *fragments.SkeletonHierarchy,
+	isCharacterAnimation bool, animation string, frameIndex int, singularBoneIndex int) {
+	if _, exists := ObjBackupVertices[mesh]; exists {
+		return
+	}
+
+	ObjBackupVertices[mesh] = append([]fragments.Vec3(nil), mesh.Vertices...)
+	helpers.ShiftMeshVertices(mesh, skeleton, isCharacterAnimation, animation, frameIndex, singularBoneIndex)
+}
+
 // restoreVertices restores backed up vertices.
 func restoreVertices() {
 	for mesh, vertices := range ObjBackupVertices {
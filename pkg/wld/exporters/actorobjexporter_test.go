@@ -0,0 +1,54 @@
+package exporters
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+func TestWriteObjAnimationFrame(t *testing.T) {
+	mesh := newTestSword()
+	bone := &fragments.SkeletonBone{
+		Index:         0,
+		Name:          "IT10_DAG",
+		CleanedName:   datatypes.CleanBoneName("IT10_DAG"),
+		MeshReference: &fragments.MeshReference{Mesh: mesh},
+	}
+	skeleton := &fragments.SkeletonHierarchy{
+		Skeleton:    []*fragments.SkeletonBone{bone},
+		BoneMapping: map[int]string{0: "IT10_DAG"},
+		Animations:  map[string]*datatypes.Animation{},
+	}
+	skeleton.SetName("IT10_HS_DEF")
+
+	// The bone is raised by 10 in the only frame of the animation
+	frame := datatypes.BoneTransform{Translation: datatypes.Vec3{Z: 10}, Rotation: datatypes.Quat{W: 1}, Scale: 1}
+	animation := datatypes.NewAnimation()
+	animation.AddTrack(&fragments.TrackFragment{TrackDefFragment: &fragments.TrackDefFragment{Frames: []datatypes.BoneTransform{frame}}},
+		"IT10_DAG", datatypes.CleanBoneName("IT10_DAG"), "root")
+	skeleton.Animations["c01"] = animation
+
+	folder := t.TempDir() + "/"
+	settings := &config.Settings{ExportAllAnimationFrames: true}
+	if err := writeObjAnimationFrame(skeleton, "c01", 0, wld.WldTypeEquipment, folder, settings); err != nil {
+		t.Fatalf("Failed to write the frame: %v", err)
+	}
+
+	data, err := os.ReadFile(folder + "it10_c01_0.obj")
+	if err != nil {
+		t.Fatalf("Failed to read the frame: %v", err)
+	}
+
+	// OBJ vertices are written as (-X, Z, Y)
+	if !strings.Contains(string(data), "v -1.000000 10.000000 0.000000\n") {
+		t.Errorf("Expected the bone mesh to be posed by its bone, got:\n%s", data)
+	}
+	if mesh.Vertices[1] != (fragments.Vec3{X: 1}) {
+		t.Errorf("Expected the mesh vertices to be restored, got %v", mesh.Vertices[1])
+	}
+}
@@ -0,0 +1,141 @@
+package exporters
+
+import (
+	"math"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
+)
+
+// PosedMesh is a mesh of an actor with its vertices placed in EQ model space for one frame of an animation.
+type PosedMesh struct {
+	// Mesh is the source mesh with the polygons, materials, UVs and vertex colors.
+	Mesh *fragments.Mesh
+
+	// Positions are the vertex positions, indexed like Mesh.Vertices.
+	Positions []fragments.Vec3
+
+	// Normals are the outward facing unit vertex normals, indexed like Mesh.Vertices.
+	Normals []fragments.Vec3
+}
+
+// GetActorMeshes returns the meshes of a static or skeletal actor and the skeleton of a skeletal actor.
+func GetActorMeshes(actor *fragments.Actor) ([]*fragments.Mesh, *fragments.SkeletonHierarchy) {
+	if actor == nil {
+		return nil, nil
+	}
+
+	if actor.ActorType == datatypes.ActorTypeStatic {
+		if mesh, ok := getMeshFromReference(actor.MeshReference); ok && mesh != nil {
+			return []*fragments.Mesh{mesh}, nil
+		}
+		return nil, nil
+	}
+
+	if actor.ActorType != datatypes.ActorTypeSkeletal || actor.SkeletonReference == nil ||
+		actor.SkeletonReference.SkeletonHierarchy == nil {
+		return nil, nil
+	}
+
+	skeleton := actor.SkeletonReference.SkeletonHierarchy
+	var meshes []*fragments.Mesh
+	for _, skinned := range getSkeletonFbxMeshes(skeleton) {
+		meshes = append(meshes, skinned.mesh)
+	}
+	return meshes, skeleton
+}
+
+// GetPosedActorMeshes returns the meshes of a static or skeletal actor as GltfWriter places them.
+// Skeletal actors are posed in a frame of an animation, falling back to the pose if the skeleton
+// has no such animation. Frames past the end of the animation hold the last frame.
+func GetPosedActorMeshes(actor *fragments.Actor, animationKey string, frame int, isCharacterAnimation bool) []PosedMesh {
+	if actor == nil {
+		return nil
+	}
+
+	if actor.ActorType == datatypes.ActorTypeStatic {
+		mesh, ok := getMeshFromReference(actor.MeshReference)
+		if !ok || mesh == nil {
+			return nil
+		}
+		return []PosedMesh{newPosedMesh(fbxMesh{mesh: mesh, singularBoneIndex: 0}, []datatypes.Mat4{helpers.Mat4Identity()})}
+	}
+
+	if actor.SkeletonReference == nil || actor.SkeletonReference.SkeletonHierarchy == nil {
+		return nil
+	}
+
+	skeleton := actor.SkeletonReference.SkeletonHierarchy
+	boneWorld := getBoneWorldMatrices(skeleton, getPoseFrame(skeleton, animationKey, frame, isCharacterAnimation))
+
+	var meshes []PosedMesh
+	for _, skinned := range getSkeletonFbxMeshes(skeleton) {
+		meshes = append(meshes, newPosedMesh(skinned, boneWorld))
+	}
+	return meshes
+}
+
+// getPoseFrame returns the local bone transforms of a frame of an animation, or of the pose.
+// Skeletons without either get identity transforms.
+func getPoseFrame(skeleton *fragments.SkeletonHierarchy, animationKey string, frame int, isCharacterAnimation bool) []datatypes.BoneTransform {
+	sampled := sampleSkeletonAnimation(skeleton, animationKey, isCharacterAnimation)
+	if sampled == nil {
+		sampled = sampleSkeletonAnimation(skeleton, defaultModelPoseAnimKey, isCharacterAnimation)
+	}
+
+	if sampled == nil {
+		transforms := make([]datatypes.BoneTransform, len(skeleton.Skeleton))
+		for i := range transforms {
+			transforms[i] = datatypes.BoneTransform{Rotation: datatypes.Quat{W: 1}, Scale: 1}
+		}
+		return transforms
+	}
+
+	if frame < 0 {
+		frame = 0
+	}
+	if frame >= len(sampled.frames) {
+		frame = len(sampled.frames) - 1
+	}
+	return sampled.frames[frame]
+}
+
+// newPosedMesh moves the vertices of a mesh by the world matrix of their bones.
+// Stored normals point inwards and are flipped.
+func newPosedMesh(skinned fbxMesh, boneWorld []datatypes.Mat4) PosedMesh {
+	mesh := skinned.mesh
+	posed := PosedMesh{
+		Mesh:      mesh,
+		Positions: make([]fragments.Vec3, len(mesh.Vertices)),
+		Normals:   make([]fragments.Vec3, len(mesh.Vertices)),
+	}
+
+	for i, vertex := range mesh.Vertices {
+		boneMatrix := boneWorld[getSkinnedVertexBone(skinned, i, len(boneWorld))]
+
+		position := transformPoint(boneMatrix, vertex)
+		position.X += mesh.Center.X
+		position.Y += mesh.Center.Y
+		position.Z += mesh.Center.Z
+		posed.Positions[i] = position
+
+		posed.Normals[i] = fragments.Vec3{Z: 1}
+		if i >= len(mesh.Normals) {
+			continue
+		}
+
+		n := mesh.Normals[i]
+		normal := fragments.Vec3{
+			X: -(boneMatrix[0]*n.X + boneMatrix[4]*n.Y + boneMatrix[8]*n.Z),
+			Y: -(boneMatrix[1]*n.X + boneMatrix[5]*n.Y + boneMatrix[9]*n.Z),
+			Z: -(boneMatrix[2]*n.X + boneMatrix[6]*n.Y + boneMatrix[10]*n.Z),
+		}
+		length := float32(math.Sqrt(float64(normal.X*normal.X + normal.Y*normal.Y + normal.Z*normal.Z)))
+		if length > 0 {
+			posed.Normals[i] = fragments.Vec3{X: normal.X / length, Y: normal.Y / length, Z: normal.Z / length}
+		}
+	}
+
+	return posed
+}
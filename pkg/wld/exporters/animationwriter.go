@@ -2,8 +2,10 @@ package exporters
 
 import (
 	"fmt"
+	"math"
 	"strings"
 
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/animation"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 )
@@ -13,6 +15,8 @@ type AnimationWriter struct {
 	TextAssetWriter
 	targetAnimation      string
 	isCharacterAnimation bool
+	animationOptions     animation.Options
+	rootMotion           *animation.RootMotion
 }
 
 // NewAnimationWriter creates a new AnimationWriter.
@@ -29,6 +33,16 @@ func (w *AnimationWriter) SetTargetAnimation(animationName string) {
 	w.targetAnimation = animationName
 }
 
+// SetAnimationOptions sets the resampling and keyframe reduction applied to the tracks.
+func (w *AnimationWriter) SetAnimationOptions(options animation.Options) {
+	w.animationOptions = options
+}
+
+// RootMotion returns the root motion extracted from the last added animation, or nil.
+func (w *AnimationWriter) RootMotion() *animation.RootMotion {
+	return w.rootMotion
+}
+
 // AddFragmentData adds skeleton hierarchy animation data to the export buffer.
 func (w *AnimationWriter) AddFragmentData(data fragments.Fragment) {
 	skeleton, ok := data.(*fragments.SkeletonHierarchy)
@@ -45,9 +59,10 @@ func (w *AnimationWriter) AddFragmentData(data fragments.Fragment) {
 		return
 	}
 
-	w.AppendLine("# Animation: " + w.targetAnimation)
-	w.AppendLine(fmt.Sprintf("framecount,%d", anim.FrameCount))
-	w.AppendLine(fmt.Sprintf("totalTimeMs,%d", anim.AnimationTimeMs))
+	w.rootMotion = nil
+
+	var tracks []animationTrack
+	frameCount := anim.FrameCount
 
 	for i := 0; i < len(skeleton.Skeleton); i++ {
 		boneName := datatypes.CleanBoneAndStripBase(skeleton.BoneMapping[i], skeleton.ModelBase)
@@ -77,8 +92,11 @@ func (w *AnimationWriter) AddFragmentData(data fragments.Fragment) {
 
 			// Get the first frame from pose track
 			if trackDefFrag, ok := trackDef.(*fragments.TrackDefFragment); ok && len(trackDefFrag.Frames) > 0 {
-				bt := trackDefFrag.Frames[0]
-				w.createTrackString(fullPath, 0, bt, anim.AnimationTimeMs)
+				tracks = append(tracks, animationTrack{
+					fullPath: fullPath,
+					keys:     []animation.Keyframe{{Transform: trackDefFrag.Frames[0]}},
+					delays:   []int{anim.AnimationTimeMs},
+				})
 			}
 		} else {
 			trackDef := track.GetTrackDefFragment()
@@ -91,24 +109,72 @@ func (w *AnimationWriter) AddFragmentData(data fragments.Fragment) {
 				continue
 			}
 
-			for j := 0; j < anim.FrameCount; j++ {
-				if j >= len(trackDefFrag.Frames) {
-					break
+			var delay int
+			if w.isCharacterAnimation {
+				if anim.FrameCount > 0 {
+					delay = anim.AnimationTimeMs / anim.FrameCount
 				}
+			} else {
+				delay = skeleton.Skeleton[i].Track.GetFrameMs()
+			}
+
+			trackFrameCount := anim.FrameCount
+			if trackFrameCount > len(trackDefFrag.Frames) {
+				trackFrameCount = len(trackDefFrag.Frames)
+			}
+
+			keys := animation.FromFrames(trackDefFrag.Frames[:trackFrameCount], delay)
+			if i == 0 && w.animationOptions.ExtractRootMotion {
+				keys, w.rootMotion = animation.ExtractRootMotion(keys)
+			}
+
+			delays := make([]int, len(keys))
+			for j := range delays {
+				delays[j] = delay
+			}
 
-				boneTransform := trackDefFrag.Frames[j]
-				var delay int
-				if w.isCharacterAnimation {
-					if anim.FrameCount > 0 {
-						delay = anim.AnimationTimeMs / anim.FrameCount
-					}
-				} else {
-					delay = skeleton.Skeleton[i].Track.GetFrameMs()
+			if w.animationOptions.IsEnabled() {
+				keys = w.animationOptions.Apply(keys)
+				delays = getKeyframeDelays(keys, delay)
+				if w.animationOptions.SampleRate > 0 && len(keys) > frameCount {
+					frameCount = len(keys)
 				}
-				w.createTrackString(fullPath, j, boneTransform, delay)
 			}
+
+			tracks = append(tracks, animationTrack{fullPath: fullPath, keys: keys, delays: delays})
+		}
+	}
+
+	w.AppendLine("# Animation: " + w.targetAnimation)
+	w.AppendLine(fmt.Sprintf("framecount,%d", frameCount))
+	w.AppendLine(fmt.Sprintf("totalTimeMs,%d", anim.AnimationTimeMs))
+
+	for _, track := range tracks {
+		for j, key := range track.keys {
+			w.createTrackString(track.fullPath, j, key.Transform, track.delays[j])
+		}
+	}
+}
+
+// animationTrack holds the keyframes of a bone and the delay after each of them.
+type animationTrack struct {
+	fullPath string
+	keys     []animation.Keyframe
+	delays   []int
+}
+
+// getKeyframeDelays returns the time in milliseconds from each key to the next.
+// The last key lasts the given frame time.
+func getKeyframeDelays(keys []animation.Keyframe, frameMs int) []int {
+	delays := make([]int, len(keys))
+	for i := range keys {
+		if i+1 < len(keys) {
+			delays[i] = int(math.Round(keys[i+1].TimeMs - keys[i].TimeMs))
+		} else {
+			delays[i] = frameMs
 		}
 	}
+	return delays
 }
 
 // createTrackString creates a track string for a single bone transform.
@@ -0,0 +1,152 @@
+package exporters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
+)
+
+// bvhChannels are the channels of every joint. EQ animations move bone positions as well as
+// rotations, so joints get position channels like the root.
+const bvhChannels = "CHANNELS 6 Xposition Yposition Zposition Zrotation Xrotation Yrotation"
+
+// BvhWriter exports a skeleton animation to the BVH motion capture format.
+// The skeleton is converted to Y-up like the glTF export. BVH has no scale channels,
+// so bone scale is not exported.
+type BvhWriter struct {
+	TextAssetWriter
+	targetAnimation      string
+	isCharacterAnimation bool
+}
+
+// NewBvhWriter creates a new BvhWriter.
+func NewBvhWriter(isCharacterAnimation bool) *BvhWriter {
+	return &BvhWriter{
+		isCharacterAnimation: isCharacterAnimation,
+	}
+}
+
+// SetTargetAnimation sets the target animation name to export.
+func (w *BvhWriter) SetTargetAnimation(animationName string) {
+	w.targetAnimation = animationName
+}
+
+// AddFragmentData adds the hierarchy and motion of the target animation of a skeleton hierarchy.
+func (w *BvhWriter) AddFragmentData(data fragments.Fragment) {
+	skeleton, ok := data.(*fragments.SkeletonHierarchy)
+	if !ok || skeleton == nil || len(skeleton.Skeleton) == 0 {
+		return
+	}
+
+	sampled := sampleSkeletonAnimation(skeleton, w.targetAnimation, w.isCharacterAnimation)
+	if sampled == nil {
+		return
+	}
+
+	boneNames := getUniqueBoneNames(skeleton)
+
+	// The hierarchy and the motion channels list the bones in the same depth-first order
+	var order []int
+	w.AppendLine("HIERARCHY")
+	w.writeJoint(skeleton, 0, 0, boneNames, sampled.frames[0], &order)
+
+	w.AppendLine("MOTION")
+	w.AppendLine(fmt.Sprintf("Frames: %d", len(sampled.frames)))
+	w.AppendLine(fmt.Sprintf("Frame Time: %.6f", float64(sampled.frameTimeMs)/1000.0))
+
+	previous := make([][3]float64, len(skeleton.Skeleton))
+	for frame, transforms := range sampled.frames {
+		values := make([]string, 0, len(order)*6)
+		for _, boneIndex := range order {
+			translation, rotation, _ := helpers.BoneLocalTRSGltf(transforms[boneIndex])
+			angles := eulerZXYDegrees(rotation)
+			if frame > 0 {
+				for axis := range angles {
+					angles[axis] = unwrapDegrees(angles[axis], previous[boneIndex][axis])
+				}
+			}
+			previous[boneIndex] = angles
+
+			values = append(values,
+				formatBvhFloat(float64(translation[0])),
+				formatBvhFloat(float64(translation[1])),
+				formatBvhFloat(float64(translation[2])),
+				formatBvhFloat(angles[2]),
+				formatBvhFloat(angles[0]),
+				formatBvhFloat(angles[1]))
+		}
+		w.AppendLine(strings.Join(values, " "))
+	}
+}
+
+// writeJoint writes a joint and its children. The offsets are the bone positions of the first frame.
+func (w *BvhWriter) writeJoint(skeleton *fragments.SkeletonHierarchy, boneIndex, depth int, boneNames []string,
+	rest []datatypes.BoneTransform, order *[]int) {
+	indent := strings.Repeat("\t", depth)
+	*order = append(*order, boneIndex)
+
+	keyword := "JOINT"
+	if depth == 0 {
+		keyword = "ROOT"
+	}
+
+	translation, _, _ := helpers.BoneLocalTRSGltf(rest[boneIndex])
+	w.AppendLine(indent + keyword + " " + boneNames[boneIndex])
+	w.AppendLine(indent + "{")
+	w.AppendLine(fmt.Sprintf("%s\tOFFSET %s %s %s", indent,
+		formatBvhFloat(float64(translation[0])),
+		formatBvhFloat(float64(translation[1])),
+		formatBvhFloat(float64(translation[2]))))
+	w.AppendLine(indent + "\t" + bvhChannels)
+
+	hasChildren := false
+	for _, childIndex := range skeleton.Skeleton[boneIndex].Children {
+		if childIndex <= 0 || childIndex >= len(skeleton.Skeleton) {
+			continue
+		}
+		hasChildren = true
+		w.writeJoint(skeleton, childIndex, depth+1, boneNames, rest, order)
+	}
+
+	// Leaf joints need an end site to define their length
+	if !hasChildren {
+		w.AppendLine(indent + "\tEnd Site")
+		w.AppendLine(indent + "\t{")
+		w.AppendLine(indent + "\t\tOFFSET 0.000000 0.000000 0.000000")
+		w.AppendLine(indent + "\t}")
+	}
+
+	w.AppendLine(indent + "}")
+}
+
+// formatBvhFloat formats a channel value with a fixed precision.
+func formatBvhFloat(v float64) string {
+	return fmt.Sprintf("%.6f", v)
+}
+
+// ExportSkeletonAnimationsToBvh writes each animation of a skeleton to its own BVH file
+// in the 'Animations' folder of the export folder.
+func ExportSkeletonAnimationsToBvh(skeleton *fragments.SkeletonHierarchy, isCharacterAnimation bool, exportFolder string) error {
+	if skeleton == nil {
+		return nil
+	}
+
+	skeletonName := helpers.CleanSkeletonName(skeleton.GetName())
+	writer := NewBvhWriter(isCharacterAnimation)
+
+	for _, animationKey := range getExportedAnimationKeys(skeleton, isCharacterAnimation) {
+		writer.ClearExportData()
+		writer.SetTargetAnimation(animationKey)
+		writer.AddFragmentData(skeleton)
+
+		filePath := fmt.Sprintf("%sAnimations/%s_%s.bvh", exportFolder, skeletonName, animationKey)
+		if err := writer.WriteAssetToFile(filePath); err != nil {
+			return fmt.Errorf("failed to write BVH animation %s: %w", animationKey, err)
+		}
+	}
+
+	return nil
+}
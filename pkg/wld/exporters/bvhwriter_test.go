@@ -0,0 +1,87 @@
+package exporters
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// axisQuat returns the quaternion (x, y, z, w) rotating by an angle in degrees around a unit axis.
+func axisQuat(axis [3]float64, angle float64) [4]float64 {
+	half := angle * math.Pi / 360
+	s := math.Sin(half)
+	return [4]float64{axis[0] * s, axis[1] * s, axis[2] * s, math.Cos(half)}
+}
+
+// quatMultiply returns the product a * b of two quaternions.
+func quatMultiply(a, b [4]float64) [4]float64 {
+	return [4]float64{
+		a[3]*b[0] + a[0]*b[3] + a[1]*b[2] - a[2]*b[1],
+		a[3]*b[1] - a[0]*b[2] + a[1]*b[3] + a[2]*b[0],
+		a[3]*b[2] + a[0]*b[1] - a[1]*b[0] + a[2]*b[3],
+		a[3]*b[3] - a[0]*b[0] - a[1]*b[1] - a[2]*b[2],
+	}
+}
+
+func TestEulerDegrees(t *testing.T) {
+	x, y, z := 30.0, -45.0, 60.0
+	rx := axisQuat([3]float64{1, 0, 0}, x)
+	ry := axisQuat([3]float64{0, 1, 0}, y)
+	rz := axisQuat([3]float64{0, 0, 1}, z)
+
+	toFloat32 := func(q [4]float64) [4]float32 {
+		return [4]float32{float32(q[0]), float32(q[1]), float32(q[2]), float32(q[3])}
+	}
+
+	tests := []struct {
+		name   string
+		angles [3]float64
+	}{
+		{"ZXY", eulerZXYDegrees(toFloat32(quatMultiply(rz, quatMultiply(rx, ry))))},
+		{"XYZ", eulerXYZDegrees(toFloat32(quatMultiply(rz, quatMultiply(ry, rx))))},
+	}
+
+	for _, test := range tests {
+		expected := [3]float64{x, y, z}
+		for axis := range expected {
+			if math.Abs(test.angles[axis]-expected[axis]) > 1e-3 {
+				t.Errorf("%s: expected %v, got %v", test.name, expected, test.angles)
+				break
+			}
+		}
+	}
+}
+
+func TestBvhWriter(t *testing.T) {
+	root := &fragments.SkeletonBone{Index: 0, CleanedName: "root", Children: []int{1}}
+	child := &fragments.SkeletonBone{Index: 1, CleanedName: "pe", Parent: root}
+	skeleton := &fragments.SkeletonHierarchy{
+		Skeleton:    []*fragments.SkeletonBone{root, child},
+		BoneMapping: map[int]string{0: "ROOT_DAG", 1: "PE_DAG"},
+		Animations:  map[string]*datatypes.Animation{},
+	}
+
+	identity := datatypes.BoneTransform{Rotation: datatypes.Quat{W: 1}, Scale: 1}
+	track := &fragments.TrackFragment{
+		TrackDefFragment: &fragments.TrackDefFragment{Frames: []datatypes.BoneTransform{identity, identity, identity}},
+		FrameMs:          50,
+	}
+
+	animation := datatypes.NewAnimation()
+	animation.AddTrack(track, "ROOT_DAG", datatypes.CleanBoneName("ROOT_DAG"), "root")
+	skeleton.Animations["c01"] = animation
+
+	writer := NewBvhWriter(false)
+	writer.SetTargetAnimation("c01")
+	writer.AddFragmentData(skeleton)
+	output := writer.GetExport().String()
+
+	for _, expected := range []string{"ROOT root", "JOINT pe", "Frames: 3", "Frame Time: 0.050000"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected BVH output to contain %q:\n%s", expected, output)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package exporters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
+)
+
+const (
+	// AttachPointPrimary is the right hand bone primary weapons are held by.
+	AttachPointPrimary = "r_point"
+	// AttachPointSecondary is the left hand bone secondary weapons are held by.
+	AttachPointSecondary = "l_point"
+	// AttachPointShield is the forearm bone shields are worn on.
+	AttachPointShield = "shield_point"
+)
+
+// Attachment is an equipment model held by a character bone.
+type Attachment struct {
+	// Actor is the static or skeletal equipment actor, e.g. IT10_ACTORDEF.
+	Actor *fragments.Actor
+
+	// AttachPoint is the cleaned name of the bone holding the equipment, e.g. AttachPointPrimary.
+	AttachPoint string
+}
+
+// ExportComposedActor exports a character with its equipment parented to the attach point bones.
+// The pose and all animations are exported so the equipment moves with the character.
+// Textures are expected in the Textures folder of the export folder.
+// Returns the path of the written .gltf or .glb file.
+func ExportComposedActor(actor *fragments.Actor, attachments []Attachment, settings *config.Settings, exportFolder string, name string) (string, error) {
+	if actor == nil || actor.SkeletonReference == nil || actor.SkeletonReference.SkeletonHierarchy == nil {
+		return "", fmt.Errorf("actor %s is not a skeletal actor", actorName(actor))
+	}
+
+	skeleton := actor.SkeletonReference.SkeletonHierarchy
+
+	exportFormat := GltfExportFormatGlTF
+	if settings.ExportGltfInGlbFormat {
+		exportFormat = GltfExportFormatGlb
+	}
+
+	gltfWriter := NewGltfWriter(settings.ExportGltfVertexColors, exportFormat)
+
+	gltfWriter.SetTextureFormat(settings.TextureFormat)
+	gltfWriter.SetLogger(settings.Logger())
+	gltfWriter.SetAnimationOptions(getAnimationOptions(settings))
+
+	materialLists := collectSkeletonMaterialLists(skeleton)
+	attachmentMeshes := make([][]*fragments.Mesh, len(attachments))
+	for i, attachment := range attachments {
+		meshes := getAttachmentMeshes(attachment.Actor)
+		if len(meshes) == 0 {
+			return "", fmt.Errorf("equipment %s has no meshes", actorName(attachment.Actor))
+		}
+		attachmentMeshes[i] = meshes
+		for _, mesh := range meshes {
+			materialLists = append(materialLists, mesh.MaterialList)
+		}
+	}
+
+	textureImageFolder := exportFolder + "Textures/"
+	gltfWriter.GenerateGltfMaterials(materialLists, textureImageFolder)
+
+	addSkeletonMeshes(gltfWriter, skeleton)
+
+	for i, attachment := range attachments {
+		boneIndex := findAttachPointBone(skeleton, attachment.AttachPoint)
+		if boneIndex < 0 {
+			return "", fmt.Errorf("skeleton %s has no %s bone", skeleton.GetName(), attachment.AttachPoint)
+		}
+
+		attached := false
+		for _, mesh := range attachmentMeshes[i] {
+			nodeName := helpers.CleanMeshName(mesh.GetName()) + "_" + attachment.AttachPoint
+			if gltfWriter.AddAttachedMesh(mesh, skeleton, boneIndex, nodeName) {
+				attached = true
+			}
+		}
+		if !attached {
+			return "", fmt.Errorf("failed to attach %s to %s", actorName(attachment.Actor), attachment.AttachPoint)
+		}
+	}
+
+	gltfWriter.ApplyAnimationToSkeleton(skeleton, defaultModelPoseAnimKey, true, true)
+	for animationKey := range skeleton.Animations {
+		gltfWriter.ApplyAnimationToSkeleton(skeleton, animationKey, true, false)
+	}
+
+	exportFilePath := gltfWriter.fixFilePath(exportFolder + name)
+	if err := gltfWriter.WriteAssetToFile(exportFilePath, true, skeleton.ModelBase); err != nil {
+		return "", err
+	}
+	return exportFilePath, nil
+}
+
+// getAttachmentMeshes returns the meshes of an equipment actor relative to the bone holding it.
+// Skeletal equipment, e.g. a bow, is baked into its pose: each bone mesh and skinned mesh is
+// returned as a copy with its vertices moved by its bones. The equipment's own animations are not exported.
+func getAttachmentMeshes(actor *fragments.Actor) []*fragments.Mesh {
+	if actor == nil {
+		return nil
+	}
+
+	if mesh, ok := getMeshFromReference(actor.MeshReference); ok && mesh != nil {
+		return []*fragments.Mesh{mesh}
+	}
+
+	if actor.SkeletonReference == nil || actor.SkeletonReference.SkeletonHierarchy == nil {
+		return nil
+	}
+
+	skeleton := actor.SkeletonReference.SkeletonHierarchy
+	restPose := make([]datatypes.BoneTransform, len(skeleton.Skeleton))
+	if pose := sampleSkeletonAnimation(skeleton, defaultModelPoseAnimKey, false); pose != nil {
+		restPose = pose.frames[0]
+	} else {
+		for i := range restPose {
+			restPose[i] = datatypes.BoneTransform{Rotation: datatypes.Quat{W: 1}, Scale: 1}
+		}
+	}
+	boneWorld := getBoneWorldMatrices(skeleton, restPose)
+
+	var meshes []*fragments.Mesh
+	for _, skinned := range getSkeletonFbxMeshes(skeleton) {
+		meshes = append(meshes, getPosedMesh(skinned, boneWorld))
+	}
+	return meshes
+}
+
+// getPosedMesh returns a copy of a skeleton mesh with its vertices and normals moved by their bones.
+func getPosedMesh(skinned fbxMesh, boneWorld []datatypes.Mat4) *fragments.Mesh {
+	posed := *skinned.mesh
+	posed.Vertices = make([]fragments.Vec3, len(skinned.mesh.Vertices))
+	posed.Normals = make([]fragments.Vec3, len(skinned.mesh.Normals))
+	posed.MobPieces = nil
+
+	for i, vertex := range skinned.mesh.Vertices {
+		posed.Vertices[i] = transformPoint(boneWorld[getSkinnedVertexBone(skinned, i, len(boneWorld))], vertex)
+	}
+
+	for i, normal := range skinned.mesh.Normals {
+		m := boneWorld[getSkinnedVertexBone(skinned, i, len(boneWorld))]
+		posed.Normals[i] = fragments.Vec3{
+			X: m[0]*normal.X + m[4]*normal.Y + m[8]*normal.Z,
+			Y: m[1]*normal.X + m[5]*normal.Y + m[9]*normal.Z,
+			Z: m[2]*normal.X + m[6]*normal.Y + m[10]*normal.Z,
+		}
+	}
+
+	return &posed
+}
+
+// findAttachPointBone returns the index of the bone with the attach point name, or -1.
+// Bone names carry the model base, e.g. HUMR_POINT_DAG is the r_point bone of hum.
+func findAttachPointBone(skeleton *fragments.SkeletonHierarchy, attachPoint string) int {
+	modelBase := strings.ToLower(skeleton.ModelBase)
+	for i, bone := range skeleton.Skeleton {
+		if datatypes.CleanBoneAndStripBase(bone.Name, modelBase) == attachPoint {
+			return i
+		}
+	}
+	return -1
+}
+
+// actorName returns the name of an actor for error messages.
+func actorName(actor *fragments.Actor) string {
+	if actor == nil {
+		return "<nil>"
+	}
+	return actor.GetName()
+}
@@ -0,0 +1,148 @@
+package exporters
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// newTestComposeSkeleton creates a hum skeleton with a root and both hand attach points.
+func newTestComposeSkeleton() *fragments.SkeletonHierarchy {
+	skeleton := &fragments.SkeletonHierarchy{
+		ModelBase:  "hum",
+		Animations: make(map[string]*datatypes.Animation),
+	}
+	for i, name := range []string{"HUM_DAG", "HUMR_POINT_DAG", "HUML_POINT_DAG"} {
+		skeleton.Skeleton = append(skeleton.Skeleton, &fragments.SkeletonBone{
+			Index:       i,
+			Name:        name,
+			CleanedName: datatypes.CleanBoneAndStripBase(name, "hum"),
+		})
+	}
+	skeleton.Skeleton[0].Children = []int{1, 2}
+	skeleton.SetName("HUM_HS_DEF")
+	return skeleton
+}
+
+// newTestSword creates a single triangle equipment mesh.
+func newTestSword() *fragments.Mesh {
+	mesh := &fragments.Mesh{
+		Vertices:             []fragments.Vec3{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 0, Y: 0, Z: 5}},
+		Normals:              []fragments.Vec3{{Y: 1}, {Y: 1}, {Y: 1}},
+		TextureUvCoordinates: []datatypes.Vec2{{}, {}, {}},
+		Indices:              []datatypes.Polygon{{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2}},
+		MaterialGroups:       []datatypes.RenderGroup{{PolygonCount: 1, MaterialIndex: 0}},
+		MaterialList:         &fragments.MaterialList{Materials: []*fragments.Material{newTestMaterial("SWORD_MDF", "sword.bmp")}},
+	}
+	mesh.SetName("IT10_DMSPRITEDEF")
+	return mesh
+}
+
+func TestFindAttachPointBone(t *testing.T) {
+	skeleton := newTestComposeSkeleton()
+
+	tests := map[string]int{
+		AttachPointPrimary:   1,
+		AttachPointSecondary: 2,
+		AttachPointShield:    -1,
+	}
+	for attachPoint, expected := range tests {
+		if index := findAttachPointBone(skeleton, attachPoint); index != expected {
+			t.Errorf("Expected %s at bone %d, got %d", attachPoint, expected, index)
+		}
+	}
+}
+
+func TestAddAttachedMesh(t *testing.T) {
+	skeleton := newTestComposeSkeleton()
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+
+	if writer.AddAttachedMesh(newTestSword(), skeleton, len(skeleton.Skeleton), "it10_r_point") {
+		t.Error("Expected a missing bone to be rejected")
+	}
+	if !writer.AddAttachedMesh(newTestSword(), skeleton, 1, "it10_r_point") {
+		t.Fatal("Expected the mesh to be attached")
+	}
+
+	bones := writer.skeletons["hum"].nodes
+	hand := writer.doc.Nodes[bones[1]]
+	if len(hand.Children) != 1 {
+		t.Fatalf("Expected the attached mesh under the r_point bone, got %d children", len(hand.Children))
+	}
+
+	node := writer.doc.Nodes[hand.Children[0]]
+	if node.Name != "it10_r_point" || node.Mesh == nil || node.Skin != nil {
+		t.Errorf("Expected an unskinned mesh node named it10_r_point, got %+v", node)
+	}
+	if len(writer.doc.Nodes[bones[2]].Children) != 0 || len(writer.doc.Nodes[bones[0]].Children) != 2 {
+		t.Error("Expected the other bones to keep their children")
+	}
+}
+
+func TestExportComposedActorPath(t *testing.T) {
+	actor := &fragments.Actor{SkeletonReference: &fragments.SkeletonHierarchyReference{SkeletonHierarchy: newTestComposeSkeleton()}}
+	sword := &fragments.Actor{MeshReference: &fragments.MeshReference{Mesh: newTestSword()}}
+	attachments := []Attachment{{Actor: sword, AttachPoint: AttachPointPrimary}}
+
+	settings := &config.Settings{ExportGltfInGlbFormat: true}
+
+	folder := t.TempDir() + "/"
+	exportPath, err := ExportComposedActor(actor, attachments, settings, folder, "hum_it10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(exportPath, "hum_it10.glb") {
+		t.Errorf("Expected a .glb path, got %s", exportPath)
+	}
+	if _, err := os.Stat(exportPath); err != nil {
+		t.Errorf("Expected the composed character at %s: %v", exportPath, err)
+	}
+
+	attachments[0].AttachPoint = AttachPointShield
+	if _, err := ExportComposedActor(actor, attachments, settings, folder, "hum_it10"); err == nil {
+		t.Error("Expected an error for a missing attach point")
+	}
+}
+
+func TestGetAttachmentMeshes(t *testing.T) {
+	// A skeletal bow with the sword mesh on its only bone, raised by 10 in the pose
+	mesh := newTestSword()
+	skeleton := &fragments.SkeletonHierarchy{
+		Skeleton:    []*fragments.SkeletonBone{{Index: 0, Name: "IT10_DAG", MeshReference: &fragments.MeshReference{Mesh: mesh}}},
+		BoneMapping: map[int]string{0: "IT10_DAG"},
+		Animations:  map[string]*datatypes.Animation{},
+	}
+	skeleton.SetName("IT10_HS_DEF")
+
+	pose := datatypes.NewAnimation()
+	frame := datatypes.BoneTransform{Translation: datatypes.Vec3{Z: 10}, Rotation: datatypes.Quat{W: 1}, Scale: 1}
+	pose.AddTrack(&fragments.TrackFragment{TrackDefFragment: &fragments.TrackDefFragment{Frames: []datatypes.BoneTransform{frame}}},
+		"IT10_DAG", datatypes.CleanBoneName("IT10_DAG"), "root")
+	skeleton.Animations[defaultModelPoseAnimKey] = pose
+
+	bow := &fragments.Actor{SkeletonReference: &fragments.SkeletonHierarchyReference{SkeletonHierarchy: skeleton}}
+	meshes := getAttachmentMeshes(bow)
+	if len(meshes) != 1 {
+		t.Fatalf("Expected the bone mesh, got %d meshes", len(meshes))
+	}
+	if meshes[0].Vertices[2] != (fragments.Vec3{Z: 15}) || meshes[0].MaterialList != mesh.MaterialList {
+		t.Errorf("Expected the mesh to be posed by its bone, got %v", meshes[0].Vertices)
+	}
+	if mesh.Vertices[2] != (fragments.Vec3{Z: 5}) {
+		t.Errorf("Expected the equipment mesh to be unchanged, got %v", mesh.Vertices[2])
+	}
+
+	actor := &fragments.Actor{SkeletonReference: &fragments.SkeletonHierarchyReference{SkeletonHierarchy: newTestComposeSkeleton()}}
+	attachments := []Attachment{{Actor: bow, AttachPoint: AttachPointSecondary}}
+	if _, err := ExportComposedActor(actor, attachments, &config.Settings{}, t.TempDir()+"/", "hum_it10"); err != nil {
+		t.Errorf("Expected skeletal equipment to be composed: %v", err)
+	}
+
+	if getAttachmentMeshes(&fragments.Actor{}) != nil {
+		t.Error("Expected no meshes for an actor without a mesh or skeleton")
+	}
+}
@@ -0,0 +1,242 @@
+package exporters
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
+)
+
+// equipmentModelPattern matches the names of item models, e.g. it10.
+var equipmentModelPattern = regexp.MustCompile(`^it\d+$`)
+
+// Shields are much thinner than they are wide and roughly as tall as wide.
+const (
+	shieldMaxThicknessRatio = 0.35
+	shieldMinAspectRatio    = 0.6
+)
+
+// EquipmentManifest describes an equipment model for tools that place or render items.
+// Positions are in EQ model space.
+type EquipmentManifest struct {
+	Name                string                  `json:"name"`
+	ActorType           string                  `json:"actorType"`
+	BoundingBox         EquipmentBounds         `json:"boundingBox"`
+	BoundingRadius      float32                 `json:"boundingRadius"`
+	MaterialLists       []EquipmentMaterialList `json:"materialLists"`
+	ParticleBones       []EquipmentParticleBone `json:"particleBones"`
+	Animations          []string                `json:"animations"`
+	SuggestedAttachBone string                  `json:"suggestedAttachBone"`
+}
+
+// EquipmentBounds is an axis aligned bounding box.
+type EquipmentBounds struct {
+	Min [3]float32 `json:"min"`
+	Max [3]float32 `json:"max"`
+}
+
+// EquipmentMaterialList is a material list used by an equipment model.
+type EquipmentMaterialList struct {
+	Name      string              `json:"name"`
+	Materials []EquipmentMaterial `json:"materials"`
+}
+
+// EquipmentMaterial is a material with its shader and exported texture.
+type EquipmentMaterial struct {
+	Name    string `json:"name"`
+	Shader  string `json:"shader"`
+	Texture string `json:"texture,omitempty"`
+}
+
+// EquipmentParticleBone is a bone that emits a particle cloud, e.g. the glow of a weapon.
+type EquipmentParticleBone struct {
+	Bone          string `json:"bone"`
+	ParticleCloud string `json:"particleCloud"`
+}
+
+// NewEquipmentManifest builds the manifest of an equipment actor.
+// Returns nil if the actor has no meshes.
+func NewEquipmentManifest(actor *fragments.Actor) *EquipmentManifest {
+	meshes, skeleton := GetActorMeshes(actor)
+	if len(meshes) == 0 {
+		return nil
+	}
+
+	manifest := &EquipmentManifest{
+		Name:          helpers.CleanActorName(actor.GetName()),
+		ActorType:     "static",
+		MaterialLists: []EquipmentMaterialList{},
+		ParticleBones: []EquipmentParticleBone{},
+		Animations:    []string{},
+	}
+
+	seenMaterialLists := make(map[*fragments.MaterialList]bool)
+	for _, mesh := range meshes {
+		if mesh.MaterialList != nil && !seenMaterialLists[mesh.MaterialList] {
+			seenMaterialLists[mesh.MaterialList] = true
+			manifest.MaterialLists = append(manifest.MaterialLists, newEquipmentMaterialList(mesh.MaterialList))
+		}
+	}
+
+	manifest.BoundingBox, manifest.BoundingRadius = getMeshBounds(meshes)
+
+	if skeleton != nil {
+		// Skeletal mesh bounds are relative to their bones, so the item is measured in its pose
+		manifest.BoundingBox, manifest.BoundingRadius = getPosedBounds(GetPosedActorMeshes(actor, defaultModelPoseAnimKey, 0, false))
+		manifest.ActorType = "skeletal"
+		manifest.ParticleBones = getParticleBones(skeleton)
+
+		for animationKey := range skeleton.Animations {
+			if animationKey != defaultModelPoseAnimKey {
+				manifest.Animations = append(manifest.Animations, animationKey)
+			}
+		}
+		sort.Strings(manifest.Animations)
+	}
+
+	manifest.SuggestedAttachBone = suggestAttachBone(manifest.BoundingBox)
+	return manifest
+}
+
+// getMeshBounds returns the union of the stored mesh bounds and the largest mesh radius.
+func getMeshBounds(meshes []*fragments.Mesh) (EquipmentBounds, float32) {
+	bounds := newEmptyEquipmentBounds()
+	radius := float32(0)
+
+	for _, mesh := range meshes {
+		bounds.add(mesh.MinPosition)
+		bounds.add(mesh.MaxPosition)
+		if mesh.MaxDistance > radius {
+			radius = mesh.MaxDistance
+		}
+	}
+
+	return bounds, radius
+}
+
+// getPosedBounds returns the bounds of the posed vertices and their largest distance from the origin.
+func getPosedBounds(meshes []PosedMesh) (EquipmentBounds, float32) {
+	bounds := newEmptyEquipmentBounds()
+	radius := 0.0
+
+	for _, mesh := range meshes {
+		for _, position := range mesh.Positions {
+			bounds.add(position)
+			radius = math.Max(radius, math.Sqrt(float64(position.X*position.X+position.Y*position.Y+position.Z*position.Z)))
+		}
+	}
+
+	// Meshes without vertices have no extent
+	if bounds.Min[0] > bounds.Max[0] {
+		return EquipmentBounds{}, 0
+	}
+	return bounds, float32(radius)
+}
+
+// newEmptyEquipmentBounds returns bounds that any point extends.
+func newEmptyEquipmentBounds() EquipmentBounds {
+	return EquipmentBounds{
+		Min: [3]float32{math.MaxFloat32, math.MaxFloat32, math.MaxFloat32},
+		Max: [3]float32{-math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32},
+	}
+}
+
+// add extends the bounds to contain a point.
+func (b *EquipmentBounds) add(point fragments.Vec3) {
+	for axis, value := range [3]float32{point.X, point.Y, point.Z} {
+		b.Min[axis] = float32(math.Min(float64(b.Min[axis]), float64(value)))
+		b.Max[axis] = float32(math.Max(float64(b.Max[axis]), float64(value)))
+	}
+}
+
+// newEquipmentMaterialList describes a material list. Boundary materials are skipped.
+func newEquipmentMaterialList(materialList *fragments.MaterialList) EquipmentMaterialList {
+	list := EquipmentMaterialList{
+		Name:      helpers.CleanMaterialListName(materialList.GetName()),
+		Materials: []EquipmentMaterial{},
+	}
+
+	for _, material := range materialList.Materials {
+		if material == nil || material.ShaderType == fragments.ShaderTypeBoundary {
+			continue
+		}
+
+		list.Materials = append(list.Materials, EquipmentMaterial{
+			Name:    getMaterialName(material),
+			Shader:  material.ShaderType.String(),
+			Texture: gltfGetBitmapExportFilename(material),
+		})
+	}
+
+	return list
+}
+
+// getParticleBones returns the bones of a skeleton that reference a particle cloud.
+func getParticleBones(skeleton *fragments.SkeletonHierarchy) []EquipmentParticleBone {
+	bones := []EquipmentParticleBone{}
+	boneNames := getUniqueBoneNames(skeleton)
+
+	for i, bone := range skeleton.Skeleton {
+		if bone.ParticleCloud == nil {
+			continue
+		}
+
+		bones = append(bones, EquipmentParticleBone{
+			Bone:          boneNames[i],
+			ParticleCloud: helpers.CleanName(bone.ParticleCloud.GetName(), "", true),
+		})
+	}
+
+	return bones
+}
+
+// suggestAttachBone guesses the character bone an item is held by from its shape.
+// Flat items about as tall as they are wide are worn as shields, everything else is held in the right hand.
+func suggestAttachBone(bounds EquipmentBounds) string {
+	extents := []float64{
+		float64(bounds.Max[0] - bounds.Min[0]),
+		float64(bounds.Max[1] - bounds.Min[1]),
+		float64(bounds.Max[2] - bounds.Min[2]),
+	}
+	sort.Float64s(extents)
+
+	if extents[2] > 0 && extents[0] < extents[1]*shieldMaxThicknessRatio && extents[1] > extents[2]*shieldMinAspectRatio {
+		return AttachPointShield
+	}
+	return AttachPointPrimary
+}
+
+// ExportEquipmentManifests writes a JSON manifest for each item model to the 'Manifests' folder.
+func ExportEquipmentManifests(actors []*fragments.Actor, exportFolder string) error {
+	for _, actor := range actors {
+		if !equipmentModelPattern.MatchString(helpers.CleanActorName(actor.GetName())) {
+			continue
+		}
+
+		manifest := NewEquipmentManifest(actor)
+		if manifest == nil {
+			continue
+		}
+
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode equipment manifest %s: %w", manifest.Name, err)
+		}
+
+		filePath := filepath.Join(exportFolder, "Manifests", manifest.Name+".json")
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			return fmt.Errorf("failed to create manifest directory: %w", err)
+		}
+		if err := os.WriteFile(filePath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write equipment manifest %s: %w", manifest.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package exporters
+
+import (
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+func TestSuggestAttachBone(t *testing.T) {
+	tests := []struct {
+		name     string
+		max      [3]float32
+		expected string
+	}{
+		{"round shield", [3]float32{4, 0.5, 5}, AttachPointShield},
+		{"sword", [3]float32{0.5, 0.5, 10}, AttachPointPrimary},
+		{"flat plank", [3]float32{1, 0.1, 10}, AttachPointPrimary},
+		{"thick box", [3]float32{4, 3, 5}, AttachPointPrimary},
+		{"empty", [3]float32{}, AttachPointPrimary},
+	}
+
+	for _, test := range tests {
+		if got := suggestAttachBone(EquipmentBounds{Max: test.max}); got != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestEquipmentManifestPosedBounds(t *testing.T) {
+	// Both halves of the shield are modeled at their bone, the pose moves the right half next to the left
+	newHalf := func() *fragments.Mesh {
+		return &fragments.Mesh{
+			Vertices:    []fragments.Vec3{{X: 0, Y: 0, Z: 0}, {X: 2, Y: 0, Z: 0}, {X: 0, Y: 0.5, Z: 5}, {X: 2, Y: 0.5, Z: 5}},
+			MinPosition: fragments.Vec3{X: 0, Y: 0, Z: 0},
+			MaxPosition: fragments.Vec3{X: 2, Y: 0.5, Z: 5},
+		}
+	}
+
+	root := &fragments.SkeletonBone{Index: 0, Name: "ROOT_DAG", MeshReference: &fragments.MeshReference{Mesh: newHalf()}}
+	right := &fragments.SkeletonBone{Index: 1, Name: "RIGHT_DAG", Parent: root, MeshReference: &fragments.MeshReference{Mesh: newHalf()}}
+	root.Children = []int{1}
+	skeleton := &fragments.SkeletonHierarchy{
+		Skeleton:    []*fragments.SkeletonBone{root, right},
+		BoneMapping: map[int]string{0: "ROOT_DAG", 1: "RIGHT_DAG"},
+		Animations:  map[string]*datatypes.Animation{},
+	}
+
+	identity := datatypes.BoneTransform{Rotation: datatypes.Quat{W: 1}, Scale: 1}
+	moved := identity
+	moved.Translation = datatypes.Vec3{X: 2}
+
+	pose := datatypes.NewAnimation()
+	pose.AddTrack(&fragments.TrackFragment{TrackDefFragment: &fragments.TrackDefFragment{Frames: []datatypes.BoneTransform{identity}}},
+		"ROOT_DAG", datatypes.CleanBoneName("ROOT_DAG"), "root")
+	pose.AddTrack(&fragments.TrackFragment{TrackDefFragment: &fragments.TrackDefFragment{Frames: []datatypes.BoneTransform{moved}}},
+		"RIGHT_DAG", datatypes.CleanBoneName("RIGHT_DAG"), "right")
+	skeleton.Animations[defaultModelPoseAnimKey] = pose
+
+	actor := &fragments.Actor{
+		ActorType:         datatypes.ActorTypeSkeletal,
+		SkeletonReference: &fragments.SkeletonHierarchyReference{SkeletonHierarchy: skeleton},
+	}
+	actor.SetName("IT200_ACTORDEF")
+
+	manifest := NewEquipmentManifest(actor)
+	if manifest == nil {
+		t.Fatal("Expected a manifest")
+	}
+	if manifest.ActorType != "skeletal" {
+		t.Errorf("Expected a skeletal actor, got %s", manifest.ActorType)
+	}
+
+	// The bone local bounds of either half alone look like a weapon
+	expected := EquipmentBounds{Max: [3]float32{4, 0.5, 5}}
+	if manifest.BoundingBox != expected {
+		t.Errorf("Expected the posed bounds %v, got %v", expected, manifest.BoundingBox)
+	}
+	if manifest.SuggestedAttachBone != AttachPointShield {
+		t.Errorf("Expected the posed shield to be attached as a shield, got %s", manifest.SuggestedAttachBone)
+	}
+}
@@ -0,0 +1,754 @@
+package exporters
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
+)
+
+// fbxTicksPerMs is the number of FBX time units (KTime) in a millisecond.
+const fbxTicksPerMs int64 = 46186158
+
+// fbxFirstObjectID is the first object ID. ID 0 is the scene root.
+const fbxFirstObjectID int64 = 1000000
+
+// FbxWriter exports a skinned skeleton and its animations to the ASCII FBX 7.4 format.
+// The model is converted to Y-up like the glTF export. Every animation becomes an animation stack.
+type FbxWriter struct {
+	TextAssetWriter
+	isCharacterAnimation bool
+	exportHiddenGeometry bool
+
+	objects     strings.Builder
+	connections strings.Builder
+	counts      map[string]int
+	nextID      int64
+
+	// materialIDs maps material names to their FBX material object.
+	materialIDs map[string]int64
+}
+
+// fbxMesh is a skeleton mesh with the bone moving each of its vertices.
+type fbxMesh struct {
+	mesh *fragments.Mesh
+
+	// singularBoneIndex is the bone of a bone mesh, or -1 for meshes skinned with mob pieces.
+	singularBoneIndex int
+}
+
+// NewFbxWriter creates a new FbxWriter.
+func NewFbxWriter(isCharacterAnimation bool, exportHiddenGeometry bool) *FbxWriter {
+	return &FbxWriter{
+		isCharacterAnimation: isCharacterAnimation,
+		exportHiddenGeometry: exportHiddenGeometry,
+	}
+}
+
+// AddFragmentData adds the skeleton, skinned meshes and animations of a skeleton hierarchy.
+func (w *FbxWriter) AddFragmentData(data fragments.Fragment) {
+	skeleton, ok := data.(*fragments.SkeletonHierarchy)
+	if !ok || skeleton == nil || len(skeleton.Skeleton) == 0 {
+		return
+	}
+
+	w.objects.Reset()
+	w.connections.Reset()
+	w.counts = make(map[string]int)
+	w.materialIDs = make(map[string]int64)
+	w.nextID = fbxFirstObjectID
+
+	restPose := w.getRestPose(skeleton)
+	boneWorld := getBoneWorldMatrices(skeleton, restPose)
+	boneNames := getUniqueBoneNames(skeleton)
+
+	boneIDs := w.addBones(skeleton, boneNames, restPose)
+
+	var meshIDs []int64
+	for _, skinned := range getSkeletonFbxMeshes(skeleton) {
+		if meshID, ok := w.addMesh(skinned, boneWorld, boneIDs, boneNames); ok {
+			meshIDs = append(meshIDs, meshID)
+		}
+	}
+
+	w.addBindPose(helpers.CleanSkeletonName(skeleton.GetName()), meshIDs, boneIDs, boneWorld)
+
+	for _, animationKey := range getExportedAnimationKeys(skeleton, w.isCharacterAnimation) {
+		w.addAnimationStack(skeleton, animationKey, boneIDs)
+	}
+
+	w.writeHeader()
+	w.writeDefinitions()
+	w.AppendLine("Objects:  {")
+	w.AppendString(w.objects.String())
+	w.AppendLine("}")
+	w.AppendLine("")
+	w.AppendLine("Connections:  {")
+	w.AppendString(w.connections.String())
+	w.AppendLine("}")
+}
+
+// getRestPose returns the local bone transforms of the first frame of the pose.
+// Skeletons without a pose get identity transforms.
+func (w *FbxWriter) getRestPose(skeleton *fragments.SkeletonHierarchy) []datatypes.BoneTransform {
+	if pose := sampleSkeletonAnimation(skeleton, defaultModelPoseAnimKey, w.isCharacterAnimation); pose != nil {
+		return pose.frames[0]
+	}
+
+	restPose := make([]datatypes.BoneTransform, len(skeleton.Skeleton))
+	for i := range restPose {
+		restPose[i] = datatypes.BoneTransform{Rotation: datatypes.Quat{W: 1}, Scale: 1}
+	}
+	return restPose
+}
+
+// getSkeletonFbxMeshes returns the bone meshes and skinned meshes of a skeleton.
+func getSkeletonFbxMeshes(skeleton *fragments.SkeletonHierarchy) []fbxMesh {
+	var meshes []fbxMesh
+
+	for i, bone := range skeleton.Skeleton {
+		if mesh := getMeshFromBone(bone); mesh != nil {
+			meshes = append(meshes, fbxMesh{mesh: mesh, singularBoneIndex: i})
+		}
+	}
+
+	for _, meshFrag := range skeleton.Meshes {
+		if mesh, ok := meshFrag.(*fragments.Mesh); ok {
+			meshes = append(meshes, fbxMesh{mesh: mesh, singularBoneIndex: -1})
+		}
+	}
+
+	return meshes
+}
+
+// addBones adds a limb node model for each bone and parents it to its parent bone.
+// Returns the model ID of each bone.
+func (w *FbxWriter) addBones(skeleton *fragments.SkeletonHierarchy, boneNames []string, restPose []datatypes.BoneTransform) []int64 {
+	boneIDs := make([]int64, len(skeleton.Skeleton))
+	for i := range skeleton.Skeleton {
+		boneIDs[i] = w.newID()
+	}
+
+	for i, bone := range skeleton.Skeleton {
+		attributeID := w.newID()
+		w.addObject("NodeAttribute")
+		w.objects.WriteString(fmt.Sprintf("\tNodeAttribute: %d, \"NodeAttribute::%s\", \"LimbNode\" {\n", attributeID, boneNames[i]))
+		w.objects.WriteString("\t\tTypeFlags: \"Skeleton\"\n")
+		w.objects.WriteString("\t}\n")
+
+		translation, rotation, scale := helpers.BoneLocalTRSGltf(restPose[i])
+		angles := eulerXYZDegrees(rotation)
+
+		w.addObject("Model")
+		w.objects.WriteString(fmt.Sprintf("\tModel: %d, \"Model::%s\", \"LimbNode\" {\n", boneIDs[i], boneNames[i]))
+		w.objects.WriteString("\t\tVersion: 232\n")
+		w.objects.WriteString("\t\tProperties70:  {\n")
+		w.writeVectorProperty("Lcl Translation", float64(translation[0]), float64(translation[1]), float64(translation[2]))
+		w.writeVectorProperty("Lcl Rotation", angles[0], angles[1], angles[2])
+		w.writeVectorProperty("Lcl Scaling", float64(scale[0]), float64(scale[1]), float64(scale[2]))
+		w.objects.WriteString("\t\t}\n")
+		w.objects.WriteString("\t\tShading: Y\n")
+		w.objects.WriteString("\t\tCulling: \"CullingOff\"\n")
+		w.objects.WriteString("\t}\n")
+
+		w.connect(attributeID, boneIDs[i])
+
+		parentID := int64(0)
+		if bone.Parent != nil && bone.Parent.Index >= 0 && bone.Parent.Index < len(boneIDs) && bone.Parent.Index != i {
+			parentID = boneIDs[bone.Parent.Index]
+		}
+		w.connect(boneIDs[i], parentID)
+	}
+
+	return boneIDs
+}
+
+// writeVectorProperty writes an animatable vector property of a model.
+func (w *FbxWriter) writeVectorProperty(name string, x, y, z float64) {
+	w.objects.WriteString(fmt.Sprintf("\t\t\tP: \"%s\", \"%s\", \"\", \"A\",%s,%s,%s\n",
+		name, name, formatFbxFloat(x), formatFbxFloat(y), formatFbxFloat(z)))
+}
+
+// addMesh adds the geometry, model, materials and skin of a skeleton mesh in the bind pose.
+// Returns the mesh model ID and false if the mesh has no exported polygons.
+func (w *FbxWriter) addMesh(skinned fbxMesh, boneWorld []datatypes.Mat4, boneIDs []int64, boneNames []string) (int64, bool) {
+	mesh := skinned.mesh
+
+	var polygonIndices []int
+	var normals []float64
+	var polygonMaterials []int
+	var materials []*fragments.Material
+	materialSlots := make(map[string]int)
+
+	polygonIndex := 0
+	for _, materialGroup := range mesh.MaterialGroups {
+		start := polygonIndex
+		polygonIndex += materialGroup.PolygonCount
+
+		if mesh.MaterialList == nil || materialGroup.MaterialIndex >= len(mesh.MaterialList.Materials) {
+			continue
+		}
+
+		material := mesh.MaterialList.Materials[materialGroup.MaterialIndex]
+		if material == nil || material.ShaderType == fragments.ShaderTypeBoundary {
+			continue
+		}
+		if material.ShaderType == fragments.ShaderTypeInvisible && !w.exportHiddenGeometry {
+			continue
+		}
+
+		materialName := getMaterialName(material)
+		slot, exists := materialSlots[materialName]
+		if !exists {
+			slot = len(materials)
+			materialSlots[materialName] = slot
+			materials = append(materials, material)
+		}
+
+		for i := start; i < polygonIndex && i < len(mesh.Indices); i++ {
+			polygon := mesh.Indices[i]
+
+			// Reverse the winding like the OBJ export
+			vertices := [3]int{polygon.Vertex3, polygon.Vertex2, polygon.Vertex1}
+			if !validVertexIndices(mesh, vertices) {
+				continue
+			}
+
+			for corner, vertex := range vertices {
+				index := vertex
+				if corner == 2 {
+					// The last index of a polygon is stored as its bitwise complement
+					index = -vertex - 1
+				}
+				polygonIndices = append(polygonIndices, index)
+
+				normal := w.getBindPoseNormal(mesh, vertex, boneWorld[getSkinnedVertexBone(skinned, vertex, len(boneWorld))])
+				normals = append(normals, normal[0], normal[1], normal[2])
+			}
+			polygonMaterials = append(polygonMaterials, slot)
+		}
+	}
+
+	if len(polygonMaterials) == 0 {
+		return 0, false
+	}
+
+	meshName := helpers.CleanMeshName(mesh.GetName())
+	geometryID := w.newID()
+	modelID := w.newID()
+
+	positions := make([]float64, 0, len(mesh.Vertices)*3)
+	uvs := make([]float64, 0, len(mesh.Vertices)*2)
+	boneVertices := make(map[int][]int)
+	for i, vertex := range mesh.Vertices {
+		boneIndex := getSkinnedVertexBone(skinned, i, len(boneWorld))
+		boneVertices[boneIndex] = append(boneVertices[boneIndex], i)
+
+		position := transformPoint(boneWorld[boneIndex], vertex)
+		position.X += mesh.Center.X
+		position.Y += mesh.Center.Y
+		position.Z += mesh.Center.Z
+		converted := yUpVector(position)
+		positions = append(positions, converted[0], converted[1], converted[2])
+
+		if i < len(mesh.TextureUvCoordinates) {
+			uv := mesh.TextureUvCoordinates[i]
+			uvs = append(uvs, float64(uv.X), float64(uv.Y))
+		} else {
+			uvs = append(uvs, 0, 0)
+		}
+	}
+
+	uvIndices := make([]int, len(polygonIndices))
+	for i, index := range polygonIndices {
+		if index < 0 {
+			index = -index - 1
+		}
+		uvIndices[i] = index
+	}
+
+	w.addObject("Geometry")
+	w.objects.WriteString(fmt.Sprintf("\tGeometry: %d, \"Geometry::%s\", \"Mesh\" {\n", geometryID, meshName))
+	w.writeFloatArray("\t\t", "Vertices", positions)
+	w.writeIntArray("\t\t", "PolygonVertexIndex", polygonIndices)
+	w.objects.WriteString("\t\tGeometryVersion: 124\n")
+
+	w.objects.WriteString("\t\tLayerElementNormal: 0 {\n")
+	w.objects.WriteString("\t\t\tVersion: 101\n")
+	w.objects.WriteString("\t\t\tName: \"\"\n")
+	w.objects.WriteString("\t\t\tMappingInformationType: \"ByPolygonVertex\"\n")
+	w.objects.WriteString("\t\t\tReferenceInformationType: \"Direct\"\n")
+	w.writeFloatArray("\t\t\t", "Normals", normals)
+	w.objects.WriteString("\t\t}\n")
+
+	w.objects.WriteString("\t\tLayerElementUV: 0 {\n")
+	w.objects.WriteString("\t\t\tVersion: 101\n")
+	w.objects.WriteString("\t\t\tName: \"UVMap\"\n")
+	w.objects.WriteString("\t\t\tMappingInformationType: \"ByPolygonVertex\"\n")
+	w.objects.WriteString("\t\t\tReferenceInformationType: \"IndexToDirect\"\n")
+	w.writeFloatArray("\t\t\t", "UV", uvs)
+	w.writeIntArray("\t\t\t", "UVIndex", uvIndices)
+	w.objects.WriteString("\t\t}\n")
+
+	w.objects.WriteString("\t\tLayerElementMaterial: 0 {\n")
+	w.objects.WriteString("\t\t\tVersion: 101\n")
+	w.objects.WriteString("\t\t\tName: \"\"\n")
+	w.objects.WriteString("\t\t\tMappingInformationType: \"ByPolygon\"\n")
+	w.objects.WriteString("\t\t\tReferenceInformationType: \"IndexToDirect\"\n")
+	w.writeIntArray("\t\t\t", "Materials", polygonMaterials)
+	w.objects.WriteString("\t\t}\n")
+
+	w.objects.WriteString("\t\tLayer: 0 {\n")
+	w.objects.WriteString("\t\t\tVersion: 100\n")
+	for _, layerElement := range []string{"LayerElementNormal", "LayerElementUV", "LayerElementMaterial"} {
+		w.objects.WriteString("\t\t\tLayerElement:  {\n")
+		w.objects.WriteString(fmt.Sprintf("\t\t\t\tType: \"%s\"\n", layerElement))
+		w.objects.WriteString("\t\t\t\tTypedIndex: 0\n")
+		w.objects.WriteString("\t\t\t}\n")
+	}
+	w.objects.WriteString("\t\t}\n")
+	w.objects.WriteString("\t}\n")
+
+	w.addObject("Model")
+	w.objects.WriteString(fmt.Sprintf("\tModel: %d, \"Model::%s\", \"Mesh\" {\n", modelID, meshName))
+	w.objects.WriteString("\t\tVersion: 232\n")
+	w.objects.WriteString("\t\tProperties70:  {\n")
+	w.objects.WriteString("\t\t}\n")
+	w.objects.WriteString("\t\tShading: T\n")
+	w.objects.WriteString("\t\tCulling: \"CullingOff\"\n")
+	w.objects.WriteString("\t}\n")
+
+	w.connect(modelID, 0)
+	w.connect(geometryID, modelID)
+
+	// Materials are connected in the order of their layer element slots
+	for _, material := range materials {
+		w.connect(w.getMaterialID(material), modelID)
+	}
+
+	w.addSkin(meshName, geometryID, boneVertices, boneWorld, boneIDs, boneNames)
+
+	return modelID, true
+}
+
+// getSkinnedVertexBone returns the bone moving a vertex of a skeleton mesh.
+func getSkinnedVertexBone(skinned fbxMesh, vertexIndex int, boneCount int) int {
+	boneIndex := skinned.singularBoneIndex
+	if boneIndex < 0 {
+		boneIndex = getBoneIndexForVertex(skinned.mesh, vertexIndex)
+	}
+
+	if boneIndex < 0 || boneIndex >= boneCount {
+		return 0
+	}
+	return boneIndex
+}
+
+// getBindPoseNormal returns the Y-up normal of a vertex rotated by its bone.
+// Stored normals point inwards and are flipped.
+func (w *FbxWriter) getBindPoseNormal(mesh *fragments.Mesh, vertexIndex int, boneMatrix datatypes.Mat4) [3]float64 {
+	if vertexIndex >= len(mesh.Normals) {
+		return [3]float64{0, 1, 0}
+	}
+
+	n := mesh.Normals[vertexIndex]
+	x := -(boneMatrix[0]*n.X + boneMatrix[4]*n.Y + boneMatrix[8]*n.Z)
+	y := -(boneMatrix[1]*n.X + boneMatrix[5]*n.Y + boneMatrix[9]*n.Z)
+	z := -(boneMatrix[2]*n.X + boneMatrix[6]*n.Y + boneMatrix[10]*n.Z)
+
+	normal := yUpVector(fragments.Vec3{X: x, Y: y, Z: z})
+	length := math.Sqrt(normal[0]*normal[0] + normal[1]*normal[1] + normal[2]*normal[2])
+	if length == 0 {
+		return [3]float64{0, 1, 0}
+	}
+	return [3]float64{normal[0] / length, normal[1] / length, normal[2] / length}
+}
+
+// getMaterialID returns the ID of the FBX material for an EQ material, adding it on first use.
+// Materials with a bitmap get a texture pointing at the exported image in the 'Textures' folder.
+func (w *FbxWriter) getMaterialID(material *fragments.Material) int64 {
+	materialName := getMaterialName(material)
+	if id, exists := w.materialIDs[materialName]; exists {
+		return id
+	}
+
+	materialID := w.newID()
+	w.materialIDs[materialName] = materialID
+
+	w.addObject("Material")
+	w.objects.WriteString(fmt.Sprintf("\tMaterial: %d, \"Material::%s\", \"\" {\n", materialID, materialName))
+	w.objects.WriteString("\t\tVersion: 102\n")
+	w.objects.WriteString("\t\tShadingModel: \"lambert\"\n")
+	w.objects.WriteString("\t\tMultiLayer: 0\n")
+	w.objects.WriteString("\t\tProperties70:  {\n")
+	w.objects.WriteString("\t\t\tP: \"DiffuseColor\", \"Color\", \"\", \"A\",1,1,1\n")
+	w.objects.WriteString("\t\t}\n")
+	w.objects.WriteString("\t}\n")
+
+	imageFileName := gltfGetBitmapExportFilename(material)
+	if imageFileName == "" {
+		return materialID
+	}
+
+	textureID := w.newID()
+	videoID := w.newID()
+	relativePath := "Textures/" + imageFileName
+
+	w.addObject("Video")
+	w.objects.WriteString(fmt.Sprintf("\tVideo: %d, \"Video::%s\", \"Clip\" {\n", videoID, materialName))
+	w.objects.WriteString("\t\tType: \"Clip\"\n")
+	w.objects.WriteString(fmt.Sprintf("\t\tFileName: \"%s\"\n", relativePath))
+	w.objects.WriteString(fmt.Sprintf("\t\tRelativeFilename: \"%s\"\n", relativePath))
+	w.objects.WriteString("\t}\n")
+
+	w.addObject("Texture")
+	w.objects.WriteString(fmt.Sprintf("\tTexture: %d, \"Texture::%s\", \"\" {\n", textureID, materialName))
+	w.objects.WriteString("\t\tType: \"TextureVideoClip\"\n")
+	w.objects.WriteString("\t\tVersion: 202\n")
+	w.objects.WriteString(fmt.Sprintf("\t\tTextureName: \"Texture::%s\"\n", materialName))
+	w.objects.WriteString(fmt.Sprintf("\t\tMedia: \"Video::%s\"\n", materialName))
+	w.objects.WriteString(fmt.Sprintf("\t\tFileName: \"%s\"\n", relativePath))
+	w.objects.WriteString(fmt.Sprintf("\t\tRelativeFilename: \"%s\"\n", relativePath))
+	w.objects.WriteString("\t}\n")
+
+	w.connect(videoID, textureID)
+	w.connectProperty(textureID, materialID, "DiffuseColor")
+
+	return materialID
+}
+
+// addSkin adds a skin deformer to a geometry with a cluster for each bone moving its vertices.
+// Every vertex is fully weighted to a single bone.
+func (w *FbxWriter) addSkin(meshName string, geometryID int64, boneVertices map[int][]int,
+	boneWorld []datatypes.Mat4, boneIDs []int64, boneNames []string) {
+	skinID := w.newID()
+
+	w.addObject("Deformer")
+	w.objects.WriteString(fmt.Sprintf("\tDeformer: %d, \"Deformer::%s\", \"Skin\" {\n", skinID, meshName))
+	w.objects.WriteString("\t\tVersion: 101\n")
+	w.objects.WriteString("\t\tLink_DeformAcuracy: 50\n")
+	w.objects.WriteString("\t}\n")
+	w.connect(skinID, geometryID)
+
+	for boneIndex := range boneWorld {
+		vertices, exists := boneVertices[boneIndex]
+		if !exists {
+			continue
+		}
+
+		weights := make([]float64, len(vertices))
+		for i := range weights {
+			weights[i] = 1
+		}
+
+		transformLink := yUpMatrix(boneWorld[boneIndex])
+		transform := invertAffine(transformLink)
+
+		clusterID := w.newID()
+		w.addObject("Deformer")
+		w.objects.WriteString(fmt.Sprintf("\tDeformer: %d, \"SubDeformer::%s\", \"Cluster\" {\n", clusterID, boneNames[boneIndex]))
+		w.objects.WriteString("\t\tVersion: 100\n")
+		w.objects.WriteString("\t\tUserData: \"\", \"\"\n")
+		w.writeIntArray("\t\t", "Indexes", vertices)
+		w.writeFloatArray("\t\t", "Weights", weights)
+		w.writeMatrix("\t\t", "Transform", transform)
+		w.writeMatrix("\t\t", "TransformLink", transformLink)
+		w.objects.WriteString("\t}\n")
+
+		w.connect(clusterID, skinID)
+		w.connect(boneIDs[boneIndex], clusterID)
+	}
+}
+
+// addBindPose adds the bind pose with the world matrices of the meshes and bones.
+func (w *FbxWriter) addBindPose(name string, meshIDs []int64, boneIDs []int64, boneWorld []datatypes.Mat4) {
+	poseID := w.newID()
+
+	w.addObject("Pose")
+	w.objects.WriteString(fmt.Sprintf("\tPose: %d, \"Pose::%s\", \"BindPose\" {\n", poseID, name))
+	w.objects.WriteString("\t\tType: \"BindPose\"\n")
+	w.objects.WriteString("\t\tVersion: 100\n")
+	w.objects.WriteString(fmt.Sprintf("\t\tNbPoseNodes: %d\n", len(meshIDs)+len(boneIDs)))
+
+	writePoseNode := func(id int64, matrix datatypes.Mat4) {
+		w.objects.WriteString("\t\tPoseNode:  {\n")
+		w.objects.WriteString(fmt.Sprintf("\t\t\tNode: %d\n", id))
+		w.writeMatrix("\t\t\t", "Matrix", matrix)
+		w.objects.WriteString("\t\t}\n")
+	}
+
+	for _, meshID := range meshIDs {
+		writePoseNode(meshID, helpers.Mat4Identity())
+	}
+	for i, boneID := range boneIDs {
+		writePoseNode(boneID, yUpMatrix(boneWorld[i]))
+	}
+
+	w.objects.WriteString("\t}\n")
+}
+
+// addAnimationStack adds an animation as a stack with a single layer holding
+// translation, rotation and scaling curves for every bone.
+func (w *FbxWriter) addAnimationStack(skeleton *fragments.SkeletonHierarchy, animationKey string, boneIDs []int64) {
+	sampled := sampleSkeletonAnimation(skeleton, animationKey, w.isCharacterAnimation)
+	if sampled == nil {
+		return
+	}
+
+	frameCount := len(sampled.frames)
+	keyTimes := make([]int64, frameCount)
+	for frame := range keyTimes {
+		keyTimes[frame] = int64(frame*sampled.frameTimeMs) * fbxTicksPerMs
+	}
+	stopTime := keyTimes[frameCount-1]
+
+	stackID := w.newID()
+	layerID := w.newID()
+
+	w.addObject("AnimationStack")
+	w.objects.WriteString(fmt.Sprintf("\tAnimationStack: %d, \"AnimStack::%s\", \"\" {\n", stackID, animationKey))
+	w.objects.WriteString("\t\tProperties70:  {\n")
+	w.objects.WriteString(fmt.Sprintf("\t\t\tP: \"LocalStop\", \"KTime\", \"Time\", \"\",%d\n", stopTime))
+	w.objects.WriteString(fmt.Sprintf("\t\t\tP: \"ReferenceStop\", \"KTime\", \"Time\", \"\",%d\n", stopTime))
+	w.objects.WriteString("\t\t}\n")
+	w.objects.WriteString("\t}\n")
+
+	w.addObject("AnimationLayer")
+	w.objects.WriteString(fmt.Sprintf("\tAnimationLayer: %d, \"AnimLayer::BaseLayer\", \"\" {\n", layerID))
+	w.objects.WriteString("\t}\n")
+	w.connect(layerID, stackID)
+
+	for boneIndex, boneID := range boneIDs {
+		translations := make([][3]float64, frameCount)
+		rotations := make([][3]float64, frameCount)
+		scales := make([][3]float64, frameCount)
+
+		for frame, transforms := range sampled.frames {
+			translation, rotation, scale := helpers.BoneLocalTRSGltf(transforms[boneIndex])
+			translations[frame] = [3]float64{float64(translation[0]), float64(translation[1]), float64(translation[2])}
+			scales[frame] = [3]float64{float64(scale[0]), float64(scale[1]), float64(scale[2])}
+
+			rotations[frame] = eulerXYZDegrees(rotation)
+			if frame > 0 {
+				for axis := range rotations[frame] {
+					rotations[frame][axis] = unwrapDegrees(rotations[frame][axis], rotations[frame-1][axis])
+				}
+			}
+		}
+
+		w.addCurveNode(layerID, boneID, "T", "Lcl Translation", keyTimes, translations)
+		w.addCurveNode(layerID, boneID, "R", "Lcl Rotation", keyTimes, rotations)
+		w.addCurveNode(layerID, boneID, "S", "Lcl Scaling", keyTimes, scales)
+	}
+}
+
+// addCurveNode adds a curve node animating a vector property of a model with a linear curve per axis.
+func (w *FbxWriter) addCurveNode(layerID, modelID int64, name, property string, keyTimes []int64, values [][3]float64) {
+	curveNodeID := w.newID()
+
+	w.addObject("AnimationCurveNode")
+	w.objects.WriteString(fmt.Sprintf("\tAnimationCurveNode: %d, \"AnimCurveNode::%s\", \"\" {\n", curveNodeID, name))
+	w.objects.WriteString("\t\tProperties70:  {\n")
+	for axis, axisName := range []string{"X", "Y", "Z"} {
+		w.objects.WriteString(fmt.Sprintf("\t\t\tP: \"d|%s\", \"Number\", \"\", \"A\",%s\n", axisName, formatFbxFloat(values[0][axis])))
+	}
+	w.objects.WriteString("\t\t}\n")
+	w.objects.WriteString("\t}\n")
+
+	w.connect(curveNodeID, layerID)
+	w.connectProperty(curveNodeID, modelID, property)
+
+	for axis, axisName := range []string{"X", "Y", "Z"} {
+		axisValues := make([]float64, len(values))
+		for frame := range values {
+			axisValues[frame] = values[frame][axis]
+		}
+
+		curveID := w.newID()
+		w.addObject("AnimationCurve")
+		w.objects.WriteString(fmt.Sprintf("\tAnimationCurve: %d, \"AnimCurve::\", \"\" {\n", curveID))
+		w.objects.WriteString(fmt.Sprintf("\t\tDefault: %s\n", formatFbxFloat(axisValues[0])))
+		w.objects.WriteString("\t\tKeyVer: 4009\n")
+		w.writeInt64Array("\t\t", "KeyTime", keyTimes)
+		w.writeFloatArray("\t\t", "KeyValueFloat", axisValues)
+		// All keys share linear interpolation
+		w.writeIntArray("\t\t", "KeyAttrFlags", []int{24836})
+		w.writeIntArray("\t\t", "KeyAttrDataFloat", []int{0, 0, 218434821, 0})
+		w.writeIntArray("\t\t", "KeyAttrRefCount", []int{len(keyTimes)})
+		w.objects.WriteString("\t}\n")
+
+		w.connectProperty(curveID, curveNodeID, "d|"+axisName)
+	}
+}
+
+// writeHeader writes the file header and the Y-up global settings.
+func (w *FbxWriter) writeHeader() {
+	w.AppendLine("; FBX 7.4.0 project file")
+	w.AppendLine("; " + strings.TrimPrefix(ExportHeaderTitle, "# ") + "FBX")
+	w.AppendLine("")
+	w.AppendLine("FBXHeaderExtension:  {")
+	w.AppendLine("\tFBXHeaderVersion: 1003")
+	w.AppendLine("\tFBXVersion: 7400")
+	w.AppendLine("\tCreator: \"Lantern Extractor\"")
+	w.AppendLine("}")
+	w.AppendLine("")
+	w.AppendLine("GlobalSettings:  {")
+	w.AppendLine("\tVersion: 1000")
+	w.AppendLine("\tProperties70:  {")
+	w.AppendLine("\t\tP: \"UpAxis\", \"int\", \"Integer\", \"\",1")
+	w.AppendLine("\t\tP: \"UpAxisSign\", \"int\", \"Integer\", \"\",1")
+	w.AppendLine("\t\tP: \"FrontAxis\", \"int\", \"Integer\", \"\",2")
+	w.AppendLine("\t\tP: \"FrontAxisSign\", \"int\", \"Integer\", \"\",1")
+	w.AppendLine("\t\tP: \"CoordAxis\", \"int\", \"Integer\", \"\",0")
+	w.AppendLine("\t\tP: \"CoordAxisSign\", \"int\", \"Integer\", \"\",1")
+	w.AppendLine("\t\tP: \"UnitScaleFactor\", \"double\", \"Number\", \"\",1")
+	w.AppendLine("\t}")
+	w.AppendLine("}")
+	w.AppendLine("")
+}
+
+// writeDefinitions writes the number of objects of each type.
+func (w *FbxWriter) writeDefinitions() {
+	objectTypes := []string{
+		"Model", "NodeAttribute", "Geometry", "Material", "Texture", "Video", "Deformer", "Pose",
+		"AnimationStack", "AnimationLayer", "AnimationCurveNode", "AnimationCurve",
+	}
+
+	total := 1
+	for _, objectType := range objectTypes {
+		total += w.counts[objectType]
+	}
+
+	w.AppendLine("Definitions:  {")
+	w.AppendLine("\tVersion: 100")
+	w.AppendLine(fmt.Sprintf("\tCount: %d", total))
+	w.AppendLine("\tObjectType: \"GlobalSettings\" {")
+	w.AppendLine("\t\tCount: 1")
+	w.AppendLine("\t}")
+	for _, objectType := range objectTypes {
+		if w.counts[objectType] == 0 {
+			continue
+		}
+		w.AppendLine(fmt.Sprintf("\tObjectType: \"%s\" {", objectType))
+		w.AppendLine(fmt.Sprintf("\t\tCount: %d", w.counts[objectType]))
+		w.AppendLine("\t}")
+	}
+	w.AppendLine("}")
+	w.AppendLine("")
+}
+
+// newID returns a new unique object ID.
+func (w *FbxWriter) newID() int64 {
+	w.nextID++
+	return w.nextID
+}
+
+// addObject counts an object of the given type for the definitions.
+func (w *FbxWriter) addObject(objectType string) {
+	w.counts[objectType]++
+}
+
+// connect connects a child object to a parent object.
+func (w *FbxWriter) connect(childID, parentID int64) {
+	w.connections.WriteString(fmt.Sprintf("\tC: \"OO\",%d,%d\n", childID, parentID))
+}
+
+// connectProperty connects a child object to a property of a parent object.
+func (w *FbxWriter) connectProperty(childID, parentID int64, property string) {
+	w.connections.WriteString(fmt.Sprintf("\tC: \"OP\",%d,%d,\"%s\"\n", childID, parentID, property))
+}
+
+// writeFloatArray writes a named float array.
+func (w *FbxWriter) writeFloatArray(indent, name string, values []float64) {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = formatFbxFloat(value)
+	}
+	w.writeArray(indent, name, formatted)
+}
+
+// writeIntArray writes a named integer array.
+func (w *FbxWriter) writeIntArray(indent, name string, values []int) {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = strconv.Itoa(value)
+	}
+	w.writeArray(indent, name, formatted)
+}
+
+// writeInt64Array writes a named 64-bit integer array.
+func (w *FbxWriter) writeInt64Array(indent, name string, values []int64) {
+	formatted := make([]string, len(values))
+	for i, value := range values {
+		formatted[i] = strconv.FormatInt(value, 10)
+	}
+	w.writeArray(indent, name, formatted)
+}
+
+// writeMatrix writes a named column-major 4x4 matrix.
+func (w *FbxWriter) writeMatrix(indent, name string, matrix datatypes.Mat4) {
+	values := make([]float64, len(matrix))
+	for i, value := range matrix {
+		values[i] = float64(value)
+	}
+	w.writeFloatArray(indent, name, values)
+}
+
+// writeArray writes a named array of formatted values.
+func (w *FbxWriter) writeArray(indent, name string, values []string) {
+	w.objects.WriteString(fmt.Sprintf("%s%s: *%d {\n", indent, name, len(values)))
+	w.objects.WriteString(fmt.Sprintf("%s\ta: %s\n", indent, strings.Join(values, ",")))
+	w.objects.WriteString(indent + "}\n")
+}
+
+// formatFbxFloat formats a value with the shortest representation that survives a float32 round trip.
+func formatFbxFloat(v float64) string {
+	// Avoid writing negative zero
+	if v == 0 {
+		v = 0
+	}
+	return strconv.FormatFloat(v, 'g', -1, 32)
+}
+
+// validVertexIndices reports whether all indices of a polygon are within the mesh vertices.
+func validVertexIndices(mesh *fragments.Mesh, vertices [3]int) bool {
+	for _, vertex := range vertices {
+		if vertex < 0 || vertex >= len(mesh.Vertices) {
+			return false
+		}
+	}
+	return true
+}
+
+// transformPoint transforms a point by an affine matrix.
+func transformPoint(m datatypes.Mat4, v fragments.Vec3) fragments.Vec3 {
+	return fragments.Vec3{
+		X: m[0]*v.X + m[4]*v.Y + m[8]*v.Z + m[12],
+		Y: m[1]*v.X + m[5]*v.Y + m[9]*v.Z + m[13],
+		Z: m[2]*v.X + m[6]*v.Y + m[10]*v.Z + m[14],
+	}
+}
+
+// ExportSkeletalActorToFbx writes a skeletal actor with its skinned meshes and animations
+// to '<skeleton>.fbx' in the export folder.
+func ExportSkeletalActorToFbx(actor *fragments.Actor, isCharacterAnimation bool, exportFolder string, settings *config.Settings) error {
+	if actor == nil || actor.SkeletonReference == nil || actor.SkeletonReference.SkeletonHierarchy == nil {
+		return nil
+	}
+
+	skeleton := actor.SkeletonReference.SkeletonHierarchy
+	writer := NewFbxWriter(isCharacterAnimation, settings.ExportHiddenGeometry)
+	writer.AddFragmentData(skeleton)
+
+	filePath := fmt.Sprintf("%s%s.fbx", exportFolder, helpers.CleanSkeletonName(skeleton.GetName()))
+	if err := writer.WriteAssetToFile(filePath); err != nil {
+		return fmt.Errorf("failed to write FBX model %s: %w", filePath, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package exporters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// newTestFbxSkeleton creates a two bone skeleton with a skinned triangle and a two frame animation.
+func newTestFbxSkeleton() *fragments.SkeletonHierarchy {
+	mesh := newTestSword()
+	mesh.MobPieces = map[int]datatypes.MobVertexPiece{0: {Start: 0, Count: 2}, 1: {Start: 2, Count: 1}}
+
+	skeleton := &fragments.SkeletonHierarchy{
+		Meshes:      []fragments.Fragment{mesh},
+		BoneMapping: map[int]string{0: "TST_DAG", 1: "TSTHE_DAG"},
+		Animations:  make(map[string]*datatypes.Animation),
+	}
+	for i, name := range []string{"TST_DAG", "TSTHE_DAG"} {
+		skeleton.Skeleton = append(skeleton.Skeleton, &fragments.SkeletonBone{
+			Index:       i,
+			Name:        name,
+			CleanedName: datatypes.CleanBoneName(name),
+		})
+	}
+	skeleton.Skeleton[0].Children = []int{1}
+	skeleton.Skeleton[1].Parent = skeleton.Skeleton[0]
+	skeleton.SetName("TST_HS_DEF")
+
+	animation := datatypes.NewAnimation()
+	for _, name := range []string{"TST_DAG", "TSTHE_DAG"} {
+		frames := []datatypes.BoneTransform{
+			{Rotation: datatypes.Quat{W: 1}, Scale: 1},
+			{Translation: datatypes.Vec3{Z: 1}, Rotation: datatypes.Quat{W: 1}, Scale: 1},
+		}
+		track := &fragments.TrackFragment{TrackDefFragment: &fragments.TrackDefFragment{Frames: frames}, FrameMs: 100}
+		animation.AddTrack(track, name, datatypes.CleanBoneName(name), name)
+	}
+	skeleton.Animations["c01"] = animation
+
+	return skeleton
+}
+
+func TestFbxWriter(t *testing.T) {
+	writer := NewFbxWriter(false, false)
+	writer.AddFragmentData(newTestFbxSkeleton())
+	export := writer.GetExport().String()
+
+	if !strings.HasPrefix(export, "; FBX 7.4.0 project file\n") || !strings.Contains(export, "\tFBXVersion: 7400\n") {
+		t.Errorf("Expected an FBX 7.4 header, got:\n%s", export[:strings.Index(export, "GlobalSettings")])
+	}
+
+	nodes := []string{
+		"Geometry::it10\", \"Mesh\"",
+		"Deformer::it10\", \"Skin\"",
+		"SubDeformer::tst\", \"Cluster\"",
+		"SubDeformer::tsthe\", \"Cluster\"",
+		"AnimationStack: ",
+		"\"AnimStack::c01\"",
+		"\"AnimLayer::BaseLayer\"",
+		"\"Pose::tst\", \"BindPose\"",
+	}
+	for _, node := range nodes {
+		if !strings.Contains(export, node) {
+			t.Errorf("Expected the export to contain %s", node)
+		}
+	}
+
+	// 2 per bone, 5 for the mesh, its material and texture, 5 for the skin and its clusters,
+	// 1 for the animation layer and 5 for each of the 3 curve nodes of each bone
+	connections := export[strings.Index(export, "Connections:"):]
+	if count := strings.Count(connections, "\tC: "); count != 4+5+5+1+30 {
+		t.Errorf("Expected 45 connections, got %d", count)
+	}
+
+	// The second key of the translation curves is one frame later
+	if !strings.Contains(export, "KeyTime: *2 {\n\t\t\ta: 0,4618615800\n") {
+		t.Error("Expected 2 keys 100ms apart")
+	}
+}
@@ -0,0 +1,156 @@
+package exporters
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+
+	"github.com/qmuntal/gltf"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// animatedTextureSheetSuffix is appended to the first frame name for the sprite sheet image.
+const animatedTextureSheetSuffix = "_sheet"
+
+// animatedTextureUvEpsilon is the distance outside [0, 1] a UV may reach before it counts as repeating the texture.
+const animatedTextureUvEpsilon = 1e-3
+
+// animatedTextureFrame is the first frame image of an animated material, added as a texture only when a mesh needs it.
+type animatedTextureFrame struct {
+	path string
+	name string
+}
+
+// addAnimatedTexture builds a horizontal sprite sheet for an animated material and assigns it as the base color.
+// The first frame is selected with KHR_texture_transform and the frame list and delay are stored in material extras.
+// A sheet cannot repeat a single frame, so meshes repeating the texture switch the material to the first frame,
+// see useFirstAnimationFrame. Returns the image of the first frame, or false if the material is not animated
+// or the sheet could not be created, e.g. because the frames differ in size.
+func (w *GltfWriter) addAnimatedTexture(mat *gltf.Material, material *fragments.Material, textureImageFolder string) (animatedTextureFrame, bool) {
+	bitmapInfo := gltfGetBitmapInfo(material)
+	if bitmapInfo == nil || !bitmapInfo.IsAnimated || len(bitmapInfo.BitmapNames) < 2 {
+		return animatedTextureFrame{}, false
+	}
+
+	frameNames := make([]string, 0, len(bitmapInfo.BitmapNames))
+	framePaths := make([]string, 0, len(bitmapInfo.BitmapNames))
+	for _, bitmapName := range bitmapInfo.BitmapNames {
+		frameNames = append(frameNames, bitmapName.GetFilenameWithoutExtension())
+		framePaths = append(framePaths, textureImageFolder+bitmapName.GetExportFilename())
+	}
+
+	sheetName := frameNames[0] + animatedTextureSheetSuffix
+	sheetPath := textureImageFolder + sheetName + ".png"
+	if err := writeSpriteSheet(framePaths, sheetPath); err != nil {
+		return animatedTextureFrame{}, false
+	}
+
+	frameCount := len(frameNames)
+	sheetTextureIdx := w.addTexture(sheetPath, sheetName)
+	mat.PBRMetallicRoughness.BaseColorTexture = &gltf.TextureInfo{
+		Index: sheetTextureIdx,
+		Extensions: map[string]interface{}{
+			"KHR_texture_transform": map[string]interface{}{
+				"offset": [2]float32{0, 0},
+				"scale":  [2]float32{1 / float32(frameCount), 1},
+			},
+		},
+	}
+	w.addExtensionUsed("KHR_texture_transform")
+
+	setMaterialExtra(mat, "animatedTexture", map[string]interface{}{
+		"frames":       frameNames,
+		"frameCount":   frameCount,
+		"delayMs":      bitmapInfo.AnimationDelayMs,
+		"layout":       "horizontal",
+		"sheetTexture": sheetTextureIdx,
+	})
+
+	return animatedTextureFrame{path: framePaths[0], name: frameNames[0]}, true
+}
+
+// useFirstAnimationFrame switches an animated material from its sprite sheet to its first frame,
+// adding the first frame texture on first use.
+// Under repeat wrapping, UVs outside [0, 1] would sample the neighbouring frames of the sheet.
+// The frame list stays in the extras for engines that swap the frames themselves.
+func (w *GltfWriter) useFirstAnimationFrame(matIdx uint32) {
+	firstFrame, isAnimated := w.animatedTextures[matIdx]
+	if !isAnimated || int(matIdx) >= len(w.doc.Materials) {
+		return
+	}
+
+	mat := w.doc.Materials[matIdx]
+	mat.PBRMetallicRoughness.BaseColorTexture = &gltf.TextureInfo{Index: w.addTexture(firstFrame.path, firstFrame.name)}
+	if extras, ok := mat.Extras.(map[string]interface{}); ok {
+		if animatedTexture, ok := extras["animatedTexture"].(map[string]interface{}); ok {
+			animatedTexture["layout"] = "frames"
+		}
+	}
+
+	delete(w.animatedTextures, matIdx)
+}
+
+// isRepeatingUv returns true if a UV lies outside the texture.
+func isRepeatingUv(uv [2]float32) bool {
+	return uv[0] < -animatedTextureUvEpsilon || uv[0] > 1+animatedTextureUvEpsilon ||
+		uv[1] < -animatedTextureUvEpsilon || uv[1] > 1+animatedTextureUvEpsilon
+}
+
+// writeSpriteSheet places each frame image side by side in a single PNG.
+// Every frame must have the size of the first frame.
+func writeSpriteSheet(framePaths []string, outputPath string) error {
+	frames := make([]image.Image, 0, len(framePaths))
+	for _, framePath := range framePaths {
+		frame, err := readPng(framePath)
+		if err != nil {
+			return err
+		}
+		frames = append(frames, frame)
+	}
+
+	cellWidth := frames[0].Bounds().Dx()
+	cellHeight := frames[0].Bounds().Dy()
+	for i, frame := range frames {
+		if frame.Bounds().Dx() != cellWidth || frame.Bounds().Dy() != cellHeight {
+			return fmt.Errorf("failed to create sprite sheet: frame %s is %dx%d, expected %dx%d",
+				framePaths[i], frame.Bounds().Dx(), frame.Bounds().Dy(), cellWidth, cellHeight)
+		}
+	}
+
+	sheet := image.NewNRGBA(image.Rect(0, 0, cellWidth*len(frames), cellHeight))
+
+	for i, frame := range frames {
+		cell := image.Rect(i*cellWidth, 0, (i+1)*cellWidth, cellHeight)
+		draw.Draw(sheet, cell, frame, frame.Bounds().Min, draw.Src)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create sprite sheet: %w", err)
+	}
+	defer file.Close()
+
+	if err := png.Encode(file, sheet); err != nil {
+		return fmt.Errorf("failed to encode sprite sheet: %w", err)
+	}
+
+	return nil
+}
+
+// readPng decodes a PNG image from disk.
+func readPng(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image %s: %w", path, err)
+	}
+
+	return img, nil
+}
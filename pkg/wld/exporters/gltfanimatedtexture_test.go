@@ -0,0 +1,93 @@
+package exporters
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// writeTestFrames writes a blank PNG of the given size for each frame name.
+func writeTestFrames(t *testing.T, dir string, sizes map[string]int) {
+	for name, size := range sizes {
+		file, err := os.Create(filepath.Join(dir, name+".png"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := png.Encode(file, image.NewNRGBA(image.Rect(0, 0, size, size))); err != nil {
+			t.Fatal(err)
+		}
+		file.Close()
+	}
+}
+
+// newTestAnimatedMaterial creates an animated material drawing the given frames.
+func newTestAnimatedMaterial(name string, frames ...string) *fragments.Material {
+	material := newTestMaterial(name, frames[0]+".bmp")
+	bitmapInfo := material.BitmapInfoReference.(*fragments.BitmapInfoReference).BitmapInfo
+	bitmapInfo.IsAnimated = true
+	bitmapInfo.AnimationDelayMs = 200
+	for _, frame := range frames[1:] {
+		bitmapInfo.BitmapNames = append(bitmapInfo.BitmapNames, &fragments.BitmapName{Filename: frame + ".bmp"})
+	}
+	return material
+}
+
+func TestAnimatedTexture(t *testing.T) {
+	dir := t.TempDir() + "/"
+	writeTestFrames(t, dir, map[string]int{"water1": 4, "water2": 4, "fire1": 4, "fire2": 8})
+
+	water := newTestAnimatedMaterial("WATER_MDF", "water1", "water2")
+	fire := newTestAnimatedMaterial("FIRE_MDF", "fire1", "fire2")
+	list := &fragments.MaterialList{Materials: []*fragments.Material{water, fire}}
+
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+	writer.GenerateGltfMaterials([]*fragments.MaterialList{list}, dir)
+
+	waterIdx := writer.Materials["d_water1"]
+	baseColor := writer.doc.Materials[waterIdx].PBRMetallicRoughness.BaseColorTexture
+	transform, ok := baseColor.Extensions["KHR_texture_transform"].(map[string]interface{})
+	if !ok || transform["scale"] != [2]float32{0.5, 1} {
+		t.Fatalf("Expected the sheet to show one of 2 frames, got %v", baseColor.Extensions)
+	}
+	if uri := writer.doc.Images[*writer.doc.Textures[baseColor.Index].Source].URI; uri != "Textures/water1_sheet.png" {
+		t.Errorf("Expected the sprite sheet, got %s", uri)
+	}
+
+	// Frames of different sizes are not packed into a sheet
+	fireBaseColor := writer.doc.Materials[writer.Materials["d_fire1"]].PBRMetallicRoughness.BaseColorTexture
+	if fireBaseColor.Extensions != nil || writer.doc.Images[*writer.doc.Textures[fireBaseColor.Index].Source].URI != "Textures/fire1.png" {
+		t.Errorf("Expected mismatched frames to use the first frame, got %+v", fireBaseColor)
+	}
+
+	// The first water frame is only added once a mesh repeats the texture
+	for _, img := range writer.doc.Images {
+		if img.URI == "Textures/water1.png" {
+			t.Fatalf("Expected no first frame image before it is used, got %v", writer.doc.Images)
+		}
+	}
+
+	// A quad repeating the water texture twice switches the material to the first frame
+	mesh := &fragments.Mesh{
+		Vertices:             []fragments.Vec3{{X: 0, Y: 0}, {X: 10, Y: 0}, {X: 10, Y: 10}},
+		TextureUvCoordinates: []datatypes.Vec2{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 2, Y: -2}},
+		Indices:              []datatypes.Polygon{{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2}},
+		MaterialGroups:       []datatypes.RenderGroup{{PolygonCount: 1, MaterialIndex: 0}},
+		MaterialList:         list,
+	}
+	writer.addMeshTriangles(newMeshBuilder("water", false, false), mesh, false, false, -1, nil)
+
+	baseColor = writer.doc.Materials[waterIdx].PBRMetallicRoughness.BaseColorTexture
+	if baseColor.Extensions != nil || writer.doc.Images[*writer.doc.Textures[baseColor.Index].Source].URI != "Textures/water1.png" {
+		t.Errorf("Expected repeating UVs to use the first frame, got %+v", baseColor)
+	}
+
+	extras := writer.doc.Materials[waterIdx].Extras.(map[string]interface{})["animatedTexture"].(map[string]interface{})
+	if extras["layout"] != "frames" || len(extras["frames"].([]string)) != 2 {
+		t.Errorf("Expected the frame list to stay in the extras, got %v", extras)
+	}
+}
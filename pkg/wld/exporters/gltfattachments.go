@@ -0,0 +1,47 @@
+package exporters
+
+import (
+	"github.com/qmuntal/gltf"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// AddAttachedMesh adds a mesh as a child node of a skeleton bone so it follows the bone's animation.
+// Attached meshes are defined relative to their bone, like the vertices of the skinned meshes.
+// Returns false if the bone does not exist or the mesh has no visible triangles.
+func (w *GltfWriter) AddAttachedMesh(mesh *fragments.Mesh, skeleton *fragments.SkeletonHierarchy, boneIndex int, nodeName string) bool {
+	skelData, exists := w.skeletons[skeleton.ModelBase]
+	if !exists {
+		skelData = w.addNewSkeleton(skeleton)
+	}
+
+	if mesh == nil || boneIndex < 0 || boneIndex >= len(skelData.nodes) {
+		return false
+	}
+
+	canExportVertexColors := w.exportVertexColors && len(mesh.Colors) > 0
+	builder := newMeshBuilder(nodeName, false, canExportVertexColors)
+	w.addMeshTriangles(builder, mesh, canExportVertexColors, false, -1, nil)
+
+	if len(builder.meshData.positions) == 0 {
+		return false
+	}
+
+	// The bone transforms do not mirror the model, so the winding is reversed as for skinned meshes
+	for _, prim := range builder.meshData.primitives {
+		for i := 0; i+2 < len(prim.indices); i += 3 {
+			prim.indices[i], prim.indices[i+2] = prim.indices[i+2], prim.indices[i]
+		}
+	}
+
+	meshIdx := w.buildGltfMesh(builder)
+	nodeIdx := uint32(len(w.doc.Nodes))
+	w.doc.Nodes = append(w.doc.Nodes, &gltf.Node{
+		Name: nodeName,
+		Mesh: gltf.Index(meshIdx),
+	})
+
+	bone := w.doc.Nodes[skelData.nodes[boneIndex]]
+	bone.Children = append(bone.Children, nodeIdx)
+
+	return true
+}
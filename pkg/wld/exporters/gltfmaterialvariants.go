@@ -0,0 +1,111 @@
+package exporters
+
+import (
+	"fmt"
+
+	"github.com/qmuntal/gltf"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// materialsVariantsExtension lets viewers switch the materials of a primitive between named variants.
+const materialsVariantsExtension = "KHR_materials_variants"
+
+// addMaterialVariants adds the alternate skin materials of a character material list.
+// Each skin becomes a KHR_materials_variants variant named by its index; variant 00 is the default skin.
+func (w *GltfWriter) addMaterialVariants(materialList *fragments.MaterialList, textureImageFolder string) {
+	for _, eqMaterial := range materialList.Materials {
+		if eqMaterial == nil {
+			continue
+		}
+
+		baseIdx, exists := w.Materials[getMaterialName(eqMaterial)]
+		if !exists {
+			continue
+		}
+
+		variants := materialList.GetMaterialVariants(eqMaterial)
+		skinMaterials := make([]uint32, len(variants))
+		hasVariant := false
+
+		for i, variant := range variants {
+			skinMaterials[i] = baseIdx
+			if variant == nil {
+				continue
+			}
+
+			w.addMaterial(variant, textureImageFolder)
+			if variantIdx, exists := w.Materials[getMaterialName(variant)]; exists {
+				skinMaterials[i] = variantIdx
+				hasVariant = hasVariant || variantIdx != baseIdx
+			}
+		}
+
+		if !hasVariant {
+			continue
+		}
+
+		w.materialVariants[baseIdx] = skinMaterials
+		if len(skinMaterials) > w.variantCount {
+			w.variantCount = len(skinMaterials)
+		}
+	}
+}
+
+// applyMaterialVariants maps each skin variant of the primitive's material to the skin's material.
+func (w *GltfWriter) applyMaterialVariants(prim *gltf.Primitive, materialIdx uint32) {
+	skinMaterials, exists := w.materialVariants[materialIdx]
+	if !exists {
+		return
+	}
+
+	materialOrder := []uint32{materialIdx}
+	variantsByMaterial := map[uint32][]int{materialIdx: {0}}
+
+	for skin := 1; skin <= w.variantCount; skin++ {
+		// Skins without their own texture keep the default material
+		skinMaterial := materialIdx
+		if skin-1 < len(skinMaterials) {
+			skinMaterial = skinMaterials[skin-1]
+		}
+
+		if _, seen := variantsByMaterial[skinMaterial]; !seen {
+			materialOrder = append(materialOrder, skinMaterial)
+		}
+		variantsByMaterial[skinMaterial] = append(variantsByMaterial[skinMaterial], skin)
+	}
+
+	mappings := make([]interface{}, 0, len(materialOrder))
+	for _, idx := range materialOrder {
+		mappings = append(mappings, map[string]interface{}{
+			"material": idx,
+			"variants": variantsByMaterial[idx],
+		})
+	}
+
+	if prim.Extensions == nil {
+		prim.Extensions = map[string]interface{}{}
+	}
+	prim.Extensions[materialsVariantsExtension] = map[string]interface{}{
+		"mappings": mappings,
+	}
+
+	w.addMaterialVariantNames()
+}
+
+// addMaterialVariantNames declares the skin variants on the document, named by skin index.
+func (w *GltfWriter) addMaterialVariantNames() {
+	variants := make([]interface{}, 0, w.variantCount+1)
+	for skin := 0; skin <= w.variantCount; skin++ {
+		variants = append(variants, map[string]interface{}{
+			"name": fmt.Sprintf("%02d", skin),
+		})
+	}
+
+	if w.doc.Extensions == nil {
+		w.doc.Extensions = map[string]interface{}{}
+	}
+	w.doc.Extensions[materialsVariantsExtension] = map[string]interface{}{
+		"variants": variants,
+	}
+	w.addExtensionUsed(materialsVariantsExtension)
+}
@@ -0,0 +1,127 @@
+package exporters
+
+import (
+	"testing"
+
+	"github.com/qmuntal/gltf"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// newTestAnimatedMesh creates a single triangle mesh whose last vertex moves up in the second frame.
+func newTestAnimatedMesh(name string) *fragments.Mesh {
+	mesh := newTestSword()
+	mesh.SetName(name)
+	mesh.Center = fragments.Vec3{X: 10, Y: 20, Z: 30}
+
+	animatedVertices := &fragments.MeshAnimatedVertices{}
+	animatedVertices.SetFrames([][]fragments.Vec3{
+		mesh.Vertices,
+		{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 0, Y: 0, Z: 7}},
+	})
+	animatedVertices.SetDelay(250)
+	mesh.AnimatedVerticesReference = animatedVertices
+	return mesh
+}
+
+func TestAddMorphDisplacements(t *testing.T) {
+	mesh := newTestAnimatedMesh("FLAG_DMSPRITEDEF")
+	builder := newMeshBuilder("flag", false, false)
+	builder.setAnimatedVertices(getAnimatedVerticesFromMesh(mesh))
+	if builder.meshData.morphDelayMs != 250 || len(builder.meshData.morphTargets) != 2 {
+		t.Fatalf("Expected 2 morph targets with a 250ms delay, got %d and %d",
+			len(builder.meshData.morphTargets), builder.meshData.morphDelayMs)
+	}
+
+	// The base position is in glTF order (X, Z, Y), so the EQ Z offset lands in the second component
+	builder.addMorphDisplacements(mesh, 2, [3]float32{10, 35, 20})
+	builder.addMorphDisplacements(mesh, 3, [3]float32{0, 0, 0})
+
+	expected := [][][3]float32{
+		{{0, 0, 0}, {0, 0, 0}},
+		{{0, 2, 0}, {0, 0, 0}},
+	}
+	for frame := range expected {
+		for vertex := range expected[frame] {
+			if got := builder.meshData.morphTargets[frame][vertex]; got != expected[frame][vertex] {
+				t.Errorf("Frame %d vertex %d: expected %v, got %v", frame, vertex, expected[frame][vertex], got)
+			}
+		}
+	}
+}
+
+func TestAddMorphAnimation(t *testing.T) {
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+
+	builder := newMeshBuilder("flag", false, false)
+	builder.meshData.morphTargets = make([][][3]float32, 1)
+	builder.meshData.morphDelayMs = 100
+	writer.addMorphAnimation(builder, 0)
+	if len(writer.doc.Animations) != 0 {
+		t.Fatal("Expected no animation for a single frame")
+	}
+
+	builder.meshData.morphTargets = make([][][3]float32, 3)
+	writer.addMorphAnimation(builder, 4)
+	if len(writer.doc.Animations) != 1 {
+		t.Fatalf("Expected one animation, got %d", len(writer.doc.Animations))
+	}
+
+	anim := writer.doc.Animations[0]
+	if anim.Name != "flag_vertex" || len(anim.Channels) != 1 || len(anim.Samplers) != 1 {
+		t.Fatalf("Expected a single flag_vertex channel, got %+v", anim)
+	}
+	if target := anim.Channels[0].Target; target.Path != gltf.TRSWeights || *target.Node != 4 {
+		t.Errorf("Expected the weights of node 4 to be animated, got %+v", target)
+	}
+
+	sampler := anim.Samplers[0]
+	if sampler.Interpolation != gltf.InterpolationStep {
+		t.Errorf("Expected step interpolation, got %v", sampler.Interpolation)
+	}
+
+	// One key per frame plus one closing the loop, each with a weight per target
+	times := writer.doc.Accessors[sampler.Input]
+	weights := writer.doc.Accessors[sampler.Output]
+	if times.Count != 4 || weights.Count != 12 {
+		t.Errorf("Expected 4 keys and 12 weights, got %d and %d", times.Count, weights.Count)
+	}
+	if len(times.Max) != 1 || times.Max[0] < 0.299 || times.Max[0] > 0.301 {
+		t.Errorf("Expected the loop to last 0.3 seconds, got %v", times.Max)
+	}
+}
+
+func TestCombinedAnimatedMesh(t *testing.T) {
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+
+	writer.AddFragmentDataWithOptions(newTestSword(), ModelGenerationModeCombine, false, "zone", -1, nil, 0, true)
+	writer.AddFragmentDataWithOptions(newTestAnimatedMesh("FLAG_DMSPRITEDEF"), ModelGenerationModeCombine, false, "zone", -1, nil, 0, true)
+	writer.AddCombinedMeshToScene(true, "zone", "", nil)
+
+	if len(writer.doc.Nodes) != 2 {
+		t.Fatalf("Expected the animated mesh next to the combined mesh, got %d nodes", len(writer.doc.Nodes))
+	}
+
+	zone, flag := writer.doc.Nodes[0], writer.doc.Nodes[1]
+	if zone.Name != "zone" || flag.Name != "flag" {
+		t.Fatalf("Expected zone and flag nodes, got %s and %s", zone.Name, flag.Name)
+	}
+	if flag.Matrix != zone.Matrix {
+		t.Error("Expected the animated mesh to share the zone transform")
+	}
+	if targets := writer.doc.Meshes[*flag.Mesh].Primitives[0].Targets; len(targets) != 2 {
+		t.Errorf("Expected 2 morph targets, got %d", len(targets))
+	}
+	if len(writer.doc.Meshes[*zone.Mesh].Primitives[0].Targets) != 0 {
+		t.Error("Expected no morph targets on the combined mesh")
+	}
+	if len(writer.doc.Animations) != 1 {
+		t.Errorf("Expected one vertex animation, got %d", len(writer.doc.Animations))
+	}
+
+	// Adding the shared combined mesh again also adds its animated mesh
+	writer.AddCombinedMeshToScene(true, "zone", "", nil)
+	if len(writer.doc.Nodes) != 4 || len(writer.doc.Animations) != 2 {
+		t.Errorf("Expected the shared mesh to keep its animated mesh, got %d nodes and %d animations",
+			len(writer.doc.Nodes), len(writer.doc.Animations))
+	}
+}
@@ -0,0 +1,167 @@
+package exporters
+
+import (
+	"github.com/qmuntal/gltf"
+	"github.com/qmuntal/gltf/modeler"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/bsp"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// regionVolumeColors is the display color of each region type volume.
+var regionVolumeColors = map[datatypes.RegionType][4]float32{
+	datatypes.RegionTypeWater:         {0.1, 0.3, 0.9, 0.4},
+	datatypes.RegionTypeLava:          {1.0, 0.3, 0.0, 0.5},
+	datatypes.RegionTypePvp:           {0.8, 0.1, 0.1, 0.3},
+	datatypes.RegionTypeZoneline:      {0.9, 0.9, 0.1, 0.4},
+	datatypes.RegionTypeWaterBlockLos: {0.1, 0.2, 0.6, 0.5},
+	datatypes.RegionTypeFreezingWater: {0.6, 0.8, 1.0, 0.4},
+	datatypes.RegionTypeSlippery:      {0.7, 0.9, 0.9, 0.3},
+}
+
+// AddRegionVolumes adds each region volume as a named mesh node under a "Regions" node.
+// The region types and zoneline destination are stored in the node extras.
+func (w *GltfWriter) AddRegionVolumes(volumes []*bsp.RegionVolume) {
+	if len(volumes) == 0 {
+		return
+	}
+
+	groupNode := &gltf.Node{
+		Name:   "Regions",
+		Matrix: correctedWorldMatrix(),
+	}
+	materialIndices := make(map[datatypes.RegionType]uint32)
+
+	for _, volume := range volumes {
+		if len(volume.Triangles) == 0 {
+			continue
+		}
+
+		positions := make([][3]float32, len(volume.Vertices))
+		for i, v := range volume.Vertices {
+			positions[i] = [3]float32{v.X, v.Z, v.Y}
+		}
+
+		indices := make([]uint32, 0, len(volume.Triangles)*3)
+		for _, triangle := range volume.Triangles {
+			indices = append(indices, uint32(triangle[0]), uint32(triangle[1]), uint32(triangle[2]))
+		}
+
+		regionType := getDisplayRegionType(volume)
+		materialIdx, exists := materialIndices[regionType]
+		if !exists {
+			materialIdx = w.addRegionMaterial(regionType)
+			materialIndices[regionType] = materialIdx
+		}
+
+		meshIdx := uint32(len(w.doc.Meshes))
+		w.doc.Meshes = append(w.doc.Meshes, &gltf.Mesh{
+			Name: volume.Name,
+			Primitives: []*gltf.Primitive{{
+				Attributes: map[string]uint32{
+					gltf.POSITION: modeler.WritePosition(w.doc, positions),
+				},
+				Indices:  gltf.Index(modeler.WriteIndices(w.doc, indices)),
+				Material: gltf.Index(materialIdx),
+				Mode:     gltf.PrimitiveTriangles,
+			}},
+		})
+
+		nodeIdx := uint32(len(w.doc.Nodes))
+		w.doc.Nodes = append(w.doc.Nodes, &gltf.Node{
+			Name:   volume.Name,
+			Mesh:   gltf.Index(meshIdx),
+			Extras: getRegionVolumeExtras(volume),
+		})
+		groupNode.Children = append(groupNode.Children, nodeIdx)
+	}
+
+	if len(groupNode.Children) == 0 {
+		return
+	}
+
+	groupIdx := uint32(len(w.doc.Nodes))
+	w.doc.Nodes = append(w.doc.Nodes, groupNode)
+	w.doc.Scenes[0].Nodes = append(w.doc.Scenes[0].Nodes, groupIdx)
+}
+
+// addRegionMaterial adds a translucent double sided material for a region type.
+func (w *GltfWriter) addRegionMaterial(regionType datatypes.RegionType) uint32 {
+	color, ok := regionVolumeColors[regionType]
+	if !ok {
+		color = [4]float32{0.5, 0.5, 0.5, 0.3}
+	}
+
+	mat := &gltf.Material{
+		Name:        "region_" + regionType.String(),
+		DoubleSided: true,
+		AlphaMode:   gltf.AlphaBlend,
+		PBRMetallicRoughness: &gltf.PBRMetallicRoughness{
+			BaseColorFactor: &color,
+			MetallicFactor:  gltf.Float(0),
+		},
+	}
+	setMaterialExtension(mat, "KHR_materials_unlit", map[string]interface{}{})
+	w.addExtensionUsed("KHR_materials_unlit")
+
+	materialIdx := uint32(len(w.doc.Materials))
+	w.doc.Materials = append(w.doc.Materials, mat)
+	return materialIdx
+}
+
+// getDisplayRegionType returns the region type used to color a volume.
+// Zonelines take priority over the water or lava they may also be.
+func getDisplayRegionType(volume *bsp.RegionVolume) datatypes.RegionType {
+	if volume.HasRegionType(datatypes.RegionTypeZoneline) {
+		return datatypes.RegionTypeZoneline
+	}
+	if len(volume.RegionTypes) > 0 {
+		return volume.RegionTypes[0]
+	}
+	return datatypes.RegionTypeUnknown
+}
+
+// getRegionVolumeExtras returns the extras for a region volume node.
+func getRegionVolumeExtras(volume *bsp.RegionVolume) map[string]interface{} {
+	regionTypes := make([]string, 0, len(volume.RegionTypes))
+	for _, t := range volume.RegionTypes {
+		regionTypes = append(regionTypes, t.String())
+	}
+
+	extras := map[string]interface{}{
+		"regionTypes": regionTypes,
+		"regionCount": volume.RegionCount,
+	}
+
+	if volume.RegionString != "" {
+		extras["regionString"] = volume.RegionString
+	}
+
+	if zoneline := getZonelineExtras(volume.Zoneline); zoneline != nil {
+		extras["zoneline"] = zoneline
+	}
+
+	return extras
+}
+
+// getZonelineExtras returns the zoneline destination as a map, or nil if there is none.
+// Reference zonelines point to an entry in the server zone point table,
+// absolute zonelines carry the destination position and heading.
+func getZonelineExtras(zoneline *datatypes.ZonelineInfo) map[string]interface{} {
+	if zoneline == nil {
+		return nil
+	}
+
+	if zoneline.Type == datatypes.ZonelineTypeReference {
+		return map[string]interface{}{
+			"type":  "reference",
+			"index": zoneline.Index,
+		}
+	}
+
+	return map[string]interface{}{
+		"type":      "absolute",
+		"zoneIndex": zoneline.ZoneIndex,
+		"position":  [3]float32{zoneline.Position.X, zoneline.Position.Y, zoneline.Position.Z},
+		"heading":   zoneline.Heading,
+	}
+}
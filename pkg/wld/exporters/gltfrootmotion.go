@@ -0,0 +1,37 @@
+package exporters
+
+import (
+	"github.com/qmuntal/gltf"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/animation"
+)
+
+// getRootMotionExtras returns the root motion of an animation in glTF space as extras.
+// Times are in seconds, translations are horizontal movements on the XZ plane and
+// yaw is the turn around the Y axis in radians, all relative to the first frame.
+func getRootMotionExtras(motion *animation.RootMotion) map[string]interface{} {
+	times := make([]float32, len(motion.Keys))
+	translations := make([][3]float32, len(motion.Keys))
+	yaw := make([]float32, len(motion.Keys))
+
+	for i, key := range motion.Keys {
+		times[i] = float32(key.TimeMs / 1000.0)
+		translations[i] = [3]float32{key.Translation.X, key.Translation.Z, -key.Translation.Y}
+		yaw[i] = float32(key.Yaw)
+	}
+
+	return map[string]interface{}{
+		"times":        times,
+		"translations": translations,
+		"yaw":          yaw,
+	}
+}
+
+// setAnimationExtra sets a value in the extras of an animation.
+func setAnimationExtra(anim *gltf.Animation, key string, value interface{}) {
+	extras, ok := anim.Extras.(map[string]interface{})
+	if !ok {
+		extras = map[string]interface{}{}
+		anim.Extras = extras
+	}
+	extras[key] = value
+}
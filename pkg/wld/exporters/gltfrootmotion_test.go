@@ -0,0 +1,79 @@
+package exporters
+
+import (
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/animation"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// newTestWalkSkeleton creates a single bone skeleton with a pose and a walk moving the root
+// one unit along X per 100ms frame.
+func newTestWalkSkeleton() *fragments.SkeletonHierarchy {
+	skeleton := &fragments.SkeletonHierarchy{
+		ModelBase:   "hum",
+		Skeleton:    []*fragments.SkeletonBone{{Index: 0, Name: "HUM_DAG", CleanedName: datatypes.CleanBoneAndStripBase("HUM_DAG", "hum")}},
+		BoneMapping: map[int]string{0: "HUM_DAG"},
+		Animations:  make(map[string]*datatypes.Animation),
+	}
+	skeleton.SetName("HUM_HS_DEF")
+
+	for _, key := range []string{defaultModelPoseAnimKey, "l01"} {
+		frames := make([]datatypes.BoneTransform, 3)
+		for i := range frames {
+			frames[i] = datatypes.BoneTransform{Translation: datatypes.Vec3{X: float32(i), Z: 2}, Rotation: datatypes.Quat{W: 1}, Scale: 1}
+		}
+		track := &fragments.TrackFragment{TrackDefFragment: &fragments.TrackDefFragment{Frames: frames}, FrameMs: 100}
+		anim := datatypes.NewAnimation()
+		anim.AddTrack(track, "HUM_DAG", datatypes.CleanBoneName("HUM_DAG"), datatypes.CleanBoneAndStripBase("HUM_DAG", "hum"))
+		skeleton.Animations[key] = anim
+	}
+
+	return skeleton
+}
+
+func TestRootMotionExtras(t *testing.T) {
+	skeleton := newTestWalkSkeleton()
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+	writer.SetAnimationOptions(animation.Options{ExtractRootMotion: true})
+	writer.ApplyAnimationToSkeleton(skeleton, "l01", true, false)
+
+	if len(writer.doc.Animations) != 1 {
+		t.Fatalf("Expected the walk animation, got %d animations", len(writer.doc.Animations))
+	}
+	walk := writer.doc.Animations[0]
+
+	extras, ok := walk.Extras.(map[string]interface{})["rootMotion"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected root motion in the animation extras, got %v", walk.Extras)
+	}
+
+	// The looped walk closes at 300ms on its first frame, and the root motion keeps moving to match
+	times := extras["times"].([]float32)
+	translations := extras["translations"].([][3]float32)
+	if len(times) != 4 || times[3] != 0.3 {
+		t.Fatalf("Expected 4 root motion keys ending at 0.3s, got %v", times)
+	}
+	if translations[2] != [3]float32{2, 0, 0} || translations[3] != [3]float32{3, 0, 0} {
+		t.Errorf("Expected the root to move to 2 and then 3 along X, got %v", translations)
+	}
+	if len(extras["yaw"].([]float32)) != 4 {
+		t.Errorf("Expected a yaw for each key, got %v", extras["yaw"])
+	}
+
+	// The root bone keys play in place and end at the same time as the root motion
+	for _, sampler := range walk.Samplers {
+		input := writer.doc.Accessors[sampler.Input]
+		if input.Count != 4 || input.Max[0] != 0.3 {
+			t.Errorf("Expected 4 bone keys ending at 0.3s, got %d ending at %v", input.Count, input.Max)
+		}
+	}
+
+	// Without root motion extraction the animation has no extras
+	writer = NewGltfWriter(false, GltfExportFormatGlTF)
+	writer.ApplyAnimationToSkeleton(skeleton, "l01", true, false)
+	if writer.doc.Animations[0].Extras != nil {
+		t.Errorf("Expected no root motion extras, got %v", writer.doc.Animations[0].Extras)
+	}
+}
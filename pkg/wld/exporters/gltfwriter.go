@@ -2,12 +2,16 @@
 package exporters
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"math"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/animation"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
@@ -37,9 +41,13 @@ const (
 
 const (
 	materialRoughness      = 0.9
+	materialAlphaCutoff    = 0.5
 	materialInvisName      = "Invis"
 	materialBlankName      = "Blank"
 	defaultModelPoseAnimKey = "pos"
+
+	// zoneWorldScale converts EQ zone units to glTF world units.
+	zoneWorldScale = 0.1
 )
 
 // defaultVertexColor is black with full alpha.
@@ -86,8 +94,20 @@ type GltfWriter struct {
 	doc                   *gltf.Document
 	combinedMesh          *meshBuilder
 	textureIndices        map[string]uint32
+	imageSourcePaths      []string
+	materialVariants      map[uint32][]uint32
+	variantCount          int
+	animatedTextures      map[uint32]animatedTextureFrame
+	animationOptions      animation.Options
+	textureFormat         infrastructure.TextureFormat
 	rootNode              uint32
 	nodeCount             uint32
+	logger                logger.Logger
+
+	// combinedAnimatedMeshes are the meshes with animated vertices added in Combine mode.
+	combinedAnimatedMeshes []*meshBuilder
+	// sharedAnimatedMeshes are the animated meshes written with each shared combined mesh.
+	sharedAnimatedMeshes map[string][]*meshBuilder
 }
 
 // meshData holds mesh building data.
@@ -100,6 +120,11 @@ type meshData struct {
 	weights    [][4]float32
 	indices    []uint32
 	primitives map[uint32]*primitiveData
+
+	// morphTargets holds one position displacement per vertex for each animated vertex frame.
+	morphTargets [][][3]float32
+	// morphDelayMs is the delay between animated vertex frames in milliseconds.
+	morphDelayMs int
 }
 
 // primitiveData holds primitive data for a material.
@@ -114,6 +139,9 @@ type meshBuilder struct {
 	hasColors  bool
 	meshData   *meshData
 	vertexMap  map[vertexKey]uint32
+
+	// animatedVertices is set when per-vertex animation frames are exported as morph targets.
+	animatedVertices fragments.IAnimatedVertices
 }
 
 // vertexKey uniquely identifies a vertex.
@@ -123,6 +151,7 @@ type vertexKey struct {
 	u, v                float32
 	colorR, colorG, colorB, colorA float32
 	joint               uint16
+	sourceVertex        int
 }
 
 // skeletonData holds skeleton node data.
@@ -144,14 +173,18 @@ func NewGltfWriter(exportVertexColors bool, exportFormat GltfExportFormat) *Gltf
 	doc.Scene = gltf.Index(0)
 
 	return &GltfWriter{
-		Materials:           make(map[string]uint32),
-		exportVertexColors:  exportVertexColors,
-		exportFormat:        exportFormat,
-		meshMaterialsToSkip: make(map[string]bool),
-		sharedMeshes:        make(map[string]*meshData),
-		skeletons:           make(map[string]*skeletonData),
-		doc:                 doc,
-		textureIndices:      make(map[string]uint32),
+		Materials:            make(map[string]uint32),
+		exportVertexColors:   exportVertexColors,
+		exportFormat:         exportFormat,
+		meshMaterialsToSkip:  make(map[string]bool),
+		sharedMeshes:         make(map[string]*meshData),
+		sharedAnimatedMeshes: make(map[string][]*meshBuilder),
+		skeletons:            make(map[string]*skeletonData),
+		doc:                  doc,
+		textureIndices:       make(map[string]uint32),
+		materialVariants:     make(map[uint32][]uint32),
+		animatedTextures:     make(map[uint32]animatedTextureFrame),
+		logger:               logger.NewNullLogger(),
 	}
 }
 
@@ -162,6 +195,26 @@ func (w *GltfWriter) CopyMaterialList(other *GltfWriter) {
 	w.doc.Textures = other.doc.Textures
 	w.doc.Images = other.doc.Images
 	w.textureIndices = other.textureIndices
+	w.imageSourcePaths = other.imageSourcePaths
+	w.materialVariants = other.materialVariants
+	w.variantCount = other.variantCount
+	w.animatedTextures = other.animatedTextures
+}
+
+// SetTextureFormat sets the GPU texture format referenced next to the PNG textures.
+// Textures are only referenced if the file exists next to the PNG.
+func (w *GltfWriter) SetTextureFormat(format infrastructure.TextureFormat) {
+	w.textureFormat = format
+}
+
+// SetAnimationOptions sets the resampling and keyframe reduction applied to skeletal animations.
+func (w *GltfWriter) SetAnimationOptions(options animation.Options) {
+	w.animationOptions = options
+}
+
+// SetLogger sets the logger for warnings about the exported data.
+func (w *GltfWriter) SetLogger(log logger.Logger) {
+	w.logger = log
 }
 
 // GenerateGltfMaterials generates glTF materials from material lists.
@@ -180,92 +233,187 @@ func (w *GltfWriter) GenerateGltfMaterials(materialLists []*fragments.MaterialLi
 				continue
 			}
 
-			materialName := getMaterialName(eqMaterial)
+			w.addMaterial(eqMaterial, textureImageFolder)
+		}
 
-			if _, exists := w.Materials[materialName]; exists {
-				continue
-			}
+		if materialList.VariantCount > 0 {
+			w.addMaterialVariants(materialList, textureImageFolder)
+		}
+	}
+}
 
-			if eqMaterial.ShaderType == fragments.ShaderTypeBoundary {
-				w.meshMaterialsToSkip[materialName] = true
-				continue
-			}
+// addMaterial converts an EQ material to a glTF material unless a material with the same name exists.
+func (w *GltfWriter) addMaterial(eqMaterial *fragments.Material, textureImageFolder string) {
+	materialName := getMaterialName(eqMaterial)
 
-			if eqMaterial.ShaderType == fragments.ShaderTypeInvisible {
-				w.addInvisibleMaterial(materialName)
-				continue
-			}
+	if _, exists := w.Materials[materialName]; exists {
+		return
+	}
 
-			imageFileNameWithoutExtension := gltfGetBitmapNameWithoutExtension(eqMaterial)
-			if imageFileNameWithoutExtension == "" {
-				continue
-			}
+	if eqMaterial.ShaderType == fragments.ShaderTypeBoundary {
+		w.meshMaterialsToSkip[materialName] = true
+		return
+	}
 
-			imagePath := textureImageFolder + gltfGetBitmapExportFilename(eqMaterial)
+	if eqMaterial.ShaderType == fragments.ShaderTypeInvisible {
+		w.addInvisibleMaterial(materialName)
+		return
+	}
 
-			// Create material
-			mat := &gltf.Material{
-				Name: materialName,
-				PBRMetallicRoughness: &gltf.PBRMetallicRoughness{
-					MetallicFactor:  gltf.Float(0.0),
-					RoughnessFactor: gltf.Float(materialRoughness),
-				},
-				DoubleSided: false,
-			}
+	imageFileNameWithoutExtension := gltfGetBitmapNameWithoutExtension(eqMaterial)
+	if imageFileNameWithoutExtension == "" {
+		return
+	}
 
-			// Add texture if image exists
-			if _, err := os.Stat(imagePath); err == nil {
-				textureIdx := w.addTexture(imagePath, imageFileNameWithoutExtension)
-				mat.PBRMetallicRoughness.BaseColorTexture = &gltf.TextureInfo{
-					Index: textureIdx,
-				}
-			}
+	imagePath := textureImageFolder + gltfGetBitmapExportFilename(eqMaterial)
 
-			// Set alpha mode based on shader type
-			switch eqMaterial.ShaderType {
-			case fragments.ShaderTypeTransparent25:
-				mat.AlphaMode = gltf.AlphaMask
-				mat.AlphaCutoff = gltf.Float(0.25)
-			case fragments.ShaderTypeTransparent50, fragments.ShaderTypeTransparentMasked:
-				mat.AlphaMode = gltf.AlphaMask
-				mat.AlphaCutoff = gltf.Float(0.5)
-			case fragments.ShaderTypeTransparent75:
-				mat.AlphaMode = gltf.AlphaMask
-				mat.AlphaCutoff = gltf.Float(0.75)
-			case fragments.ShaderTypeTransparentAdditive,
-				fragments.ShaderTypeTransparentAdditiveUnlit,
-				fragments.ShaderTypeTransparentSkydome,
-				fragments.ShaderTypeTransparentAdditiveUnlitSkydome:
-				mat.AlphaMode = gltf.AlphaBlend
-			default:
-				mat.AlphaMode = gltf.AlphaOpaque
-			}
+	// Create material
+	mat := &gltf.Material{
+		Name: materialName,
+		PBRMetallicRoughness: &gltf.PBRMetallicRoughness{
+			MetallicFactor:  gltf.Float(0.0),
+			RoughnessFactor: gltf.Float(materialRoughness),
+		},
+		DoubleSided: false,
+	}
 
-			// Set unlit extension for certain shader types
-			if eqMaterial.ShaderType == fragments.ShaderTypeTransparentAdditiveUnlit ||
-				eqMaterial.ShaderType == fragments.ShaderTypeDiffuseSkydome ||
-				eqMaterial.ShaderType == fragments.ShaderTypeTransparentAdditiveUnlitSkydome {
-				mat.Extensions = map[string]interface{}{
-					"KHR_materials_unlit": map[string]interface{}{},
-				}
-				// Add the extension to the document if not already present
-				hasUnlit := false
-				for _, ext := range w.doc.ExtensionsUsed {
-					if ext == "KHR_materials_unlit" {
-						hasUnlit = true
-						break
-					}
-				}
-				if !hasUnlit {
-					w.doc.ExtensionsUsed = append(w.doc.ExtensionsUsed, "KHR_materials_unlit")
-				}
+	// Animated materials use a sprite sheet, everything else the first bitmap if the image exists
+	firstFrame, isAnimated := w.addAnimatedTexture(mat, eqMaterial, textureImageFolder)
+	if !isAnimated {
+		if _, err := os.Stat(imagePath); err == nil {
+			textureIdx := w.addTexture(imagePath, imageFileNameWithoutExtension)
+			mat.PBRMetallicRoughness.BaseColorTexture = &gltf.TextureInfo{
+				Index: textureIdx,
 			}
+		}
+	}
+
+	w.applyShaderType(mat, eqMaterial)
+
+	matIdx := uint32(len(w.doc.Materials))
+	w.doc.Materials = append(w.doc.Materials, mat)
+	w.Materials[materialName] = matIdx
+
+	if isAnimated {
+		w.animatedTextures[matIdx] = firstFrame
+	}
+}
+
+// applyShaderType maps the EQ shader type onto glTF alpha modes and material extensions.
+func (w *GltfWriter) applyShaderType(mat *gltf.Material, eqMaterial *fragments.Material) {
+	isUnlit := false
+	isAdditive := false
+
+	switch eqMaterial.ShaderType {
+	case fragments.ShaderTypeTransparent25:
+		setMaterialOpacity(mat, 0.25)
+	case fragments.ShaderTypeTransparent50:
+		setMaterialOpacity(mat, 0.5)
+	case fragments.ShaderTypeTransparent75:
+		setMaterialOpacity(mat, 0.75)
+	case fragments.ShaderTypeTransparentMasked:
+		mat.AlphaMode = gltf.AlphaMask
+		mat.AlphaCutoff = gltf.Float(materialAlphaCutoff)
+	case fragments.ShaderTypeTransparentAdditive:
+		mat.AlphaMode = gltf.AlphaBlend
+		isAdditive = true
+	case fragments.ShaderTypeTransparentAdditiveUnlit,
+		fragments.ShaderTypeTransparentAdditiveUnlitSkydome:
+		mat.AlphaMode = gltf.AlphaBlend
+		isAdditive = true
+		isUnlit = true
+	case fragments.ShaderTypeDiffuseSkydome:
+		mat.AlphaMode = gltf.AlphaOpaque
+		isUnlit = true
+	case fragments.ShaderTypeTransparentSkydome:
+		setMaterialOpacity(mat, 0.5)
+		isUnlit = true
+	default:
+		mat.AlphaMode = gltf.AlphaOpaque
+	}
+
+	setMaterialExtra(mat, "shaderType", eqMaterial.ShaderType.String())
+
+	// glTF has no additive blending, so renderers that support it need a hint
+	if isAdditive {
+		setMaterialExtra(mat, "blendMode", "additive")
+	}
+
+	if isUnlit {
+		setMaterialExtension(mat, "KHR_materials_unlit", map[string]interface{}{})
+		w.addExtensionUsed("KHR_materials_unlit")
+		return
+	}
+
+	w.applyMaterialEmissive(mat, eqMaterial)
+}
+
+// applyMaterialEmissive makes self-illuminated materials emit their base color texture.
+// Brightness sets the emissive strength and ScaledAmbient scales it the way it scales ambient light.
+func (w *GltfWriter) applyMaterialEmissive(mat *gltf.Material, eqMaterial *fragments.Material) {
+	if eqMaterial.Brightness <= 0 {
+		return
+	}
+
+	strength := eqMaterial.Brightness
+	if eqMaterial.ScaledAmbient > 0 {
+		strength *= eqMaterial.ScaledAmbient
+	}
+
+	setMaterialExtra(mat, "brightness", eqMaterial.Brightness)
+	setMaterialExtra(mat, "scaledAmbient", eqMaterial.ScaledAmbient)
+
+	if mat.PBRMetallicRoughness != nil && mat.PBRMetallicRoughness.BaseColorTexture != nil {
+		emissiveTexture := *mat.PBRMetallicRoughness.BaseColorTexture
+		mat.EmissiveTexture = &emissiveTexture
+	}
+
+	// emissiveFactor is limited to [0, 1], anything brighter goes through the strength extension
+	factor := float32(math.Min(float64(strength), 1))
+	mat.EmissiveFactor = [3]float32{factor, factor, factor}
+	if strength > 1 {
+		setMaterialExtension(mat, "KHR_materials_emissive_strength", map[string]interface{}{
+			"emissiveStrength": strength,
+		})
+		w.addExtensionUsed("KHR_materials_emissive_strength")
+	}
+}
 
-			matIdx := uint32(len(w.doc.Materials))
-			w.doc.Materials = append(w.doc.Materials, mat)
-			w.Materials[materialName] = matIdx
+// setMaterialOpacity makes the material blend at a constant opacity.
+func setMaterialOpacity(mat *gltf.Material, opacity float32) {
+	mat.AlphaMode = gltf.AlphaBlend
+	if mat.PBRMetallicRoughness == nil {
+		mat.PBRMetallicRoughness = &gltf.PBRMetallicRoughness{}
+	}
+	mat.PBRMetallicRoughness.BaseColorFactor = &[4]float32{1, 1, 1, opacity}
+}
+
+// setMaterialExtension sets a material extension, keeping any that are already present.
+func setMaterialExtension(mat *gltf.Material, name string, value interface{}) {
+	if mat.Extensions == nil {
+		mat.Extensions = map[string]interface{}{}
+	}
+	mat.Extensions[name] = value
+}
+
+// setMaterialExtra sets a value in the material extras, keeping any that are already present.
+func setMaterialExtra(mat *gltf.Material, key string, value interface{}) {
+	extras, ok := mat.Extras.(map[string]interface{})
+	if !ok {
+		extras = map[string]interface{}{}
+		mat.Extras = extras
+	}
+	extras[key] = value
+}
+
+// addExtensionUsed adds the extension to the document if not already present.
+func (w *GltfWriter) addExtensionUsed(extension string) {
+	for _, ext := range w.doc.ExtensionsUsed {
+		if ext == extension {
+			return
 		}
 	}
+	w.doc.ExtensionsUsed = append(w.doc.ExtensionsUsed, extension)
 }
 
 // addTexture adds a texture to the document and returns its index.
@@ -280,18 +428,54 @@ func (w *GltfWriter) addTexture(imagePath, imageName string) uint32 {
 		Name: imageName,
 		URI:  "Textures/" + filepath.Base(imagePath),
 	})
+	w.imageSourcePaths = append(w.imageSourcePaths, imagePath)
 
 	// Add texture
 	textureIdx := uint32(len(w.doc.Textures))
-	w.doc.Textures = append(w.doc.Textures, &gltf.Texture{
+	texture := &gltf.Texture{
 		Name:   imageName,
 		Source: gltf.Index(imageIdx),
-	})
+	}
+	w.doc.Textures = append(w.doc.Textures, texture)
+	w.addGpuTextureSource(texture, imagePath, imageName)
 
 	w.textureIndices[imagePath] = textureIdx
 	return textureIdx
 }
 
+// addGpuTextureSource references the DDS or KTX2 file next to a PNG texture.
+// DDS files become the MSFT_texture_dds source. KTX2 files hold uncompressed RGBA8 rather than
+// the Basis payloads KHR_texture_basisu requires, so they are only named in the texture extras.
+// The PNG stays the source for viewers without the extension.
+func (w *GltfWriter) addGpuTextureSource(texture *gltf.Texture, imagePath, imageName string) {
+	if w.textureFormat == infrastructure.TextureFormatPng {
+		return
+	}
+
+	gpuImagePath := infrastructure.GetTexturePath(imagePath, w.textureFormat)
+	if _, err := os.Stat(gpuImagePath); err != nil {
+		return
+	}
+
+	uri := "Textures/" + filepath.Base(gpuImagePath)
+	if w.textureFormat == infrastructure.TextureFormatKtx2 {
+		texture.Extras = map[string]interface{}{"ktx2": uri}
+		return
+	}
+
+	imageIdx := uint32(len(w.doc.Images))
+	w.doc.Images = append(w.doc.Images, &gltf.Image{
+		Name: imageName,
+		URI:  uri,
+	})
+	w.imageSourcePaths = append(w.imageSourcePaths, gpuImagePath)
+
+	texture.Extensions = gltf.Extensions{
+		"MSFT_texture_dds": map[string]interface{}{"source": imageIdx},
+	}
+	w.addExtensionUsed("MSFT_texture_dds")
+}
+
 // addBlankMaterial adds a blank white material.
 func (w *GltfWriter) addBlankMaterial() {
 	mat := &gltf.Material{
@@ -377,16 +561,53 @@ func (w *GltfWriter) AddFragmentDataWithOptions(
 
 	// Get or create mesh builder
 	var builder *meshBuilder
-	if generationMode == ModelGenerationModeCombine {
+	if animatedVertices := getAnimatedVerticesFromMesh(mesh); generationMode == ModelGenerationModeCombine && animatedVertices != nil {
+		// Morph targets cannot be shared with the rest of the combined mesh, so the
+		// animated mesh gets its own node when the combined mesh is added to the scene
+		builder = newMeshBuilder(helpers.CleanMeshName(mesh.GetName()), isSkinned, canExportVertexColors)
+		builder.setAnimatedVertices(animatedVertices)
+		w.combinedAnimatedMeshes = append(w.combinedAnimatedMeshes, builder)
+	} else if generationMode == ModelGenerationModeCombine {
 		if w.combinedMesh == nil {
 			w.combinedMesh = newMeshBuilder(meshName, isSkinned, canExportVertexColors)
 		}
 		builder = w.combinedMesh
 	} else {
 		builder = newMeshBuilder(meshName, isSkinned, canExportVertexColors)
+		builder.setAnimatedVertices(getAnimatedVerticesFromMesh(mesh))
+	}
+
+	w.addMeshTriangles(builder, mesh, canExportVertexColors, isSkinned, singularBoneIndex, objectInstance)
+
+	if generationMode == ModelGenerationModeSeparate {
+		// Build and add the mesh to the scene
+		transform := identityMatrix()
+		if isZoneMesh {
+			transform = correctedWorldMatrix()
+		} else {
+			transform = mirrorXAxisMatrix()
+		}
+
+		if objectInstance != nil {
+			objTransform := createTransformMatrixForObjectInstance(objectInstance)
+			transform = multiplyMatrices(objTransform, transform)
+		}
+
+		w.addMeshToScene(builder, transform)
+		w.sharedMeshes[meshName] = builder.meshData
 	}
+}
 
-	// Process triangles
+// addMeshTriangles adds the triangles of each material group to the mesh builder,
+// skipping the groups with boundary materials.
+func (w *GltfWriter) addMeshTriangles(
+	builder *meshBuilder,
+	mesh *fragments.Mesh,
+	canExportVertexColors bool,
+	isSkinned bool,
+	singularBoneIndex int,
+	objectInstance *fragments.ObjectInstance,
+) {
 	polygonIndex := 0
 	for _, materialGroup := range mesh.MaterialGroups {
 		if mesh.MaterialList == nil || materialGroup.MaterialIndex >= len(mesh.MaterialList.Materials) {
@@ -415,24 +636,6 @@ func (w *GltfWriter) AddFragmentDataWithOptions(
 			polygonIndex++
 		}
 	}
-
-	if generationMode == ModelGenerationModeSeparate {
-		// Build and add the mesh to the scene
-		transform := identityMatrix()
-		if isZoneMesh {
-			transform = correctedWorldMatrix()
-		} else {
-			transform = mirrorXAxisMatrix()
-		}
-
-		if objectInstance != nil {
-			objTransform := createTransformMatrixForObjectInstance(objectInstance)
-			transform = multiplyMatrices(objTransform, transform)
-		}
-
-		w.addMeshToScene(builder, transform)
-		w.sharedMeshes[meshName] = builder.meshData
-	}
 }
 
 // addTriangleToMesh adds a triangle to the mesh builder.
@@ -485,6 +688,10 @@ func (w *GltfWriter) addTriangleToMesh(
 		}
 		uv := mesh.TextureUvCoordinates[vi]
 		uvs[i] = [2]float32{uv.X, -uv.Y} // Negate V
+
+		if _, isAnimated := w.animatedTextures[matIdx]; isAnimated && isRepeatingUv(uvs[i]) {
+			w.useFirstAnimationFrame(matIdx)
+		}
 	}
 
 	// Get bone indices
@@ -524,6 +731,10 @@ func (w *GltfWriter) addTriangleToMesh(
 		if isSkinned {
 			key.joint = uint16(boneIndices[i])
 		}
+		if builder.animatedVertices != nil {
+			// Vertices sharing a position may still move independently
+			key.sourceVertex = vertexIndices[i]
+		}
 
 		if existingIdx, ok := builder.vertexMap[key]; ok {
 			indices[i] = existingIdx
@@ -542,6 +753,10 @@ func (w *GltfWriter) addTriangleToMesh(
 				builder.meshData.weights = append(builder.meshData.weights, [4]float32{1.0, 0.0, 0.0, 0.0})
 			}
 
+			if builder.animatedVertices != nil {
+				builder.addMorphDisplacements(mesh, vertexIndices[i], positions[i])
+			}
+
 			builder.vertexMap[key] = idx
 			indices[i] = idx
 		}
@@ -571,12 +786,15 @@ func (w *GltfWriter) AddCombinedMeshToScene(isZoneMesh bool, meshName string, sk
 				name:     meshName,
 				meshData: data,
 			}
+			if len(w.combinedAnimatedMeshes) == 0 {
+				w.combinedAnimatedMeshes = w.sharedAnimatedMeshes[meshName]
+			}
 		}
 	}
 	if builder == nil {
 		builder = w.combinedMesh
 	}
-	if builder == nil {
+	if builder == nil && len(w.combinedAnimatedMeshes) == 0 {
 		return
 	}
 
@@ -596,20 +814,23 @@ func (w *GltfWriter) AddCombinedMeshToScene(isZoneMesh bool, meshName string, sk
 		}
 	}
 
-	if skeletonModelBase != "" {
-		if skelData, exists := w.skeletons[skeletonModelBase]; exists {
-			w.addSkinnedMeshToScene(builder, transform, skelData)
+	skelData, hasSkeleton := w.skeletons[skeletonModelBase]
+	for _, sceneMesh := range append([]*meshBuilder{builder}, w.combinedAnimatedMeshes...) {
+		if skeletonModelBase != "" && hasSkeleton {
+			w.addSkinnedMeshToScene(sceneMesh, transform, skelData)
 		} else {
-			w.addMeshToScene(builder, transform)
+			w.addMeshToScene(sceneMesh, transform)
 		}
-	} else {
-		w.addMeshToScene(builder, transform)
 	}
 
 	if meshName != "" {
-		w.sharedMeshes[meshName] = builder.meshData
+		if builder != nil {
+			w.sharedMeshes[meshName] = builder.meshData
+		}
+		w.sharedAnimatedMeshes[meshName] = w.combinedAnimatedMeshes
 	}
 	w.combinedMesh = nil
+	w.combinedAnimatedMeshes = nil
 }
 
 // addMeshToScene adds a mesh to the scene with transform.
@@ -634,6 +855,8 @@ func (w *GltfWriter) addMeshToScene(builder *meshBuilder, transform [16]float32)
 	nodeIdx := uint32(len(w.doc.Nodes))
 	w.doc.Nodes = append(w.doc.Nodes, node)
 	w.doc.Scenes[0].Nodes = append(w.doc.Scenes[0].Nodes, nodeIdx)
+
+	w.addMorphAnimation(builder, nodeIdx)
 }
 
 // addSkinnedMeshToScene adds a skinned mesh to the scene.
@@ -674,6 +897,8 @@ func (w *GltfWriter) addSkinnedMeshToScene(builder *meshBuilder, transform [16]f
 	nodeIdx := uint32(len(w.doc.Nodes))
 	w.doc.Nodes = append(w.doc.Nodes, node)
 	w.doc.Scenes[0].Nodes = append(w.doc.Scenes[0].Nodes, nodeIdx)
+
+	w.addMorphAnimation(builder, nodeIdx)
 }
 
 // buildGltfMesh builds a glTF mesh from the builder.
@@ -700,6 +925,16 @@ func (w *GltfWriter) buildGltfMesh(builder *meshBuilder) uint32 {
 		weightAccessor = modeler.WriteWeights(w.doc, builder.meshData.weights)
 	}
 
+	// Morph targets are shared by all primitives, the first frame is the rest pose
+	morphAccessors := make([]uint32, 0, len(builder.meshData.morphTargets))
+	for _, displacements := range builder.meshData.morphTargets {
+		morphAccessors = append(morphAccessors, modeler.WritePosition(w.doc, displacements))
+	}
+	if len(morphAccessors) > 0 {
+		mesh.Weights = make([]float32, len(morphAccessors))
+		mesh.Weights[0] = 1
+	}
+
 	// Create primitives for each material
 	for matIdx, primData := range builder.meshData.primitives {
 		if len(primData.indices) == 0 {
@@ -728,6 +963,12 @@ func (w *GltfWriter) buildGltfMesh(builder *meshBuilder) uint32 {
 			prim.Attributes[gltf.WEIGHTS_0] = weightAccessor
 		}
 
+		for _, targetAccessor := range morphAccessors {
+			prim.Targets = append(prim.Targets, gltf.Attribute{gltf.POSITION: targetAccessor})
+		}
+
+		w.applyMaterialVariants(prim, matIdx)
+
 		mesh.Primitives = append(mesh.Primitives, prim)
 	}
 
@@ -815,28 +1056,35 @@ func (w *GltfWriter) ApplyAnimationToSkeleton(skeleton *fragments.SkeletonHierar
 		}
 		frames := getTrackDefFrames(trackDef)
 
-		totalTimeForBone := 0
-		for frame := 0; frame < animation.FrameCount; frame++ {
-			if frame >= len(frames) {
-				break
-			}
+		var frameMs int
+		if isCharacterAnimation {
+			frameMs = animation.AnimationTimeMs / animation.FrameCount
+		} else {
+			frameMs = skeleton.Skeleton[i].Track.GetFrameMs()
+		}
+
+		frameCount := animation.FrameCount
+		if frameCount > len(frames) {
+			frameCount = len(frames)
+		}
 
-			boneTransform := &frames[frame]
+		keys, rootMotion := w.getBoneKeyframes(frames[:frameCount], frameMs, i == 0)
 
-			if !staticPose && gltfAnim != nil {
-				w.addAnimationKeyframe(gltfAnim, nodeIdx, boneTransform, animationKey, float32(totalTimeForBone)/1000.0)
-				if frame == 0 && loopedAnimationKeys[animationKey] {
-					w.addAnimationKeyframe(gltfAnim, nodeIdx, boneTransform, animationKey, float32(animation.AnimationTimeMs)/1000.0)
+		if len(keys.times) > 0 {
+			// Looped animations end on their first frame so playback wraps smoothly,
+			// and the root motion gets a matching key continuing the movement into the next cycle
+			if loopedAnimationKeys[animationKey] && len(frames) > 0 {
+				endTime := float32(animation.AnimationTimeMs) / 1000.0
+				if endTime > keys.times[len(keys.times)-1] {
+					keys.add(endTime, &frames[0])
+					rootMotion.AddClosingKey(float64(animation.AnimationTimeMs))
 				}
-			} else {
-				w.applyBoneTransformation(nodeIdx, boneTransform, staticPose)
 			}
+			w.addBoneAnimationChannels(gltfAnim, nodeIdx, keys)
+		}
 
-			if isCharacterAnimation {
-				totalTimeForBone += animation.AnimationTimeMs / animation.FrameCount
-			} else {
-				totalTimeForBone += skeleton.Skeleton[i].Track.GetFrameMs()
-			}
+		if rootMotion.HasMotion() {
+			setAnimationExtra(gltfAnim, "rootMotion", getRootMotionExtras(rootMotion))
 		}
 	}
 
@@ -852,122 +1100,147 @@ func (w *GltfWriter) applyBoneTransformation(nodeIdx uint32, transform *datatype
 	}
 
 	node := w.doc.Nodes[nodeIdx]
+	translation, rotation, scale := helpers.BoneLocalTRSGltf(*transform)
 
-	// Convert rotation
-	rotX := transform.Rotation.X * math.Pi / 180.0
-	rotY := transform.Rotation.Z * math.Pi / 180.0 // Swap Y and Z
-	rotZ := -transform.Rotation.Y * math.Pi / 180.0
-	rotW := transform.Rotation.W * math.Pi / 180.0
-
-	// Normalize quaternion
-	length := float32(math.Sqrt(float64(rotX*rotX + rotY*rotY + rotZ*rotZ + rotW*rotW)))
-	if length > 0 {
-		rotX /= length
-		rotY /= length
-		rotZ /= length
-		rotW /= length
+	if staticPose {
+		node.Scale = scale
+		node.Rotation = rotation
+		node.Translation = translation
 	}
+}
 
-	// Convert translation (swap Y and Z, negate Z)
-	transX := transform.Translation.X
-	transY := transform.Translation.Z
-	transZ := -transform.Translation.Y
+// boneKeyframes holds the sampled transforms of a single bone for one animation.
+type boneKeyframes struct {
+	times        []float32
+	translations [][3]float32
+	rotations    [][4]float32
+	scales       [][3]float32
+}
 
-	scale := transform.Scale
-	if scale == 0 {
-		scale = 1.0
-	}
+// getBoneKeyframes returns the keyframes of a bone track in glTF space after applying the animation options.
+// When root motion is extracted, the horizontal movement and turn of the root bone are returned separately.
+func (w *GltfWriter) getBoneKeyframes(frames []datatypes.BoneTransform, frameMs int, isRoot bool) (*boneKeyframes, *animation.RootMotion) {
+	boneKeys := animation.FromFrames(frames, frameMs)
 
-	if staticPose {
-		node.Scale = [3]float32{scale, scale, scale}
-		node.Rotation = [4]float32{rotX, rotY, rotZ, rotW}
-		node.Translation = [3]float32{transX, transY, transZ}
+	var rootMotion *animation.RootMotion
+	if isRoot && w.animationOptions.ExtractRootMotion {
+		boneKeys, rootMotion = animation.ExtractRootMotion(boneKeys)
 	}
-}
 
-// addAnimationKeyframe adds a keyframe to an animation.
-func (w *GltfWriter) addAnimationKeyframe(anim *gltf.Animation, nodeIdx uint32, transform *datatypes.BoneTransform, animKey string, time float32) {
-	// Convert rotation
-	rotX := transform.Rotation.X * math.Pi / 180.0
-	rotY := transform.Rotation.Z * math.Pi / 180.0
-	rotZ := -transform.Rotation.Y * math.Pi / 180.0
-	rotW := transform.Rotation.W * math.Pi / 180.0
+	if w.animationOptions.IsEnabled() {
+		boneKeys = w.animationOptions.Apply(boneKeys)
+	}
 
-	// Normalize quaternion
-	length := float32(math.Sqrt(float64(rotX*rotX + rotY*rotY + rotZ*rotZ + rotW*rotW)))
-	if length > 0 {
-		rotX /= length
-		rotY /= length
-		rotZ /= length
-		rotW /= length
+	keys := &boneKeyframes{}
+	for i := range boneKeys {
+		keys.add(float32(boneKeys[i].TimeMs)/1000.0, &boneKeys[i].Transform)
 	}
+	return keys, rootMotion
+}
 
-	// Convert translation
-	transX := transform.Translation.X
-	transY := transform.Translation.Z
-	transZ := -transform.Translation.Y
+// add appends a keyframe converted to glTF space.
+func (k *boneKeyframes) add(time float32, transform *datatypes.BoneTransform) {
+	translation, rotation, scale := helpers.BoneLocalTRSGltf(*transform)
+	k.times = append(k.times, time)
+	k.translations = append(k.translations, translation)
+	k.rotations = append(k.rotations, rotation)
+	k.scales = append(k.scales, scale)
+}
 
-	scale := transform.Scale
-	if scale == 0 {
-		scale = 1.0
+// addMorphAnimation adds a looping weights animation that steps through each morph target in turn.
+func (w *GltfWriter) addMorphAnimation(builder *meshBuilder, nodeIdx uint32) {
+	frameCount := len(builder.meshData.morphTargets)
+	delayMs := builder.meshData.morphDelayMs
+	if frameCount < 2 || delayMs <= 0 {
+		return
 	}
 
-	// Add scale sampler
-	scaleInputAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, []float32{time})
-	scaleOutputAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, []float32{scale, scale, scale})
-	w.doc.Accessors[scaleOutputAccessor].Type = gltf.AccessorVec3
+	// One extra key returns to the first frame so the loop lasts the full cycle
+	times := make([]float32, 0, frameCount+1)
+	weights := make([]float32, 0, (frameCount+1)*frameCount)
+	for key := 0; key <= frameCount; key++ {
+		times = append(times, float32(key*delayMs)/1000.0)
+		for target := 0; target < frameCount; target++ {
+			if target == key%frameCount {
+				weights = append(weights, 1)
+			} else {
+				weights = append(weights, 0)
+			}
+		}
+	}
 
-	scaleSamplerIdx := uint32(len(anim.Samplers))
-	anim.Samplers = append(anim.Samplers, &gltf.AnimationSampler{
-		Input:         scaleInputAccessor,
-		Output:        scaleOutputAccessor,
-		Interpolation: gltf.InterpolationLinear,
-	})
-	anim.Channels = append(anim.Channels, &gltf.Channel{
-		Sampler: gltf.Index(scaleSamplerIdx),
-		Target: gltf.ChannelTarget{
-			Node: gltf.Index(nodeIdx),
-			Path: gltf.TRSScale,
-		},
+	timeAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, times)
+	w.setAccessorTimeBounds(timeAccessor, times)
+	weightsAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, weights)
+
+	w.doc.Animations = append(w.doc.Animations, &gltf.Animation{
+		Name: builder.name + "_vertex",
+		Samplers: []*gltf.AnimationSampler{{
+			Input:         timeAccessor,
+			Output:        weightsAccessor,
+			Interpolation: gltf.InterpolationStep,
+		}},
+		Channels: []*gltf.Channel{{
+			Sampler: gltf.Index(0),
+			Target: gltf.ChannelTarget{
+				Node: gltf.Index(nodeIdx),
+				Path: gltf.TRSWeights,
+			},
+		}},
 	})
+}
 
-	// Add rotation sampler
-	rotInputAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, []float32{time})
-	rotOutputAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, []float32{rotX, rotY, rotZ, rotW})
-	w.doc.Accessors[rotOutputAccessor].Type = gltf.AccessorVec4
+// addBoneAnimationChannels adds one sampler and channel per TRS path for a bone.
+// All paths share the same keyframe time accessor.
+func (w *GltfWriter) addBoneAnimationChannels(anim *gltf.Animation, nodeIdx uint32, keys *boneKeyframes) {
+	timeAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, keys.times)
+	w.setAccessorTimeBounds(timeAccessor, keys.times)
+
+	outputs := []struct {
+		accessor uint32
+		path     gltf.TRSProperty
+	}{
+		{modeler.WriteAccessor(w.doc, gltf.TargetNone, keys.scales), gltf.TRSScale},
+		{modeler.WriteAccessor(w.doc, gltf.TargetNone, keys.rotations), gltf.TRSRotation},
+		{modeler.WriteAccessor(w.doc, gltf.TargetNone, keys.translations), gltf.TRSTranslation},
+	}
+
+	for _, output := range outputs {
+		samplerIdx := uint32(len(anim.Samplers))
+		anim.Samplers = append(anim.Samplers, &gltf.AnimationSampler{
+			Input:         timeAccessor,
+			Output:        output.accessor,
+			Interpolation: gltf.InterpolationLinear,
+		})
+		anim.Channels = append(anim.Channels, &gltf.Channel{
+			Sampler: gltf.Index(samplerIdx),
+			Target: gltf.ChannelTarget{
+				Node: gltf.Index(nodeIdx),
+				Path: output.path,
+			},
+		})
+	}
+}
 
-	rotSamplerIdx := uint32(len(anim.Samplers))
-	anim.Samplers = append(anim.Samplers, &gltf.AnimationSampler{
-		Input:         rotInputAccessor,
-		Output:        rotOutputAccessor,
-		Interpolation: gltf.InterpolationLinear,
-	})
-	anim.Channels = append(anim.Channels, &gltf.Channel{
-		Sampler: gltf.Index(rotSamplerIdx),
-		Target: gltf.ChannelTarget{
-			Node: gltf.Index(nodeIdx),
-			Path: gltf.TRSRotation,
-		},
-	})
+// setAccessorTimeBounds sets the min and max of a keyframe time accessor.
+// glTF requires these bounds on animation sampler inputs.
+func (w *GltfWriter) setAccessorTimeBounds(accessorIdx uint32, times []float32) {
+	if len(times) == 0 {
+		return
+	}
 
-	// Add translation sampler
-	transInputAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, []float32{time})
-	transOutputAccessor := modeler.WriteAccessor(w.doc, gltf.TargetNone, []float32{transX, transY, transZ})
-	w.doc.Accessors[transOutputAccessor].Type = gltf.AccessorVec3
+	minTime, maxTime := times[0], times[0]
+	for _, t := range times {
+		if t < minTime {
+			minTime = t
+		}
+		if t > maxTime {
+			maxTime = t
+		}
+	}
 
-	transSamplerIdx := uint32(len(anim.Samplers))
-	anim.Samplers = append(anim.Samplers, &gltf.AnimationSampler{
-		Input:         transInputAccessor,
-		Output:        transOutputAccessor,
-		Interpolation: gltf.InterpolationLinear,
-	})
-	anim.Channels = append(anim.Channels, &gltf.Channel{
-		Sampler: gltf.Index(transSamplerIdx),
-		Target: gltf.ChannelTarget{
-			Node: gltf.Index(nodeIdx),
-			Path: gltf.TRSTranslation,
-		},
-	})
+	w.doc.Accessors[accessorIdx].Min = []float32{minTime}
+	w.doc.Accessors[accessorIdx].Max = []float32{maxTime}
 }
 
 // addNewSkeleton adds a new skeleton to the writer.
@@ -1041,12 +1314,145 @@ func (w *GltfWriter) WriteAssetToFile(fileName string, useExistingImages bool, s
 	}
 
 	if w.exportFormat == GltfExportFormatGlb {
+		if err := w.embedImages(); err != nil {
+			return err
+		}
 		return gltf.SaveBinary(w.doc, outputPath)
 	}
 
 	return gltf.Save(w.doc, outputPath)
 }
 
+// embedImages moves all referenced images into the binary buffer so the GLB is self-contained.
+// Images with identical content are stored once and every bufferView starts on a 4-byte boundary.
+func (w *GltfWriter) embedImages() error {
+	if len(w.doc.Images) == 0 {
+		return nil
+	}
+
+	if len(w.doc.Buffers) == 0 {
+		w.doc.Buffers = append(w.doc.Buffers, &gltf.Buffer{})
+	}
+	buffer := w.doc.Buffers[0]
+
+	// Images and textures may be shared with other writers through CopyMaterialList, so build new ones
+	// Images that can not be read are dropped, imageRemap is -1 for them
+	images := make([]*gltf.Image, 0, len(w.doc.Images))
+	imageRemap := make([]int, len(w.doc.Images))
+	imagesByHash := make(map[[sha256.Size]byte]int)
+	sourcePaths := make([]string, 0, len(w.doc.Images))
+
+	for i, image := range w.doc.Images {
+		if i >= len(w.imageSourcePaths) {
+			return fmt.Errorf("failed to embed image %s: source path unknown", image.Name)
+		}
+
+		data, err := os.ReadFile(w.imageSourcePaths[i])
+		if err != nil {
+			w.logger.LogWarning(fmt.Sprintf("GltfWriter: Unable to embed image %s: %v", image.Name, err))
+			imageRemap[i] = -1
+			continue
+		}
+
+		hash := sha256.Sum256(data)
+		if idx, exists := imagesByHash[hash]; exists {
+			imageRemap[i] = idx
+			continue
+		}
+
+		padBufferToAlignment(buffer)
+		bufferViewIdx := uint32(len(w.doc.BufferViews))
+		w.doc.BufferViews = append(w.doc.BufferViews, &gltf.BufferView{
+			Buffer:     0,
+			ByteOffset: buffer.ByteLength,
+			ByteLength: uint32(len(data)),
+		})
+		buffer.Data = append(buffer.Data, data...)
+		buffer.ByteLength = uint32(len(buffer.Data))
+
+		imageIdx := len(images)
+		images = append(images, &gltf.Image{
+			Name:       image.Name,
+			MimeType:   getImageMimeType(w.imageSourcePaths[i]),
+			BufferView: gltf.Index(bufferViewIdx),
+		})
+		sourcePaths = append(sourcePaths, w.imageSourcePaths[i])
+		imagesByHash[hash] = imageIdx
+		imageRemap[i] = imageIdx
+	}
+
+	padBufferToAlignment(buffer)
+
+	textures := make([]*gltf.Texture, len(w.doc.Textures))
+	for i, texture := range w.doc.Textures {
+		embedded := *texture
+		if texture.Source != nil && int(*texture.Source) < len(imageRemap) {
+			embedded.Source = nil
+			if source := imageRemap[*texture.Source]; source >= 0 {
+				embedded.Source = gltf.Index(uint32(source))
+			}
+		}
+		embedded.Extensions = remapTextureExtensionSources(texture.Extensions, imageRemap)
+		textures[i] = &embedded
+	}
+
+	w.doc.Images = images
+	w.doc.Textures = textures
+	w.imageSourcePaths = sourcePaths
+	return nil
+}
+
+// remapTextureExtensionSources returns texture extensions with their image sources pointing at the embedded images.
+// Extensions whose image was dropped are left out.
+func remapTextureExtensionSources(extensions gltf.Extensions, imageRemap []int) gltf.Extensions {
+	if len(extensions) == 0 {
+		return extensions
+	}
+
+	remapped := make(gltf.Extensions, len(extensions))
+	for name, value := range extensions {
+		remapped[name] = value
+
+		extension, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		source, ok := extension["source"].(uint32)
+		if !ok || int(source) >= len(imageRemap) {
+			continue
+		}
+
+		if imageRemap[source] < 0 {
+			delete(remapped, name)
+			continue
+		}
+		remapped[name] = map[string]interface{}{"source": uint32(imageRemap[source])}
+	}
+	return remapped
+}
+
+// padBufferToAlignment pads the buffer with zeros to the next 4-byte boundary.
+func padBufferToAlignment(buffer *gltf.Buffer) {
+	if padding := (4 - len(buffer.Data)%4) % 4; padding != 0 {
+		buffer.Data = append(buffer.Data, make([]byte, padding)...)
+	}
+	buffer.ByteLength = uint32(len(buffer.Data))
+}
+
+// getImageMimeType returns the glTF mime type for an image file.
+func getImageMimeType(imagePath string) string {
+	switch strings.ToLower(filepath.Ext(imagePath)) {
+	case ".jpg", ".jpeg":
+		return "image/jpeg"
+	case ".dds":
+		return "image/vnd-ms.dds"
+	case ".ktx2":
+		return "image/ktx2"
+	default:
+		return "image/png"
+	}
+}
+
 // ClearExportData clears all export data for reuse.
 func (w *GltfWriter) ClearExportData() {
 	w.doc = gltf.NewDocument()
@@ -1056,10 +1462,14 @@ func (w *GltfWriter) ClearExportData() {
 
 	w.Materials = make(map[string]uint32)
 	w.sharedMeshes = make(map[string]*meshData)
+	w.sharedAnimatedMeshes = make(map[string][]*meshBuilder)
 	w.skeletons = make(map[string]*skeletonData)
 	w.meshMaterialsToSkip = make(map[string]bool)
 	w.textureIndices = make(map[string]uint32)
+	w.imageSourcePaths = nil
+	w.animatedTextures = make(map[uint32]animatedTextureFrame)
 	w.combinedMesh = nil
+	w.combinedAnimatedMeshes = nil
 }
 
 // fixFilePath ensures the file has the correct extension.
@@ -1088,44 +1498,68 @@ func newMeshBuilder(name string, isSkinned, hasColors bool) *meshBuilder {
 	}
 }
 
+// setAnimatedVertices enables morph target export for the builder's vertices.
+func (b *meshBuilder) setAnimatedVertices(animatedVertices fragments.IAnimatedVertices) {
+	if animatedVertices == nil || len(animatedVertices.GetFrames()) == 0 {
+		return
+	}
+
+	b.animatedVertices = animatedVertices
+	b.meshData.morphTargets = make([][][3]float32, len(animatedVertices.GetFrames()))
+	b.meshData.morphDelayMs = animatedVertices.GetDelay()
+}
+
+// addMorphDisplacements records how far a new vertex moves from its base position in each frame.
+func (b *meshBuilder) addMorphDisplacements(mesh *fragments.Mesh, sourceVertex int, basePosition [3]float32) {
+	for frameIdx, frame := range b.animatedVertices.GetFrames() {
+		displacement := [3]float32{}
+		if sourceVertex < len(frame) {
+			v := frame[sourceVertex]
+			displacement = [3]float32{
+				v.X + mesh.Center.X - basePosition[0],
+				v.Z + mesh.Center.Z - basePosition[1],
+				v.Y + mesh.Center.Y - basePosition[2],
+			}
+		}
+		b.meshData.morphTargets[frameIdx] = append(b.meshData.morphTargets[frameIdx], displacement)
+	}
+}
+
 func getMaterialName(material *fragments.Material) string {
 	prefix := fragments.GetMaterialPrefix(material.ShaderType)
 	bitmapName := gltfGetBitmapNameWithoutExtension(material)
 	return prefix + bitmapName
 }
 
-func gltfGetBitmapNameWithoutExtension(material *fragments.Material) string {
+func gltfGetBitmapInfo(material *fragments.Material) *fragments.BitmapInfo {
 	if material.BitmapInfoReference == nil {
-		return ""
+		return nil
 	}
 
 	bitmapInfoRef, ok := material.BitmapInfoReference.(*fragments.BitmapInfoReference)
-	if !ok || bitmapInfoRef.BitmapInfo == nil {
-		return ""
-	}
-
-	if len(bitmapInfoRef.BitmapInfo.BitmapNames) == 0 {
-		return ""
+	if !ok {
+		return nil
 	}
 
-	return bitmapInfoRef.BitmapInfo.BitmapNames[0].GetFilenameWithoutExtension()
+	return bitmapInfoRef.BitmapInfo
 }
 
-func gltfGetBitmapExportFilename(material *fragments.Material) string {
-	if material.BitmapInfoReference == nil {
+func gltfGetBitmapNameWithoutExtension(material *fragments.Material) string {
+	bitmapInfo := gltfGetBitmapInfo(material)
+	if bitmapInfo == nil || len(bitmapInfo.BitmapNames) == 0 {
 		return ""
 	}
 
-	bitmapInfoRef, ok := material.BitmapInfoReference.(*fragments.BitmapInfoReference)
-	if !ok || bitmapInfoRef.BitmapInfo == nil {
-		return ""
-	}
+	return bitmapInfo.BitmapNames[0].GetFilenameWithoutExtension()
+}
 
-	if len(bitmapInfoRef.BitmapInfo.BitmapNames) == 0 {
+func gltfGetBitmapExportFilename(material *fragments.Material) string {
+	bitmapInfo := gltfGetBitmapInfo(material)
+	if bitmapInfo == nil || len(bitmapInfo.BitmapNames) == 0 {
 		return ""
 	}
 
-	return bitmapInfoRef.BitmapInfo.BitmapNames[0].GetExportFilename()
+	return bitmapInfo.BitmapNames[0].GetExportFilename()
 }
 
 func getBoneIndexForVertex(mesh *fragments.Mesh, vertexIndex int) int {
@@ -1177,7 +1611,8 @@ func hasObjectColors(objectInstance *fragments.ObjectInstance) bool {
 	return false
 }
 
-func getTrackDefFrames(trackDef interface{}) []datatypes.BoneTransform {
+// getTrackDefFrames returns the bone transforms of a track definition.
+func getTrackDefFrames(trackDef datatypes.TrackDefFragment) []datatypes.BoneTransform {
 	if td, ok := trackDef.(*fragments.TrackDefFragment); ok {
 		return td.Frames
 	}
@@ -1205,11 +1640,11 @@ func mirrorXAxisMatrix() [16]float32 {
 }
 
 func correctedWorldMatrix() [16]float32 {
-	// Mirror X axis * scale 0.1
+	// Mirror X axis * zone scale
 	return [16]float32{
-		-0.1, 0, 0, 0,
-		0, 0.1, 0, 0,
-		0, 0, 0.1, 0,
+		-zoneWorldScale, 0, 0, 0,
+		0, zoneWorldScale, 0, 0,
+		0, 0, zoneWorldScale, 0,
 		0, 0, 0, 1,
 	}
 }
@@ -0,0 +1,302 @@
+package exporters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qmuntal/gltf"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+func TestEmbedImages(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"a.png": {1, 2, 3, 4, 5},
+		"b.png": {1, 2, 3, 4, 5},
+		"c.png": {6, 7, 8},
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer := NewGltfWriter(false, GltfExportFormatGlb)
+	writer.addTexture(filepath.Join(dir, "a.png"), "a")
+	writer.addTexture(filepath.Join(dir, "b.png"), "b")
+	writer.addTexture(filepath.Join(dir, "c.png"), "c")
+
+	if err := writer.embedImages(); err != nil {
+		t.Fatalf("embedImages failed: %v", err)
+	}
+
+	doc := writer.doc
+	if len(doc.Images) != 2 {
+		t.Fatalf("Expected 2 unique images, got %d", len(doc.Images))
+	}
+	if len(doc.Textures) != 3 {
+		t.Fatalf("Expected 3 textures, got %d", len(doc.Textures))
+	}
+	if *doc.Textures[0].Source != *doc.Textures[1].Source {
+		t.Error("Expected identical images to share a source")
+	}
+	if *doc.Textures[2].Source == *doc.Textures[0].Source {
+		t.Error("Expected different images to have different sources")
+	}
+
+	for _, image := range doc.Images {
+		if image.URI != "" || image.BufferView == nil {
+			t.Fatalf("Expected image %s to be embedded", image.Name)
+		}
+		if doc.BufferViews[*image.BufferView].ByteOffset%4 != 0 {
+			t.Errorf("Expected image %s to be 4-byte aligned", image.Name)
+		}
+	}
+
+	if doc.Buffers[0].ByteLength%4 != 0 || int(doc.Buffers[0].ByteLength) != len(doc.Buffers[0].Data) {
+		t.Errorf("Expected padded buffer, got length %d", doc.Buffers[0].ByteLength)
+	}
+}
+
+func TestEmbedMissingImage(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.png"), []byte{1, 2, 3, 4}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	writer := NewGltfWriter(false, GltfExportFormatGlb)
+	writer.addTexture(filepath.Join(dir, "missing.png"), "missing")
+	writer.addTexture(filepath.Join(dir, "a.png"), "a")
+
+	outputPath := filepath.Join(dir, "model.glb")
+	if err := writer.WriteAssetToFile(outputPath, false, ""); err != nil {
+		t.Fatalf("Expected a missing image not to fail the export, got %v", err)
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Fatalf("Expected the GLB to be written: %v", err)
+	}
+
+	doc := writer.doc
+	if len(doc.Images) != 1 || doc.Images[0].Name != "a" {
+		t.Fatalf("Expected only image a to be embedded, got %d images", len(doc.Images))
+	}
+	if doc.Textures[0].Source != nil {
+		t.Errorf("Expected the texture of the missing image to have no source, got %d", *doc.Textures[0].Source)
+	}
+	if doc.Textures[1].Source == nil || *doc.Textures[1].Source != 0 {
+		t.Error("Expected the texture of image a to use the embedded image")
+	}
+}
+
+func TestGpuTextureSources(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.png", "a.dds", "a.ktx2"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte{1, 2, 3, 4}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+	writer.SetTextureFormat(infrastructure.TextureFormatDds)
+	texture := writer.doc.Textures[writer.addTexture(filepath.Join(dir, "a.png"), "a")]
+
+	dds, ok := texture.Extensions["MSFT_texture_dds"].(map[string]interface{})
+	if !ok || writer.doc.Images[dds["source"].(uint32)].URI != "Textures/a.dds" {
+		t.Fatalf("Expected the DDS file as the MSFT_texture_dds source, got %v", texture.Extensions)
+	}
+	if *texture.Source != 0 || len(writer.doc.ExtensionsUsed) != 1 {
+		t.Errorf("Expected the PNG fallback and one extension used, got %v", writer.doc.ExtensionsUsed)
+	}
+
+	// KTX2 files are uncompressed RGBA8, which KHR_texture_basisu does not allow
+	writer = NewGltfWriter(false, GltfExportFormatGlTF)
+	writer.SetTextureFormat(infrastructure.TextureFormatKtx2)
+	texture = writer.doc.Textures[writer.addTexture(filepath.Join(dir, "a.png"), "a")]
+
+	if texture.Extensions != nil || len(writer.doc.ExtensionsUsed) != 0 || len(writer.doc.Images) != 1 {
+		t.Errorf("Expected no texture extension for KTX2, got %v", texture.Extensions)
+	}
+	if extras, ok := texture.Extras.(map[string]interface{}); !ok || extras["ktx2"] != "Textures/a.ktx2" {
+		t.Errorf("Expected the KTX2 file in the texture extras, got %v", texture.Extras)
+	}
+}
+
+// newTestMaterial creates a diffuse material using a single bitmap.
+func newTestMaterial(name, bitmap string) *fragments.Material {
+	material := &fragments.Material{
+		ShaderType: fragments.ShaderTypeDiffuse,
+		BitmapInfoReference: &fragments.BitmapInfoReference{
+			BitmapInfo: &fragments.BitmapInfo{
+				BitmapNames: []*fragments.BitmapName{{Filename: bitmap}},
+			},
+		},
+	}
+	material.SetName(name)
+	return material
+}
+
+func TestMaterialVariants(t *testing.T) {
+	chest := newTestMaterial("HUMCH0001_MDF", "humch0001.bmp")
+	leg := newTestMaterial("HUMLG0001_MDF", "humlg0001.bmp")
+
+	list := &fragments.MaterialList{Materials: []*fragments.Material{chest, leg}}
+	list.SetName("HUM_MP")
+	list.BuildSlotMapping()
+	list.AddVariant(newTestMaterial("HUMCH0101_MDF", "humch0101.bmp"))
+	list.AddVariant(newTestMaterial("HUMCH0201_MDF", "humch0201.bmp"))
+
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+	writer.GenerateGltfMaterials([]*fragments.MaterialList{list}, t.TempDir()+"/")
+
+	chestIdx := writer.Materials["d_humch0001"]
+	legIdx := writer.Materials["d_humlg0001"]
+	if _, exists := writer.Materials["d_humch0201"]; !exists {
+		t.Fatal("Expected skin variant material to be generated")
+	}
+
+	chestPrim := &gltf.Primitive{}
+	writer.applyMaterialVariants(chestPrim, chestIdx)
+	mappings := chestPrim.Extensions[materialsVariantsExtension].(map[string]interface{})["mappings"].([]interface{})
+	if len(mappings) != 3 {
+		t.Fatalf("Expected a mapping for each chest skin, got %d", len(mappings))
+	}
+	if variants := mappings[2].(map[string]interface{})["variants"].([]int); len(variants) != 1 || variants[0] != 2 {
+		t.Errorf("Expected skin 02 to map to its own material, got %v", variants)
+	}
+
+	legPrim := &gltf.Primitive{}
+	writer.applyMaterialVariants(legPrim, legIdx)
+	if legPrim.Extensions != nil {
+		t.Error("Expected materials without skins to have no variant mappings")
+	}
+
+	variants := writer.doc.Extensions[materialsVariantsExtension].(map[string]interface{})["variants"].([]interface{})
+	if len(variants) != 3 {
+		t.Errorf("Expected 3 variants, got %d", len(variants))
+	}
+}
+
+func TestShaderTypes(t *testing.T) {
+	tests := []struct {
+		shader     fragments.ShaderType
+		alphaMode  gltf.AlphaMode
+		opacity    float32
+		isMasked   bool
+		isAdditive bool
+		isUnlit    bool
+	}{
+		{fragments.ShaderTypeDiffuse, gltf.AlphaOpaque, 1, false, false, false},
+		{fragments.ShaderTypeTransparent25, gltf.AlphaBlend, 0.25, false, false, false},
+		{fragments.ShaderTypeTransparent50, gltf.AlphaBlend, 0.5, false, false, false},
+		{fragments.ShaderTypeTransparent75, gltf.AlphaBlend, 0.75, false, false, false},
+		{fragments.ShaderTypeTransparentAdditive, gltf.AlphaBlend, 1, false, true, false},
+		{fragments.ShaderTypeTransparentAdditiveUnlit, gltf.AlphaBlend, 1, false, true, true},
+		{fragments.ShaderTypeTransparentMasked, gltf.AlphaMask, 1, true, false, false},
+		{fragments.ShaderTypeDiffuseSkydome, gltf.AlphaOpaque, 1, false, false, true},
+		{fragments.ShaderTypeTransparentSkydome, gltf.AlphaBlend, 0.5, false, false, true},
+		{fragments.ShaderTypeTransparentAdditiveUnlitSkydome, gltf.AlphaBlend, 1, false, true, true},
+	}
+
+	dir := t.TempDir() + "/"
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+
+	for _, test := range tests {
+		material := newTestMaterial(test.shader.String()+"_MDF", test.shader.String()+".bmp")
+		material.ShaderType = test.shader
+		material.Brightness = 2
+		material.ScaledAmbient = 0.75
+		if err := os.WriteFile(dir+gltfGetBitmapExportFilename(material), []byte{1}, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		writer.addMaterial(material, dir)
+		mat := writer.doc.Materials[writer.Materials[getMaterialName(material)]]
+		extras := mat.Extras.(map[string]interface{})
+
+		if mat.AlphaMode != test.alphaMode {
+			t.Errorf("%s: expected alpha mode %v, got %v", test.shader, test.alphaMode, mat.AlphaMode)
+		}
+		opacity := float32(1)
+		if factor := mat.PBRMetallicRoughness.BaseColorFactor; factor != nil {
+			opacity = factor[3]
+		}
+		if opacity != test.opacity {
+			t.Errorf("%s: expected opacity %v, got %v", test.shader, test.opacity, opacity)
+		}
+		if (mat.AlphaCutoff != nil && *mat.AlphaCutoff == materialAlphaCutoff) != test.isMasked {
+			t.Errorf("%s: expected masked %v, got cutoff %v", test.shader, test.isMasked, mat.AlphaCutoff)
+		}
+		if extras["shaderType"] != test.shader.String() {
+			t.Errorf("%s: expected the shader type extra, got %v", test.shader, extras["shaderType"])
+		}
+		if (extras["blendMode"] == "additive") != test.isAdditive {
+			t.Errorf("%s: expected additive %v, got blend mode %v", test.shader, test.isAdditive, extras["blendMode"])
+		}
+		if _, isUnlit := mat.Extensions["KHR_materials_unlit"]; isUnlit != test.isUnlit {
+			t.Errorf("%s: expected unlit %v", test.shader, test.isUnlit)
+		}
+
+		// Unlit materials are drawn at full brightness and are not emissive
+		strength, isEmissive := mat.Extensions["KHR_materials_emissive_strength"]
+		if isEmissive == test.isUnlit || (mat.EmissiveTexture != nil) == test.isUnlit {
+			t.Errorf("%s: expected emissive %v", test.shader, !test.isUnlit)
+		}
+		if isEmissive {
+			if value := strength.(map[string]interface{})["emissiveStrength"]; value != float32(1.5) {
+				t.Errorf("%s: expected emissive strength 1.5, got %v", test.shader, value)
+			}
+			if mat.EmissiveFactor != [3]float32{1, 1, 1} || mat.EmissiveTexture.Index != mat.PBRMetallicRoughness.BaseColorTexture.Index {
+				t.Errorf("%s: expected the base color texture to emit at full factor", test.shader)
+			}
+		}
+	}
+
+	// Invisible materials stay in the mesh but are never drawn, boundaries are dropped
+	invisible := newTestMaterial("INVIS_MDF", "invis.bmp")
+	invisible.ShaderType = fragments.ShaderTypeInvisible
+	boundary := newTestMaterial("BOUNDARY_MDF", "boundary.bmp")
+	boundary.ShaderType = fragments.ShaderTypeBoundary
+	writer.addMaterial(invisible, dir)
+	writer.addMaterial(boundary, dir)
+
+	invisibleIdx, exists := writer.Materials[getMaterialName(invisible)]
+	if !exists || writer.doc.Materials[invisibleIdx].PBRMetallicRoughness.BaseColorFactor[3] != 0 {
+		t.Error("Expected a fully transparent invisible material")
+	}
+	if _, exists := writer.Materials[getMaterialName(boundary)]; exists || !writer.meshMaterialsToSkip[getMaterialName(boundary)] {
+		t.Error("Expected the boundary material to be skipped")
+	}
+}
+
+func TestMaterialEmissive(t *testing.T) {
+	tests := []struct {
+		brightness    float32
+		scaledAmbient float32
+		factor        float32
+		strength      float32
+	}{
+		{0, 1, 0, 0},
+		{0.5, 0, 0.5, 0},
+		{0.5, 0.5, 0.25, 0},
+		{4, 0.5, 1, 2},
+	}
+
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+	for _, test := range tests {
+		mat := &gltf.Material{}
+		writer.applyMaterialEmissive(mat, &fragments.Material{Brightness: test.brightness, ScaledAmbient: test.scaledAmbient})
+
+		if mat.EmissiveFactor != [3]float32{test.factor, test.factor, test.factor} {
+			t.Errorf("Brightness %v: expected emissive factor %v, got %v", test.brightness, test.factor, mat.EmissiveFactor)
+		}
+
+		extension, exists := mat.Extensions["KHR_materials_emissive_strength"]
+		if exists != (test.strength > 0) {
+			t.Errorf("Brightness %v: expected emissive strength %v, got %v", test.brightness, test.strength, extension)
+		} else if exists && extension.(map[string]interface{})["emissiveStrength"] != test.strength {
+			t.Errorf("Brightness %v: expected emissive strength %v, got %v", test.brightness, test.strength, extension)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package exporters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qmuntal/gltf"
+	"github.com/tmyhres/LanternGoExtract/pkg/sound"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/bsp"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// ZoneSceneData holds zone data that is added to the zone glTF scene alongside the meshes.
+type ZoneSceneData struct {
+	// LightInstances are the placed lights from the lights WLD.
+	LightInstances []*fragments.LightInstance
+
+	// AmbientLight is the global ambient light of the zone, if any.
+	AmbientLight *fragments.GlobalAmbientLight
+
+	// AudioInstances are the sound emitters and music regions of the zone.
+	AudioInstances []sound.AudioInstance
+
+	// RegionVolumes are the water, lava, PvP and zoneline volumes built from the BSP tree.
+	RegionVolumes []*bsp.RegionVolume
+}
+
+// AddZoneSceneData adds lights and other zone data to the scene.
+func (w *GltfWriter) AddZoneSceneData(data *ZoneSceneData) {
+	if data == nil {
+		return
+	}
+
+	w.AddLightInstances(data.LightInstances)
+	w.AddAudioInstances(data.AudioInstances)
+	w.AddRegionVolumes(data.RegionVolumes)
+
+	if data.AmbientLight != nil {
+		color := data.AmbientLight.Color
+		w.setSceneExtra("ambientLight", [3]float32{
+			float32(color.R) / 255.0,
+			float32(color.G) / 255.0,
+			float32(color.B) / 255.0,
+		})
+	}
+}
+
+// AddLightInstances adds each light instance as a KHR_lights_punctual point light.
+// The lights are grouped under one node that uses the same transform as the zone mesh.
+func (w *GltfWriter) AddLightInstances(lightInstances []*fragments.LightInstance) {
+	if len(lightInstances) == 0 {
+		return
+	}
+
+	lights := make([]interface{}, 0, len(lightInstances))
+	groupNode := &gltf.Node{
+		Name:   "Lights",
+		Matrix: correctedWorldMatrix(),
+	}
+
+	for i, instance := range lightInstances {
+		if instance == nil {
+			continue
+		}
+
+		lightIdx := len(lights)
+		lights = append(lights, createPointLight(instance))
+
+		nodeIdx := uint32(len(w.doc.Nodes))
+		w.doc.Nodes = append(w.doc.Nodes, &gltf.Node{
+			Name: lightNodeName(instance, i),
+			Translation: [3]float32{
+				instance.Position.X,
+				instance.Position.Z,
+				instance.Position.Y,
+			},
+			Extensions: map[string]interface{}{
+				"KHR_lights_punctual": map[string]interface{}{
+					"light": lightIdx,
+				},
+			},
+		})
+		groupNode.Children = append(groupNode.Children, nodeIdx)
+	}
+
+	if len(lights) == 0 {
+		return
+	}
+
+	groupIdx := uint32(len(w.doc.Nodes))
+	w.doc.Nodes = append(w.doc.Nodes, groupNode)
+	w.doc.Scenes[0].Nodes = append(w.doc.Scenes[0].Nodes, groupIdx)
+
+	if w.doc.Extensions == nil {
+		w.doc.Extensions = map[string]interface{}{}
+	}
+	w.doc.Extensions["KHR_lights_punctual"] = map[string]interface{}{
+		"lights": lights,
+	}
+	w.addExtensionUsed("KHR_lights_punctual")
+}
+
+// createPointLight converts a light instance to a KHR_lights_punctual light definition.
+// Light properties ignore node scale, so the range is converted to world units.
+// The intensity gives full brightness at half the range.
+func createPointLight(instance *fragments.LightInstance) map[string]interface{} {
+	color := [3]float32{1, 1, 1}
+	if instance.LightReference != nil && instance.LightReference.LightSource != nil &&
+		instance.LightReference.LightSource.IsColoredLight {
+		sourceColor := instance.LightReference.LightSource.Color
+		color = [3]float32{sourceColor.X, sourceColor.Y, sourceColor.Z}
+	}
+
+	lightRange := instance.Radius * zoneWorldScale
+	halfRange := lightRange / 2
+
+	light := map[string]interface{}{
+		"type":      "point",
+		"color":     color,
+		"intensity": halfRange * halfRange,
+		"extras": map[string]interface{}{
+			"radius": instance.Radius,
+		},
+	}
+
+	if lightRange > 0 {
+		light["range"] = lightRange
+	}
+
+	return light
+}
+
+// lightNodeName returns the node name for a light instance.
+func lightNodeName(instance *fragments.LightInstance, index int) string {
+	if instance.LightReference != nil && instance.LightReference.LightSource != nil &&
+		instance.LightReference.LightSource.GetName() != "" {
+		return fmt.Sprintf("%s_%d", instance.LightReference.LightSource.GetName(), index)
+	}
+	return fmt.Sprintf("light_%d", index)
+}
+
+// AddAudioInstances adds each sound emitter and music region as an empty node.
+// The sound names, radius, cooldowns and volumes are stored in the node extras.
+func (w *GltfWriter) AddAudioInstances(audioInstances []sound.AudioInstance) {
+	if len(audioInstances) == 0 {
+		return
+	}
+
+	groupNode := &gltf.Node{
+		Name:   "Sounds",
+		Matrix: correctedWorldMatrix(),
+	}
+
+	for i, instance := range audioInstances {
+		extras := getAudioInstanceExtras(instance)
+		if extras == nil {
+			continue
+		}
+
+		x, y, z := instance.Position()
+		nodeIdx := uint32(len(w.doc.Nodes))
+		w.doc.Nodes = append(w.doc.Nodes, &gltf.Node{
+			Name:        fmt.Sprintf("%s_%d", strings.ToLower(instance.Type().String()), i),
+			Translation: [3]float32{x, z, y},
+			Extras:      extras,
+		})
+		groupNode.Children = append(groupNode.Children, nodeIdx)
+	}
+
+	if len(groupNode.Children) == 0 {
+		return
+	}
+
+	groupIdx := uint32(len(w.doc.Nodes))
+	w.doc.Nodes = append(w.doc.Nodes, groupNode)
+	w.doc.Scenes[0].Nodes = append(w.doc.Scenes[0].Nodes, groupIdx)
+}
+
+// getAudioInstanceExtras returns the extras for an audio instance node.
+// Sound names refer to the WAV files extracted from the sound archives.
+func getAudioInstanceExtras(instance sound.AudioInstance) map[string]interface{} {
+	extras := map[string]interface{}{
+		"audioType": instance.Type().String(),
+		"radius":    instance.Radius(),
+	}
+
+	switch audio := instance.(type) {
+	case *sound.SoundInstance2D:
+		extras["soundDay"] = audio.Sound1
+		extras["soundNight"] = audio.Sound2
+		extras["cooldownDay"] = audio.Cooldown1
+		extras["cooldownNight"] = audio.Cooldown2
+		extras["cooldownRandom"] = audio.CooldownRandom
+		extras["volumeDay"] = audio.Volume1
+		extras["volumeNight"] = audio.Volume2
+	case *sound.SoundInstance3D:
+		extras["sound"] = audio.Sound1
+		extras["cooldown"] = audio.Cooldown1
+		extras["cooldownRandom"] = audio.CooldownRandom
+		extras["volume"] = audio.Volume1
+		extras["multiplier"] = audio.Multiplier
+	case *sound.MusicInstance:
+		extras["trackIndexDay"] = audio.TrackIndexDay
+		extras["trackIndexNight"] = audio.TrackIndexNight
+		extras["loopCountDay"] = audio.LoopCountDay
+		extras["loopCountNight"] = audio.LoopCountNight
+		extras["fadeOutMs"] = audio.FadeOutMs
+	default:
+		return nil
+	}
+
+	return extras
+}
+
+// setSceneExtra sets a value in the scene extras, keeping any that are already present.
+func (w *GltfWriter) setSceneExtra(key string, value interface{}) {
+	scene := w.doc.Scenes[0]
+	extras, ok := scene.Extras.(map[string]interface{})
+	if !ok {
+		extras = map[string]interface{}{}
+		scene.Extras = extras
+	}
+	extras[key] = value
+}
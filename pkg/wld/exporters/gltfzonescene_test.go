@@ -0,0 +1,127 @@
+package exporters
+
+import (
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/sound"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+func TestAddZoneSceneData(t *testing.T) {
+	source := &fragments.LightSource{
+		IsColoredLight: true,
+		Color:          datatypes.Vec4{X: 1, Y: 0.5, Z: 0.25, W: 1},
+	}
+	source.SetName("TORCH_LDEF")
+
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+	writer.AddZoneSceneData(&ZoneSceneData{
+		LightInstances: []*fragments.LightInstance{{
+			LightReference: &fragments.LightSourceReference{LightSource: source},
+			Position:       datatypes.Vec3{X: 10, Y: 20, Z: 30},
+			Radius:         40,
+		}},
+		AmbientLight: &fragments.GlobalAmbientLight{Color: datatypes.NewColor(51, 102, 255, 255)},
+	})
+
+	if len(writer.doc.Nodes) != 2 || len(writer.doc.Scenes[0].Nodes) != 1 {
+		t.Fatalf("Expected one light under a group node, got %d nodes", len(writer.doc.Nodes))
+	}
+
+	// The group uses the zone transform, the light node is in EQ units with Y and Z swapped
+	group := writer.doc.Nodes[writer.doc.Scenes[0].Nodes[0]]
+	if group.Name != "Lights" || group.Matrix != correctedWorldMatrix() || len(group.Children) != 1 {
+		t.Fatalf("Expected a Lights group with the zone transform, got %+v", group)
+	}
+	node := writer.doc.Nodes[group.Children[0]]
+	if node.Name != "TORCH_LDEF_0" || node.Translation != [3]float32{10, 30, 20} {
+		t.Errorf("Expected TORCH_LDEF_0 at (10, 30, 20), got %s at %v", node.Name, node.Translation)
+	}
+	if node.Extensions["KHR_lights_punctual"].(map[string]interface{})["light"] != 0 {
+		t.Errorf("Expected the node to use light 0, got %v", node.Extensions)
+	}
+
+	lights := writer.doc.Extensions["KHR_lights_punctual"].(map[string]interface{})["lights"].([]interface{})
+	if len(lights) != 1 {
+		t.Fatalf("Expected one light definition, got %d", len(lights))
+	}
+
+	// Light ranges ignore the node scale, so the range is converted to world units
+	light := lights[0].(map[string]interface{})
+	if light["type"] != "point" || light["range"] != float32(4) || light["intensity"] != float32(4) {
+		t.Errorf("Expected a point light with range 4 and intensity 4, got %v", light)
+	}
+	if light["color"] != [3]float32{1, 0.5, 0.25} {
+		t.Errorf("Expected the light source color, got %v", light["color"])
+	}
+	if light["extras"].(map[string]interface{})["radius"] != float32(40) {
+		t.Errorf("Expected the EQ radius in the extras, got %v", light["extras"])
+	}
+	if len(writer.doc.ExtensionsUsed) != 1 || writer.doc.ExtensionsUsed[0] != "KHR_lights_punctual" {
+		t.Errorf("Expected KHR_lights_punctual to be used, got %v", writer.doc.ExtensionsUsed)
+	}
+
+	ambient := writer.doc.Scenes[0].Extras.(map[string]interface{})["ambientLight"]
+	if ambient != [3]float32{0.2, 0.4, 1} {
+		t.Errorf("Expected the ambient light in the scene extras, got %v", ambient)
+	}
+}
+
+func TestAddAudioInstances(t *testing.T) {
+	writer := NewGltfWriter(false, GltfExportFormatGlTF)
+	writer.AddAudioInstances([]sound.AudioInstance{
+		sound.NewSoundInstance2D(sound.AudioTypeSound2D, 0, 0, 0, 50, 0.5, "wind.wav", 100, "owl.wav", 200, 10, 0.25),
+		sound.NewSoundInstance3D(sound.AudioTypeSound3D, 10, 20, 30, 40, 1, "fire.wav", 0, 5, 2),
+		sound.NewMusicInstance(sound.AudioTypeMusic, -10, 0, 5, 200, 1, 2, 0, 1, 3000),
+	})
+
+	if len(writer.doc.Nodes) != 4 || len(writer.doc.Scenes[0].Nodes) != 1 {
+		t.Fatalf("Expected three sounds under a group node, got %d nodes", len(writer.doc.Nodes))
+	}
+	group := writer.doc.Nodes[writer.doc.Scenes[0].Nodes[0]]
+	if group.Name != "Sounds" || len(group.Children) != 3 {
+		t.Fatalf("Expected a Sounds group with three children, got %+v", group)
+	}
+
+	// Node positions in the scene after the zone transform of the group
+	expectedPositions := [][3]float32{{0, 0, 0}, {-1, 3, 2}, {1, 0.5, 0}}
+	expectedNames := []string{"sound2d_0", "sound3d_1", "music_2"}
+	for i, child := range group.Children {
+		node := writer.doc.Nodes[child]
+		var position [3]float32
+		for axis := range position {
+			position[axis] = group.Matrix[axis]*node.Translation[0] + group.Matrix[4+axis]*node.Translation[1] +
+				group.Matrix[8+axis]*node.Translation[2] + group.Matrix[12+axis]
+		}
+		if node.Name != expectedNames[i] || position != expectedPositions[i] {
+			t.Errorf("Expected %s at %v, got %s at %v", expectedNames[i], expectedPositions[i], node.Name, position)
+		}
+	}
+
+	expectedKeys := [][]string{
+		{"audioType", "radius", "soundDay", "soundNight", "cooldownDay", "cooldownNight", "cooldownRandom", "volumeDay", "volumeNight"},
+		{"audioType", "radius", "sound", "cooldown", "cooldownRandom", "volume", "multiplier"},
+		{"audioType", "radius", "trackIndexDay", "trackIndexNight", "loopCountDay", "loopCountNight", "fadeOutMs"},
+	}
+	for i, child := range group.Children {
+		extras := writer.doc.Nodes[child].Extras.(map[string]interface{})
+		if len(extras) != len(expectedKeys[i]) {
+			t.Errorf("Expected %d extras on %s, got %v", len(expectedKeys[i]), expectedNames[i], extras)
+		}
+		for _, key := range expectedKeys[i] {
+			if _, exists := extras[key]; !exists {
+				t.Errorf("Expected %s in the extras of %s", key, expectedNames[i])
+			}
+		}
+	}
+
+	extras := writer.doc.Nodes[group.Children[0]].Extras.(map[string]interface{})
+	if extras["audioType"] != "Sound2D" || extras["soundDay"] != "wind.wav" || extras["soundNight"] != "owl.wav" || extras["radius"] != float32(50) {
+		t.Errorf("Expected the day and night sounds of the 2D sound, got %v", extras)
+	}
+	extras = writer.doc.Nodes[group.Children[2]].Extras.(map[string]interface{})
+	if extras["trackIndexNight"] != int32(2) || extras["fadeOutMs"] != int32(3000) {
+		t.Errorf("Expected the music tracks and fade out, got %v", extras)
+	}
+}
@@ -4,21 +4,13 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
 )
 
-// MeshExporterSettings contains configuration for mesh export.
-type MeshExporterSettings struct {
-	// ExportZoneMeshGroups exports zone meshes as groups.
-	ExportZoneMeshGroups bool
-
-	// ExportCharactersToSingleFolder exports all characters to a single folder.
-	ExportCharactersToSingleFolder bool
-}
-
 // WldFileInterface defines the minimal interface needed for mesh export.
 type WldFileInterface interface {
 	GetWldType() wld.WldType
@@ -34,7 +26,7 @@ func ExportMeshes(
 	wldType wld.WldType,
 	exportFolder string,
 	zoneShortname string,
-	settings *MeshExporterSettings,
+	settings *config.Settings,
 	log logger.Logger,
 ) error {
 	meshFolder := "Meshes/"
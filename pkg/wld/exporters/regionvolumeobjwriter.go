@@ -0,0 +1,97 @@
+package exporters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/bsp"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// RegionVolumeObjWriter exports region volumes to an OBJ file with one group per volume.
+// Vertices use the same coordinate conversion as the zone OBJ so both files line up.
+type RegionVolumeObjWriter struct {
+	TextAssetWriter
+
+	// baseVertex is the number of vertices written by previous volumes.
+	baseVertex int
+}
+
+// NewRegionVolumeObjWriter creates a new RegionVolumeObjWriter.
+func NewRegionVolumeObjWriter() *RegionVolumeObjWriter {
+	w := &RegionVolumeObjWriter{}
+	w.AppendLine(ExportHeaderTitle + "Region Volumes")
+	return w
+}
+
+// AddRegionVolume adds a region volume as a named group.
+// The region types and zoneline destination are written as comments before the group.
+func (w *RegionVolumeObjWriter) AddRegionVolume(volume *bsp.RegionVolume) {
+	if volume == nil || len(volume.Triangles) == 0 {
+		return
+	}
+
+	regionTypes := make([]string, 0, len(volume.RegionTypes))
+	for _, t := range volume.RegionTypes {
+		regionTypes = append(regionTypes, t.String())
+	}
+
+	w.AppendLine("# RegionTypes: " + strings.Join(regionTypes, ","))
+	w.AppendLine(fmt.Sprintf("# RegionCount: %d", volume.RegionCount))
+	if volume.RegionString != "" {
+		w.AppendLine("# RegionString: " + volume.RegionString)
+	}
+	if zoneline := formatZoneline(volume.Zoneline); zoneline != "" {
+		w.AppendLine("# Zoneline: " + zoneline)
+	}
+	w.AppendLine("g " + volume.Name)
+
+	for _, v := range volume.Vertices {
+		w.AppendLine(fmt.Sprintf("v %f %f %f", -v.X, v.Z, v.Y))
+	}
+
+	for _, triangle := range volume.Triangles {
+		w.AppendLine(fmt.Sprintf("f %d %d %d",
+			w.baseVertex+triangle[0]+1,
+			w.baseVertex+triangle[1]+1,
+			w.baseVertex+triangle[2]+1))
+	}
+
+	w.baseVertex += len(volume.Vertices)
+}
+
+// ExportRegionVolumesToObj writes the region volumes of a zone next to the zone OBJ.
+func ExportRegionVolumesToObj(volumes []*bsp.RegionVolume, exportFolder, zoneShortname string) error {
+	if len(volumes) == 0 {
+		return nil
+	}
+
+	writer := NewRegionVolumeObjWriter()
+	for _, volume := range volumes {
+		writer.AddRegionVolume(volume)
+	}
+
+	if err := writer.WriteAssetToFile(getMeshPath(exportFolder, zoneShortname+"_regions")); err != nil {
+		return fmt.Errorf("failed to write region volumes: %w", err)
+	}
+
+	return nil
+}
+
+// formatZoneline describes a zoneline destination on a single line.
+func formatZoneline(zoneline *datatypes.ZonelineInfo) string {
+	if zoneline == nil {
+		return ""
+	}
+
+	if zoneline.Type == datatypes.ZonelineTypeReference {
+		return fmt.Sprintf("reference index=%d", zoneline.Index)
+	}
+
+	return fmt.Sprintf("absolute zone=%d position=%s,%s,%s heading=%d",
+		zoneline.ZoneIndex,
+		formatFloat(zoneline.Position.X),
+		formatFloat(zoneline.Position.Y),
+		formatFloat(zoneline.Position.Z),
+		zoneline.Heading)
+}
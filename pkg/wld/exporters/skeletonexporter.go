@@ -0,0 +1,86 @@
+package exporters
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/animation"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// ExportSkeletonsAndAnimations exports the skeletons of a WLD file to the 'Skeletons' folder
+// and each of their animations to the 'Animations' folder in the intermediate format.
+func ExportSkeletonsAndAnimations(skeletons []*fragments.SkeletonHierarchy, wldType wld.WldType,
+	exportFolder string, settings *config.Settings) error {
+	skeletonsFolder := exportFolder + "Skeletons/"
+	animationsFolder := exportFolder + "Animations/"
+
+	isCharacterAnimation := wldType == wld.WldTypeCharacters
+	skeletonWriter := NewSkeletonHierarchyWriter(isCharacterAnimation)
+	animationWriter := NewAnimationWriter(isCharacterAnimation)
+	animationWriter.SetAnimationOptions(getAnimationOptions(settings))
+
+	for _, skeleton := range skeletons {
+		filePath := skeletonsFolder + skeleton.ModelBase + ".txt"
+		skeletonWriter.AddFragmentData(skeleton)
+
+		// Characters exported to a single folder keep the most complete skeleton
+		keepExisting := false
+		if isCharacterAnimation && settings.ExportCharactersToSingleFolder && fileExists(filePath) {
+			if oldContent, err := os.ReadFile(filePath); err == nil {
+				keepExisting = skeletonWriter.GetExportByteCount() <= len(oldContent)
+			}
+		}
+
+		if !keepExisting {
+			if err := skeletonWriter.WriteAssetToFile(filePath); err != nil {
+				return err
+			}
+		}
+		skeletonWriter.ClearExportData()
+
+		for animationKey, anim := range skeleton.Animations {
+			modelBase := anim.AnimModelBase
+			if modelBase == "" {
+				modelBase = skeleton.ModelBase
+			}
+
+			animationWriter.SetTargetAnimation(animationKey)
+			animationWriter.AddFragmentData(skeleton)
+			filePath := animationsFolder + modelBase + "_" + animationKey
+			if err := animationWriter.WriteAssetToFile(filePath + ".txt"); err != nil {
+				return err
+			}
+			animationWriter.ClearExportData()
+
+			if rootMotion := animationWriter.RootMotion(); rootMotion.HasMotion() {
+				if err := writeRootMotion(filePath+"_rootmotion.txt", rootMotion); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeRootMotion writes a root motion curve in the coordinate system of the intermediate animation tracks.
+func writeRootMotion(filePath string, motion *animation.RootMotion) error {
+	writer := &TextAssetWriter{}
+	writer.AppendLine(ExportHeaderTitle + "Root Motion")
+	writer.AppendLine(ExportHeaderFormat + "Frame, TimeMs, TranslationX, TranslationY, TranslationZ, YawDegrees")
+
+	for i, key := range motion.Keys {
+		// Y and Z are swapped like the animation tracks, which also mirrors the turn direction
+		writer.AppendLine(fmt.Sprintf("%d,%g,%g,%g,%g,%g", i, key.TimeMs,
+			key.Translation.X, key.Translation.Z, key.Translation.Y, -key.Yaw*180/math.Pi))
+	}
+
+	if err := writer.WriteAssetToFile(filePath); err != nil {
+		return fmt.Errorf("failed to write root motion: %w", err)
+	}
+	return nil
+}
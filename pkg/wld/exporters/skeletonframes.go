@@ -0,0 +1,280 @@
+package exporters
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/helpers"
+)
+
+// defaultFrameTimeMs is the frame time used for animations without timing, e.g. poses.
+const defaultFrameTimeMs = 100
+
+// skeletonFrames holds the local transform of every bone for each frame of an animation.
+type skeletonFrames struct {
+	// frames is indexed by frame, then by bone.
+	frames [][]datatypes.BoneTransform
+
+	// frameTimeMs is the time between frames in milliseconds.
+	frameTimeMs int
+}
+
+// sampleSkeletonAnimation returns the local bone transforms of each frame of an animation.
+// Bones without a track hold their pose and shorter tracks hold their last frame.
+// Returns nil if the skeleton has no such animation.
+func sampleSkeletonAnimation(skeleton *fragments.SkeletonHierarchy, animationKey string, isCharacterAnimation bool) *skeletonFrames {
+	animation, exists := skeleton.Animations[animationKey]
+	if !exists || animation == nil {
+		return nil
+	}
+
+	pose := skeleton.Animations[defaultModelPoseAnimKey]
+
+	frameCount := animation.FrameCount
+	if frameCount < 1 {
+		frameCount = 1
+	}
+
+	frameTimeMs := defaultFrameTimeMs
+	if animation.FrameCount > 0 && animation.AnimationTimeMs > 0 {
+		frameTimeMs = animation.AnimationTimeMs / animation.FrameCount
+	}
+
+	sampled := &skeletonFrames{
+		frames:      make([][]datatypes.BoneTransform, frameCount),
+		frameTimeMs: frameTimeMs,
+	}
+	for frame := range sampled.frames {
+		sampled.frames[frame] = make([]datatypes.BoneTransform, len(skeleton.Skeleton))
+	}
+
+	for i := range skeleton.Skeleton {
+		boneFrames := getAnimationBoneFrames(skeleton, animation, i, isCharacterAnimation)
+		if len(boneFrames) == 0 && pose != nil {
+			boneFrames = getAnimationBoneFrames(skeleton, pose, i, isCharacterAnimation)
+			if len(boneFrames) > 1 {
+				boneFrames = boneFrames[:1]
+			}
+		}
+
+		for frame := 0; frame < frameCount; frame++ {
+			switch {
+			case len(boneFrames) == 0:
+				sampled.frames[frame][i] = datatypes.BoneTransform{Rotation: datatypes.Quat{W: 1}, Scale: 1}
+			case frame < len(boneFrames):
+				sampled.frames[frame][i] = boneFrames[frame]
+			default:
+				sampled.frames[frame][i] = boneFrames[len(boneFrames)-1]
+			}
+		}
+	}
+
+	return sampled
+}
+
+// getAnimationBoneFrames returns the frames of the track animating a bone, looked up the way
+// GltfWriter.ApplyAnimationToSkeleton looks them up.
+func getAnimationBoneFrames(skeleton *fragments.SkeletonHierarchy, animation *datatypes.Animation, boneIndex int, isCharacterAnimation bool) []datatypes.BoneTransform {
+	var track datatypes.TrackFragment
+	if isCharacterAnimation {
+		track = animation.TracksCleanedStripped[datatypes.CleanBoneAndStripBase(skeleton.BoneMapping[boneIndex], skeleton.ModelBase)]
+	} else {
+		track = animation.TracksCleaned[datatypes.CleanBoneName(skeleton.BoneMapping[boneIndex])]
+	}
+
+	if track == nil {
+		return nil
+	}
+
+	trackFragment, ok := track.(*fragments.TrackFragment)
+	if !ok || trackFragment.TrackDefFragment == nil {
+		return nil
+	}
+
+	return trackFragment.TrackDefFragment.Frames
+}
+
+// getExportedAnimationKeys returns the sorted animation keys of a skeleton to export.
+// The pose of a character is only exported if it has no other animations.
+func getExportedAnimationKeys(skeleton *fragments.SkeletonHierarchy, isCharacterAnimation bool) []string {
+	keys := make([]string, 0, len(skeleton.Animations))
+	for key := range skeleton.Animations {
+		if isCharacterAnimation && key == defaultModelPoseAnimKey && len(skeleton.Animations) > 1 {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// getUniqueBoneNames returns the cleaned bone names with duplicates numbered like the glTF bone nodes.
+func getUniqueBoneNames(skeleton *fragments.SkeletonHierarchy) []string {
+	names := make([]string, len(skeleton.Skeleton))
+	duplicateNameCount := make(map[string]int)
+
+	for i, bone := range skeleton.Skeleton {
+		boneName := bone.CleanedName
+		if boneName == "" {
+			boneName = datatypes.CleanBoneAndStripBase(bone.Name, skeleton.ModelBase)
+		}
+
+		if count, exists := duplicateNameCount[boneName]; exists {
+			names[i] = fmt.Sprintf("%s_%02d", boneName, count)
+			duplicateNameCount[boneName]++
+		} else {
+			names[i] = boneName
+			duplicateNameCount[boneName] = 1
+		}
+	}
+
+	return names
+}
+
+// getBoneWorldMatrices returns the world matrix of each bone for one frame of local bone transforms.
+func getBoneWorldMatrices(skeleton *fragments.SkeletonHierarchy, transforms []datatypes.BoneTransform) []datatypes.Mat4 {
+	matrices := make([]datatypes.Mat4, len(skeleton.Skeleton))
+	for i, bone := range skeleton.Skeleton {
+		matrix := helpers.Mat4Identity()
+		for current := bone; current != nil; current = current.Parent {
+			if current.Index < 0 || current.Index >= len(transforms) {
+				break
+			}
+			matrix = helpers.Mat4Multiply(helpers.BoneLocalMatrix(transforms[current.Index]), matrix)
+		}
+		matrices[i] = matrix
+	}
+	return matrices
+}
+
+// yUpMatrix converts an EverQuest Z-up matrix to Y-up the way BoneLocalTRSGltf converts bone transforms.
+func yUpMatrix(m datatypes.Mat4) datatypes.Mat4 {
+	// Conjugate with the basis change (x, y, z) -> (x, z, -y)
+	basis := datatypes.Mat4{
+		1, 0, 0, 0,
+		0, 0, -1, 0,
+		0, 1, 0, 0,
+		0, 0, 0, 1,
+	}
+	inverseBasis := datatypes.Mat4{
+		1, 0, 0, 0,
+		0, 0, 1, 0,
+		0, -1, 0, 0,
+		0, 0, 0, 1,
+	}
+	return helpers.Mat4Multiply(basis, helpers.Mat4Multiply(m, inverseBasis))
+}
+
+// yUpVector converts an EverQuest Z-up vector to Y-up.
+func yUpVector(v fragments.Vec3) [3]float64 {
+	return [3]float64{float64(v.X), float64(v.Z), float64(-v.Y)}
+}
+
+// invertAffine returns the inverse of an affine transform matrix.
+func invertAffine(m datatypes.Mat4) datatypes.Mat4 {
+	a, b, c := float64(m[0]), float64(m[4]), float64(m[8])
+	d, e, f := float64(m[1]), float64(m[5]), float64(m[9])
+	g, h, k := float64(m[2]), float64(m[6]), float64(m[10])
+
+	det := a*(e*k-f*h) - b*(d*k-f*g) + c*(d*h-e*g)
+	if math.Abs(det) < 1e-12 {
+		return helpers.Mat4Identity()
+	}
+
+	// Inverse of the linear part, stored column-major
+	inv := [9]float64{
+		(e*k - f*h) / det, -(d*k - f*g) / det, (d*h - e*g) / det,
+		-(b*k - c*h) / det, (a*k - c*g) / det, -(a*h - b*g) / det,
+		(b*f - c*e) / det, -(a*f - c*d) / det, (a*e - b*d) / det,
+	}
+
+	tx, ty, tz := float64(m[12]), float64(m[13]), float64(m[14])
+
+	var result datatypes.Mat4
+	for col := 0; col < 3; col++ {
+		for row := 0; row < 3; row++ {
+			result[col*4+row] = float32(inv[col*3+row])
+		}
+	}
+	for row := 0; row < 3; row++ {
+		result[12+row] = float32(-(inv[row]*tx + inv[3+row]*ty + inv[6+row]*tz))
+	}
+	result[15] = 1
+
+	return result
+}
+
+// quatToRotationMatrix returns the row-major 3x3 rotation matrix of a unit quaternion (x, y, z, w).
+func quatToRotationMatrix(q [4]float32) [3][3]float64 {
+	x, y, z, w := float64(q[0]), float64(q[1]), float64(q[2]), float64(q[3])
+	return [3][3]float64{
+		{1 - 2*(y*y+z*z), 2 * (x*y - z*w), 2 * (x*z + y*w)},
+		{2 * (x*y + z*w), 1 - 2*(x*x+z*z), 2 * (y*z - x*w)},
+		{2 * (x*z - y*w), 2 * (y*z + x*w), 1 - 2*(x*x+y*y)},
+	}
+}
+
+// eulerZXYDegrees returns the angles (x, y, z) in degrees for the rotation Rz * Rx * Ry.
+// This is the rotation order of the BVH channels "Zrotation Xrotation Yrotation".
+func eulerZXYDegrees(q [4]float32) [3]float64 {
+	r := quatToRotationMatrix(q)
+
+	var x, y, z float64
+	sx := clampUnit(r[2][1])
+	x = math.Asin(sx)
+	if math.Abs(sx) < 0.9999999 {
+		y = math.Atan2(-r[2][0], r[2][2])
+		z = math.Atan2(-r[0][1], r[1][1])
+	} else {
+		// Gimbal lock, the Y rotation is folded into Z
+		y = 0
+		z = math.Atan2(r[1][0], r[0][0])
+	}
+
+	return [3]float64{degrees(x), degrees(y), degrees(z)}
+}
+
+// eulerXYZDegrees returns the angles (x, y, z) in degrees for the rotation Rz * Ry * Rx.
+// This is the default FBX rotation order eEulerXYZ.
+func eulerXYZDegrees(q [4]float32) [3]float64 {
+	r := quatToRotationMatrix(q)
+
+	var x, y, z float64
+	sy := clampUnit(-r[2][0])
+	y = math.Asin(sy)
+	if math.Abs(sy) < 0.9999999 {
+		x = math.Atan2(r[2][1], r[2][2])
+		z = math.Atan2(r[1][0], r[0][0])
+	} else {
+		// Gimbal lock, the X rotation is folded into Z
+		x = 0
+		z = math.Atan2(-r[0][1], r[1][1])
+	}
+
+	return [3]float64{degrees(x), degrees(y), degrees(z)}
+}
+
+// unwrapDegrees returns the angle shifted by whole turns to be closest to the previous angle.
+// This keeps interpolation between keys from spinning the long way around.
+func unwrapDegrees(angle, previous float64) float64 {
+	for angle-previous > 180 {
+		angle -= 360
+	}
+	for angle-previous < -180 {
+		angle += 360
+	}
+	return angle
+}
+
+// degrees converts radians to degrees.
+func degrees(radians float64) float64 {
+	return radians * 180 / math.Pi
+}
+
+// clampUnit clamps a value to [-1, 1].
+func clampUnit(v float64) float64 {
+	return math.Max(-1, math.Min(1, v))
+}
@@ -278,8 +278,9 @@ func (f *SkeletonHierarchy) addPoseTrack(track *TrackFragment, pieceName string)
 		f.Animations["pos"] = datatypes.NewAnimation()
 	}
 
-	// Note: The original C# uses Animation.AddTrack which requires TrackFragment interface
-	// This is a simplified version
+	f.Animations["pos"].AddTrack(track, pieceName, datatypes.CleanBoneName(pieceName),
+		datatypes.CleanBoneAndStripBase(pieceName, f.ModelBase))
+
 	if track.TrackDefFragment != nil {
 		track.TrackDefFragment.IsAssigned = true
 	}
@@ -387,26 +388,34 @@ func (f *SkeletonHierarchy) RenameNodeBase(newBase string) {
 	f.ModelBase = newBase
 }
 
-// AddTrack adds a track to the skeleton's animations.
+// AddTrack adds an animation track to the skeleton's animations.
+// The animation, model and piece names are parsed from the track name,
+// e.g. C05HUMPE_TRACK is animation c05, model hum and piece pe.
 func (f *SkeletonHierarchy) AddTrack(track *TrackFragment) {
 	if track == nil {
 		return
 	}
 
-	animName := track.AnimationName
-	if animName == "" {
-		animName = "default"
+	cleanedName := CleanTrackName(track.Name)
+	if len(cleanedName) <= 3 {
+		return
 	}
 
-	if _, exists := f.Animations[animName]; !exists {
-		f.Animations[animName] = datatypes.NewAnimation()
+	animationName := cleanedName[:3]
+	cleanedName = cleanedName[3:]
+
+	if len(cleanedName) < 3 {
+		return
 	}
 
-	// Mark track as processed
-	track.IsProcessed = true
-	if track.TrackDefFragment != nil {
-		track.TrackDefFragment.IsAssigned = true
+	modelName := cleanedName[:3]
+	pieceName := cleanedName[3:]
+	if pieceName == "" {
+		pieceName = "root"
 	}
+
+	track.SetTrackData(modelName, animationName, pieceName)
+	f.addAnimationTrack(track, track.Name)
 }
 
 // AddSecondaryMesh adds a mesh to the secondary meshes list.
@@ -428,19 +437,24 @@ func (f *SkeletonHierarchy) AddTrackEquipment(track *TrackFragment, boneName str
 		return
 	}
 
-	animName := track.AnimationName
-	if animName == "" {
-		animName = "default"
+	cleanedName := CleanTrackName(track.Name)
+	if len(cleanedName) <= 3 {
+		return
 	}
 
-	if _, exists := f.Animations[animName]; !exists {
-		f.Animations[animName] = datatypes.NewAnimation()
+	animationName := cleanedName[:3]
+	if len(cleanedName[3:]) < 3 {
+		return
 	}
 
-	// Mark track as processed
-	track.IsProcessed = true
-	if track.TrackDefFragment != nil {
-		track.TrackDefFragment.IsAssigned = true
+	pieceName := boneName
+	if pieceName == "" {
+		pieceName = "root"
+	}
+
+	track.SetTrackData(f.ModelBase, animationName, pieceName)
+	if !f.addAnimationTrack(track, track.PieceName) {
+		return
 	}
 
 	// Find and update the bone's animation tracks
@@ -449,8 +463,36 @@ func (f *SkeletonHierarchy) AddTrackEquipment(track *TrackFragment, boneName str
 			if bone.AnimationTracks == nil {
 				bone.AnimationTracks = make(map[string]*TrackFragment)
 			}
-			bone.AnimationTracks[animName] = track
+			bone.AnimationTracks[animationName] = track
 			break
 		}
 	}
 }
+
+// addAnimationTrack registers a track whose track data has already been set.
+// Tracks belonging to this model replace animations injected from another model,
+// and injected tracks never replace the model's own animations.
+// It returns false if the track was rejected.
+func (f *SkeletonHierarchy) addAnimationTrack(track *TrackFragment, trackKey string) bool {
+	animation, exists := f.Animations[track.AnimationName]
+	if exists {
+		if track.ModelName == f.ModelBase && f.ModelBase != animation.AnimModelBase {
+			delete(f.Animations, track.AnimationName)
+		} else if track.ModelName != f.ModelBase && f.ModelBase == animation.AnimModelBase {
+			return false
+		}
+	}
+
+	if _, exists := f.Animations[track.AnimationName]; !exists {
+		f.Animations[track.AnimationName] = datatypes.NewAnimation()
+	}
+
+	f.Animations[track.AnimationName].AddTrack(track, trackKey, datatypes.CleanBoneName(track.PieceName),
+		datatypes.CleanBoneAndStripBase(track.PieceName, f.ModelBase))
+
+	if track.TrackDefFragment != nil {
+		track.TrackDefFragment.IsAssigned = true
+	}
+	track.IsProcessed = true
+	return true
+}
@@ -3,6 +3,8 @@ package fragments
 import (
 	"fmt"
 	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
 )
 
 // TrackFragment (0x13)
@@ -52,7 +54,11 @@ func (f *TrackFragment) GetFrameMs() int {
 }
 
 // GetTrackDefFragment returns the track definition fragment interface.
-func (f *TrackFragment) GetTrackDefFragment() interface{} {
+// A nil interface is returned when the track definition reference is missing.
+func (f *TrackFragment) GetTrackDefFragment() datatypes.TrackDefFragment {
+	if f.TrackDefFragment == nil {
+		return nil
+	}
 	return f.TrackDefFragment
 }
 
@@ -183,3 +189,6 @@ func CleanTrackName(name string) string {
 	cleanedName = strings.TrimSuffix(cleanedName, "_track")
 	return strings.TrimSpace(cleanedName)
 }
+
+// Ensure TrackFragment can be stored in an Animation.
+var _ datatypes.TrackFragment = (*TrackFragment)(nil)
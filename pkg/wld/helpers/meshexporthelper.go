@@ -1,6 +1,8 @@
 package helpers
 
 import (
+	"math"
+
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 )
@@ -128,25 +130,99 @@ func transformVec3ByMat4(v fragments.Vec3, m datatypes.Mat4) fragments.Vec3 {
 	return fragments.Vec3{X: x, Y: y, Z: z}
 }
 
-// GetBoneMatrix retrieves the model matrix for a bone at a specific animation frame.
-// This is a placeholder implementation - the actual implementation should be in
-// the SkeletonHierarchy type or use the skeleton's internal matrix calculation.
-//
-// Note: This function needs to be implemented based on the skeleton's bone hierarchy
-// and animation system. The implementation should:
-// 1. Get the bone transform from the animation at the specified frame
-// 2. Calculate the world matrix by multiplying parent transforms
-// 3. Return the final model matrix
+// GetBoneMatrix returns the world matrix of a bone for the given animation frame.
+// The bone's local transform is combined with every parent up to the skeleton root.
+// An identity matrix is returned if the animation or frame does not exist.
 func GetBoneMatrix(skeleton *fragments.SkeletonHierarchy, boneIndex int, animName string, frame int) datatypes.Mat4 {
-	// Return identity matrix as placeholder
-	// The actual implementation should traverse the bone hierarchy and calculate
-	// the cumulative transform matrix
-	return datatypes.Mat4{
-		1, 0, 0, 0,
-		0, 1, 0, 0,
-		0, 0, 1, 0,
-		0, 0, 0, 1,
+	if skeleton == nil || boneIndex < 0 || boneIndex >= len(skeleton.Skeleton) {
+		return Mat4Identity()
+	}
+
+	animation, exists := skeleton.Animations[animName]
+	if !exists || frame < 0 || frame >= animation.FrameCount {
+		return Mat4Identity()
+	}
+
+	boneMatrix := Mat4Identity()
+
+	for bone := skeleton.Skeleton[boneIndex]; bone != nil; bone = bone.Parent {
+		frames := getBoneTrackFrames(animation, bone)
+		if len(frames) == 0 {
+			break
+		}
+
+		realFrame := frame
+		if realFrame >= len(frames) {
+			realFrame = 0
+		}
+
+		boneMatrix = Mat4Multiply(BoneLocalMatrix(frames[realFrame]), boneMatrix)
+	}
+
+	return boneMatrix
+}
+
+// getBoneTrackFrames returns the frames of the track animating a bone.
+// Character tracks are keyed by the stripped bone name, other tracks by the cleaned name.
+func getBoneTrackFrames(animation *datatypes.Animation, bone *fragments.SkeletonBone) []datatypes.BoneTransform {
+	track, exists := animation.TracksCleanedStripped[bone.CleanedName]
+	if !exists {
+		track, exists = animation.TracksCleaned[bone.CleanedName]
+	}
+	if !exists {
+		return nil
+	}
+
+	trackFragment, ok := track.(*fragments.TrackFragment)
+	if !ok || trackFragment.TrackDefFragment == nil {
+		return nil
+	}
+
+	return trackFragment.TrackDefFragment.Frames
+}
+
+// BoneLocalTRS returns the translation, rotation and uniform scale of a bone frame
+// relative to its parent. Frames without a scale are treated as unscaled.
+func BoneLocalTRS(transform datatypes.BoneTransform) (datatypes.Vec3, datatypes.Quat, float32) {
+	rotation := transform.Rotation
+	length := float32(math.Sqrt(float64(rotation.X*rotation.X + rotation.Y*rotation.Y +
+		rotation.Z*rotation.Z + rotation.W*rotation.W)))
+	if length > 0 {
+		rotation = datatypes.Quat{
+			X: rotation.X / length,
+			Y: rotation.Y / length,
+			Z: rotation.Z / length,
+			W: rotation.W / length,
+		}
+	} else {
+		rotation = datatypes.Quat{W: 1}
+	}
+
+	scale := transform.Scale
+	if scale == 0 {
+		scale = 1.0
 	}
+
+	return transform.Translation, rotation, scale
+}
+
+// BoneLocalMatrix returns the local model matrix (T * R * S) of a bone frame.
+func BoneLocalMatrix(transform datatypes.BoneTransform) datatypes.Mat4 {
+	translation, rotation, scale := BoneLocalTRS(transform)
+	return Mat4FromTRS(translation, rotation, scale)
+}
+
+// BoneLocalTRSGltf returns the local transform of a bone frame converted from
+// EverQuest's Z-up coordinate system to glTF's Y-up coordinate system.
+func BoneLocalTRSGltf(transform datatypes.BoneTransform) (translation [3]float32, rotation [4]float32, scale [3]float32) {
+	t, r, s := BoneLocalTRS(transform)
+
+	// Swap Y and Z and negate the new Z axis
+	translation = [3]float32{t.X, t.Z, -t.Y}
+	rotation = [4]float32{r.X, r.Z, -r.Y, r.W}
+	scale = [3]float32{s, s, s}
+
+	return translation, rotation, scale
 }
 
 // RestoreVertices restores mesh vertices to their original positions.
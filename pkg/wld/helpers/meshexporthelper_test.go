@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+func newTestTrack(name string, frames ...datatypes.BoneTransform) *fragments.TrackFragment {
+	track := &fragments.TrackFragment{TrackDefFragment: &fragments.TrackDefFragment{Frames: frames}}
+	track.Name = name
+	return track
+}
+
+func TestGetBoneMatrix(t *testing.T) {
+	root := &fragments.SkeletonBone{Index: 0, CleanedName: "root"}
+	child := &fragments.SkeletonBone{Index: 1, CleanedName: "pe", Parent: root}
+	skeleton := &fragments.SkeletonHierarchy{
+		Skeleton:   []*fragments.SkeletonBone{root, child},
+		Animations: map[string]*datatypes.Animation{},
+	}
+
+	// Root is moved along X and rotated 90 degrees around Z
+	halfAngle := float32(math.Sqrt2 / 2)
+	rootTrack := newTestTrack("root", datatypes.BoneTransform{
+		Translation: datatypes.Vec3{X: 1},
+		Rotation:    datatypes.Quat{Z: halfAngle, W: halfAngle},
+		Scale:       1,
+	})
+	childTrack := newTestTrack("pe", datatypes.BoneTransform{
+		Translation: datatypes.Vec3{Y: 2},
+		Rotation:    datatypes.Quat{W: 1},
+		Scale:       1,
+	})
+
+	animation := datatypes.NewAnimation()
+	animation.AddTrack(rootTrack, "root", "root", "root")
+	animation.AddTrack(childTrack, "pe", "pe", "pe")
+	skeleton.Animations["pos"] = animation
+
+	result := transformVec3ByMat4(fragments.Vec3{}, GetBoneMatrix(skeleton, 1, "pos", 0))
+	expected := fragments.Vec3{X: -1, Y: 0, Z: 0}
+
+	if math.Abs(float64(result.X-expected.X)) > 1e-5 ||
+		math.Abs(float64(result.Y-expected.Y)) > 1e-5 ||
+		math.Abs(float64(result.Z-expected.Z)) > 1e-5 {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	if GetBoneMatrix(skeleton, 1, "pos", 1) != Mat4Identity() {
+		t.Error("Expected identity matrix for out of range frame")
+	}
+
+	if GetBoneMatrix(skeleton, 1, "c01", 0) != Mat4Identity() {
+		t.Error("Expected identity matrix for missing animation")
+	}
+}
@@ -0,0 +1,241 @@
+// Package retarget shares skeletal animations between character models.
+// Animation tracks are collected from any number of character WLD files and matched
+// to the bones of a skeleton by their cleaned names, so a model can play the animations
+// of the models it borrows from in animationsources.txt.
+package retarget
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// minBoneMatchRatio is the share of an animation's tracks that must match a skeleton bone
+// for the animation to be retargeted. Animations of very different skeletons are skipped.
+const minBoneMatchRatio = 0.5
+
+// Library holds the animations of character models keyed by model and animation name.
+type Library struct {
+	sources    map[string]string
+	animations map[string]map[string]*sourceAnimation
+}
+
+// sourceAnimation is an animation of a single model.
+type sourceAnimation struct {
+	model string
+
+	// tracks maps the cleaned piece name without model base to the piece's track.
+	tracks map[string]*fragments.TrackFragment
+}
+
+// NewLibrary creates an empty animation library.
+// Sources maps lower case model names to the model they borrow animations from, e.g. "huf" to "elf".
+func NewLibrary(sources map[string]string) *Library {
+	if sources == nil {
+		sources = make(map[string]string)
+	}
+
+	return &Library{
+		sources:    sources,
+		animations: make(map[string]map[string]*sourceAnimation),
+	}
+}
+
+// AddWld adds the animation tracks of a characters WLD file.
+func (l *Library) AddWld(wldFile wld.WldFile) {
+	l.AddTracks(wld.GetFragmentsByType[*fragments.TrackFragment](wldFile))
+}
+
+// AddTracks adds animation tracks to the library.
+// Pose tracks are skipped and tracks of an animation already in the library are not replaced.
+func (l *Library) AddTracks(tracks []*fragments.TrackFragment) {
+	for _, track := range tracks {
+		if track == nil || track.IsPoseAnimation || track.TrackDefFragment == nil {
+			continue
+		}
+
+		animationName, modelName, pieceName, ok := parseTrackName(track.GetName())
+		if !ok {
+			continue
+		}
+
+		modelAnimations, exists := l.animations[modelName]
+		if !exists {
+			modelAnimations = make(map[string]*sourceAnimation)
+			l.animations[modelName] = modelAnimations
+		}
+
+		animation, exists := modelAnimations[animationName]
+		if !exists {
+			animation = &sourceAnimation{
+				model:  modelName,
+				tracks: make(map[string]*fragments.TrackFragment),
+			}
+			modelAnimations[animationName] = animation
+		}
+
+		if _, exists := animation.tracks[pieceName]; !exists {
+			animation.tracks[pieceName] = track
+		}
+	}
+}
+
+// Overlay returns a library with the animations of this library and the given tracks.
+// This library is left unchanged, so a shared library can be extended for a single WLD file.
+func (l *Library) Overlay(tracks []*fragments.TrackFragment) *Library {
+	overlay := &Library{
+		sources:    l.sources,
+		animations: make(map[string]map[string]*sourceAnimation, len(l.animations)),
+	}
+
+	// AddTracks adds to existing animations, so they are copied as well
+	for model, modelAnimations := range l.animations {
+		copied := make(map[string]*sourceAnimation, len(modelAnimations))
+		for name, animation := range modelAnimations {
+			copiedAnimation := &sourceAnimation{
+				model:  animation.model,
+				tracks: make(map[string]*fragments.TrackFragment, len(animation.tracks)),
+			}
+			for piece, track := range animation.tracks {
+				copiedAnimation.tracks[piece] = track
+			}
+			copied[name] = copiedAnimation
+		}
+		overlay.animations[model] = copied
+	}
+
+	overlay.AddTracks(tracks)
+	return overlay
+}
+
+// Models returns the names of the models with animations in the library.
+func (l *Library) Models() []string {
+	models := make([]string, 0, len(l.animations))
+	for model := range l.animations {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// AnimationNames returns the names of the animations a model plays.
+// These include the animations of the models it borrows from.
+func (l *Library) AnimationNames(model string) []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	for _, source := range l.sourceChain(model) {
+		for name := range l.animations[source] {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Retarget adds the animations the skeleton's model plays that it does not have yet.
+// The model's own animations are preferred over the animations of the models it borrows from.
+// Bones without a track keep their pose and tracks of bones the skeleton lacks are dropped.
+// Returns the names of the added animations.
+func (l *Library) Retarget(skeleton *fragments.SkeletonHierarchy) []string {
+	if skeleton == nil {
+		return nil
+	}
+
+	if skeleton.Animations == nil {
+		skeleton.Animations = make(map[string]*datatypes.Animation)
+	}
+
+	var added []string
+	for _, source := range l.sourceChain(skeleton.ModelBase) {
+		modelAnimations := l.animations[source]
+
+		names := make([]string, 0, len(modelAnimations))
+		for name := range modelAnimations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if _, exists := skeleton.Animations[name]; exists {
+				continue
+			}
+
+			animation := retargetAnimation(skeleton, modelAnimations[name])
+			if animation == nil {
+				continue
+			}
+
+			skeleton.Animations[name] = animation
+			added = append(added, name)
+		}
+	}
+
+	return added
+}
+
+// sourceChain returns the model followed by the models it borrows animations from.
+func (l *Library) sourceChain(model string) []string {
+	model = strings.ToLower(model)
+	visited := make(map[string]bool)
+	var chain []string
+
+	for model != "" && !visited[model] {
+		visited[model] = true
+		chain = append(chain, model)
+		model = l.sources[model]
+	}
+
+	return chain
+}
+
+// retargetAnimation maps the tracks of an animation onto the skeleton bones with the same cleaned names.
+// Returns nil if too few tracks match a bone.
+func retargetAnimation(skeleton *fragments.SkeletonHierarchy, source *sourceAnimation) *datatypes.Animation {
+	animation := datatypes.NewAnimation()
+	animation.AnimModelBase = source.model
+
+	matched := 0
+	for i := range skeleton.Skeleton {
+		boneName := skeleton.BoneMappingClean[i]
+		track, exists := source.tracks[boneName]
+		if !exists {
+			continue
+		}
+
+		fullBoneName := skeleton.BoneMapping[i]
+		animation.AddTrack(track, fullBoneName, datatypes.CleanBoneName(fullBoneName), boneName)
+		matched++
+	}
+
+	if matched == 0 || float32(matched) < float32(len(source.tracks))*minBoneMatchRatio {
+		return nil
+	}
+
+	return animation
+}
+
+// parseTrackName splits an animation track name into the animation, model and piece names,
+// e.g. C05HUMPE_TRACK is animation c05, model hum and piece pe. The root piece has no name.
+func parseTrackName(name string) (animationName, modelName, pieceName string, ok bool) {
+	cleanedName := fragments.CleanTrackName(name)
+	if len(cleanedName) < 6 {
+		return "", "", "", false
+	}
+
+	animationName = cleanedName[:3]
+	modelName = cleanedName[3:6]
+	pieceName = cleanedName[6:]
+	if pieceName == "" {
+		pieceName = "root"
+	}
+
+	return animationName, modelName, pieceName, true
+}
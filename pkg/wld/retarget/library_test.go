@@ -0,0 +1,108 @@
+package retarget
+
+import (
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// newTestTrack creates an animation track with the given number of frames.
+func newTestTrack(name string, frameCount int) *fragments.TrackFragment {
+	track := &fragments.TrackFragment{
+		TrackDefFragment: &fragments.TrackDefFragment{
+			Frames: make([]datatypes.BoneTransform, frameCount),
+		},
+		FrameMs: 100,
+	}
+	track.SetName(name)
+	return track
+}
+
+// newTestSkeleton creates a skeleton with the given bone names.
+func newTestSkeleton(modelBase string, boneNames ...string) *fragments.SkeletonHierarchy {
+	skeleton := &fragments.SkeletonHierarchy{
+		ModelBase:        modelBase,
+		Animations:       make(map[string]*datatypes.Animation),
+		BoneMapping:      make(map[int]string),
+		BoneMappingClean: make(map[int]string),
+	}
+
+	for i, name := range boneNames {
+		skeleton.Skeleton = append(skeleton.Skeleton, &fragments.SkeletonBone{Index: i, Name: name})
+		skeleton.BoneMapping[i] = name
+		skeleton.BoneMappingClean[i] = datatypes.CleanBoneAndStripBase(name, modelBase)
+	}
+
+	return skeleton
+}
+
+func TestRetarget(t *testing.T) {
+	library := NewLibrary(map[string]string{"huf": "elf"})
+	library.AddTracks([]*fragments.TrackFragment{
+		newTestTrack("C01ELF_TRACK", 10),
+		newTestTrack("C01ELFPE_TRACK", 10),
+		newTestTrack("C01ELFTAIL_TRACK", 10),
+		newTestTrack("L01ELF_TRACK", 20),
+		newTestTrack("L01ELFPE_TRACK", 20),
+		newTestTrack("P01HUF_TRACK", 5),
+		newTestTrack("P01HUFPE_TRACK", 5),
+		newTestTrack("P01ELF_TRACK", 8),
+		newTestTrack("D01ELFAA_TRACK", 8),
+		newTestTrack("D01ELFBB_TRACK", 8),
+		newTestTrack("D01ELFCC_TRACK", 8),
+	})
+
+	skeleton := newTestSkeleton("huf", "HUF_DAG", "HUFPE_DAG", "HUFHE_DAG")
+	skeleton.Animations["l01"] = datatypes.NewAnimation()
+
+	added := library.Retarget(skeleton)
+	if len(added) != 2 || added[0] != "p01" || added[1] != "c01" {
+		t.Fatalf("Expected p01 and c01 to be added, got %v", added)
+	}
+
+	p01 := skeleton.Animations["p01"]
+	if p01.AnimModelBase != "huf" {
+		t.Errorf("Expected the model's own p01 to be preferred, got %s", p01.AnimModelBase)
+	}
+
+	c01 := skeleton.Animations["c01"]
+	if len(c01.TracksCleanedStripped) != 2 || c01.TracksCleanedStripped["pe"] == nil || c01.TracksCleanedStripped["root"] == nil {
+		t.Errorf("Expected root and pe tracks on c01, got %v", c01.TracksCleanedStripped)
+	}
+	if c01.FrameCount != 10 {
+		t.Errorf("Expected 10 frames, got %d", c01.FrameCount)
+	}
+
+	if _, exists := skeleton.Animations["d01"]; exists {
+		t.Error("Expected an animation without matching bones to be skipped")
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	library := NewLibrary(nil)
+	library.AddTracks([]*fragments.TrackFragment{
+		newTestTrack("C01ELF_TRACK", 10),
+		newTestTrack("C01ELFPE_TRACK", 10),
+	})
+
+	overlay := library.Overlay([]*fragments.TrackFragment{
+		newTestTrack("C01ELFHE_TRACK", 10),
+		newTestTrack("C01DWF_TRACK", 10),
+	})
+
+	if models := overlay.Models(); len(models) != 2 || models[0] != "dwf" || models[1] != "elf" {
+		t.Errorf("Expected the overlay to have dwf and elf, got %v", models)
+	}
+	if tracks := overlay.animations["elf"]["c01"].tracks; len(tracks) != 3 {
+		t.Errorf("Expected the overlay to add the he track to elf c01, got %d tracks", len(tracks))
+	}
+
+	// The shared library keeps only its own tracks
+	if models := library.Models(); len(models) != 1 || models[0] != "elf" {
+		t.Errorf("Expected the library to only have elf, got %v", models)
+	}
+	if tracks := library.animations["elf"]["c01"].tracks; len(tracks) != 2 {
+		t.Errorf("Expected the library to keep 2 tracks on elf c01, got %d", len(tracks))
+	}
+}
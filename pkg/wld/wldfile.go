@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 )
@@ -111,37 +112,11 @@ type BaseWldFile struct {
 	FilenameChanges map[string]string
 
 	// Settings contains configuration options.
-	Settings *Settings
+	Settings *config.Settings
 }
 
-// Settings contains configuration options for WLD file processing.
-type Settings struct {
-	// ModelExportFormat determines the export format for models.
-	ModelExportFormat ModelExportFormat
-
-	// ExportCharactersToSingleFolder exports all characters to one folder.
-	ExportCharactersToSingleFolder bool
-
-	// ExportEquipmentToSingleFolder exports all equipment to one folder.
-	ExportEquipmentToSingleFolder bool
-}
-
-// ModelExportFormat represents the format for exporting models.
-type ModelExportFormat int
-
-const (
-	// ModelExportFormatIntermediate exports to intermediate text format.
-	ModelExportFormatIntermediate ModelExportFormat = iota
-
-	// ModelExportFormatObj exports to OBJ format.
-	ModelExportFormatObj
-
-	// ModelExportFormatGltf exports to glTF format.
-	ModelExportFormatGltf
-)
-
 // NewBaseWldFile creates a new BaseWldFile.
-func NewBaseWldFile(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *Settings, wldToInject WldFile) *BaseWldFile {
+func NewBaseWldFile(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *config.Settings, wldToInject WldFile) *BaseWldFile {
 	return &BaseWldFile{
 		WldType:                wldType,
 		ZoneName:               strings.ToLower(zoneName),
@@ -389,7 +364,7 @@ func (w *BaseWldFile) GetExportFolderForWldType() string {
 		return w.GetRootExportFolder()
 	case WldTypeCharacters:
 		if w.Settings != nil && w.Settings.ExportCharactersToSingleFolder &&
-			w.Settings.ModelExportFormat == ModelExportFormatIntermediate {
+			w.Settings.ModelExportFormat == config.ModelExportFormatIntermediate {
 			return w.GetRootExportFolder()
 		}
 		return w.GetRootExportFolder() + "Characters/"
@@ -0,0 +1,103 @@
+package wld
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// testWld builds an old format WLD file in memory.
+type testWld struct {
+	strings   []string
+	fragments bytes.Buffer
+	count     uint32
+}
+
+// stringRef adds a string to the string hash and returns its reference.
+func (w *testWld) stringRef(s string) int32 {
+	offset := 1
+	for _, existing := range w.strings {
+		offset += len(existing) + 1
+	}
+	w.strings = append(w.strings, s)
+	return -int32(offset)
+}
+
+// addFragment adds a fragment made of int32 fields.
+func (w *testWld) addFragment(id int32, fields ...int32) {
+	binary.Write(&w.fragments, binary.LittleEndian, uint32(len(fields)*4))
+	binary.Write(&w.fragments, binary.LittleEndian, id)
+	binary.Write(&w.fragments, binary.LittleEndian, fields)
+	w.count++
+}
+
+// file returns the WLD file.
+func (w *testWld) file() archive.File {
+	hash := EncodeString("\x00" + strings.Join(w.strings, "\x00") + "\x00")
+
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, []int32{WldFileIdentifier, WldFormatOldIdentifier})
+	binary.Write(&data, binary.LittleEndian, []uint32{w.count, 0, 0, uint32(len(hash)), 0})
+	data.Write(hash)
+	data.Write(w.fragments.Bytes())
+
+	file := archive.NewBaseFile(uint32(data.Len()), 0, data.Bytes())
+	file.SetName("test.wld")
+	return file
+}
+
+// addSkeleton adds a skeleton with a single bone.
+func (w *testWld) addSkeleton(name, boneName string) {
+	nameRef := w.stringRef(name)
+	boneRef := w.stringRef(boneName)
+
+	// Name, flags, bone count, fragment 18, then the bone: name, flags, track, mesh and child count
+	w.addFragment(0x10, nameRef, 0, 1, 0, boneRef, 0, 0, 0, 0)
+}
+
+func TestCharactersInitializeProcessesData(t *testing.T) {
+	var builder testWld
+	builder.addSkeleton("HUM_HS_DEF", "HUM_DAG")
+
+	wldFile := NewWldFileCharacters(builder.file(), "global_chr", WldTypeCharacters, logger.NewNullLogger(), nil, nil)
+	if err := wldFile.Initialize("", false); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	skeletons := GetFragmentsByType[*fragments.SkeletonHierarchy](wldFile)
+	if len(skeletons) != 1 {
+		t.Fatalf("Expected one skeleton, got %d", len(skeletons))
+	}
+
+	// The bone paths are only set by the character processing
+	if bone := skeletons[0].Skeleton[0]; bone.FullPath != "HUM_DAG" {
+		t.Errorf("Expected the skeleton data to be built, got bone path %q", bone.FullPath)
+	}
+}
+
+func TestEquipmentInitializeProcessesData(t *testing.T) {
+	var builder testWld
+	builder.addSkeleton("IT1_DEF", "IT1_DAG")
+
+	// Name, flags, callback, size 1, component count, fragment 2 and the trailing field
+	builder.addFragment(0x14, builder.stringRef("IT1_ACTORDEF"), 0, 0, 0, 0, 0, 0)
+
+	wldFile := NewWldFileEquipment(builder.file(), "gequip", WldTypeEquipment, logger.NewNullLogger(), nil, nil)
+	if err := wldFile.Initialize("", false); err != nil {
+		t.Fatalf("Failed to initialize: %v", err)
+	}
+
+	// Skeletons not referenced by an actor are assigned by name in the equipment processing
+	actors := wldFile.GetActors()
+	if len(actors) != 1 || actors[0].SkeletonReference == nil {
+		t.Fatal("Expected the skeleton to be assigned to IT1_ACTORDEF")
+	}
+	if actors[0].SkeletonReference.SkeletonHierarchy.GetName() != "IT1_DEF" {
+		t.Errorf("Expected IT1_DEF, got %s", actors[0].SkeletonReference.SkeletonHierarchy.GetName())
+	}
+}
@@ -2,11 +2,13 @@ package wld
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"strings"
 	"unicode"
 
 	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 )
@@ -20,7 +22,7 @@ type WldFileCharacters struct {
 }
 
 // NewWldFileCharacters creates a new characters WLD file handler.
-func NewWldFileCharacters(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *Settings, wldToInject WldFile) *WldFileCharacters {
+func NewWldFileCharacters(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *config.Settings, wldToInject WldFile) *WldFileCharacters {
 	w := &WldFileCharacters{
 		BaseWldFile:      NewBaseWldFile(wldData, zoneName, wldType, log, settings, wldToInject),
 		animationSources: make(map[string]string),
@@ -29,17 +31,30 @@ func NewWldFileCharacters(wldData archive.File, zoneName string, wldType WldType
 	return w
 }
 
+// AnimationSourcesFile lists the models each character model borrows its animations from.
+const AnimationSourcesFile = "ClientData/animationsources.txt"
+
 // parseAnimationSources loads the animation sources mapping from file.
 func (w *WldFileCharacters) parseAnimationSources() {
-	filename := "ClientData/animationsources.txt"
-
-	file, err := os.Open(filename)
+	sources, err := ParseAnimationSources(AnimationSourcesFile)
 	if err != nil {
 		w.Logger.LogError("WldFileCharacters: No animationsources.txt file found.")
 		return
 	}
+	w.animationSources = sources
+}
+
+// ParseAnimationSources reads an animation sources file.
+// Each line maps a model to the model it borrows animations from, e.g. "HUF,ELF".
+// The returned map uses lower case model names.
+func ParseAnimationSources(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open animation sources: %w", err)
+	}
 	defer file.Close()
 
+	sources := make(map[string]string)
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -56,8 +71,14 @@ func (w *WldFileCharacters) parseAnimationSources() {
 
 		key := strings.TrimSpace(strings.ToLower(parts[0]))
 		value := strings.TrimSpace(strings.ToLower(parts[1]))
-		w.animationSources[key] = value
+		sources[key] = value
 	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read animation sources: %w", err)
+	}
+
+	return sources, nil
 }
 
 // getAnimationModelLink returns the animation source model for a given model name.
@@ -68,6 +89,17 @@ func (w *WldFileCharacters) getAnimationModelLink(modelName string) string {
 	return modelName
 }
 
+// Initialize parses the characters WLD file and runs the character processing, which assigns
+// the animations, additional meshes and material variants of each model to its skeleton.
+func (w *WldFileCharacters) Initialize(rootFolder string, exportData bool) error {
+	if err := w.BaseWldFile.Initialize(rootFolder, exportData); err != nil {
+		return err
+	}
+
+	w.ProcessData()
+	return nil
+}
+
 // ProcessData processes the characters WLD data.
 func (w *WldFileCharacters) ProcessData() {
 	w.BaseWldFile.ProcessData()
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 )
@@ -14,12 +15,23 @@ type WldFileEquipment struct {
 }
 
 // NewWldFileEquipment creates a new equipment WLD file handler.
-func NewWldFileEquipment(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *Settings, wldToInject WldFile) *WldFileEquipment {
+func NewWldFileEquipment(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *config.Settings, wldToInject WldFile) *WldFileEquipment {
 	return &WldFileEquipment{
 		BaseWldFile: NewBaseWldFile(wldData, zoneName, wldType, log, settings, wldToInject),
 	}
 }
 
+// Initialize parses the equipment WLD file and runs the equipment processing, which finds
+// the skeletons and animations of animated equipment.
+func (w *WldFileEquipment) Initialize(rootFolder string, exportData bool) error {
+	if err := w.BaseWldFile.Initialize(rootFolder, exportData); err != nil {
+		return err
+	}
+
+	w.ProcessData()
+	return nil
+}
+
 // ProcessData processes the equipment WLD data.
 func (w *WldFileEquipment) ProcessData() {
 	w.BaseWldFile.ProcessData()
@@ -2,6 +2,7 @@ package wld
 
 import (
 	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 )
@@ -12,7 +13,7 @@ type WldFileLights struct {
 }
 
 // NewWldFileLights creates a new lights WLD file handler.
-func NewWldFileLights(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *Settings, wldToInject WldFile) *WldFileLights {
+func NewWldFileLights(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *config.Settings, wldToInject WldFile) *WldFileLights {
 	return &WldFileLights{
 		BaseWldFile: NewBaseWldFile(wldData, zoneName, wldType, log, settings, wldToInject),
 	}
@@ -2,7 +2,9 @@ package wld
 
 import (
 	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
+	"github.com/tmyhres/LanternGoExtract/pkg/sound"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 )
@@ -25,15 +27,33 @@ type WldFileZone struct {
 
 	// WldFileToInject is an additional WLD file to inject data from.
 	WldFileToInject WldFile
+
+	// LightInstances are the placed lights from the zone's lights WLD.
+	LightInstances []*fragments.LightInstance
+
+	// AudioInstances are the sound emitters and music regions of the zone.
+	AudioInstances []sound.AudioInstance
 }
 
 // NewWldFileZone creates a new zone WLD file handler.
-func NewWldFileZone(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *Settings, wldToInject WldFile) *WldFileZone {
+func NewWldFileZone(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *config.Settings, wldToInject WldFile) *WldFileZone {
 	return &WldFileZone{
 		BaseWldFile: NewBaseWldFile(wldData, zoneName, wldType, log, settings, wldToInject),
 	}
 }
 
+// Initialize parses the zone WLD file and runs the zone processing.
+// Region volumes need the BSP tree linked to its regions and region types, and meshes
+// need the vertex colors of the injected lit WLD file before they are exported.
+func (w *WldFileZone) Initialize(rootFolder string, exportData bool) error {
+	if err := w.BaseWldFile.Initialize(rootFolder, exportData); err != nil {
+		return err
+	}
+
+	w.ProcessData()
+	return nil
+}
+
 // ProcessData processes the zone WLD data.
 func (w *WldFileZone) ProcessData() {
 	w.BaseWldFile.ProcessData()
@@ -128,7 +148,7 @@ func (w *WldFileZone) linkBspReferences() {
 	bspRegions := GetFragmentsByType[*fragments.BspRegion](w)
 	regionTypes := GetFragmentsByType[*fragments.BspRegionType](w)
 
-	if len(bspTrees) == 0 || len(bspRegions) == 0 || len(regionTypes) == 0 {
+	if len(bspTrees) == 0 || len(bspRegions) == 0 {
 		return
 	}
 
@@ -2,6 +2,7 @@ package wld
 
 import (
 	"github.com/tmyhres/LanternGoExtract/pkg/archive"
+	"github.com/tmyhres/LanternGoExtract/pkg/config"
 	"github.com/tmyhres/LanternGoExtract/pkg/infrastructure/logger"
 	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
 )
@@ -12,7 +13,7 @@ type WldFileZoneObjects struct {
 }
 
 // NewWldFileZoneObjects creates a new zone objects WLD file handler.
-func NewWldFileZoneObjects(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *Settings, wldToInject WldFile) *WldFileZoneObjects {
+func NewWldFileZoneObjects(wldData archive.File, zoneName string, wldType WldType, log logger.Logger, settings *config.Settings, wldToInject WldFile) *WldFileZoneObjects {
 	return &WldFileZoneObjects{
 		BaseWldFile: NewBaseWldFile(wldData, zoneName, wldType, log, settings, wldToInject),
 	}
@@ -0,0 +1,244 @@
+package zonemap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// reliefZFactor exaggerates slopes in the relief image so low terrain still reads.
+const reliefZFactor = 2
+
+// overlayOpacity is the opacity of region overlays in the color image.
+const overlayOpacity = 0.45
+
+// regionOverlayColors are the overlay colors of the special region types.
+var regionOverlayColors = map[datatypes.RegionType]color.NRGBA{
+	datatypes.RegionTypeWater:         {R: 40, G: 110, B: 255, A: 255},
+	datatypes.RegionTypeWaterBlockLos: {R: 40, G: 110, B: 255, A: 255},
+	datatypes.RegionTypeFreezingWater: {R: 150, G: 220, B: 255, A: 255},
+	datatypes.RegionTypeLava:          {R: 255, G: 70, B: 0, A: 255},
+	datatypes.RegionTypePvp:           {R: 200, G: 0, B: 200, A: 255},
+	datatypes.RegionTypeZoneline:      {R: 255, G: 230, B: 0, A: 255},
+	datatypes.RegionTypeSlippery:      {R: 200, G: 200, B: 200, A: 255},
+}
+
+// HeightImage returns the heights normalized to the full 16 bit range. Empty pixels are black.
+func (m *Map) HeightImage() *image.Gray16 {
+	img := image.NewGray16(image.Rect(0, 0, m.Width, m.Height))
+	low, high := m.HeightRange()
+	scale := float32(0)
+	if high > low {
+		scale = 65535 / (high - low)
+	}
+
+	for i, h := range m.Heights {
+		if isEmpty(h) {
+			continue
+		}
+		img.Pix[i*2], img.Pix[i*2+1] = encodeGray16(uint16(vecmath.ClampInt(int((h-low)*scale+0.5), 0, 65535)))
+	}
+
+	return img
+}
+
+// ReliefImage returns a hillshade of the heights lit from the north west.
+func (m *Map) ReliefImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, m.Width, m.Height))
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			if isEmpty(m.Heights[x+y*m.Width]) {
+				continue
+			}
+			shade := uint8(m.shade(x, y)*255 + 0.5)
+			img.SetNRGBA(x, y, color.NRGBA{R: shade, G: shade, B: shade, A: 255})
+		}
+	}
+	return img
+}
+
+// ColorImage returns the surface colors darkened by the relief with the region overlays blended on top.
+func (m *Map) ColorImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, m.Width, m.Height))
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			i := x + y*m.Width
+			pixel := color.NRGBA{}
+
+			if !isEmpty(m.Heights[i]) {
+				light := 0.5 + 0.5*m.shade(x, y)
+				c := m.Colors[i]
+				pixel = color.NRGBA{
+					R: uint8(float32(c.R) * light),
+					G: uint8(float32(c.G) * light),
+					B: uint8(float32(c.B) * light),
+					A: 255,
+				}
+			}
+
+			if overlay, ok := regionOverlayColors[m.Regions[i]]; ok {
+				pixel = blend(pixel, overlay, overlayOpacity)
+			}
+
+			img.SetNRGBA(x, y, pixel)
+		}
+	}
+	return img
+}
+
+// shade returns the lambert term of a pixel lit from the north west at 45 degrees.
+func (m *Map) shade(x, y int) float32 {
+	dx := (m.heightAt(x+1, y, x, y) - m.heightAt(x-1, y, x, y)) / (2 * m.UnitsPerPixel)
+	dy := (m.heightAt(x, y+1, x, y) - m.heightAt(x, y-1, x, y)) / (2 * m.UnitsPerPixel)
+
+	// Image x runs east and image y runs south, so the light comes from -x, -y
+	nx, ny, nz := -dx*reliefZFactor, -dy*reliefZFactor, float32(1)
+	length := float32(math.Sqrt(float64(nx*nx + ny*ny + nz*nz)))
+	const l = 0.5
+	const lz = 0.70710678
+	shade := (-nx*l - ny*l + nz*lz) / length
+	return minFloat(maxFloat(shade, 0), 1)
+}
+
+// heightAt returns the height of a pixel, or of the fallback pixel if it is empty or outside the map.
+func (m *Map) heightAt(x, y, fallbackX, fallbackY int) float32 {
+	if x >= 0 && y >= 0 && x < m.Width && y < m.Height {
+		if h := m.Heights[x+y*m.Width]; !isEmpty(h) {
+			return h
+		}
+	}
+	return m.Heights[fallbackX+fallbackY*m.Width]
+}
+
+// blend mixes an overlay color into a pixel. Empty pixels take the overlay color at the given opacity.
+func blend(pixel, overlay color.NRGBA, opacity float32) color.NRGBA {
+	if pixel.A == 0 {
+		return color.NRGBA{R: overlay.R, G: overlay.G, B: overlay.B, A: uint8(opacity * 255)}
+	}
+	mix := func(a, b uint8) uint8 {
+		return uint8(float32(a)*(1-opacity) + float32(b)*opacity)
+	}
+	return color.NRGBA{R: mix(pixel.R, overlay.R), G: mix(pixel.G, overlay.G), B: mix(pixel.B, overlay.B), A: pixel.A}
+}
+
+// encodeGray16 splits a 16 bit value into big endian bytes.
+func encodeGray16(value uint16) (uint8, uint8) {
+	return uint8(value >> 8), uint8(value)
+}
+
+// WritePng writes an image to a PNG file, creating its directory.
+func WritePng(filePath string, img image.Image) error {
+	return writeFile(filePath, func(w io.Writer) error {
+		return png.Encode(w, img)
+	})
+}
+
+// WriteHeightExr writes the raw heights as a single channel 32 bit float OpenEXR image.
+// Empty pixels are written as NaN.
+func (m *Map) WriteHeightExr(filePath string) error {
+	return writeFile(filePath, m.encodeExr)
+}
+
+// encodeExr writes an uncompressed scanline OpenEXR image with a single FLOAT "Y" channel.
+func (m *Map) encodeExr(w io.Writer) error {
+	var header []byte
+	attribute := func(name, kind string, value []byte) {
+		header = append(header, name...)
+		header = append(header, 0)
+		header = append(header, kind...)
+		header = append(header, 0)
+		header = binary.LittleEndian.AppendUint32(header, uint32(len(value)))
+		header = append(header, value...)
+	}
+	box := func(x0, y0, x1, y1 int32) []byte {
+		var b []byte
+		for _, v := range []int32{x0, y0, x1, y1} {
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		}
+		return b
+	}
+	float := func(v float32) []byte {
+		return binary.LittleEndian.AppendUint32(nil, math.Float32bits(v))
+	}
+
+	// Channel list: name, pixel type FLOAT (2), pLinear, reserved, x and y sampling, terminator
+	channels := append([]byte("Y\x00"), 2, 0, 0, 0, 0, 0, 0, 0)
+	channels = binary.LittleEndian.AppendUint32(channels, 1)
+	channels = binary.LittleEndian.AppendUint32(channels, 1)
+	channels = append(channels, 0)
+
+	window := box(0, 0, int32(m.Width-1), int32(m.Height-1))
+	attribute("channels", "chlist", channels)
+	attribute("compression", "compression", []byte{0})
+	attribute("dataWindow", "box2i", window)
+	attribute("displayWindow", "box2i", window)
+	attribute("lineOrder", "lineOrder", []byte{0})
+	attribute("pixelAspectRatio", "float", float(1))
+	attribute("screenWindowCenter", "v2f", append(float(0), float(0)...))
+	attribute("screenWindowWidth", "float", float(1))
+	header = append(header, 0)
+
+	// Magic number and version 2 with no flags
+	out := binary.LittleEndian.AppendUint32(nil, 20000630)
+	out = binary.LittleEndian.AppendUint32(out, 2)
+	out = append(out, header...)
+
+	lineSize := m.Width * 4
+	offset := uint64(len(out) + m.Height*8)
+	for y := 0; y < m.Height; y++ {
+		out = binary.LittleEndian.AppendUint64(out, offset)
+		offset += uint64(8 + lineSize)
+	}
+
+	if _, err := w.Write(out); err != nil {
+		return err
+	}
+
+	line := make([]byte, 8+lineSize)
+	for y := 0; y < m.Height; y++ {
+		binary.LittleEndian.PutUint32(line[0:], uint32(y))
+		binary.LittleEndian.PutUint32(line[4:], uint32(lineSize))
+		for x := 0; x < m.Width; x++ {
+			binary.LittleEndian.PutUint32(line[8+x*4:], math.Float32bits(m.Heights[x+y*m.Width]))
+		}
+		if _, err := w.Write(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFile creates a file with its directory and writes it with encode.
+func writeFile(filePath string, encode func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create map directory: %w", err)
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create map file: %w", err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if err := encode(writer); err != nil {
+		return fmt.Errorf("failed to write map file %s: %w", filePath, err)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write map file %s: %w", filePath, err)
+	}
+
+	return nil
+}
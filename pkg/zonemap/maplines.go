@@ -0,0 +1,206 @@
+package zonemap
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/bsp"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// lineWeldPrecision is the grid size used to match shared polygon edges.
+const lineWeldPrecision = 0.1
+
+// MapLine is a line of an in-game map file in world coordinates.
+type MapLine struct {
+	From  datatypes.Vec3
+	To    datatypes.Vec3
+	Color color.NRGBA
+}
+
+// MapLabel is a labeled point of an in-game map file in world coordinates.
+type MapLabel struct {
+	Position datatypes.Vec3
+	Color    color.NRGBA
+	Size     int
+	Text     string
+}
+
+// MapFile holds the contents of the in-game map files of a zone.
+type MapFile struct {
+	Lines  []MapLine
+	Labels []MapLabel
+}
+
+// mapEdge tracks the polygons sharing an edge.
+type mapEdge struct {
+	from   datatypes.Vec3
+	to     datatypes.Vec3
+	count  int
+	floors int
+}
+
+// BuildMapFile traces the outlines of the floors in the height range and labels the zonelines.
+// A line is drawn where a floor ends or meets a wall.
+func BuildMapFile(meshes []*fragments.Mesh, volumes []*bsp.RegionVolume, options Options) *MapFile {
+	edges := make(map[[2][3]int64]*mapEdge)
+	var order [][2][3]int64
+
+	for _, mesh := range meshes {
+		if mesh == nil {
+			continue
+		}
+
+		forEachPolygon(mesh, func(polygon datatypes.Polygon, material *fragments.Material) {
+			if material != nil && isHiddenMaterial(material) {
+				return
+			}
+
+			vertices, ok := polygonVertices(mesh, polygon)
+			if !ok || !inZRange(vertices, options) {
+				return
+			}
+
+			normal, ok := upNormal(mesh, polygon, vertices)
+			if !ok {
+				return
+			}
+			isFloor := normal.Z >= floorNormalZ
+
+			for i := 0; i < 3; i++ {
+				from, to := vertices[i], vertices[(i+1)%3]
+				key := edgeKey(from, to)
+				if key[0] == key[1] {
+					continue
+				}
+
+				e, exists := edges[key]
+				if !exists {
+					e = &mapEdge{from: from, to: to}
+					edges[key] = e
+					order = append(order, key)
+				}
+				e.count++
+				if isFloor {
+					e.floors++
+				}
+			}
+		})
+	}
+
+	file := &MapFile{}
+	for _, key := range order {
+		e := edges[key]
+		if e.floors == 0 || (e.count > 1 && e.floors == e.count) {
+			continue
+		}
+		file.Lines = append(file.Lines, MapLine{From: e.from, To: e.to, Color: color.NRGBA{A: 255}})
+	}
+
+	for _, volume := range volumes {
+		if !volume.HasRegionType(datatypes.RegionTypeZoneline) || len(volume.Vertices) == 0 {
+			continue
+		}
+
+		center := volumeCenter(volume)
+		if center.Z < options.MinZ || center.Z > options.MaxZ {
+			continue
+		}
+
+		file.Labels = append(file.Labels, MapLabel{
+			Position: center,
+			Color:    color.NRGBA{R: 240, G: 200, B: 0, A: 255},
+			Size:     2,
+			Text:     getZonelineLabel(volume),
+		})
+	}
+
+	return file
+}
+
+// LinesText returns the line file contents. Map coordinates have the X and Y axes negated.
+func (f *MapFile) LinesText() string {
+	var sb strings.Builder
+	for _, line := range f.Lines {
+		sb.WriteString(fmt.Sprintf("L %.4f, %.4f, %.4f, %.4f, %.4f, %.4f, %d, %d, %d\n",
+			-line.From.X, -line.From.Y, line.From.Z,
+			-line.To.X, -line.To.Y, line.To.Z,
+			line.Color.R, line.Color.G, line.Color.B))
+	}
+	return sb.String()
+}
+
+// LabelsText returns the label file contents. Spaces in labels are written as underscores.
+func (f *MapFile) LabelsText() string {
+	var sb strings.Builder
+	for _, label := range f.Labels {
+		sb.WriteString(fmt.Sprintf("P %.4f, %.4f, %.4f, %d, %d, %d, %d, %s\n",
+			-label.Position.X, -label.Position.Y, label.Position.Z,
+			label.Color.R, label.Color.G, label.Color.B,
+			label.Size, strings.ReplaceAll(label.Text, " ", "_")))
+	}
+	return sb.String()
+}
+
+// WriteFiles writes the lines to <zone>.txt and the labels to <zone>_1.txt in the folder.
+func (f *MapFile) WriteFiles(folder, zoneShortname string) error {
+	files := map[string]string{
+		zoneShortname + ".txt":   f.LinesText(),
+		zoneShortname + "_1.txt": f.LabelsText(),
+	}
+
+	for name, contents := range files {
+		if err := writeFile(folder+name, func(w io.Writer) error {
+			_, err := io.WriteString(w, contents)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// edgeKey returns an order independent key of an edge with welded endpoints.
+func edgeKey(a, b datatypes.Vec3) [2][3]int64 {
+	ka, kb := weldPoint(a), weldPoint(b)
+	if ka[0] > kb[0] || (ka[0] == kb[0] && (ka[1] > kb[1] || (ka[1] == kb[1] && ka[2] > kb[2]))) {
+		ka, kb = kb, ka
+	}
+	return [2][3]int64{ka, kb}
+}
+
+// weldPoint snaps a point to the weld grid.
+func weldPoint(p datatypes.Vec3) [3]int64 {
+	return [3]int64{
+		int64(math.Round(float64(p.X) / lineWeldPrecision)),
+		int64(math.Round(float64(p.Y) / lineWeldPrecision)),
+		int64(math.Round(float64(p.Z) / lineWeldPrecision)),
+	}
+}
+
+// volumeCenter returns the center of the bounding box of a volume.
+func volumeCenter(volume *bsp.RegionVolume) datatypes.Vec3 {
+	low, high := volume.Vertices[0], volume.Vertices[0]
+	for _, v := range volume.Vertices {
+		low = datatypes.Vec3{X: minFloat(low.X, v.X), Y: minFloat(low.Y, v.Y), Z: minFloat(low.Z, v.Z)}
+		high = datatypes.Vec3{X: maxFloat(high.X, v.X), Y: maxFloat(high.Y, v.Y), Z: maxFloat(high.Z, v.Z)}
+	}
+	return datatypes.Vec3{X: (low.X + high.X) / 2, Y: (low.Y + high.Y) / 2, Z: (low.Z + high.Z) / 2}
+}
+
+// getZonelineLabel describes the destination of a zoneline volume.
+func getZonelineLabel(volume *bsp.RegionVolume) string {
+	zoneline := volume.Zoneline
+	if zoneline == nil {
+		return "Zoneline"
+	}
+	if zoneline.Type == datatypes.ZonelineTypeReference {
+		return fmt.Sprintf("Zoneline %d", zoneline.Index)
+	}
+	return fmt.Sprintf("Zoneline to zone %d", zoneline.ZoneIndex)
+}
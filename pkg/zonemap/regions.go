@@ -0,0 +1,95 @@
+package zonemap
+
+import (
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/bsp"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+)
+
+// overlayPriority orders the region types when overlays overlap. Earlier types are drawn on top.
+var overlayPriority = []datatypes.RegionType{
+	datatypes.RegionTypeZoneline,
+	datatypes.RegionTypeLava,
+	datatypes.RegionTypePvp,
+	datatypes.RegionTypeFreezingWater,
+	datatypes.RegionTypeWater,
+	datatypes.RegionTypeWaterBlockLos,
+	datatypes.RegionTypeSlippery,
+}
+
+// AddRegionOverlays marks the pixels covered by the top down footprint of the region volumes.
+// Volume triangles outside the height range of the map are ignored.
+func (m *Map) AddRegionOverlays(volumes []*bsp.RegionVolume) {
+	for _, volume := range volumes {
+		regionType := getOverlayRegionType(volume)
+		if regionType == datatypes.RegionTypeNormal {
+			continue
+		}
+
+		for _, triangle := range volume.Triangles {
+			var points [3][2]float32
+			low, high := volume.Vertices[triangle[0]].Z, volume.Vertices[triangle[0]].Z
+			for i, index := range triangle {
+				v := volume.Vertices[index]
+				points[i][0], points[i][1] = m.WorldToPixel(v.X, v.Y)
+				low, high = minFloat(low, v.Z), maxFloat(high, v.Z)
+			}
+
+			if high < m.Options.MinZ || low > m.Options.MaxZ {
+				continue
+			}
+
+			m.fillRegion(points, regionType)
+		}
+	}
+}
+
+// fillRegion marks the pixels covered by a projected triangle unless they hold a higher priority region.
+func (m *Map) fillRegion(points [3][2]float32, regionType datatypes.RegionType) {
+	a, b, c := points[0], points[1], points[2]
+	area := edge(a[0], a[1], b[0], b[1], c[0], c[1])
+	if area == 0 {
+		return
+	}
+
+	x0 := vecmath.ClampInt(int(minFloat(a[0], minFloat(b[0], c[0]))), 0, m.Width-1)
+	x1 := vecmath.ClampInt(int(maxFloat(a[0], maxFloat(b[0], c[0]))), 0, m.Width-1)
+	y0 := vecmath.ClampInt(int(minFloat(a[1], minFloat(b[1], c[1]))), 0, m.Height-1)
+	y1 := vecmath.ClampInt(int(maxFloat(a[1], maxFloat(b[1], c[1]))), 0, m.Height-1)
+
+	for py := y0; py <= y1; py++ {
+		for px := x0; px <= x1; px++ {
+			sx, sy := float32(px)+0.5, float32(py)+0.5
+			w0 := edge(b[0], b[1], c[0], c[1], sx, sy) / area
+			w1 := edge(c[0], c[1], a[0], a[1], sx, sy) / area
+			if w0 < 0 || w1 < 0 || w0+w1 > 1 {
+				continue
+			}
+
+			i := px + py*m.Width
+			if overlayRank(regionType) < overlayRank(m.Regions[i]) {
+				m.Regions[i] = regionType
+			}
+		}
+	}
+}
+
+// getOverlayRegionType returns the highest priority region type of a volume.
+func getOverlayRegionType(volume *bsp.RegionVolume) datatypes.RegionType {
+	for _, regionType := range overlayPriority {
+		if volume.HasRegionType(regionType) {
+			return regionType
+		}
+	}
+	return datatypes.RegionTypeNormal
+}
+
+// overlayRank returns the position of a region type in the overlay priority, lower is drawn on top.
+func overlayRank(regionType datatypes.RegionType) int {
+	for i, t := range overlayPriority {
+		if t == regionType {
+			return i
+		}
+	}
+	return len(overlayPriority)
+}
@@ -0,0 +1,404 @@
+// Package zonemap rasterizes zone geometry from the top down into height, relief and color maps
+// and generates in-game map line files.
+//
+// Images are oriented with north (+Y) up and east (-X) to the right, matching the in-game map.
+package zonemap
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/vecmath"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// ColorMode selects where the color image takes its colors from.
+type ColorMode int
+
+const (
+	// ColorModeTexture samples the material textures, falling back to vertex colors.
+	ColorModeTexture ColorMode = iota
+	// ColorModeVertex uses the mesh vertex colors only.
+	ColorModeVertex
+)
+
+// floorNormalZ is the minimum up component of a normal for a surface to count as a floor.
+const floorNormalZ = 0.7
+
+// alphaCutoff is the texture alpha below which a pixel is treated as a hole.
+const alphaCutoff = 128
+
+// Options configures the rasterizer.
+type Options struct {
+	// Resolution is the size of the longest image side in pixels.
+	Resolution int
+
+	// MinZ and MaxZ limit the geometry to a height range, e.g. one floor of a dungeon.
+	MinZ float32
+	MaxZ float32
+
+	// ColorMode selects the color source of the color image.
+	ColorMode ColorMode
+}
+
+// DefaultOptions returns the default rasterizer options.
+func DefaultOptions() Options {
+	return Options{
+		Resolution: 2048,
+		MinZ:       -math.MaxFloat32,
+		MaxZ:       math.MaxFloat32,
+		ColorMode:  ColorModeTexture,
+	}
+}
+
+// Map is a top-down raster of a zone.
+type Map struct {
+	// Options are the options the map was rasterized with.
+	Options Options
+
+	// Width and Height are the image size in pixels.
+	Width  int
+	Height int
+
+	// MinX, MinY, MaxX and MaxY are the world bounds covered by the map.
+	MinX float32
+	MinY float32
+	MaxX float32
+	MaxY float32
+
+	// UnitsPerPixel is the world size of a pixel.
+	UnitsPerPixel float32
+
+	// Heights holds the top surface height of each pixel, NaN where there is no geometry.
+	Heights []float32
+
+	// Colors holds the surface color of each pixel.
+	Colors []color.NRGBA
+
+	// Regions holds the special region type of each pixel, RegionTypeNormal if there is none.
+	Regions []datatypes.RegionType
+}
+
+// surfaceVertex is a projected vertex with its interpolated attributes.
+type surfaceVertex struct {
+	x, y, z float32
+	u, v    float32
+	color   [4]float32
+}
+
+// Rasterize renders the up facing visible polygons of the meshes from the top down.
+// Textures are keyed by lower case bitmap filename and may be nil.
+func Rasterize(meshes []*fragments.Mesh, textures map[string]image.Image, options Options) *Map {
+	m := newMap(meshes, options)
+	if m == nil {
+		return nil
+	}
+
+	for _, mesh := range meshes {
+		if mesh == nil {
+			continue
+		}
+
+		forEachPolygon(mesh, func(polygon datatypes.Polygon, material *fragments.Material) {
+			if material != nil && isHiddenMaterial(material) {
+				return
+			}
+
+			vertices, ok := polygonVertices(mesh, polygon)
+			if !ok || !inZRange(vertices, options) {
+				return
+			}
+
+			if normal, ok := upNormal(mesh, polygon, vertices); ok && normal.Z <= 0 {
+				return
+			}
+
+			var texture image.Image
+			if options.ColorMode == ColorModeTexture && material != nil {
+				texture = textures[getBitmapFilename(material)]
+			}
+
+			m.rasterizeTriangle(mesh, polygon, vertices, texture)
+		})
+	}
+
+	return m
+}
+
+// newMap sizes an empty map to the bounds of the geometry in the height range.
+func newMap(meshes []*fragments.Mesh, options Options) *Map {
+	minX, minY := float32(math.MaxFloat32), float32(math.MaxFloat32)
+	maxX, maxY := float32(-math.MaxFloat32), float32(-math.MaxFloat32)
+
+	for _, mesh := range meshes {
+		if mesh == nil {
+			continue
+		}
+		for _, v := range mesh.Vertices {
+			p := worldPosition(mesh, v)
+			if p.Z < options.MinZ || p.Z > options.MaxZ {
+				continue
+			}
+			minX, minY = minFloat(minX, p.X), minFloat(minY, p.Y)
+			maxX, maxY = maxFloat(maxX, p.X), maxFloat(maxY, p.Y)
+		}
+	}
+
+	if minX > maxX || minY > maxY || options.Resolution <= 0 {
+		return nil
+	}
+
+	unitsPerPixel := maxFloat(maxX-minX, maxY-minY) / float32(options.Resolution)
+	if unitsPerPixel <= 0 {
+		unitsPerPixel = 1
+	}
+
+	width := int(math.Ceil(float64((maxX-minX)/unitsPerPixel))) + 1
+	height := int(math.Ceil(float64((maxY-minY)/unitsPerPixel))) + 1
+
+	m := &Map{
+		Options:       options,
+		Width:         width,
+		Height:        height,
+		MinX:          minX,
+		MinY:          minY,
+		MaxX:          minX + float32(width)*unitsPerPixel,
+		MaxY:          minY + float32(height)*unitsPerPixel,
+		UnitsPerPixel: unitsPerPixel,
+		Heights:       make([]float32, width*height),
+		Colors:        make([]color.NRGBA, width*height),
+		Regions:       make([]datatypes.RegionType, width*height),
+	}
+
+	for i := range m.Heights {
+		m.Heights[i] = float32(math.NaN())
+	}
+
+	return m
+}
+
+// WorldToPixel converts a world position to fractional pixel coordinates.
+func (m *Map) WorldToPixel(x, y float32) (float32, float32) {
+	return (m.MaxX - x) / m.UnitsPerPixel, (m.MaxY - y) / m.UnitsPerPixel
+}
+
+// HeightRange returns the lowest and highest height in the map.
+func (m *Map) HeightRange() (float32, float32) {
+	low, high := float32(math.MaxFloat32), float32(-math.MaxFloat32)
+	for _, h := range m.Heights {
+		if isEmpty(h) {
+			continue
+		}
+		low, high = minFloat(low, h), maxFloat(high, h)
+	}
+	if low > high {
+		return 0, 0
+	}
+	return low, high
+}
+
+// rasterizeTriangle writes the triangle into the pixels where it is the top surface.
+func (m *Map) rasterizeTriangle(mesh *fragments.Mesh, polygon datatypes.Polygon, world [3]datatypes.Vec3, texture image.Image) {
+	indices := [3]int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3}
+
+	var vertices [3]surfaceVertex
+	for i, index := range indices {
+		px, py := m.WorldToPixel(world[i].X, world[i].Y)
+		vertices[i] = surfaceVertex{x: px, y: py, z: world[i].Z, color: [4]float32{128, 128, 128, 255}}
+
+		if index < len(mesh.TextureUvCoordinates) {
+			uv := mesh.TextureUvCoordinates[index]
+			vertices[i].u, vertices[i].v = uv.X, -uv.Y
+		}
+		if index < len(mesh.Colors) {
+			c := mesh.Colors[index]
+			vertices[i].color = [4]float32{float32(c.R), float32(c.G), float32(c.B), 255}
+		}
+	}
+
+	a, b, c := vertices[0], vertices[1], vertices[2]
+	area := edge(a.x, a.y, b.x, b.y, c.x, c.y)
+	if area == 0 {
+		return
+	}
+
+	x0 := vecmath.ClampInt(int(math.Floor(float64(minFloat(a.x, minFloat(b.x, c.x))))), 0, m.Width-1)
+	x1 := vecmath.ClampInt(int(math.Ceil(float64(maxFloat(a.x, maxFloat(b.x, c.x))))), 0, m.Width-1)
+	y0 := vecmath.ClampInt(int(math.Floor(float64(minFloat(a.y, minFloat(b.y, c.y))))), 0, m.Height-1)
+	y1 := vecmath.ClampInt(int(math.Ceil(float64(maxFloat(a.y, maxFloat(b.y, c.y))))), 0, m.Height-1)
+
+	for py := y0; py <= y1; py++ {
+		for px := x0; px <= x1; px++ {
+			sx, sy := float32(px)+0.5, float32(py)+0.5
+			w0 := edge(b.x, b.y, c.x, c.y, sx, sy) / area
+			w1 := edge(c.x, c.y, a.x, a.y, sx, sy) / area
+			w2 := 1 - w0 - w1
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+
+			i := px + py*m.Width
+			z := w0*a.z + w1*b.z + w2*c.z
+			if !isEmpty(m.Heights[i]) && z <= m.Heights[i] {
+				continue
+			}
+
+			pixel := interpolateColor(a.color, b.color, c.color, w0, w1, w2)
+			if texture != nil {
+				u := w0*a.u + w1*b.u + w2*c.u
+				v := w0*a.v + w1*b.v + w2*c.v
+				sample := sampleTexture(texture, u, v)
+				if sample.A < alphaCutoff {
+					continue
+				}
+				pixel = sample
+			}
+
+			m.Heights[i] = z
+			m.Colors[i] = pixel
+		}
+	}
+}
+
+// forEachPolygon calls fn for each polygon with the material of its render group.
+func forEachPolygon(mesh *fragments.Mesh, fn func(datatypes.Polygon, *fragments.Material)) {
+	polygonIndex := 0
+	for _, group := range mesh.MaterialGroups {
+		var material *fragments.Material
+		if mesh.MaterialList != nil && group.MaterialIndex >= 0 && group.MaterialIndex < len(mesh.MaterialList.Materials) {
+			material = mesh.MaterialList.Materials[group.MaterialIndex]
+		}
+
+		for i := 0; i < group.PolygonCount && polygonIndex < len(mesh.Indices); i++ {
+			fn(mesh.Indices[polygonIndex], material)
+			polygonIndex++
+		}
+	}
+
+	// Polygons not covered by a render group have no material
+	for ; polygonIndex < len(mesh.Indices); polygonIndex++ {
+		fn(mesh.Indices[polygonIndex], nil)
+	}
+}
+
+// isHiddenMaterial returns true for materials that are not drawn by the client.
+func isHiddenMaterial(material *fragments.Material) bool {
+	return material.ShaderType == fragments.ShaderTypeInvisible || material.ShaderType == fragments.ShaderTypeBoundary
+}
+
+// getBitmapFilename returns the lower case bitmap filename of a material's first frame.
+func getBitmapFilename(material *fragments.Material) string {
+	bitmapInfoRef, ok := material.BitmapInfoReference.(*fragments.BitmapInfoReference)
+	if !ok || bitmapInfoRef == nil || bitmapInfoRef.BitmapInfo == nil || len(bitmapInfoRef.BitmapInfo.BitmapNames) == 0 {
+		return ""
+	}
+	return bitmapInfoRef.BitmapInfo.BitmapNames[0].Filename
+}
+
+// polygonVertices returns the world positions of a polygon.
+func polygonVertices(mesh *fragments.Mesh, polygon datatypes.Polygon) ([3]datatypes.Vec3, bool) {
+	var vertices [3]datatypes.Vec3
+	for i, index := range [3]int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3} {
+		if index < 0 || index >= len(mesh.Vertices) {
+			return vertices, false
+		}
+		vertices[i] = worldPosition(mesh, mesh.Vertices[index])
+	}
+	return vertices, true
+}
+
+// worldPosition returns a mesh vertex in world space.
+func worldPosition(mesh *fragments.Mesh, v fragments.Vec3) datatypes.Vec3 {
+	return datatypes.Vec3{X: v.X + mesh.Center.X, Y: v.Y + mesh.Center.Y, Z: v.Z + mesh.Center.Z}
+}
+
+// inZRange returns true if any vertex of the polygon lies in the height range.
+func inZRange(vertices [3]datatypes.Vec3, options Options) bool {
+	for _, v := range vertices {
+		if v.Z >= options.MinZ && v.Z <= options.MaxZ {
+			return true
+		}
+	}
+	return false
+}
+
+// upNormal returns the unit normal of a polygon facing away from its solid side.
+// The facing is taken from the vertex normals, which are stored inverted.
+// Returns false if the polygon has no area.
+func upNormal(mesh *fragments.Mesh, polygon datatypes.Polygon, vertices [3]datatypes.Vec3) (datatypes.Vec3, bool) {
+	e1 := datatypes.Vec3{X: vertices[1].X - vertices[0].X, Y: vertices[1].Y - vertices[0].Y, Z: vertices[1].Z - vertices[0].Z}
+	e2 := datatypes.Vec3{X: vertices[2].X - vertices[0].X, Y: vertices[2].Y - vertices[0].Y, Z: vertices[2].Z - vertices[0].Z}
+	n := datatypes.Vec3{
+		X: e1.Y*e2.Z - e1.Z*e2.Y,
+		Y: e1.Z*e2.X - e1.X*e2.Z,
+		Z: e1.X*e2.Y - e1.Y*e2.X,
+	}
+
+	length := float32(math.Sqrt(float64(n.X*n.X + n.Y*n.Y + n.Z*n.Z)))
+	if length == 0 {
+		return datatypes.Vec3{}, false
+	}
+	n = datatypes.Vec3{X: n.X / length, Y: n.Y / length, Z: n.Z / length}
+
+	var facing float32
+	for _, index := range [3]int{polygon.Vertex1, polygon.Vertex2, polygon.Vertex3} {
+		if index < len(mesh.Normals) {
+			stored := mesh.Normals[index]
+			facing -= stored.X*n.X + stored.Y*n.Y + stored.Z*n.Z
+		}
+	}
+
+	if facing < 0 || (facing == 0 && n.Z < 0) {
+		n = datatypes.Vec3{X: -n.X, Y: -n.Y, Z: -n.Z}
+	}
+
+	return n, true
+}
+
+// sampleTexture returns the nearest texel at a wrapped UV coordinate.
+func sampleTexture(texture image.Image, u, v float32) color.NRGBA {
+	bounds := texture.Bounds()
+	u -= float32(math.Floor(float64(u)))
+	v -= float32(math.Floor(float64(v)))
+
+	x := bounds.Min.X + vecmath.ClampInt(int(u*float32(bounds.Dx())), 0, bounds.Dx()-1)
+	y := bounds.Min.Y + vecmath.ClampInt(int(v*float32(bounds.Dy())), 0, bounds.Dy()-1)
+	return color.NRGBAModel.Convert(texture.At(x, y)).(color.NRGBA)
+}
+
+// interpolateColor blends three vertex colors with barycentric weights.
+func interpolateColor(a, b, c [4]float32, w0, w1, w2 float32) color.NRGBA {
+	var out [4]uint8
+	for i := 0; i < 4; i++ {
+		out[i] = uint8(vecmath.ClampInt(int(a[i]*w0+b[i]*w1+c[i]*w2+0.5), 0, 255))
+	}
+	return color.NRGBA{R: out[0], G: out[1], B: out[2], A: out[3]}
+}
+
+// edge returns twice the signed area of the triangle (a, b, p).
+func edge(ax, ay, bx, by, px, py float32) float32 {
+	return (bx-ax)*(py-ay) - (by-ay)*(px-ax)
+}
+
+// isEmpty returns true if a height sample has no geometry.
+func isEmpty(h float32) bool {
+	return h != h
+}
+
+// minFloat returns the smaller of a and b.
+func minFloat(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxFloat returns the larger of a and b.
+func maxFloat(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
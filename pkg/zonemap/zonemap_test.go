@@ -0,0 +1,77 @@
+package zonemap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/datatypes"
+	"github.com/tmyhres/LanternGoExtract/pkg/wld/fragments"
+)
+
+// quad returns a square mesh at height z with the stored normals pointing along normalZ.
+func quad(center fragments.Vec3, halfSize, normalZ float32, color datatypes.Color) *fragments.Mesh {
+	return &fragments.Mesh{
+		Center: center,
+		Vertices: []fragments.Vec3{
+			{X: -halfSize, Y: -halfSize}, {X: halfSize, Y: -halfSize},
+			{X: halfSize, Y: halfSize}, {X: -halfSize, Y: halfSize},
+		},
+		Normals: []fragments.Vec3{{Z: normalZ}, {Z: normalZ}, {Z: normalZ}, {Z: normalZ}},
+		Colors:  []datatypes.Color{color, color, color, color},
+		Indices: []datatypes.Polygon{
+			{IsSolid: true, Vertex1: 0, Vertex2: 1, Vertex3: 2},
+			{IsSolid: true, Vertex1: 0, Vertex2: 2, Vertex3: 3},
+		},
+		MaterialGroups: []datatypes.RenderGroup{{PolygonCount: 2}},
+	}
+}
+
+func TestRasterize(t *testing.T) {
+	gray := datatypes.Color{R: 100, G: 100, B: 100, A: 255}
+	red := datatypes.Color{R: 255, A: 255}
+
+	// A 40x40 floor, a raised platform in the +X +Y quarter and a ceiling above it.
+	// Normals are stored inverted, so -1 faces up.
+	meshes := []*fragments.Mesh{
+		quad(fragments.Vec3{}, 20, -1, gray),
+		quad(fragments.Vec3{X: 10, Y: 10, Z: 5}, 5, -1, red),
+		quad(fragments.Vec3{X: 10, Y: 10, Z: 10}, 5, 1, gray),
+	}
+
+	options := DefaultOptions()
+	options.Resolution = 40
+	options.ColorMode = ColorModeVertex
+	m := Rasterize(meshes, nil, options)
+
+	if m == nil || m.Width != 41 || m.Height != 41 || m.UnitsPerPixel != 1 {
+		t.Fatalf("Expected a 41x41 map at 1 unit per pixel, got %+v", m)
+	}
+
+	height := func(x, y float32) (float32, int) {
+		px, py := m.WorldToPixel(x, y)
+		i := int(px) + int(py)*m.Width
+		return m.Heights[i], i
+	}
+
+	if h, i := height(10, 10); h != 5 || m.Colors[i].R != 255 || m.Colors[i].G != 0 {
+		t.Errorf("Expected the red platform at height 5, got %v %v", h, m.Colors[i])
+	}
+
+	if h, i := height(-10, -10); h != 0 || m.Colors[i].R != 100 {
+		t.Errorf("Expected the gray floor at height 0, got %v %v", h, m.Colors[i])
+	}
+
+	// +X is west, so the platform is in the top left quarter of the image
+	if px, py := m.WorldToPixel(10, 10); px >= 20 || py >= 20 {
+		t.Errorf("Expected +X +Y in the top left of the image, got %v, %v", px, py)
+	}
+
+	file := BuildMapFile(meshes, nil, options)
+	if len(file.Lines) != 8 {
+		t.Errorf("Expected the outlines of the floor and platform, got %d lines", len(file.Lines))
+	}
+
+	if text := file.LinesText(); !strings.HasPrefix(text, "L 20.0000, 20.0000, 0.0000, -20.0000, 20.0000, 0.0000, 0, 0, 0\n") {
+		t.Errorf("Unexpected line file contents:\n%s", text)
+	}
+}